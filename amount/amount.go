@@ -0,0 +1,224 @@
+package amount
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MaxDecimals is the largest number of decimals an Amount can have, since 10^19 is the largest power of ten that fits
+// in a uint64
+const MaxDecimals = 19
+
+var (
+	ErrInvalid          = errors.New("invalid amount")
+	ErrTooPrecise       = errors.New("amount has more decimal places than its token")
+	ErrOutOfRange       = errors.New("amount out of range")
+	ErrDecimalsMismatch = errors.New("amounts have different decimals")
+	ErrInexactFloat     = errors.New("amount cannot be represented exactly as a float64")
+)
+
+var pow10 = func() [MaxDecimals + 1]uint64 {
+	var p [MaxDecimals + 1]uint64
+	p[0] = 1
+	for i := 1; i < len(p); i++ {
+		p[i] = p[i-1] * 10
+	}
+	return p
+}()
+
+// Amount is an exact, non-negative fixed-point amount: a raw integer amount (e.g. lamports) together with the number
+// of decimals of its token (e.g. 9 for SOL). The zero value is 0 with no decimals.
+type Amount struct {
+	raw      uint64
+	decimals uint8
+}
+
+// New creates an amount from its raw integer value, e.g. New(1_500_000_000, 9) is 1.5 SOL
+func New(raw uint64, decimals int) (Amount, error) {
+	if decimals < 0 || decimals > MaxDecimals {
+		return Amount{}, fmt.Errorf("%w: %v decimals", ErrOutOfRange, decimals)
+	}
+	return Amount{raw: raw, decimals: uint8(decimals)}, nil
+}
+
+// Lamports creates an amount of SOL from lamports
+func Lamports(lamports uint64) Amount {
+	return Amount{raw: lamports, decimals: 9}
+}
+
+// Parse reads a decimal string such as "1.5" as an amount with the given decimals. Extra decimal places are only
+// accepted if they are zeros, so no precision is silently lost.
+func Parse(s string, decimals int) (Amount, error) {
+	if decimals < 0 || decimals > MaxDecimals {
+		return Amount{}, fmt.Errorf("%w: %v decimals", ErrOutOfRange, decimals)
+	}
+
+	intPart, fracPart, _ := strings.Cut(strings.TrimSpace(s), ".")
+	if (intPart == "" && fracPart == "") || !isDigits(intPart) || !isDigits(fracPart) {
+		return Amount{}, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	if len(fracPart) > decimals {
+		if strings.Trim(fracPart[decimals:], "0") != "" {
+			return Amount{}, fmt.Errorf("%w: %q has more than %v decimals", ErrTooPrecise, s, decimals)
+		}
+		fracPart = fracPart[:decimals]
+	}
+	digits := strings.TrimLeft(intPart+fracPart+strings.Repeat("0", decimals-len(fracPart)), "0")
+	if digits == "" {
+		return Amount{decimals: uint8(decimals)}, nil
+	}
+
+	raw, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		return Amount{}, fmt.Errorf("%w: %q", ErrOutOfRange, s)
+	}
+	return Amount{raw: raw, decimals: uint8(decimals)}, nil
+}
+
+// MustParse is like Parse, but panics on error. It's meant for constants.
+func MustParse(s string, decimals int) Amount {
+	a, err := Parse(s, decimals)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// FromFloat64 converts a float64 to the nearest amount with the given decimals
+func FromFloat64(f float64, decimals int) (Amount, error) {
+	if f < 0 {
+		return Amount{}, fmt.Errorf("%w: %v", ErrOutOfRange, f)
+	}
+	if decimals < 0 || decimals > MaxDecimals {
+		return Amount{}, fmt.Errorf("%w: %v decimals", ErrOutOfRange, decimals)
+	}
+	// FormatFloat rounds the exact binary value correctly, unlike multiplying by a power of ten
+	return Parse(strconv.FormatFloat(f, 'f', decimals, 64), decimals)
+}
+
+// Raw returns the amount in the token's smallest unit, e.g. lamports
+func (a Amount) Raw() uint64 {
+	return a.raw
+}
+
+// Decimals returns the number of decimals of the amount's token
+func (a Amount) Decimals() int {
+	return int(a.decimals)
+}
+
+func (a Amount) IsZero() bool {
+	return a.raw == 0
+}
+
+// String formats the amount exactly, without trailing zeros
+func (a Amount) String() string {
+	if a.decimals == 0 {
+		return strconv.FormatUint(a.raw, 10)
+	}
+
+	unit := pow10[a.decimals]
+	frac := strconv.FormatUint(a.raw%unit, 10)
+	frac = strings.TrimRight(strings.Repeat("0", int(a.decimals)-len(frac))+frac, "0")
+	if frac == "" {
+		return strconv.FormatUint(a.raw/unit, 10)
+	}
+	return strconv.FormatUint(a.raw/unit, 10) + "." + frac
+}
+
+// Float64 returns the float64 nearest to the amount
+func (a Amount) Float64() float64 {
+	f, _ := strconv.ParseFloat(a.String(), 64)
+	return f
+}
+
+// ExactFloat64 returns the float64 nearest to the amount, failing if it doesn't convert back to the same raw amount.
+// Requests carry amounts as float64, so this guarantees the server sees exactly this amount.
+func (a Amount) ExactFloat64() (float64, error) {
+	f := a.Float64()
+	back, err := FromFloat64(f, int(a.decimals))
+	if err != nil || back.raw != a.raw {
+		return 0, fmt.Errorf("%w: %v", ErrInexactFloat, a)
+	}
+	return f, nil
+}
+
+// Rescale converts the amount to a different number of decimals, failing if that would lose precision
+func (a Amount) Rescale(decimals int) (Amount, error) {
+	if decimals < 0 || decimals > MaxDecimals {
+		return Amount{}, fmt.Errorf("%w: %v decimals", ErrOutOfRange, decimals)
+	}
+	if decimals == int(a.decimals) {
+		return a, nil
+	}
+	if decimals < int(a.decimals) {
+		unit := pow10[int(a.decimals)-decimals]
+		if a.raw%unit != 0 {
+			return Amount{}, fmt.Errorf("%w: %v has more than %v decimals", ErrTooPrecise, a, decimals)
+		}
+		return Amount{raw: a.raw / unit, decimals: uint8(decimals)}, nil
+	}
+
+	unit := pow10[decimals-int(a.decimals)]
+	if a.raw > ^uint64(0)/unit {
+		return Amount{}, fmt.Errorf("%w: %v with %v decimals", ErrOutOfRange, a, decimals)
+	}
+	return Amount{raw: a.raw * unit, decimals: uint8(decimals)}, nil
+}
+
+// Add returns a + b. Both amounts must have the same decimals.
+func (a Amount) Add(b Amount) (Amount, error) {
+	if a.decimals != b.decimals {
+		return Amount{}, fmt.Errorf("%w: %v and %v", ErrDecimalsMismatch, a.decimals, b.decimals)
+	}
+	sum := a.raw + b.raw
+	if sum < a.raw {
+		return Amount{}, fmt.Errorf("%w: %v + %v", ErrOutOfRange, a, b)
+	}
+	return Amount{raw: sum, decimals: a.decimals}, nil
+}
+
+// Sub returns a - b. Both amounts must have the same decimals, and b can't be larger than a.
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if a.decimals != b.decimals {
+		return Amount{}, fmt.Errorf("%w: %v and %v", ErrDecimalsMismatch, a.decimals, b.decimals)
+	}
+	if b.raw > a.raw {
+		return Amount{}, fmt.Errorf("%w: %v - %v", ErrOutOfRange, a, b)
+	}
+	return Amount{raw: a.raw - b.raw, decimals: a.decimals}, nil
+}
+
+// Cmp compares the values of a and b, which may have different decimals, returning -1, 0 or 1
+func (a Amount) Cmp(b Amount) int {
+	if a.decimals == b.decimals {
+		switch {
+		case a.raw < b.raw:
+			return -1
+		case a.raw > b.raw:
+			return 1
+		}
+		return 0
+	}
+	return a.scaled(b.decimals).Cmp(b.scaled(a.decimals))
+}
+
+// scaled returns the raw amount expressed with the larger of the two decimals
+func (a Amount) scaled(other uint8) *big.Int {
+	v := new(big.Int).SetUint64(a.raw)
+	if other > a.decimals {
+		v.Mul(v, new(big.Int).SetUint64(pow10[other-a.decimals]))
+	}
+	return v
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package amount
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAndString(t *testing.T) {
+	a, err := Parse("1.5", 9)
+	require.Nil(t, err)
+	assert.Equal(t, uint64(1_500_000_000), a.Raw())
+	assert.Equal(t, "1.5", a.String())
+
+	a, err = Parse(".000000001", 9)
+	require.Nil(t, err)
+	assert.Equal(t, uint64(1), a.Raw())
+	assert.Equal(t, "0.000000001", a.String())
+
+	a, err = Parse("2.500", 1)
+	require.Nil(t, err)
+	assert.Equal(t, "2.5", a.String())
+
+	_, err = Parse("0.0000000001", 9)
+	assert.ErrorIs(t, err, ErrTooPrecise)
+
+	_, err = Parse("18446744073.709551616", 9)
+	assert.ErrorIs(t, err, ErrOutOfRange)
+
+	for _, s := range []string{"", ".", "-1", "1e5", "1.2.3"} {
+		_, err = Parse(s, 9)
+		assert.ErrorIs(t, err, ErrInvalid, s)
+	}
+}
+
+func TestFromFloat64(t *testing.T) {
+	// 0.1 + 0.2 is 0.30000000000000004 as a float64
+	a, err := FromFloat64(0.1+0.2, 9)
+	require.Nil(t, err)
+	assert.Equal(t, uint64(300_000_000), a.Raw())
+}
+
+func TestExactFloat64(t *testing.T) {
+	a := Lamports(123_456_789_123_456_789)
+	_, err := a.ExactFloat64()
+	assert.ErrorIs(t, err, ErrInexactFloat)
+
+	a = Lamports(9_007_199_123_456_789)
+	f, err := a.ExactFloat64()
+	require.Nil(t, err)
+	back, err := FromFloat64(f, 9)
+	require.Nil(t, err)
+	assert.Equal(t, a, back)
+}
+
+func TestArithmetic(t *testing.T) {
+	a := MustParse("1.25", 6)
+	b := MustParse("0.75", 6)
+
+	sum, err := a.Add(b)
+	require.Nil(t, err)
+	assert.Equal(t, "2", sum.String())
+
+	diff, err := a.Sub(b)
+	require.Nil(t, err)
+	assert.Equal(t, "0.5", diff.String())
+
+	_, err = b.Sub(a)
+	assert.ErrorIs(t, err, ErrOutOfRange)
+
+	_, err = a.Add(Lamports(1))
+	assert.ErrorIs(t, err, ErrDecimalsMismatch)
+
+	assert.Equal(t, 0, a.Cmp(MustParse("1.25", 9)))
+	assert.Equal(t, 1, a.Cmp(MustParse("1.249999999", 9)))
+
+	rescaled, err := a.Rescale(2)
+	require.Nil(t, err)
+	assert.Equal(t, uint64(125), rescaled.Raw())
+	_, err = a.Rescale(1)
+	assert.ErrorIs(t, err, ErrTooPrecise)
+}
@@ -0,0 +1,164 @@
+package backtest
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/metadata"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/bloXroute-Labs/solana-trader-proto/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// reserves of a SOL/USDC pool with 1000 SOL at price
+func reserves(at time.Duration, price float64) Event {
+	return Event{Time: start.Add(at), Reserves: &pb.PoolReserves{
+		PoolAddress:    "pool",
+		Project:        pb.Project_P_RAYDIUM,
+		Token1Address:  metadata.SOLMint,
+		Token1Reserves: "1000000000000",
+		Token2Address:  metadata.USDCMint,
+		Token2Reserves: strconv.FormatUint(uint64(price*1000*1e6), 10),
+	}}
+}
+
+func TestSwapBacktest(t *testing.T) {
+	venue, err := NewVenue(VenueOpts{
+		Balances:   map[string]float64{"USDC": 1000, "SOL": 1},
+		Latency:    time.Second,
+		SwapFeeBps: 25,
+		NetworkFee: 0.001,
+	})
+	require.Nil(t, err)
+
+	bought := false
+	strategy := StrategyFunc(func(ctx context.Context, client Client, event Event) error {
+		if bought {
+			return nil
+		}
+		bought = true
+		resp, err := client.PostTradeSwap(ctx, "owner", "USDC", "SOL", 1000, 1, pb.Project_P_ALL)
+		require.Nil(t, err)
+		assert.InDelta(t, 9.87, resp.OutAmount, 0.01)
+		return nil
+	})
+
+	events := FromSlice([]Event{reserves(0, 100), reserves(2*time.Second, 100), reserves(3*time.Second, 120)})
+	report, err := NewEngine(venue, strategy).Run(context.Background(), events)
+	require.Nil(t, err)
+
+	assert.Equal(t, 3, report.Events)
+	assert.Equal(t, 1, report.Submitted)
+	assert.Equal(t, 1, report.Landed)
+	assert.Equal(t, time.Second, report.Latency.Max)
+	assert.InDelta(t, 0, report.Final[metadata.USDCMint], 1e-9)
+	assert.InDelta(t, 1-0.001+9.87, report.Final[metadata.SOLMint], 0.01)
+	assert.InDelta(t, 2.5, report.Fees[metadata.USDCMint], 1e-9)
+	// bought ~9.87 SOL for 1000 USDC and the price rose to 120
+	assert.InDelta(t, 9.87*120-1000-0.12, report.PnL, 1)
+}
+
+func TestOrderBacktest(t *testing.T) {
+	market := metadata.Market{Name: "SOL/USDC", BaseMint: metadata.SOLMint, QuoteMint: metadata.USDCMint}
+	venue, err := NewVenue(VenueOpts{Balances: map[string]float64{"USDC": 1000}, Markets: []metadata.Market{market}, MakerFeeBps: 10})
+	require.Nil(t, err)
+
+	book := func(at time.Duration, bid, ask float64) Event {
+		return Event{Time: start.Add(at), Orderbook: &pb.GetOrderbookResponse{
+			Market: "SOL/USDC",
+			Bids:   []*pb.OrderbookItem{{Price: bid, Size: 10}},
+			Asks:   []*pb.OrderbookItem{{Price: ask, Size: 3}},
+		}}
+	}
+
+	var fills []Fill
+	strategy := &orderStrategy{fills: &fills}
+	report, err := NewEngine(venue, strategy).Run(context.Background(), FromSlice([]Event{book(0, 99, 101), book(time.Second, 97, 99)}))
+	require.Nil(t, err)
+
+	require.Len(t, fills, 1)
+	assert.True(t, fills[0].Maker)
+	assert.Equal(t, 100.0, fills[0].Price)
+	assert.Equal(t, 3.0, fills[0].Quantity)
+
+	assert.Equal(t, 3.0, report.Final[metadata.SOLMint])
+	// 2 SOL are still bid for at 100
+	assert.InDelta(t, 1000-500-0.3, venue.Balance("USDC"), 1e-9)
+	balance, err := venue.GetAccountBalance(context.Background(), "owner")
+	require.Nil(t, err)
+	for _, token := range balance.Tokens {
+		if token.TokenMint == metadata.USDCMint {
+			assert.InDelta(t, 200, token.OpenOrdersAmount, 1e-9)
+			assert.InDelta(t, 1000-500-0.3, token.SettledAmount, 1e-9)
+		}
+	}
+}
+
+func TestOrderSlippage(t *testing.T) {
+	market := metadata.Market{Name: "SOL/USDC", BaseMint: metadata.SOLMint, QuoteMint: metadata.USDCMint}
+	venue, err := NewVenue(VenueOpts{Balances: map[string]float64{"USDC": 1000}, Markets: []metadata.Market{market}, Slippage: 1})
+	require.Nil(t, err)
+
+	events := FromSlice([]Event{
+		{Time: start, Orderbook: &pb.GetOrderbookResponse{
+			Market: "SOL/USDC",
+			Asks:   []*pb.OrderbookItem{{Price: 98, Size: 1}, {Price: 99.8, Size: 1}},
+		}},
+		{Time: start.Add(time.Second), Orderbook: &pb.GetOrderbookResponse{
+			Market: "SOL/USDC",
+			Asks:   []*pb.OrderbookItem{{Price: 99, Size: 3}},
+		}},
+	})
+	var fills []Fill
+	_, err = NewEngine(venue, &orderStrategy{fills: &fills}).Run(context.Background(), events)
+	require.Nil(t, err)
+
+	// fills slip, but never past the bid's limit of 100
+	require.Len(t, fills, 3)
+	assert.False(t, fills[0].Maker)
+	assert.InDelta(t, 98.98, fills[0].Price, 1e-9)
+	assert.Equal(t, 100.0, fills[1].Price)
+	assert.True(t, fills[2].Maker)
+	assert.Equal(t, 100.0, fills[2].Price)
+	assert.InDelta(t, 1000-98.98-100-300, venue.Balance("USDC"), 1e-9)
+}
+
+type orderStrategy struct {
+	placed bool
+	fills  *[]Fill
+}
+
+func (s *orderStrategy) OnEvent(ctx context.Context, client Client, _ Event) error {
+	if s.placed {
+		return nil
+	}
+	s.placed = true
+	_, err := client.PostOrder(ctx, "owner", "owner", "SOL/USDC", pb.Side_S_BID, []common.OrderType{common.OrderType_OT_LIMIT}, 5, 100, pb.Project_P_OPENBOOK, provider.PostOrderOpts{})
+	return err
+}
+
+func (s *orderStrategy) OnFill(_ context.Context, _ Client, fill Fill) error {
+	*s.fills = append(*s.fills, fill)
+	return nil
+}
+
+func TestDroppedTransactions(t *testing.T) {
+	venue, err := NewVenue(VenueOpts{Balances: map[string]float64{"USDC": 1000}, DropRate: 1})
+	require.Nil(t, err)
+
+	strategy := StrategyFunc(func(ctx context.Context, client Client, event Event) error {
+		_, err := client.PostTradeSwap(ctx, "owner", "USDC", "SOL", 10, 1, pb.Project_P_ALL)
+		return err
+	})
+	report, err := NewEngine(venue, strategy).Run(context.Background(), FromSlice([]Event{reserves(0, 100)}))
+	require.Nil(t, err)
+	assert.Equal(t, 1, report.Dropped)
+	assert.ErrorIs(t, report.Executions[0].Err, ErrNotLanded)
+	assert.Equal(t, 1000.0, report.Final[metadata.USDCMint])
+}
@@ -0,0 +1,291 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/metadata"
+)
+
+// Strategy is called with each market data event, after the venue applied it. Strategies implementing
+// ExecutionHandler or FillHandler are also notified of landed transactions and fills of resting orders.
+type Strategy interface {
+	OnEvent(ctx context.Context, client Client, event Event) error
+}
+
+type ExecutionHandler interface {
+	OnExecution(ctx context.Context, client Client, execution Execution) error
+}
+
+type FillHandler interface {
+	OnFill(ctx context.Context, client Client, fill Fill) error
+}
+
+// StrategyFunc is a Strategy that only handles events
+type StrategyFunc func(ctx context.Context, client Client, event Event) error
+
+func (f StrategyFunc) OnEvent(ctx context.Context, client Client, event Event) error {
+	return f(ctx, client, event)
+}
+
+// Engine feeds market data to a strategy and its simulated venue
+type Engine struct {
+	venue    *Venue
+	strategy Strategy
+}
+
+func NewEngine(venue *Venue, strategy Strategy) *Engine {
+	return &Engine{venue: venue, strategy: strategy}
+}
+
+// Run replays events until they end (io.EOF) and reports the results. Transactions still pending at the end fail with
+// ErrNoMarketData.
+func (e *Engine) Run(ctx context.Context, events connections.Streamer[Event]) (*Report, error) {
+	var (
+		start, end time.Time
+		count      int
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		event, err := events()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read market data: %w", err)
+		}
+		if event.Time.Before(end) {
+			return nil, fmt.Errorf("market data is out of order: event at %v after %v", event.Time, end)
+		}
+		if start.IsZero() {
+			start = event.Time
+		}
+		end = event.Time
+		count++
+
+		// transactions land against the state before the event
+		if err = e.land(ctx, event.Time); err != nil {
+			return nil, err
+		}
+		for _, fill := range e.venue.apply(event) {
+			if err = e.notifyFill(ctx, fill); err != nil {
+				return nil, err
+			}
+		}
+		if err = e.strategy.OnEvent(ctx, e.venue, event); err != nil {
+			return nil, fmt.Errorf("strategy failed at %v: %w", event.Time, err)
+		}
+		// transactions without latency land right away
+		if err = e.land(ctx, event.Time); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, execution := range e.venue.expire() {
+		if err := e.notifyExecution(ctx, execution); err != nil {
+			return nil, err
+		}
+	}
+	return e.venue.report(start, end, count), nil
+}
+
+// land lands the transactions due by t, including those submitted by the strategy's handlers
+func (e *Engine) land(ctx context.Context, t time.Time) error {
+	for {
+		fills := len(e.venue.Fills())
+		landed := e.venue.advance(t)
+		if len(landed) == 0 {
+			return nil
+		}
+		for _, execution := range landed {
+			if err := e.notifyExecution(ctx, execution); err != nil {
+				return err
+			}
+		}
+		for _, fill := range e.venue.Fills()[fills:] {
+			if err := e.notifyFill(ctx, fill); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (e *Engine) notifyExecution(ctx context.Context, execution Execution) error {
+	if handler, ok := e.strategy.(ExecutionHandler); ok {
+		if err := handler.OnExecution(ctx, e.venue, execution); err != nil {
+			return fmt.Errorf("strategy failed on execution %v: %w", execution.ID, err)
+		}
+	}
+	return nil
+}
+
+func (e *Engine) notifyFill(ctx context.Context, fill Fill) error {
+	if handler, ok := e.strategy.(FillHandler); ok {
+		if err := handler.OnFill(ctx, e.venue, fill); err != nil {
+			return fmt.Errorf("strategy failed on fill of order %v: %w", fill.OrderID, err)
+		}
+	}
+	return nil
+}
+
+type LatencyStats struct {
+	Count int
+	Mean  time.Duration
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// Report summarizes a backtest. Values are in the venue's quote mint, at the prices at the end of the data.
+type Report struct {
+	Start  time.Time
+	End    time.Time
+	Events int
+
+	Executions []Execution
+	Fills      []Fill
+	Submitted  int
+	// Landed transactions executed, Failed ones landed with an error (e.g. slippage) and Dropped ones didn't land
+	Landed  int
+	Failed  int
+	Dropped int
+	Latency LatencyStats
+
+	// Initial and Final balances by mint, including funds locked in open orders
+	Initial map[string]float64
+	Final   map[string]float64
+	// Fees paid by mint, including network fees
+	Fees map[string]float64
+
+	QuoteMint string
+	// FinalValue of the final balances and HoldValue of the initial balances
+	FinalValue float64
+	HoldValue  float64
+	// PnL is the value gained over holding the initial balances
+	PnL float64
+	// Unpriced are the mints without a price, excluded from the values
+	Unpriced []string
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v events from %v to %v\n", r.Events, r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339))
+	fmt.Fprintf(&b, "transactions: %v submitted, %v landed, %v failed, %v dropped\n", r.Submitted, r.Landed, r.Failed, r.Dropped)
+	fmt.Fprintf(&b, "fills: %v\n", len(r.Fills))
+	fmt.Fprintf(&b, "latency: mean %v, p50 %v, p90 %v, p99 %v, max %v\n", r.Latency.Mean, r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max)
+	fmt.Fprintf(&b, "value: %.6f (hold %.6f), pnl %.6f\n", r.FinalValue, r.HoldValue, r.PnL)
+	if len(r.Unpriced) > 0 {
+		fmt.Fprintf(&b, "unpriced: %v\n", strings.Join(r.Unpriced, ", "))
+	}
+	return b.String()
+}
+
+func (v *Venue) report(start, end time.Time, events int) *Report {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	r := &Report{
+		Start:      start,
+		End:        end,
+		Events:     events,
+		Executions: append([]Execution(nil), v.executions...),
+		Fills:      append([]Fill(nil), v.fills...),
+		Submitted:  len(v.executions),
+		Initial:    make(map[string]float64),
+		Final:      make(map[string]float64),
+		Fees:       make(map[string]float64),
+		QuoteMint:  v.opts.QuoteMint,
+	}
+
+	var latencies []time.Duration
+	for _, execution := range v.executions {
+		switch {
+		case !execution.Landed:
+			r.Dropped++
+			continue
+		case execution.Err != nil:
+			r.Failed++
+		default:
+			r.Landed++
+		}
+		latencies = append(latencies, execution.Latency())
+		r.Fees[metadata.SOLMint] += v.opts.NetworkFee
+
+		switch execution.Kind {
+		case KindSwap:
+			r.Fees[execution.InMint] += execution.Fee
+		case KindPumpFun:
+			r.Fees[metadata.SOLMint] += execution.Fee
+		}
+	}
+	for _, fill := range v.fills {
+		if market, ok := v.market(fill.Market); ok {
+			r.Fees[market.QuoteMint] += fill.Fee
+		}
+	}
+	r.Latency = latencyStats(latencies)
+
+	for mint, balance := range v.initial {
+		r.Initial[mint] = balance
+	}
+	for mint, balance := range v.balances {
+		r.Final[mint] += balance
+	}
+	for mint, locked := range v.locked {
+		r.Final[mint] += locked
+	}
+
+	unpriced := make(map[string]bool)
+	value := func(balances map[string]float64) float64 {
+		total := 0.0
+		for mint, balance := range balances {
+			if balance == 0 {
+				continue
+			}
+			price, ok := v.price(mint)
+			if !ok {
+				unpriced[mint] = true
+				continue
+			}
+			total += balance * price
+		}
+		return total
+	}
+	r.FinalValue = value(r.Final)
+	r.HoldValue = value(r.Initial)
+	r.PnL = r.FinalValue - r.HoldValue
+	for mint := range unpriced {
+		r.Unpriced = append(r.Unpriced, mint)
+	}
+	sort.Strings(r.Unpriced)
+	return r
+}
+
+func latencyStats(latencies []time.Duration) LatencyStats {
+	stats := LatencyStats{Count: len(latencies)}
+	if len(latencies) == 0 {
+		return stats
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	percentile := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+	stats.Mean = total / time.Duration(len(latencies))
+	stats.P50, stats.P90, stats.P99 = percentile(0.5), percentile(0.9), percentile(0.99)
+	stats.Max = latencies[len(latencies)-1]
+	return stats
+}
@@ -0,0 +1,109 @@
+package backtest
+
+import (
+	"io"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/recorder"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// Event is a market data update. Exactly one of Reserves, Orderbook and PumpFunSwap is set.
+type Event struct {
+	Time time.Time
+	Slot uint64
+	// Reserves of an AMM pool, in raw token units
+	Reserves *pb.PoolReserves
+	// Orderbook of an Openbook market
+	Orderbook *pb.GetOrderbookResponse
+	// PumpFunSwap carries the virtual reserves of a bonding curve after a swap
+	PumpFunSwap *pb.GetPumpFunSwapsStreamResponse
+}
+
+// EventFrom converts a pool reserves, orderbook or Pump.fun swaps stream update received at receivedAt. Other updates
+// aren't market data and return false.
+func EventFrom(msg proto.Message, receivedAt time.Time) (Event, bool) {
+	event := Event{Time: receivedAt}
+	switch m := msg.(type) {
+	case *pb.GetPoolReservesStreamResponse:
+		if m.Reserves == nil {
+			return event, false
+		}
+		event.Slot, event.Reserves = uint64(m.Slot), m.Reserves
+	case *pb.GetOrderbooksStreamResponse:
+		if m.Orderbook == nil {
+			return event, false
+		}
+		event.Slot, event.Orderbook = uint64(m.Slot), m.Orderbook
+	case *pb.GetPumpFunSwapsStreamResponse:
+		event.Slot, event.PumpFunSwap = uint64(m.Slot), m
+	default:
+		return event, false
+	}
+	return event, true
+}
+
+// FromRecords converts replayed recordings to events, timed when the updates were received. Updates that aren't market
+// data are skipped.
+func FromRecords[T proto.Message](records connections.Streamer[recorder.Record[T]]) connections.Streamer[Event] {
+	return func() (Event, error) {
+		for {
+			record, err := records()
+			if err != nil {
+				return Event{}, err
+			}
+			if event, ok := EventFrom(record.Message, record.ReceivedAt); ok {
+				return event, nil
+			}
+		}
+	}
+}
+
+// FromSlice streams synthetic events, returning io.EOF after the last one
+func FromSlice(events []Event) connections.Streamer[Event] {
+	i := 0
+	return func() (Event, error) {
+		if i >= len(events) {
+			return Event{}, io.EOF
+		}
+		i++
+		return events[i-1], nil
+	}
+}
+
+// Sequence merges event streams in time order, assuming each stream is ordered. It ends with the first error of a
+// stream other than io.EOF, or io.EOF once all streams ended.
+func Sequence(streams ...connections.Streamer[Event]) connections.Streamer[Event] {
+	heads := make([]*Event, len(streams))
+	done := make([]bool, len(streams))
+	return func() (Event, error) {
+		next := -1
+		for i, stream := range streams {
+			if done[i] {
+				continue
+			}
+			if heads[i] == nil {
+				event, err := stream()
+				if err == io.EOF {
+					done[i] = true
+					continue
+				}
+				if err != nil {
+					return Event{}, err
+				}
+				heads[i] = &event
+			}
+			if next < 0 || heads[i].Time.Before(heads[next].Time) {
+				next = i
+			}
+		}
+		if next < 0 {
+			return Event{}, io.EOF
+		}
+		event := *heads[next]
+		heads[next] = nil
+		return event, nil
+	}
+}
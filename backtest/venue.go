@@ -0,0 +1,833 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/metadata"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/bloXroute-Labs/solana-trader-proto/common"
+)
+
+const (
+	openOrdersAddress = "backtest-open-orders"
+	// Pump.fun tokens have 6 decimals
+	pumpFunTokenUnits = 1e6
+	lamportsPerSOL    = 1e9
+)
+
+var (
+	ErrNoLiquidity       = errors.New("no liquidity")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrSlippageExceeded  = errors.New("slippage exceeded")
+	ErrNotLanded         = errors.New("transaction did not land")
+	ErrNoMarketData      = errors.New("no market data after submission")
+	ErrUnknownMarket     = errors.New("unknown market")
+	ErrWouldCross        = errors.New("post only order would cross the book")
+	ErrInvalidOrder      = errors.New("invalid order")
+)
+
+// Client is the part of the client API simulated by the venue. It's implemented by the GRPC client, so strategies
+// written against it run both live and in backtests. Unlike the API, the venue submits transactions as soon as they
+// are posted.
+type Client interface {
+	PostTradeSwap(ctx context.Context, ownerAddress, inToken, outToken string, inAmount, slippage float64, project pb.Project) (*pb.TradeSwapResponse, error)
+	PostRaydiumSwap(ctx context.Context, request *pb.PostRaydiumSwapRequest) (*pb.PostRaydiumSwapResponse, error)
+	PostPumpFunSwap(ctx context.Context, request *pb.PostPumpFunSwapRequest) (*pb.PostPumpFunSwapResponse, error)
+	PostOrder(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts provider.PostOrderOpts) (*pb.PostOrderResponse, error)
+	GetAccountBalance(ctx context.Context, owner string) (*pb.GetAccountBalanceResponse, error)
+}
+
+type VenueOpts struct {
+	// Balances are the initial balances of the simulated wallet, by mint or symbol
+	Balances map[string]float64
+	// Registry resolves symbols and token decimals (default well known tokens only, add others with SetToken)
+	Registry *metadata.Registry
+	// Markets are the Openbook markets orders can be placed on, in addition to the registry's
+	Markets []metadata.Market
+	// QuoteMint values the wallet in reports (default USDC)
+	QuoteMint string
+	// Latency between a submission and its landing, plus a uniformly random LatencyJitter
+	Latency       time.Duration
+	LatencyJitter time.Duration
+	// DropRate is the probability that a transaction doesn't land
+	DropRate float64
+	// Slippage is an adverse price move in percent applied to every fill, on top of the simulated price impact. Order
+	// fills never slip past the limit price.
+	Slippage float64
+	// SwapFeeBps is the AMM fee charged on swap inputs, and PumpFunFeeBps the bonding curve fee on SOL amounts
+	SwapFeeBps    float64
+	PumpFunFeeBps float64
+	// TakerFeeBps and MakerFeeBps are charged on the quote amount of Openbook fills
+	TakerFeeBps float64
+	MakerFeeBps float64
+	// NetworkFee in SOL is charged for every landed transaction
+	NetworkFee float64
+	// Seed of the random latency jitter and landing (default 1)
+	Seed int64
+}
+
+type ExecutionKind string
+
+const (
+	KindSwap    ExecutionKind = "swap"
+	KindPumpFun ExecutionKind = "pumpfun"
+	KindOrder   ExecutionKind = "order"
+)
+
+// Execution is a simulated transaction. For orders, In is the amount locked when the order landed and Out the amount
+// received by its immediate fills; later fills are reported as Fills.
+type Execution struct {
+	ID          string
+	Kind        ExecutionKind
+	Market      string
+	InMint      string
+	InAmount    float64
+	OutMint     string
+	OutAmount   float64
+	ExpectedOut float64
+	Fee         float64
+	SubmittedAt time.Time
+	LandedAt    time.Time
+	Landed      bool
+	Err         error
+}
+
+// Latency between the submission and landing of the execution
+func (e Execution) Latency() time.Duration {
+	return e.LandedAt.Sub(e.SubmittedAt)
+}
+
+// Fill is a simulated Openbook fill
+type Fill struct {
+	OrderID  string
+	Market   string
+	Side     string
+	Price    float64
+	Quantity float64
+	// Fee in quote tokens
+	Fee   float64
+	Maker bool
+	Time  time.Time
+}
+
+type pool struct {
+	project  pb.Project
+	mints    [2]string
+	reserves [2]float64
+}
+
+// quote returns the output and fee of swapping amount of mint in the pool
+func (p *pool) quote(mint string, amount, feeBps float64) (float64, float64, bool) {
+	in := 0
+	if p.mints[1] == mint {
+		in = 1
+	} else if p.mints[0] != mint {
+		return 0, 0, false
+	}
+	if p.reserves[0] <= 0 || p.reserves[1] <= 0 {
+		return 0, 0, false
+	}
+	fee := amount * feeBps / 10000
+	net := amount - fee
+	return p.reserves[1-in] * net / (p.reserves[in] + net), fee, true
+}
+
+func (p *pool) other(mint string) string {
+	if p.mints[0] == mint {
+		return p.mints[1]
+	}
+	return p.mints[0]
+}
+
+type level struct {
+	price float64
+	size  float64
+}
+
+type book struct {
+	bids []level
+	asks []level
+}
+
+type curve struct {
+	sol    float64
+	tokens float64
+}
+
+type restingOrder struct {
+	id        string
+	market    metadata.Market
+	side      pb.Side
+	price     float64
+	remaining float64
+}
+
+type pendingTx struct {
+	index int
+	due   time.Time
+	run   func(exec *Execution) error
+}
+
+// Venue simulates the execution of swaps and orders against recorded market data. It's driven by an Engine.
+type Venue struct {
+	opts     VenueOpts
+	registry *metadata.Registry
+	markets  map[string]metadata.Market
+	initial  map[string]float64
+
+	m          sync.Mutex
+	rand       *rand.Rand
+	now        time.Time
+	balances   map[string]float64
+	locked     map[string]float64
+	pools      map[string]*pool
+	books      map[string]*book
+	curves     map[string]*curve
+	orders     []*restingOrder
+	pending    []pendingTx
+	executions []Execution
+	fills      []Fill
+}
+
+func NewVenue(opts VenueOpts) (*Venue, error) {
+	if opts.Registry == nil {
+		opts.Registry = metadata.NewRegistry(nil, metadata.Opts{SkipPools: true})
+	}
+	if opts.QuoteMint == "" {
+		opts.QuoteMint = metadata.USDCMint
+	}
+	if opts.Seed == 0 {
+		opts.Seed = 1
+	}
+
+	v := &Venue{
+		opts:     opts,
+		registry: opts.Registry,
+		markets:  make(map[string]metadata.Market),
+		initial:  make(map[string]float64),
+		rand:     rand.New(rand.NewSource(opts.Seed)),
+		balances: make(map[string]float64),
+		locked:   make(map[string]float64),
+		pools:    make(map[string]*pool),
+		books:    make(map[string]*book),
+		curves:   make(map[string]*curve),
+	}
+	for _, market := range opts.Markets {
+		v.markets[market.Name] = market
+		v.markets[market.Address] = market
+	}
+	for token, balance := range opts.Balances {
+		mint, err := v.resolve(token)
+		if err != nil {
+			return nil, err
+		}
+		v.balances[mint] += balance
+		v.initial[mint] += balance
+	}
+	return v, nil
+}
+
+// Now returns the simulated time, i.e. the time of the latest event
+func (v *Venue) Now() time.Time {
+	v.m.Lock()
+	defer v.m.Unlock()
+	return v.now
+}
+
+// Balance returns the free balance of a token, excluding the funds locked in open orders
+func (v *Venue) Balance(mintOrSymbol string) float64 {
+	mint, err := v.resolve(mintOrSymbol)
+	if err != nil {
+		return 0
+	}
+
+	v.m.Lock()
+	defer v.m.Unlock()
+	return v.balances[mint]
+}
+
+// Executions returns the simulated transactions so far
+func (v *Venue) Executions() []Execution {
+	v.m.Lock()
+	defer v.m.Unlock()
+	return append([]Execution(nil), v.executions...)
+}
+
+// Fills returns the simulated Openbook fills so far
+func (v *Venue) Fills() []Fill {
+	v.m.Lock()
+	defer v.m.Unlock()
+	return append([]Fill(nil), v.fills...)
+}
+
+// PostTradeSwap swaps through the pool with the best output among those of the project (all projects for P_ALL or
+// P_JUPITER)
+func (v *Venue) PostTradeSwap(_ context.Context, _, inToken, outToken string, inAmount, slippage float64, project pb.Project) (*pb.TradeSwapResponse, error) {
+	exec, err := v.postSwap(inToken, outToken, inAmount, slippage, project)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.TradeSwapResponse{
+		Project:      project,
+		Transactions: []*pb.TransactionMessage{{Content: exec.ID}},
+		OutAmount:    exec.ExpectedOut,
+		OutAmountMin: exec.ExpectedOut * (1 - slippage/100),
+	}, nil
+}
+
+// PostRaydiumSwap swaps through the Raydium pool with the best output
+func (v *Venue) PostRaydiumSwap(_ context.Context, request *pb.PostRaydiumSwapRequest) (*pb.PostRaydiumSwapResponse, error) {
+	exec, err := v.postSwap(request.InToken, request.OutToken, request.InAmount, request.Slippage, pb.Project_P_RAYDIUM)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PostRaydiumSwapResponse{
+		Transactions: []*pb.TransactionMessage{{Content: exec.ID}},
+		OutAmount:    exec.ExpectedOut,
+		OutAmountMin: exec.ExpectedOut * (1 - request.Slippage/100),
+	}, nil
+}
+
+// PostPumpFunSwap buys or sells TokenAmount tokens on the token's bonding curve, failing if the SOL amount is beyond
+// SolThreshold
+func (v *Venue) PostPumpFunSwap(_ context.Context, request *pb.PostPumpFunSwapRequest) (*pb.PostPumpFunSwapResponse, error) {
+	if request.TokenAmount <= 0 {
+		return nil, fmt.Errorf("%w: token amount must be positive", ErrInvalidOrder)
+	}
+
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	c, ok := v.curves[request.TokenAddress]
+	if !ok {
+		return nil, fmt.Errorf("%w: no bonding curve data for %v", ErrNoLiquidity, request.TokenAddress)
+	}
+	exec := Execution{Kind: KindPumpFun, Market: request.TokenAddress}
+	sol, _, _, ok := v.pumpFunQuote(c, request.TokenAmount, request.IsBuy)
+	if !ok {
+		return nil, fmt.Errorf("%w: bonding curve of %v", ErrNoLiquidity, request.TokenAddress)
+	}
+	if request.IsBuy {
+		exec.InMint, exec.InAmount = metadata.SOLMint, sol
+		exec.OutMint, exec.OutAmount, exec.ExpectedOut = request.TokenAddress, 0, request.TokenAmount
+	} else {
+		exec.InMint, exec.InAmount = request.TokenAddress, request.TokenAmount
+		exec.OutMint, exec.ExpectedOut = metadata.SOLMint, sol
+	}
+
+	tokenAmount, threshold, isBuy := request.TokenAmount, request.SolThreshold, request.IsBuy
+	exec = v.schedule(exec, func(exec *Execution) error {
+		c := v.curves[exec.Market]
+		sol, fee, moved, ok := v.pumpFunQuote(c, tokenAmount, isBuy)
+		if !ok {
+			return ErrNoLiquidity
+		}
+		exec.Fee = fee
+		if isBuy {
+			if sol > threshold {
+				return fmt.Errorf("%w: costs %v SOL, more than %v", ErrSlippageExceeded, sol, threshold)
+			}
+			if v.balances[metadata.SOLMint] < sol {
+				return ErrInsufficientFunds
+			}
+			exec.InAmount, exec.OutAmount = sol, tokenAmount
+			c.sol += moved
+			c.tokens -= tokenAmount
+		} else {
+			if sol < threshold {
+				return fmt.Errorf("%w: returns %v SOL, less than %v", ErrSlippageExceeded, sol, threshold)
+			}
+			if v.balances[exec.InMint] < tokenAmount {
+				return ErrInsufficientFunds
+			}
+			exec.OutAmount = sol
+			c.sol -= moved
+			c.tokens += tokenAmount
+		}
+		v.balances[exec.InMint] -= exec.InAmount
+		v.balances[exec.OutMint] += exec.OutAmount
+		return nil
+	})
+	return &pb.PostPumpFunSwapResponse{Transaction: &pb.TransactionMessageV2{Content: exec.ID}}, nil
+}
+
+// PostOrder places an Openbook order. Marketable quantity fills against the book when the order lands, and the
+// remainder of limit orders rests until the book trades through its price.
+func (v *Venue) PostOrder(_ context.Context, _, _, marketName string, side pb.Side, types []common.OrderType, amount, price float64, _ pb.Project, _ provider.PostOrderOpts) (*pb.PostOrderResponse, error) {
+	market, ok := v.market(marketName)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownMarket, marketName)
+	}
+	if amount <= 0 || price <= 0 || (side != pb.Side_S_BID && side != pb.Side_S_ASK) {
+		return nil, fmt.Errorf("%w: %v %v at %v", ErrInvalidOrder, side, amount, price)
+	}
+
+	var immediate, postOnly bool
+	for _, t := range types {
+		switch t {
+		case common.OrderType_OT_MARKET, common.OrderType_OT_IOC:
+			immediate = true
+		case common.OrderType_OT_POST:
+			postOnly = true
+		}
+	}
+
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	exec := Execution{Kind: KindOrder, Market: market.Name, ExpectedOut: amount}
+	if side == pb.Side_S_BID {
+		exec.InMint, exec.InAmount, exec.OutMint = market.QuoteMint, amount*price, market.BaseMint
+	} else {
+		exec.InMint, exec.InAmount, exec.OutMint, exec.ExpectedOut = market.BaseMint, amount, market.QuoteMint, amount*price
+	}
+
+	exec = v.schedule(exec, func(exec *Execution) error {
+		if v.balances[exec.InMint] < exec.InAmount {
+			return ErrInsufficientFunds
+		}
+		order := &restingOrder{id: exec.ID, market: market, side: side, price: price, remaining: amount}
+		if postOnly && v.crosses(order) {
+			return ErrWouldCross
+		}
+
+		v.balances[exec.InMint] -= exec.InAmount
+		v.locked[exec.InMint] += exec.InAmount
+		exec.OutAmount, exec.Fee = v.match(order, false)
+		switch {
+		case order.remaining <= 0:
+		case immediate:
+			v.cancel(order)
+		default:
+			v.orders = append(v.orders, order)
+		}
+		return nil
+	})
+	return &pb.PostOrderResponse{Transaction: &pb.TransactionMessage{Content: exec.ID}, OpenOrdersAddress: openOrdersAddress}, nil
+}
+
+// GetAccountBalance reports the simulated wallet, with the funds locked in open orders
+func (v *Venue) GetAccountBalance(context.Context, string) (*pb.GetAccountBalanceResponse, error) {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	mints := make(map[string]bool)
+	for mint := range v.balances {
+		mints[mint] = true
+	}
+	for mint := range v.locked {
+		mints[mint] = true
+	}
+
+	resp := &pb.GetAccountBalanceResponse{}
+	for mint := range mints {
+		balance := &pb.TokenBalance{TokenMint: mint, SettledAmount: v.balances[mint], OpenOrdersAmount: v.locked[mint]}
+		if token, err := v.registry.Token(mint); err == nil {
+			balance.Symbol = token.Symbol
+		}
+		resp.Tokens = append(resp.Tokens, balance)
+	}
+	sort.Slice(resp.Tokens, func(i, j int) bool { return resp.Tokens[i].TokenMint < resp.Tokens[j].TokenMint })
+	return resp, nil
+}
+
+func (v *Venue) postSwap(inToken, outToken string, inAmount, slippage float64, project pb.Project) (Execution, error) {
+	inMint, err := v.resolve(inToken)
+	if err != nil {
+		return Execution{}, err
+	}
+	outMint, err := v.resolve(outToken)
+	if err != nil {
+		return Execution{}, err
+	}
+	if inAmount <= 0 {
+		return Execution{}, fmt.Errorf("%w: input amount must be positive", ErrInvalidOrder)
+	}
+
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	address, expected := "", 0.0
+	for a, p := range v.pools {
+		if project != pb.Project_P_ALL && project != pb.Project_P_JUPITER && project != pb.Project_P_UNKNOWN && p.project != project {
+			continue
+		}
+		if p.other(inMint) != outMint {
+			continue
+		}
+		if out, _, ok := p.quote(inMint, inAmount, v.opts.SwapFeeBps); ok && out > expected {
+			address, expected = a, out
+		}
+	}
+	if address == "" {
+		return Execution{}, fmt.Errorf("%w: %v to %v", ErrNoLiquidity, inToken, outToken)
+	}
+
+	minOut := expected * (1 - slippage/100)
+	exec := Execution{Kind: KindSwap, Market: address, InMint: inMint, InAmount: inAmount, OutMint: outMint, ExpectedOut: expected}
+	return v.schedule(exec, func(exec *Execution) error {
+		p := v.pools[exec.Market]
+		if v.balances[exec.InMint] < exec.InAmount {
+			return ErrInsufficientFunds
+		}
+		out, fee, ok := p.quote(exec.InMint, exec.InAmount, v.opts.SwapFeeBps)
+		if !ok {
+			return ErrNoLiquidity
+		}
+		filled := out * (1 - v.opts.Slippage/100)
+		if filled < minOut {
+			return fmt.Errorf("%w: out %v, minimum %v", ErrSlippageExceeded, filled, minOut)
+		}
+
+		in := 0
+		if p.mints[1] == exec.InMint {
+			in = 1
+		}
+		p.reserves[in] += exec.InAmount - fee
+		p.reserves[1-in] -= out
+		v.balances[exec.InMint] -= exec.InAmount
+		v.balances[exec.OutMint] += filled
+		exec.OutAmount, exec.Fee = filled, fee
+		return nil
+	}), nil
+}
+
+// schedule records a submission and queues its landing. The lock must be held.
+func (v *Venue) schedule(exec Execution, run func(exec *Execution) error) Execution {
+	exec.ID = "backtest-" + strconv.Itoa(len(v.executions)+1)
+	exec.SubmittedAt = v.now
+
+	latency := v.opts.Latency
+	if v.opts.LatencyJitter > 0 {
+		latency += time.Duration(v.rand.Int63n(int64(v.opts.LatencyJitter) + 1))
+	}
+	due := v.now.Add(latency)
+
+	v.executions = append(v.executions, exec)
+	i := sort.Search(len(v.pending), func(i int) bool { return v.pending[i].due.After(due) })
+	v.pending = append(v.pending, pendingTx{})
+	copy(v.pending[i+1:], v.pending[i:])
+	v.pending[i] = pendingTx{index: len(v.executions) - 1, due: due, run: run}
+	return exec
+}
+
+// advance lands the transactions due by to and returns them
+func (v *Venue) advance(to time.Time) []Execution {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	var landed []Execution
+	for len(v.pending) > 0 && !v.pending[0].due.After(to) {
+		p := v.pending[0]
+		v.pending = v.pending[1:]
+		if p.due.After(v.now) {
+			v.now = p.due
+		}
+
+		exec := &v.executions[p.index]
+		exec.LandedAt = p.due
+		switch {
+		case v.rand.Float64() < v.opts.DropRate:
+			exec.Err = ErrNotLanded
+		case v.balances[metadata.SOLMint] < v.opts.NetworkFee:
+			exec.Err = fmt.Errorf("%w: network fee", ErrInsufficientFunds)
+		default:
+			exec.Landed = true
+			v.balances[metadata.SOLMint] -= v.opts.NetworkFee
+			exec.Err = p.run(exec)
+		}
+		landed = append(landed, *exec)
+	}
+	if to.After(v.now) {
+		v.now = to
+	}
+	return landed
+}
+
+// expire fails the transactions still pending at the end of the data
+func (v *Venue) expire() []Execution {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	var expired []Execution
+	for _, p := range v.pending {
+		exec := &v.executions[p.index]
+		exec.Err = ErrNoMarketData
+		expired = append(expired, *exec)
+	}
+	v.pending = nil
+	return expired
+}
+
+// apply updates the market state with an event and returns the fills of resting orders it caused
+func (v *Venue) apply(event Event) []Fill {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	if event.Time.After(v.now) {
+		v.now = event.Time
+	}
+	switch {
+	case event.Reserves != nil:
+		v.applyReserves(event.Reserves)
+	case event.Orderbook != nil:
+		return v.applyOrderbook(event.Orderbook)
+	case event.PumpFunSwap != nil:
+		swap := event.PumpFunSwap
+		v.curves[swap.MintAddress] = &curve{
+			sol:    float64(swap.VirtualSolReserves) / lamportsPerSOL,
+			tokens: float64(swap.VirtualTokenReserves) / pumpFunTokenUnits,
+		}
+	}
+	return nil
+}
+
+func (v *Venue) applyReserves(reserves *pb.PoolReserves) {
+	p := &pool{project: reserves.Project, mints: [2]string{reserves.Token1Address, reserves.Token2Address}}
+	for i, raw := range []string{reserves.Token1Reserves, reserves.Token2Reserves} {
+		amount, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return
+		}
+		decimals, err := v.registry.Decimals(p.mints[i])
+		if err != nil {
+			return
+		}
+		p.reserves[i] = float64(amount) / math.Pow10(decimals)
+	}
+	v.pools[reserves.PoolAddress] = p
+}
+
+func (v *Venue) applyOrderbook(orderbook *pb.GetOrderbookResponse) []Fill {
+	b := &book{}
+	for _, item := range orderbook.Bids {
+		b.bids = append(b.bids, level{price: item.Price, size: item.Size})
+	}
+	for _, item := range orderbook.Asks {
+		b.asks = append(b.asks, level{price: item.Price, size: item.Size})
+	}
+	sort.Slice(b.bids, func(i, j int) bool { return b.bids[i].price > b.bids[j].price })
+	sort.Slice(b.asks, func(i, j int) bool { return b.asks[i].price < b.asks[j].price })
+	v.books[orderbook.Market] = b
+	if orderbook.MarketAddress != "" {
+		v.books[orderbook.MarketAddress] = b
+	}
+
+	// resting orders fill when the book trades through them
+	fillsBefore := len(v.fills)
+	remaining := v.orders[:0]
+	for _, order := range v.orders {
+		if order.market.Name == orderbook.Market || order.market.Address == orderbook.MarketAddress {
+			v.match(order, true)
+		}
+		if order.remaining > 0 {
+			remaining = append(remaining, order)
+		}
+	}
+	v.orders = remaining
+	return append([]Fill(nil), v.fills[fillsBefore:]...)
+}
+
+// crosses returns true if an order would fill immediately. The lock must be held.
+func (v *Venue) crosses(order *restingOrder) bool {
+	b := v.bookOf(order.market)
+	if b == nil {
+		return false
+	}
+	if order.side == pb.Side_S_BID {
+		return len(b.asks) > 0 && b.asks[0].price <= order.price
+	}
+	return len(b.bids) > 0 && b.bids[0].price >= order.price
+}
+
+// match fills an order against the book's levels at or better than its price, consuming them. Taker fills are at the
+// levels' prices, maker fills at the order's price, and slippage is capped at the order's price. It returns the amount received and the fees. The lock must be held.
+func (v *Venue) match(order *restingOrder, maker bool) (float64, float64) {
+	b := v.bookOf(order.market)
+	if b == nil {
+		return 0, 0
+	}
+	levels := &b.asks
+	if order.side == pb.Side_S_ASK {
+		levels = &b.bids
+	}
+
+	var received, fees float64
+	for len(*levels) > 0 && order.remaining > 0 {
+		l := &(*levels)[0]
+		if (order.side == pb.Side_S_BID && l.price > order.price) || (order.side == pb.Side_S_ASK && l.price < order.price) {
+			break
+		}
+
+		quantity := math.Min(order.remaining, l.size)
+		price, feeBps := l.price, v.opts.TakerFeeBps
+		if maker {
+			price, feeBps = order.price, v.opts.MakerFeeBps
+		}
+		if order.side == pb.Side_S_BID {
+			price = math.Min(price*(1+v.opts.Slippage/100), order.price)
+		} else {
+			price = math.Max(price*(1-v.opts.Slippage/100), order.price)
+		}
+		fee := quantity * price * feeBps / 10000
+
+		if order.side == pb.Side_S_BID {
+			// the locked quote covers the order price, the difference is returned
+			v.locked[order.market.QuoteMint] -= quantity * order.price
+			v.balances[order.market.QuoteMint] += quantity*order.price - quantity*price - fee
+			v.balances[order.market.BaseMint] += quantity
+			received += quantity
+		} else {
+			v.locked[order.market.BaseMint] -= quantity
+			v.balances[order.market.QuoteMint] += quantity*price - fee
+			received += quantity*price - fee
+		}
+		fees += fee
+		order.remaining -= quantity
+		l.size -= quantity
+		if l.size <= 0 {
+			*levels = (*levels)[1:]
+		}
+
+		v.fills = append(v.fills, Fill{
+			OrderID:  order.id,
+			Market:   order.market.Name,
+			Side:     sideName(order.side),
+			Price:    price,
+			Quantity: quantity,
+			Fee:      fee,
+			Maker:    maker,
+			Time:     v.now,
+		})
+	}
+	return received, fees
+}
+
+// cancel returns the funds locked by the remainder of an order. The lock must be held.
+func (v *Venue) cancel(order *restingOrder) {
+	if order.side == pb.Side_S_BID {
+		v.locked[order.market.QuoteMint] -= order.remaining * order.price
+		v.balances[order.market.QuoteMint] += order.remaining * order.price
+	} else {
+		v.locked[order.market.BaseMint] -= order.remaining
+		v.balances[order.market.BaseMint] += order.remaining
+	}
+	order.remaining = 0
+}
+
+func (v *Venue) bookOf(market metadata.Market) *book {
+	if b, ok := v.books[market.Name]; ok {
+		return b
+	}
+	return v.books[market.Address]
+}
+
+// resolve returns the mint of a symbol, or the token as is if the registry doesn't know it
+func (v *Venue) resolve(mintOrSymbol string) (string, error) {
+	mint, err := v.registry.ResolveMint(mintOrSymbol)
+	if errors.Is(err, metadata.ErrUnknownToken) {
+		return mintOrSymbol, nil
+	}
+	return mint, err
+}
+
+func (v *Venue) market(nameOrAddress string) (metadata.Market, bool) {
+	if market, ok := v.markets[nameOrAddress]; ok {
+		return market, true
+	}
+	return v.registry.Market(nameOrAddress)
+}
+
+// pumpFunQuote returns the SOL cost of buying (or proceeds of selling) tokens on a bonding curve with fees and
+// slippage, the fee, and the SOL amount moved on the curve. The lock must be held.
+func (v *Venue) pumpFunQuote(c *curve, tokens float64, buy bool) (float64, float64, float64, bool) {
+	if c == nil || c.sol <= 0 || c.tokens <= 0 {
+		return 0, 0, 0, false
+	}
+	if buy {
+		if tokens >= c.tokens {
+			return 0, 0, 0, false
+		}
+		cost := c.sol * tokens / (c.tokens - tokens)
+		fee := cost * v.opts.PumpFunFeeBps / 10000
+		return (cost + fee) * (1 + v.opts.Slippage/100), fee, cost, true
+	}
+	proceeds := c.sol * tokens / (c.tokens + tokens)
+	fee := proceeds * v.opts.PumpFunFeeBps / 10000
+	return (proceeds - fee) * (1 - v.opts.Slippage/100), fee, proceeds, true
+}
+
+// price of a token in the quote mint, from the deepest pool, the book mid or the bonding curve, possibly through SOL.
+// The lock must be held.
+func (v *Venue) price(mint string) (float64, bool) {
+	if mint == v.opts.QuoteMint {
+		return 1, true
+	}
+	if price, ok := v.directPrice(mint, v.opts.QuoteMint); ok {
+		return price, true
+	}
+	if mint == metadata.SOLMint {
+		return 0, false
+	}
+	solPrice, ok := v.directPrice(metadata.SOLMint, v.opts.QuoteMint)
+	if !ok {
+		return 0, false
+	}
+	if price, ok := v.directPrice(mint, metadata.SOLMint); ok {
+		return price * solPrice, true
+	}
+	return 0, false
+}
+
+func (v *Venue) directPrice(mint, quote string) (float64, bool) {
+	price, depth := 0.0, 0.0
+	for _, p := range v.pools {
+		if p.other(mint) != quote || p.reserves[0] <= 0 || p.reserves[1] <= 0 {
+			continue
+		}
+		base, quoteReserve := p.reserves[0], p.reserves[1]
+		if p.mints[0] == quote {
+			base, quoteReserve = quoteReserve, base
+		}
+		if quoteReserve > depth {
+			price, depth = quoteReserve/base, quoteReserve
+		}
+	}
+	if depth > 0 {
+		return price, true
+	}
+
+	for name, b := range v.books {
+		market, ok := v.market(name)
+		if !ok || market.BaseMint != mint || market.QuoteMint != quote || len(b.bids) == 0 || len(b.asks) == 0 {
+			continue
+		}
+		return (b.bids[0].price + b.asks[0].price) / 2, true
+	}
+
+	if c, ok := v.curves[mint]; ok && quote == metadata.SOLMint && c.tokens > 0 {
+		return c.sol / c.tokens, true
+	}
+	return 0, false
+}
+
+func sideName(side pb.Side) string {
+	if side == pb.Side_S_BID {
+		return "bid"
+	}
+	return "ask"
+}
+
+var (
+	_ Client = (*provider.GRPCClient)(nil)
+	_ Client = (*Venue)(nil)
+)
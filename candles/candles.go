@@ -0,0 +1,385 @@
+package candles
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	MinTimeframe = time.Second
+	MaxTimeframe = 24 * time.Hour
+	// DefaultSlotDuration is the slot time used to convert timeframes and lateness to slots
+	DefaultSlotDuration = 400 * time.Millisecond
+
+	defaultTimeframe = time.Minute
+	defaultLateness  = 2 * time.Second
+	defaultHistory   = 1000
+)
+
+var ErrInvalidTimeframe = errors.New("invalid timeframe")
+
+// Alignment selects how bars are delimited
+type Alignment int
+
+const (
+	// AlignTime aligns bars to multiples of the timeframe since the Unix epoch, so daily bars start at midnight UTC
+	AlignTime Alignment = iota
+	// AlignSlot aligns bars to multiples of the number of slots in the timeframe
+	AlignSlot
+)
+
+type AggregatorOpts struct {
+	// Timeframes of the bars built for each key, multiples of 1s between 1s and 1d (default 1m)
+	Timeframes []time.Duration
+	Align      Alignment
+	// SlotDuration converts timeframes and lateness to slots with AlignSlot (default 400ms)
+	SlotDuration time.Duration
+	// Lateness is how long bars stay open after their end to include late updates (default 2s)
+	Lateness time.Duration
+	// History is the number of closed bars kept per key and timeframe (default 1000). Updates for closed bars still in
+	// the history revise them, and older updates are dropped.
+	History int
+}
+
+// Tick is a trade or swap of Volume base units at Price, with QuoteVolume quote units exchanged. Ticks are grouped by
+// Key, e.g. a market or a token mint.
+type Tick struct {
+	Key         string
+	Price       float64
+	Volume      float64
+	QuoteVolume float64
+	Time        time.Time
+	Slot        uint64
+}
+
+// Bar is an OHLCV candle. With AlignTime, Start and End delimit the bar and the slots are those of its first and last
+// updates. With AlignSlot, StartSlot and EndSlot delimit the bar and the times are those of its first and last updates.
+type Bar struct {
+	Key         string
+	Timeframe   time.Duration
+	Start       time.Time
+	End         time.Time
+	StartSlot   uint64
+	EndSlot     uint64
+	Open        float64
+	High        float64
+	Low         float64
+	Close       float64
+	Volume      float64
+	QuoteVolume float64
+	Trades      int
+	// Late is set on bars revised or created by an update received after they had closed
+	Late bool
+
+	position int64
+	first    int64
+	last     int64
+}
+
+type seriesKey struct {
+	key       string
+	timeframe time.Duration
+}
+
+type series struct {
+	open   map[int64]*Bar
+	closed []Bar
+}
+
+// Aggregator builds bars of several timeframes from ticks, closing each bar once updates (or the clock) are past its
+// end and lateness
+type Aggregator struct {
+	opts     AggregatorOpts
+	sizes    []int64
+	lateness int64
+
+	m         sync.Mutex
+	series    map[seriesKey]*series
+	watermark int64
+	// latest slot seen and when, to estimate the current slot with AlignSlot
+	slot   int64
+	slotAt time.Time
+	// dropped counts the updates older than the history
+	dropped int
+}
+
+func NewAggregator(opts AggregatorOpts) (*Aggregator, error) {
+	if len(opts.Timeframes) == 0 {
+		opts.Timeframes = []time.Duration{defaultTimeframe}
+	}
+	if opts.SlotDuration <= 0 {
+		opts.SlotDuration = DefaultSlotDuration
+	}
+	if opts.Lateness <= 0 {
+		opts.Lateness = defaultLateness
+	}
+	if opts.History <= 0 {
+		opts.History = defaultHistory
+	}
+
+	a := &Aggregator{
+		opts:   opts,
+		series: make(map[seriesKey]*series),
+	}
+	for _, timeframe := range opts.Timeframes {
+		if timeframe < MinTimeframe || timeframe > MaxTimeframe || timeframe%time.Second != 0 {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTimeframe, timeframe)
+		}
+		a.sizes = append(a.sizes, a.positions(timeframe))
+	}
+	a.lateness = a.positions(opts.Lateness)
+	return a, nil
+}
+
+// Add aggregates a tick and returns the bars it closed or revised. Ticks without a time (or slot with AlignSlot) are
+// placed at the current time (or latest slot).
+func (a *Aggregator) Add(tick Tick) []Bar {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	if tick.Time.IsZero() {
+		tick.Time = time.Now()
+	}
+	if tick.Slot == 0 && a.opts.Align == AlignSlot {
+		tick.Slot = uint64(a.slot)
+	}
+	position := tick.Time.UnixNano()
+	if a.opts.Align == AlignSlot {
+		position = int64(tick.Slot)
+		if position > a.slot {
+			a.slot, a.slotAt = position, time.Now()
+		}
+	}
+
+	var bars []Bar
+	for i, timeframe := range a.opts.Timeframes {
+		size := a.sizes[i]
+		start := position - position%size
+		s := a.seriesOf(tick.Key, timeframe)
+
+		if start+size+a.lateness > a.watermark {
+			bar, ok := s.open[start]
+			if !ok {
+				bar = a.newBar(tick.Key, timeframe, start, size)
+				s.open[start] = bar
+			}
+			update(bar, tick, position, a.opts.Align)
+			continue
+		}
+
+		// the bar has closed: revise it if it's still in the history
+		j := sort.Search(len(s.closed), func(j int) bool { return s.closed[j].position >= start })
+		if j == len(s.closed) || s.closed[j].position != start {
+			if j == 0 && len(s.closed) >= a.opts.History {
+				a.dropped++
+				continue
+			}
+			s.closed = append(s.closed, Bar{})
+			copy(s.closed[j+1:], s.closed[j:])
+			s.closed[j] = *a.newBar(tick.Key, timeframe, start, size)
+			if len(s.closed) > a.opts.History {
+				s.closed = trim(s.closed, a.opts.History)
+				j--
+			}
+		}
+		bar := &s.closed[j]
+		update(bar, tick, position, a.opts.Align)
+		bar.Late = true
+		bars = append(bars, *bar)
+	}
+
+	if position > a.watermark {
+		a.watermark = position
+		bars = append(bars, a.close()...)
+	}
+	return bars
+}
+
+// Advance closes the bars that have ended by now and returns them. With AlignSlot, the current slot is estimated from
+// the latest slot seen.
+func (a *Aggregator) Advance(now time.Time) []Bar {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	position := now.UnixNano()
+	if a.opts.Align == AlignSlot {
+		if a.slotAt.IsZero() {
+			return nil
+		}
+		position = a.slot + int64(now.Sub(a.slotAt)/a.opts.SlotDuration)
+	}
+	if position <= a.watermark {
+		return nil
+	}
+	a.watermark = position
+	return a.close()
+}
+
+// Flush closes all open bars and returns them, e.g. when the ticks end
+func (a *Aggregator) Flush() []Bar {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	var bars []Bar
+	for _, s := range a.series {
+		for start, bar := range s.open {
+			bars = append(bars, *bar)
+			s.closed = insert(s.closed, *bar)
+			delete(s.open, start)
+		}
+		s.closed = trim(s.closed, a.opts.History)
+	}
+	sortBars(bars)
+	return bars
+}
+
+// Bars returns the closed bars of a key and timeframe kept in the history, oldest first
+func (a *Aggregator) Bars(key string, timeframe time.Duration) []Bar {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	s, ok := a.series[seriesKey{key: key, timeframe: timeframe}]
+	if !ok {
+		return nil
+	}
+	return append([]Bar(nil), s.closed...)
+}
+
+// Dropped returns the number of updates dropped because their bars were older than the history
+func (a *Aggregator) Dropped() int {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	return a.dropped
+}
+
+// close moves the bars past the watermark to the history. The lock must be held.
+func (a *Aggregator) close() []Bar {
+	var bars []Bar
+	for sk, s := range a.series {
+		size := a.sizeOf(sk.timeframe)
+		for start, bar := range s.open {
+			if start+size+a.lateness <= a.watermark {
+				bars = append(bars, *bar)
+				s.closed = insert(s.closed, *bar)
+				delete(s.open, start)
+			}
+		}
+		s.closed = trim(s.closed, a.opts.History)
+	}
+	sortBars(bars)
+	return bars
+}
+
+func (a *Aggregator) seriesOf(key string, timeframe time.Duration) *series {
+	sk := seriesKey{key: key, timeframe: timeframe}
+	s, ok := a.series[sk]
+	if !ok {
+		s = &series{open: make(map[int64]*Bar)}
+		a.series[sk] = s
+	}
+	return s
+}
+
+func (a *Aggregator) newBar(key string, timeframe time.Duration, start, size int64) *Bar {
+	bar := &Bar{Key: key, Timeframe: timeframe, position: start}
+	if a.opts.Align == AlignSlot {
+		bar.StartSlot, bar.EndSlot = uint64(start), uint64(start+size)
+	} else {
+		bar.Start = time.Unix(0, start).UTC()
+		bar.End = bar.Start.Add(timeframe)
+	}
+	return bar
+}
+
+func (a *Aggregator) sizeOf(timeframe time.Duration) int64 {
+	for i, tf := range a.opts.Timeframes {
+		if tf == timeframe {
+			return a.sizes[i]
+		}
+	}
+	return a.positions(timeframe)
+}
+
+// positions converts a duration to nanoseconds, or to slots with AlignSlot
+func (a *Aggregator) positions(d time.Duration) int64 {
+	if a.opts.Align != AlignSlot {
+		return d.Nanoseconds()
+	}
+	slots := int64(d / a.opts.SlotDuration)
+	if slots < 1 {
+		slots = 1
+	}
+	return slots
+}
+
+func update(bar *Bar, tick Tick, position int64, align Alignment) {
+	if bar.Trades == 0 {
+		bar.Open, bar.High, bar.Low, bar.Close = tick.Price, tick.Price, tick.Price, tick.Price
+		bar.first, bar.last = position, position
+	} else {
+		if position < bar.first {
+			bar.Open, bar.first = tick.Price, position
+		}
+		if position >= bar.last {
+			bar.Close, bar.last = tick.Price, position
+		}
+		if tick.Price > bar.High {
+			bar.High = tick.Price
+		}
+		if tick.Price < bar.Low {
+			bar.Low = tick.Price
+		}
+	}
+	bar.Volume += tick.Volume
+	bar.QuoteVolume += tick.QuoteVolume
+	bar.Trades++
+
+	if align == AlignSlot {
+		// slot aligned bars track the times of their updates
+		if bar.Start.IsZero() || tick.Time.Before(bar.Start) {
+			bar.Start = tick.Time
+		}
+		if tick.Time.After(bar.End) {
+			bar.End = tick.Time
+		}
+		return
+	}
+	// time aligned bars track the slots of their updates
+	if tick.Slot > 0 && (bar.StartSlot == 0 || tick.Slot < bar.StartSlot) {
+		bar.StartSlot = tick.Slot
+	}
+	if tick.Slot > bar.EndSlot {
+		bar.EndSlot = tick.Slot
+	}
+}
+
+func insert(bars []Bar, bar Bar) []Bar {
+	i := sort.Search(len(bars), func(i int) bool { return bars[i].position >= bar.position })
+	bars = append(bars, Bar{})
+	copy(bars[i+1:], bars[i:])
+	bars[i] = bar
+	return bars
+}
+
+func trim(bars []Bar, history int) []Bar {
+	if len(bars) > history {
+		bars = append(bars[:0], bars[len(bars)-history:]...)
+	}
+	return bars
+}
+
+func sortBars(bars []Bar) {
+	sort.Slice(bars, func(i, j int) bool {
+		if bars[i].Timeframe != bars[j].Timeframe {
+			return bars[i].Timeframe < bars[j].Timeframe
+		}
+		if bars[i].position != bars[j].position {
+			return bars[i].position < bars[j].position
+		}
+		return bars[i].Key < bars[j].Key
+	})
+}
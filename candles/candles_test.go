@@ -0,0 +1,135 @@
+package candles
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregatorTimeAligned(t *testing.T) {
+	a, err := NewAggregator(AggregatorOpts{Timeframes: []time.Duration{time.Minute, 5 * time.Minute}, Lateness: time.Second})
+	require.Nil(t, err)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return base.Add(d) }
+
+	assert.Empty(t, a.Add(Tick{Key: "SOL", Price: 100, Volume: 1, Time: at(10 * time.Second)}))
+	assert.Empty(t, a.Add(Tick{Key: "SOL", Price: 105, Volume: 2, Time: at(20 * time.Second)}))
+	assert.Empty(t, a.Add(Tick{Key: "SOL", Price: 95, Volume: 1, Time: at(40 * time.Second)}))
+	// a late update within the lateness is still included
+	assert.Empty(t, a.Add(Tick{Key: "SOL", Price: 101, Volume: 1, Time: at(time.Minute)}))
+	assert.Empty(t, a.Add(Tick{Key: "SOL", Price: 99, Volume: 1, Time: at(50 * time.Second)}))
+
+	bars := a.Add(Tick{Key: "SOL", Price: 102, Volume: 1, Time: at(time.Minute + 2*time.Second)})
+	require.Len(t, bars, 1)
+	bar := bars[0]
+	assert.Equal(t, time.Minute, bar.Timeframe)
+	assert.Equal(t, base, bar.Start)
+	assert.Equal(t, at(time.Minute), bar.End)
+	assert.Equal(t, 100.0, bar.Open)
+	assert.Equal(t, 105.0, bar.High)
+	assert.Equal(t, 95.0, bar.Low)
+	assert.Equal(t, 99.0, bar.Close)
+	assert.Equal(t, 5.0, bar.Volume)
+	assert.Equal(t, 4, bar.Trades)
+	assert.False(t, bar.Late)
+
+	// an update after the bar closed revises it
+	bars = a.Add(Tick{Key: "SOL", Price: 110, Volume: 1, Time: at(30 * time.Second)})
+	require.Len(t, bars, 1)
+	assert.True(t, bars[0].Late)
+	assert.Equal(t, 110.0, bars[0].High)
+	assert.Equal(t, 99.0, bars[0].Close)
+	assert.Equal(t, 110.0, a.Bars("SOL", time.Minute)[0].High)
+
+	bars = a.Advance(at(5*time.Minute + time.Second))
+	require.Len(t, bars, 2)
+	assert.Equal(t, time.Minute, bars[0].Timeframe)
+	assert.Equal(t, 5*time.Minute, bars[1].Timeframe)
+	assert.Equal(t, 7, bars[1].Trades)
+}
+
+func TestAggregatorSlotAligned(t *testing.T) {
+	a, err := NewAggregator(AggregatorOpts{Timeframes: []time.Duration{4 * time.Second}, Align: AlignSlot, Lateness: time.Nanosecond})
+	require.Nil(t, err)
+
+	assert.Empty(t, a.Add(Tick{Key: "mint", Price: 1, Slot: 100}))
+	assert.Empty(t, a.Add(Tick{Key: "mint", Price: 2, Slot: 109}))
+	bars := a.Add(Tick{Key: "mint", Price: 3, Slot: 111})
+	require.Len(t, bars, 1)
+	assert.Equal(t, uint64(100), bars[0].StartSlot)
+	assert.Equal(t, uint64(110), bars[0].EndSlot)
+	assert.Equal(t, 2.0, bars[0].Close)
+}
+
+func TestAggregatorInvalidTimeframe(t *testing.T) {
+	_, err := NewAggregator(AggregatorOpts{Timeframes: []time.Duration{48 * time.Hour}})
+	assert.ErrorIs(t, err, ErrInvalidTimeframe)
+	_, err = NewAggregator(AggregatorOpts{Timeframes: []time.Duration{1500 * time.Millisecond}})
+	assert.ErrorIs(t, err, ErrInvalidTimeframe)
+}
+
+func TestAggregatorStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, err := NewAggregator(AggregatorOpts{Timeframes: []time.Duration{time.Second}, Lateness: time.Millisecond})
+	require.Nil(t, err)
+
+	ticks := make(chan Tick, 1)
+	ticks <- Tick{Key: "SOL", Price: 100, Volume: 1, Time: time.Now()}
+	stream := a.Stream(ctx, connections.FromChannel[Tick](ctx, ticks))
+
+	// the bar is closed by the clock
+	bar, err := stream()
+	require.Nil(t, err)
+	assert.Equal(t, "SOL", bar.Key)
+	assert.Equal(t, 100.0, bar.Open)
+}
+
+type fakeTradesClient struct {
+	trades []*pb.Trade
+}
+
+func (c *fakeTradesClient) GetTrades(context.Context, string, uint32, pb.Project) (*pb.GetTradesResponse, error) {
+	return &pb.GetTradesResponse{Trades: c.trades}, nil
+}
+
+func (c *fakeTradesClient) GetTradesStream(context.Context, string, uint32, pb.Project) (connections.Streamer[*pb.GetTradesStreamResponse], error) {
+	return nil, nil
+}
+
+func TestAggregatorBackfill(t *testing.T) {
+	ctx := context.Background()
+	a, err := NewAggregator(AggregatorOpts{Timeframes: []time.Duration{time.Minute}, Lateness: time.Second})
+	require.Nil(t, err)
+
+	now := time.Now()
+	assert.Empty(t, a.Add(Tick{Key: "SOL/USDC", Price: 100, Volume: 1, Time: now}))
+
+	// trades are dated by the caller, and those it can't date don't count towards the current bar
+	client := &fakeTradesClient{trades: []*pb.Trade{
+		{FillPrice: 90, Size: 2, OrderID: "old"},
+		{FillPrice: 95, Size: 3, OrderID: "undated"},
+		{FillPrice: 95, Size: 3, OrderID: "maker", IsMaker: true},
+	}}
+	bars, err := a.Backfill(ctx, client, "SOL/USDC", 10, pb.Project_P_OPENBOOK, func(trade *pb.Trade) (time.Time, uint64, bool) {
+		return now.Add(-time.Hour), 0, trade.OrderID != "undated"
+	})
+	require.Nil(t, err)
+	require.Len(t, bars, 1)
+	assert.True(t, bars[0].Late)
+	assert.Equal(t, 90.0, bars[0].Open)
+	assert.Equal(t, 2.0, bars[0].Volume)
+
+	open := a.Flush()
+	require.Len(t, open, 1)
+	assert.Equal(t, 1.0, open[0].Volume)
+	assert.Equal(t, 1, open[0].Trades)
+	assert.Equal(t, 100.0, open[0].Open)
+}
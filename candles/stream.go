@@ -0,0 +1,293 @@
+package candles
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+)
+
+const (
+	// bars are closed by the clock at this interval when no updates arrive
+	advanceInterval = 250 * time.Millisecond
+
+	lamportsPerSOL    = 1e9
+	pumpFunTokenUnits = 1e6
+)
+
+// TradesClient is implemented by all clients
+type TradesClient interface {
+	GetTrades(ctx context.Context, market string, limit uint32, project pb.Project) (*pb.GetTradesResponse, error)
+	GetTradesStream(ctx context.Context, market string, limit uint32, project pb.Project) (connections.Streamer[*pb.GetTradesStreamResponse], error)
+}
+
+// SwapsClient is implemented by all clients
+type SwapsClient interface {
+	GetSwapsStream(ctx context.Context, projects []pb.Project, markets []string, includeFailed bool) (connections.Streamer[*pb.GetSwapsStreamResponse], error)
+}
+
+// PumpFunClient is implemented by all clients
+type PumpFunClient interface {
+	GetPumpFunSwapsStream(ctx context.Context, req *pb.GetPumpFunSwapsStreamRequest) (connections.Streamer[*pb.GetPumpFunSwapsStreamResponse], error)
+}
+
+type barResult struct {
+	bar Bar
+	err error
+}
+
+type tickResult struct {
+	tick Tick
+	err  error
+}
+
+// Stream aggregates ticks until their stream ends or ctx is canceled, streaming each bar as it closes and each late
+// revision. Bars still open when the ticks end aren't streamed; use Flush to get them.
+func (a *Aggregator) Stream(ctx context.Context, ticks connections.Streamer[Tick]) connections.Streamer[Bar] {
+	results := make(chan barResult, 100)
+	tickCh := make(chan tickResult)
+
+	go func() {
+		for {
+			tick, err := ticks()
+			select {
+			case tickCh <- tickResult{tick: tick, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(advanceInterval)
+		defer ticker.Stop()
+
+		send := func(bars []Bar) bool {
+			for _, bar := range bars {
+				select {
+				case results <- barResult{bar: bar}:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+		for {
+			select {
+			case now := <-ticker.C:
+				if !send(a.Advance(now)) {
+					return
+				}
+			case r := <-tickCh:
+				if r.err != nil {
+					select {
+					case results <- barResult{err: r.err}:
+					case <-ctx.Done():
+					}
+					return
+				}
+				if !send(a.Add(r.tick)) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() (Bar, error) {
+		select {
+		case r := <-results:
+			return r.bar, r.err
+		case <-ctx.Done():
+			return Bar{}, ctx.Err()
+		}
+	}
+}
+
+// TradeTimeFunc dates a past trade, e.g. from a recording of the trades stream, with its time and slot. It returns
+// false for trades it can't date.
+type TradeTimeFunc func(trade *pb.Trade) (time.Time, uint64, bool)
+
+// Backfill aggregates the recent trades of a market and returns the bars this closed or revised. The API doesn't
+// report when past trades happened, so tradeTime dates them. Trades it can't date are skipped, rather than counted in
+// the current bars.
+func (a *Aggregator) Backfill(ctx context.Context, client TradesClient, market string, limit uint32, project pb.Project, tradeTime TradeTimeFunc) ([]Bar, error) {
+	resp, err := client.GetTrades(ctx, market, limit, project)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch trades of %v: %w", market, err)
+	}
+
+	var bars []Bar
+	for _, trade := range resp.GetTrades() {
+		at, slot, ok := tradeTime(trade)
+		if !ok || (a.opts.Align == AlignSlot && slot == 0) || (a.opts.Align == AlignTime && at.IsZero()) {
+			continue
+		}
+		if tick, ok := tickFromTrade(market, trade, at, slot); ok {
+			bars = append(bars, a.Add(tick)...)
+		}
+	}
+	return bars, nil
+}
+
+// TradeTicks streams the trades of an Openbook market as ticks keyed by market
+func TradeTicks(ctx context.Context, client TradesClient, market string, limit uint32, project pb.Project) (connections.Streamer[Tick], error) {
+	stream, err := client.GetTradesStream(ctx, market, limit, project)
+	if err != nil {
+		return nil, err
+	}
+	return flatten(stream, func(update *pb.GetTradesStreamResponse) []Tick {
+		return TicksFromTrades(market, update)
+	}), nil
+}
+
+// SwapTicks streams the successful swaps of pools as ticks keyed by the mint of the token traded against one of
+// quoteMints. Swaps without a quote token are skipped.
+func SwapTicks(ctx context.Context, client SwapsClient, projects []pb.Project, pools []string, quoteMints []string) (connections.Streamer[Tick], error) {
+	stream, err := client.GetSwapsStream(ctx, projects, pools, false)
+	if err != nil {
+		return nil, err
+	}
+	return flatten(stream, func(update *pb.GetSwapsStreamResponse) []Tick {
+		if tick, ok := TickFromSwap(update, quoteMints); ok {
+			return []Tick{tick}
+		}
+		return nil
+	}), nil
+}
+
+// PumpFunTicks streams the Pump.fun swaps of tokens as ticks keyed by mint, priced in SOL
+func PumpFunTicks(ctx context.Context, client PumpFunClient, tokens []string) (connections.Streamer[Tick], error) {
+	stream, err := client.GetPumpFunSwapsStream(ctx, &pb.GetPumpFunSwapsStreamRequest{Tokens: tokens})
+	if err != nil {
+		return nil, err
+	}
+	return flatten(stream, func(update *pb.GetPumpFunSwapsStreamResponse) []Tick {
+		if tick, ok := TickFromPumpFunSwap(update); ok {
+			return []Tick{tick}
+		}
+		return nil
+	}), nil
+}
+
+// TicksFromTrades converts a trades stream update. Maker fills are skipped so each match is counted once.
+func TicksFromTrades(market string, update *pb.GetTradesStreamResponse) []Tick {
+	ts := time.Now()
+	if update.Timestamp != nil {
+		ts = update.Timestamp.AsTime()
+	}
+
+	var ticks []Tick
+	for _, trade := range update.GetTrades().GetTrades() {
+		if tick, ok := tickFromTrade(market, trade, ts, uint64(update.Slot)); ok {
+			ticks = append(ticks, tick)
+		}
+	}
+	return ticks
+}
+
+// tickFromTrade converts a taker fill. Maker fills are skipped so each match is counted once.
+func tickFromTrade(market string, trade *pb.Trade, at time.Time, slot uint64) (Tick, bool) {
+	if trade.IsMaker || trade.FillPrice <= 0 {
+		return Tick{}, false
+	}
+	return Tick{
+		Key:         market,
+		Price:       trade.FillPrice,
+		Volume:      trade.Size,
+		QuoteVolume: trade.Size * trade.FillPrice,
+		Time:        at,
+		Slot:        slot,
+	}, true
+}
+
+// TickFromSwap converts a successful swap against one of quoteMints. The swaps stream only reports the minimum output
+// amount, so prices of buys are estimates.
+func TickFromSwap(update *pb.GetSwapsStreamResponse, quoteMints []string) (Tick, bool) {
+	swap := update.GetSwap()
+	if swap == nil || !swap.Success || swap.InAmount <= 0 || swap.OutAmountMin <= 0 {
+		return Tick{}, false
+	}
+
+	tick := Tick{Slot: uint64(update.Slot), Time: time.Now()}
+	if update.Timestamp != nil {
+		tick.Time = update.Timestamp.AsTime()
+	}
+	switch {
+	case contains(quoteMints, swap.InTokenAddress):
+		tick.Key, tick.Volume, tick.QuoteVolume = swap.OutTokenAddress, swap.OutAmountMin, swap.InAmount
+	case contains(quoteMints, swap.OutTokenAddress):
+		tick.Key, tick.Volume, tick.QuoteVolume = swap.InTokenAddress, swap.InAmount, swap.OutAmountMin
+	default:
+		return Tick{}, false
+	}
+	tick.Price = tick.QuoteVolume / tick.Volume
+	return tick, true
+}
+
+// TickFromPumpFunSwap converts a Pump.fun swap, priced in SOL
+func TickFromPumpFunSwap(update *pb.GetPumpFunSwapsStreamResponse) (Tick, bool) {
+	if update.GetTokenAmount() == 0 || update.GetSolAmount() == 0 {
+		return Tick{}, false
+	}
+
+	tick := Tick{
+		Key:         update.MintAddress,
+		Volume:      float64(update.TokenAmount) / pumpFunTokenUnits,
+		QuoteVolume: float64(update.SolAmount) / lamportsPerSOL,
+		Slot:        uint64(update.Slot),
+		Time:        time.Now(),
+	}
+	if update.Timestamp != nil {
+		tick.Time = update.Timestamp.AsTime()
+	}
+	tick.Price = tick.QuoteVolume / tick.Volume
+	return tick, true
+}
+
+// flatten converts each update of a stream to any number of ticks
+func flatten[T any](stream connections.Streamer[T], convert func(T) []Tick) connections.Streamer[Tick] {
+	var pending []Tick
+	return func() (Tick, error) {
+		for len(pending) == 0 {
+			update, err := stream()
+			if err != nil {
+				return Tick{}, err
+			}
+			pending = convert(update)
+		}
+		tick := pending[0]
+		pending = pending[1:]
+		return tick, nil
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	_ TradesClient = (*provider.GRPCClient)(nil)
+	_ TradesClient = (*provider.WSClient)(nil)
+	_ TradesClient = (*provider.HTTPClient)(nil)
+
+	_ SwapsClient = (*provider.GRPCClient)(nil)
+	_ SwapsClient = (*provider.WSClient)(nil)
+	_ SwapsClient = (*provider.HTTPClient)(nil)
+
+	_ PumpFunClient = (*provider.GRPCClient)(nil)
+	_ PumpFunClient = (*provider.WSClient)(nil)
+	_ PumpFunClient = (*provider.HTTPClient)(nil)
+)
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/urfave/cli/v2"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	MarketFlag = &cli.StringFlag{
+		Name:     "market",
+		Usage:    "market name or address",
+		Required: true,
+	}
+	ProjectFlag = &cli.StringFlag{
+		Name:  "project",
+		Usage: "project (e.g. raydium, jupiter, openbook)",
+	}
+	LimitFlag = &cli.UintFlag{
+		Name:  "limit",
+		Usage: "maximum number of entries",
+		Value: 10,
+	}
+	SkipPreFlightFlag = &cli.BoolFlag{
+		Name:  "skip-preflight",
+		Usage: "skip the preflight checks of the transactions",
+	}
+)
+
+func commands() []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:      "methods",
+			Usage:     "List the methods of the transport's client",
+			ArgsUsage: "[FILTER]",
+			Action:    listMethods,
+		},
+		{
+			Name:      "call",
+			Usage:     "Call any method of the transport's client",
+			ArgsUsage: "METHOD [ARG...]",
+			Description: "Arguments follow the method's parameters, without the context. Enums are passed by name " +
+				"(e.g. raydium) or number, requests and option structs as JSON, lists as comma separated values and " +
+				"exact amounts as value:decimals. Methods that start streams print their updates until interrupted.",
+			Action: action(callMethod),
+		},
+		{
+			Name:  "quotes",
+			Usage: "Get swap quotes",
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "in", Usage: "input token", Required: true},
+				&cli.StringFlag{Name: "out", Usage: "output token", Required: true},
+				&cli.Float64Flag{Name: "amount", Usage: "input amount", Required: true},
+				&cli.Float64Flag{Name: "slippage", Usage: "slippage in percent", Value: 1},
+				&cli.IntFlag{Name: "limit", Usage: "maximum number of routes", Value: 3},
+				&cli.StringFlag{Name: "projects", Usage: "comma separated projects", Value: "all"},
+			},
+			Action: action(func(ctx context.Context, s *session, c *cli.Context) error {
+				return s.call(ctx, []string{"GetQuotes"}, c.String("in"), c.String("out"), c.Float64("amount"),
+					c.Float64("slippage"), int32(c.Int("limit")), c.String("projects"))
+			}),
+		},
+		{
+			Name:  "swap",
+			Usage: "Swap tokens",
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "in", Usage: "input token", Required: true},
+				&cli.StringFlag{Name: "out", Usage: "output token", Required: true},
+				&cli.Float64Flag{Name: "amount", Usage: "input amount", Required: true},
+				&cli.Float64Flag{Name: "slippage", Usage: "slippage in percent", Value: 1},
+				&cli.StringFlag{Name: "project", Usage: "project to swap on", Value: "raydium"},
+				&cli.StringFlag{Name: "strategy", Usage: "submit strategy of multiple transactions (e.g. abort_on_first_error)"},
+				SkipPreFlightFlag,
+			},
+			Action: action(swap),
+		},
+		{
+			Name:  "orderbook",
+			Usage: "Get the orderbook of a market",
+			Flags: []cli.Flag{MarketFlag, LimitFlag, ProjectFlag},
+			Action: action(func(ctx context.Context, s *session, c *cli.Context) error {
+				return s.call(ctx, []string{"GetOrderbook"}, c.String(MarketFlag.Name), uint32(c.Uint(LimitFlag.Name)), project(c, "openbook"))
+			}),
+		},
+		{
+			Name:  "order",
+			Usage: "Place, cancel and replace orders",
+			Subcommands: []*cli.Command{
+				{
+					Name:   "place",
+					Usage:  "Place an order",
+					Flags:  orderFlags(false),
+					Action: action(placeOrder),
+				},
+				{
+					Name:  "cancel",
+					Usage: "Cancel an order",
+					Flags: []cli.Flag{
+						MarketFlag,
+						&cli.StringFlag{Name: "order-id", Usage: "ID of the order", Required: true},
+						&cli.StringFlag{Name: "side", Usage: "side of the order (bid or ask)", Required: true},
+						&cli.StringFlag{Name: "open-orders", Usage: "open orders account"},
+						ProjectFlag,
+						SkipPreFlightFlag,
+					},
+					Action: action(cancelOrder),
+				},
+				{
+					Name:   "replace",
+					Usage:  "Replace an order",
+					Flags:  orderFlags(true),
+					Action: action(placeOrder),
+				},
+			},
+		},
+		{
+			Name:  "stream",
+			Usage: "Stream market data to stdout",
+			Subcommands: []*cli.Command{
+				{
+					Name:  "orderbooks",
+					Usage: "Stream orderbook updates",
+					Flags: []cli.Flag{
+						&cli.StringSliceFlag{Name: "markets", Usage: "market names or addresses", Required: true},
+						LimitFlag,
+						ProjectFlag,
+					},
+					Action: action(func(ctx context.Context, s *session, c *cli.Context) error {
+						return s.call(ctx, []string{"GetOrderbooksStream", "GetOrderbookStream"}, c.StringSlice("markets"),
+							uint32(c.Uint(LimitFlag.Name)), project(c, "openbook"))
+					}),
+				},
+				{
+					Name:  "trades",
+					Usage: "Stream trades of a market",
+					Flags: []cli.Flag{MarketFlag, LimitFlag, ProjectFlag},
+					Action: action(func(ctx context.Context, s *session, c *cli.Context) error {
+						return s.call(ctx, []string{"GetTradesStream"}, c.String(MarketFlag.Name), uint32(c.Uint(LimitFlag.Name)), project(c, "openbook"))
+					}),
+				},
+				{
+					Name:  "prices",
+					Usage: "Stream token prices",
+					Flags: []cli.Flag{
+						&cli.StringSliceFlag{Name: "tokens", Usage: "token mints or symbols", Required: true},
+						&cli.StringFlag{Name: "projects", Usage: "comma separated projects", Value: "raydium"},
+					},
+					Action: action(func(ctx context.Context, s *session, c *cli.Context) error {
+						return s.call(ctx, []string{"GetPricesStream"}, c.String("projects"), c.StringSlice("tokens"))
+					}),
+				},
+			},
+		},
+		{
+			Name:      "submit",
+			Usage:     "Sign and submit a base64 encoded transaction",
+			ArgsUsage: "TRANSACTION",
+			Flags: []cli.Flag{
+				SkipPreFlightFlag,
+				&cli.BoolFlag{Name: "front-running-protection", Usage: "submit with front running protection"},
+				&cli.BoolFlag{Name: "staked-rpcs", Usage: "submit through staked RPCs"},
+			},
+			Action: action(submit),
+		},
+		{
+			Name:  "balance",
+			Usage: "Get the token balances of the owner",
+			Action: action(func(ctx context.Context, s *session, c *cli.Context) error {
+				owner, err := s.owner(c)
+				if err != nil {
+					return err
+				}
+				return s.call(ctx, []string{"GetAccountBalance"}, owner)
+			}),
+		},
+		{
+			Name:   "profiles",
+			Usage:  "List the profiles of the config file",
+			Action: listProfiles,
+		},
+	}
+}
+
+func orderFlags(replace bool) []cli.Flag {
+	flags := []cli.Flag{
+		MarketFlag,
+		&cli.StringFlag{Name: "side", Usage: "bid or ask", Required: true},
+		&cli.StringFlag{Name: "type", Usage: "comma separated order types (limit, ioc, post, market)", Value: "limit"},
+		&cli.Float64Flag{Name: "amount", Usage: "order size", Required: true},
+		&cli.Float64Flag{Name: "price", Usage: "limit price", Required: true},
+		&cli.StringFlag{Name: "payer", Usage: "payer of the order (default: owner)"},
+		&cli.StringFlag{Name: "open-orders", Usage: "open orders account"},
+		&cli.Uint64Flag{Name: "client-order-id", Usage: "client order ID"},
+		ProjectFlag,
+		SkipPreFlightFlag,
+	}
+	if replace {
+		flags = append(flags, &cli.StringFlag{Name: "order-id", Usage: "ID of the order to replace", Required: true})
+	}
+	return flags
+}
+
+func project(c *cli.Context, fallback string) string {
+	if p := c.String(ProjectFlag.Name); p != "" {
+		return p
+	}
+	return fallback
+}
+
+func skipPreFlight(c *cli.Context) *bool {
+	if !c.IsSet(SkipPreFlightFlag.Name) {
+		return nil
+	}
+	skip := c.Bool(SkipPreFlightFlag.Name)
+	return &skip
+}
+
+func listMethods(c *cli.Context) error {
+	p, err := settings(c)
+	if err != nil {
+		return err
+	}
+	out := &printer{w: c.App.Writer, format: p.Output}
+	filter := strings.ToLower(c.Args().First())
+
+	var list []map[string]any
+	for _, m := range methods(clientType(p.Transport)) {
+		if filter != "" && !strings.Contains(strings.ToLower(m.Name), filter) {
+			continue
+		}
+		if p.Output == outputTable {
+			fmt.Fprintln(out.w, signature(m))
+			continue
+		}
+		list = append(list, map[string]any{"name": m.Name, "signature": signature(m), "submits": mutating(m.Name), "stream": returnsStream(m)})
+	}
+	if p.Output == outputTable {
+		return nil
+	}
+	return out.print(list, false)
+}
+
+func callMethod(ctx context.Context, s *session, c *cli.Context) error {
+	if c.NArg() == 0 {
+		return errors.New("missing method, see `trader methods`")
+	}
+	name := c.Args().First()
+	var args []any
+	for _, arg := range c.Args().Tail() {
+		args = append(args, arg)
+	}
+
+	m, err := lookup(s.client, name)
+	if err != nil {
+		return err
+	}
+	if s.dryRun && mutating(m.Name) {
+		in, err := arguments(ctx, m, args)
+		if err != nil {
+			return err
+		}
+		return s.out.print(dryRun(m, in), false)
+	}
+	return s.call(ctx, []string{m.Name}, args...)
+}
+
+// dryRun describes a call that would have submitted transactions
+func dryRun(m reflect.Method, in []reflect.Value) map[string]any {
+	var args []json.RawMessage
+	for i, v := range in {
+		if m.Type.In(i+1) == contextType {
+			continue
+		}
+		var data []byte
+		if msg, ok := v.Interface().(proto.Message); ok {
+			data, _ = protojson.Marshal(msg)
+		} else {
+			data, _ = json.Marshal(v.Interface())
+		}
+		args = append(args, data)
+	}
+	return map[string]any{"dryRun": true, "method": m.Name, "args": args}
+}
+
+func swap(ctx context.Context, s *session, c *cli.Context) error {
+	owner, err := s.owner(c)
+	if err != nil {
+		return err
+	}
+	args := []any{owner, c.String("in"), c.String("out"), c.Float64("amount"), c.Float64("slippage"), c.String("project")}
+	if s.dryRun {
+		return s.call(ctx, []string{"PostTradeSwap"}, args...)
+	}
+
+	opts := provider.SubmitOpts{SkipPreFlight: skipPreFlight(c)}
+	if strategy := c.String("strategy"); strategy != "" {
+		v, err := parse(strategy, reflect.TypeOf(pb.SubmitStrategy(0)))
+		if err != nil {
+			return err
+		}
+		opts.SubmitStrategy = v.Interface().(pb.SubmitStrategy)
+	}
+	return s.call(ctx, []string{"SubmitTradeSwap"}, append(args, opts)...)
+}
+
+func placeOrder(ctx context.Context, s *session, c *cli.Context) error {
+	owner, err := s.owner(c)
+	if err != nil {
+		return err
+	}
+	payer := c.String("payer")
+	if payer == "" {
+		payer = owner
+	}
+	opts := provider.PostOrderOpts{
+		OpenOrdersAddress: c.String("open-orders"),
+		ClientOrderID:     c.Uint64("client-order-id"),
+		SkipPreFlight:     skipPreFlight(c),
+	}
+	args := []any{owner, payer, c.String(MarketFlag.Name), c.String("side"), c.String("type"), c.Float64("amount"),
+		c.Float64("price"), project(c, "openbook"), opts}
+
+	method := "Order"
+	if c.IsSet("order-id") {
+		method = "ReplaceOrder"
+		args = append([]any{c.String("order-id")}, args...)
+	}
+	if s.dryRun {
+		return s.call(ctx, []string{"Post" + method}, args...)
+	}
+	return s.call(ctx, []string{"Submit" + method}, args...)
+}
+
+func cancelOrder(ctx context.Context, s *session, c *cli.Context) error {
+	owner, err := s.owner(c)
+	if err != nil {
+		return err
+	}
+	var (
+		market     = c.String(MarketFlag.Name)
+		orderID    = c.String("order-id")
+		openOrders = c.String("open-orders")
+		skip       = c.Bool(SkipPreFlightFlag.Name)
+	)
+	side, err := parse(c.String("side"), reflect.TypeOf(pb.Side(0)))
+	if err != nil {
+		return err
+	}
+	proj, err := parse(project(c, "openbook"), reflect.TypeOf(pb.Project(0)))
+	if err != nil {
+		return err
+	}
+
+	// the websocket client takes a request, the others its fields
+	var args []any
+	if s.transport == transportWS {
+		args = []any{&pb.PostCancelOrderRequest{
+			OrderID:           orderID,
+			Side:              side.Interface().(pb.Side),
+			MarketAddress:     market,
+			OwnerAddress:      owner,
+			OpenOrdersAddress: openOrders,
+			Project:           proj.Interface().(pb.Project),
+		}}
+	} else {
+		args = []any{orderID, side.Interface(), owner, market, openOrders, proj.Interface()}
+	}
+	if s.dryRun {
+		return s.call(ctx, []string{"PostCancelOrder"}, args...)
+	}
+	return s.call(ctx, []string{"SubmitCancelOrder"}, append(args, skip)...)
+}
+
+func submit(ctx context.Context, s *session, c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("expected a single base64 encoded transaction")
+	}
+	content := c.Args().First()
+	if s.dryRun {
+		description, err := describeTransaction(content)
+		if err != nil {
+			return err
+		}
+		return s.out.print(description, false)
+	}
+	return s.call(ctx, []string{"SignAndSubmit"}, &pb.TransactionMessage{Content: content},
+		c.Bool(SkipPreFlightFlag.Name), c.Bool("front-running-protection"), c.Bool("staked-rpcs"))
+}
+
+// printDryRun prints the transactions the client built instead of submitting them
+func (s *session) printDryRun(dryRun *provider.DryRunError) error {
+	for _, entry := range dryRun.Request.Entries {
+		description, err := describeTransaction(entry.Transaction.Content)
+		if err != nil {
+			return err
+		}
+		description["skipPreFlight"] = entry.SkipPreFlight
+		if err = s.out.print(description, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeTransaction decodes a transaction for dry runs
+func describeTransaction(content string) (map[string]any, error) {
+	data, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode transaction: %w", err)
+	}
+	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse transaction: %w", err)
+	}
+
+	var signers, programs []string
+	for i := 0; i < int(tx.Message.Header.NumRequiredSignatures) && i < len(tx.Message.AccountKeys); i++ {
+		signers = append(signers, tx.Message.AccountKeys[i].String())
+	}
+	seen := make(map[string]bool)
+	for _, instruction := range tx.Message.Instructions {
+		if program, err := tx.Message.Program(instruction.ProgramIDIndex); err == nil && !seen[program.String()] {
+			seen[program.String()] = true
+			programs = append(programs, program.String())
+		}
+	}
+	sort.Strings(programs)
+
+	return map[string]any{
+		"dryRun":          true,
+		"version":         fmt.Sprint(tx.Message.GetVersion()),
+		"recentBlockhash": tx.Message.RecentBlockhash.String(),
+		"signers":         signers,
+		"accounts":        len(tx.Message.AccountKeys),
+		"lookupTables":    len(tx.Message.AddressTableLookups),
+		"instructions":    len(tx.Message.Instructions),
+		"programs":        programs,
+	}, nil
+}
+
+func listProfiles(c *cli.Context) error {
+	config, err := loadConfig(c.String(ConfigFlag.Name), c.IsSet(ConfigFlag.Name))
+	if err != nil {
+		return err
+	}
+	p, err := settings(c)
+	if err != nil {
+		return err
+	}
+
+	profiles := make(map[string]any)
+	for name, profile := range config.Profiles {
+		profiles[name] = map[string]any{
+			"transport": profile.Transport,
+			"env":       profile.Env,
+			"endpoint":  profile.Endpoint,
+			"owner":     profile.owner(),
+		}
+	}
+	return (&printer{w: c.App.Writer, format: p.Output}).print(profiles, false)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	transportGRPC = "grpc"
+	transportWS   = "ws"
+	transportHTTP = "http"
+
+	defaultProfile = "default"
+)
+
+// Profile is a named set of connection settings in the config file
+type Profile struct {
+	// Transport is grpc, ws or http
+	Transport string `json:"transport,omitempty"`
+	// Env selects the endpoint: mainnet, mainnet-uk, pump-ny, testnet, devnet or local
+	Env string `json:"env,omitempty"`
+	// Endpoint overrides the endpoint of the environment
+	Endpoint string `json:"endpoint,omitempty"`
+	// UseTLS applies to GRPC endpoints set explicitly
+	UseTLS     bool   `json:"useTLS,omitempty"`
+	AuthHeader string `json:"authHeader,omitempty"`
+	// PrivateKey in base58, used to sign transactions
+	PrivateKey string `json:"privateKey,omitempty"`
+	// Owner is the default wallet address, the private key's by default
+	Owner string `json:"owner,omitempty"`
+	// Output is json or table
+	Output string `json:"output,omitempty"`
+}
+
+type Config struct {
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+type endpoints struct {
+	grpc, ws, http string
+	tls            bool
+}
+
+var environments = map[string]endpoints{
+	"mainnet":    {grpc: provider.MainnetNYGRPC, ws: provider.MainnetNYWS, http: provider.MainnetNYHTTP, tls: true},
+	"mainnet-uk": {grpc: provider.MainnetUKGRPC, ws: provider.MainnetUKWS, http: provider.MainnetUKHTTP, tls: true},
+	"pump-ny":    {grpc: provider.MainnetPumpNYGRPC, ws: provider.MainnetPumpNYWS, http: provider.MainnetPumpNYHTTP, tls: true},
+	"testnet":    {grpc: provider.TestnetGRPC, ws: provider.TestnetWS, http: provider.TestnetHTTP, tls: true},
+	"devnet":     {grpc: provider.DevnetGRPC, ws: provider.DevnetWS, http: provider.DevnetHTTP},
+	"local":      {grpc: provider.LocalGRPC, ws: provider.LocalWS, http: provider.LocalHTTP},
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "solana-trader", "config.json")
+}
+
+// loadConfig reads the config file. A missing file is an empty config, unless it was asked for explicitly.
+func loadConfig(path string, explicit bool) (Config, error) {
+	var config Config
+	if path == "" {
+		return config, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("could not read config: %w", err)
+	}
+	if err = json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("could not parse config %v: %w", path, err)
+	}
+	return config, nil
+}
+
+// profile returns a profile of the config. Only a missing default profile is allowed.
+func (c Config) profile(name string) (Profile, error) {
+	profile, ok := c.Profiles[name]
+	if !ok && name != defaultProfile {
+		return profile, fmt.Errorf("unknown profile %v", name)
+	}
+	return profile, nil
+}
+
+// merge fills the unset settings of p from other
+func (p Profile) merge(other Profile) Profile {
+	first := func(a, b string) string {
+		if a != "" {
+			return a
+		}
+		return b
+	}
+	p.Transport = strings.ToLower(first(p.Transport, other.Transport))
+	p.Env = strings.ToLower(first(p.Env, other.Env))
+	p.Endpoint = first(p.Endpoint, other.Endpoint)
+	p.UseTLS = p.UseTLS || other.UseTLS
+	p.AuthHeader = first(p.AuthHeader, other.AuthHeader)
+	p.PrivateKey = first(p.PrivateKey, other.PrivateKey)
+	p.Owner = first(p.Owner, other.Owner)
+	p.Output = strings.ToLower(first(p.Output, other.Output))
+	return p
+}
+
+// rpcOpts returns the client options of a complete profile
+func (p Profile) rpcOpts() (provider.RPCOpts, error) {
+	opts := provider.RPCOpts{Endpoint: p.Endpoint, AuthHeader: p.AuthHeader, UseTLS: p.UseTLS}
+	if opts.Endpoint == "" {
+		env, ok := environments[p.Env]
+		if !ok {
+			return opts, fmt.Errorf("unknown environment %v", p.Env)
+		}
+		switch p.Transport {
+		case transportGRPC:
+			opts.Endpoint = env.grpc
+		case transportWS:
+			opts.Endpoint = env.ws
+		case transportHTTP:
+			opts.Endpoint = env.http
+		}
+		opts.UseTLS = env.tls
+	}
+	if p.PrivateKey != "" {
+		privateKey, err := solana.PrivateKeyFromBase58(p.PrivateKey)
+		if err != nil {
+			return opts, fmt.Errorf("invalid private key: %w", err)
+		}
+		opts.PrivateKey = &privateKey
+	}
+	return opts, nil
+}
+
+// owner returns the configured owner, or the address of the private key
+func (p Profile) owner() string {
+	if p.Owner != "" {
+		return p.Owner
+	}
+	if privateKey, err := solana.PrivateKeyFromBase58(p.PrivateKey); err == nil {
+		return privateKey.PublicKey().String()
+	}
+	return ""
+}
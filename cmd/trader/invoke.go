@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/amount"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	ErrUnknownMethod = errors.New("unknown method")
+	ErrArguments     = errors.New("wrong arguments")
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	messageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+	enumType    = reflect.TypeOf((*protoreflect.Enum)(nil)).Elem()
+	amountType  = reflect.TypeOf(amount.Amount{})
+)
+
+// stream is a started stream of any update type
+type stream func() (any, error)
+
+// mutating reports whether calling a method submits transactions
+func mutating(name string) bool {
+	for _, prefix := range []string{"Submit", "SignAndSubmit", "PostSubmit"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// returnsStream reports whether a method starts a stream
+func returnsStream(m reflect.Method) bool {
+	if m.Type.NumOut() == 0 {
+		return false
+	}
+	return isStreamer(m.Type.Out(0))
+}
+
+func isStreamer(t reflect.Type) bool {
+	return t.Kind() == reflect.Func && t.NumIn() == 0 && t.NumOut() == 2 && t.Out(1) == errorType
+}
+
+// methods lists the exported methods of a client, sorted by name
+func methods(client any) []reflect.Method {
+	t := reflect.TypeOf(client)
+	var list []reflect.Method
+	for i := 0; i < t.NumMethod(); i++ {
+		list = append(list, t.Method(i))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// signature describes the arguments and results of a method, without the receiver and context
+func signature(m reflect.Method) string {
+	var in, out []string
+	for i := 1; i < m.Type.NumIn(); i++ {
+		if t := m.Type.In(i); t != contextType {
+			in = append(in, t.String())
+		}
+	}
+	for i := 0; i < m.Type.NumOut(); i++ {
+		out = append(out, m.Type.Out(i).String())
+	}
+	return fmt.Sprintf("%v(%v) (%v)", m.Name, strings.Join(in, ", "), strings.Join(out, ", "))
+}
+
+// lookup returns the first method of a client matching one of the names, case-insensitively
+func lookup(client any, names ...string) (reflect.Method, error) {
+	t := reflect.TypeOf(client)
+	for _, name := range names {
+		if m, ok := t.MethodByName(name); ok {
+			return m, nil
+		}
+		for i := 0; i < t.NumMethod(); i++ {
+			if m := t.Method(i); strings.EqualFold(m.Name, name) {
+				return m, nil
+			}
+		}
+	}
+	return reflect.Method{}, fmt.Errorf("%w: %v", ErrUnknownMethod, strings.Join(names, " or "))
+}
+
+// arguments converts args to the parameters of a method. Args can be values of the parameter type or strings, parsed
+// as described by parse. The context is passed implicitly.
+func arguments(ctx context.Context, m reflect.Method, args []any) ([]reflect.Value, error) {
+	var in []reflect.Value
+	next := 0
+	for i := 1; i < m.Type.NumIn(); i++ {
+		t := m.Type.In(i)
+		if t == contextType {
+			in = append(in, reflect.ValueOf(ctx))
+			continue
+		}
+		if m.Type.IsVariadic() && i == m.Type.NumIn()-1 {
+			break
+		}
+		if next >= len(args) {
+			return nil, fmt.Errorf("%w: %v", ErrArguments, signature(m))
+		}
+		v, err := convert(args[next], t)
+		if err != nil {
+			return nil, fmt.Errorf("argument %v of %v: %w", next+1, m.Name, err)
+		}
+		in = append(in, v)
+		next++
+	}
+	if next != len(args) {
+		return nil, fmt.Errorf("%w: %v", ErrArguments, signature(m))
+	}
+	return in, nil
+}
+
+// invoke calls a method and returns its result, or a stream if it starts one
+func invoke(client any, m reflect.Method, in []reflect.Value) (any, error) {
+	out := reflect.ValueOf(client).Method(m.Index).Call(in)
+
+	var result reflect.Value
+	for _, v := range out {
+		if v.Type() == errorType {
+			if !v.IsNil() {
+				return nil, v.Interface().(error)
+			}
+			continue
+		}
+		result = v
+	}
+	if !result.IsValid() {
+		return nil, nil
+	}
+	if isStreamer(result.Type()) {
+		return stream(func() (any, error) {
+			out := result.Call(nil)
+			if err, _ := out[1].Interface().(error); err != nil {
+				return nil, err
+			}
+			return out[0].Interface(), nil
+		}), nil
+	}
+	return result.Interface(), nil
+}
+
+func convert(arg any, t reflect.Type) (reflect.Value, error) {
+	if arg == nil {
+		return reflect.Zero(t), nil
+	}
+	v := reflect.ValueOf(arg)
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+	if s, ok := arg.(string); ok {
+		return parse(s, t)
+	}
+	if v.Type().ConvertibleTo(t) && v.Kind() == t.Kind() {
+		return v.Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("%w: %v is not a %v", ErrArguments, v.Type(), t)
+}
+
+// parse converts a string to a parameter: enums by name (e.g. raydium, P_RAYDIUM) or number, requests as protojson,
+// option structs as JSON, slices as comma separated values or JSON, and exact amounts as value:decimals
+func parse(s string, t reflect.Type) (reflect.Value, error) {
+	switch {
+	case t == amountType:
+		value, decimals, ok := strings.Cut(s, ":")
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("%w: amount %q needs decimals (value:decimals)", ErrArguments, s)
+		}
+		d, err := strconv.Atoi(decimals)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%w: amount %q: %v", ErrArguments, s, err)
+		}
+		a, err := amount.Parse(value, d)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(a), nil
+	case t.Implements(enumType):
+		return parseEnum(s, t)
+	case t.Implements(messageType) && t.Kind() == reflect.Ptr:
+		msg := reflect.New(t.Elem())
+		if s == "" {
+			s = "{}"
+		}
+		if err := protojson.Unmarshal([]byte(s), msg.Interface().(proto.Message)); err != nil {
+			return reflect.Value{}, fmt.Errorf("%w: %v: %v", ErrArguments, t.Elem().Name(), err)
+		}
+		return msg, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(s).Convert(t), nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%w: %v", ErrArguments, err)
+		}
+		return reflect.ValueOf(b).Convert(t), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%w: %v", ErrArguments, err)
+		}
+		return reflect.ValueOf(i).Convert(t), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%w: %v", ErrArguments, err)
+		}
+		return reflect.ValueOf(u).Convert(t), nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%w: %v", ErrArguments, err)
+		}
+		return reflect.ValueOf(f).Convert(t), nil
+	case reflect.Ptr:
+		if s == "" || s == "null" {
+			return reflect.Zero(t), nil
+		}
+		v, err := parse(s, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(v)
+		return p, nil
+	case reflect.Slice:
+		if strings.HasPrefix(s, "[") && !t.Elem().Implements(enumType) && !t.Elem().Implements(messageType) {
+			return unmarshalJSON(s, t)
+		}
+		slice := reflect.MakeSlice(t, 0, 0)
+		if s == "" {
+			return slice, nil
+		}
+		for _, item := range strings.Split(strings.Trim(s, "[]"), ",") {
+			v, err := parse(strings.Trim(strings.TrimSpace(item), `"`), t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			slice = reflect.Append(slice, v)
+		}
+		return slice, nil
+	case reflect.Struct, reflect.Map:
+		if s == "" {
+			return reflect.Zero(t), nil
+		}
+		return unmarshalJSON(s, t)
+	}
+	return reflect.Value{}, fmt.Errorf("%w: can't parse %v", ErrArguments, t)
+}
+
+func parseEnum(s string, t reflect.Type) (reflect.Value, error) {
+	values := reflect.Zero(t).Interface().(protoreflect.Enum).Descriptor().Values()
+	if n, err := strconv.ParseInt(s, 10, 32); err == nil {
+		if values.ByNumber(protoreflect.EnumNumber(n)) == nil {
+			return reflect.Value{}, fmt.Errorf("%w: no %v %v", ErrArguments, t.Name(), n)
+		}
+		return reflect.ValueOf(n).Convert(t), nil
+	}
+
+	var names []string
+	for i := 0; i < values.Len(); i++ {
+		value := values.Get(i)
+		name := string(value.Name())
+		// values are prefixed with an abbreviation of the type, e.g. P_RAYDIUM
+		_, short, _ := strings.Cut(name, "_")
+		if strings.EqualFold(s, name) || strings.EqualFold(s, short) {
+			return reflect.ValueOf(int64(value.Number())).Convert(t), nil
+		}
+		names = append(names, strings.ToLower(short))
+	}
+	return reflect.Value{}, fmt.Errorf("%w: %v is not a %v (%v)", ErrArguments, s, t.Name(), strings.Join(names, ", "))
+}
+
+func unmarshalJSON(s string, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t)
+	if err := json.Unmarshal([]byte(s), v.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("%w: %v: %v", ErrArguments, t, err)
+	}
+	return v.Elem(), nil
+}
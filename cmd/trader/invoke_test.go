@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/amount"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	v, err := parse("raydium", reflect.TypeOf(pb.Project(0)))
+	require.Nil(t, err)
+	assert.Equal(t, pb.Project_P_RAYDIUM, v.Interface())
+
+	v, err = parse("P_JUPITER", reflect.TypeOf(pb.Project(0)))
+	require.Nil(t, err)
+	assert.Equal(t, pb.Project_P_JUPITER, v.Interface())
+
+	_, err = parse("nope", reflect.TypeOf(pb.Project(0)))
+	assert.ErrorIs(t, err, ErrArguments)
+
+	v, err = parse("raydium, jupiter", reflect.TypeOf([]pb.Project{}))
+	require.Nil(t, err)
+	assert.Equal(t, []pb.Project{pb.Project_P_RAYDIUM, pb.Project_P_JUPITER}, v.Interface())
+
+	v, err = parse(`{"inToken":"SOL","slippage":0.5}`, reflect.TypeOf(&pb.GetRaydiumQuotesRequest{}))
+	require.Nil(t, err)
+	assert.Equal(t, "SOL", v.Interface().(*pb.GetRaydiumQuotesRequest).InToken)
+	assert.Equal(t, 0.5, v.Interface().(*pb.GetRaydiumQuotesRequest).Slippage)
+
+	v, err = parse("1.5:9", amountType)
+	require.Nil(t, err)
+	f, err := v.Interface().(amount.Amount).ExactFloat64()
+	require.Nil(t, err)
+	assert.Equal(t, 1.5, f)
+
+	v, err = parse("true", reflect.TypeOf((*bool)(nil)))
+	require.Nil(t, err)
+	assert.True(t, *v.Interface().(*bool))
+
+	v, err = parse(`{"SkipPreFlight":true}`, reflect.TypeOf(provider.SubmitOpts{}))
+	require.Nil(t, err)
+	assert.True(t, *v.Interface().(provider.SubmitOpts).SkipPreFlight)
+}
+
+func TestArguments(t *testing.T) {
+	m, err := lookup(clientType(transportGRPC), "getquotes")
+	require.Nil(t, err)
+	assert.Equal(t, "GetQuotes", m.Name)
+	assert.False(t, mutating(m.Name))
+
+	in, err := arguments(context.Background(), m, []any{"SOL", "USDC", "1", 0.5, "3", "raydium"})
+	require.Nil(t, err)
+	require.Len(t, in, 7)
+	assert.Equal(t, int32(3), in[5].Interface())
+	assert.Equal(t, []pb.Project{pb.Project_P_RAYDIUM}, in[6].Interface())
+
+	_, err = arguments(context.Background(), m, []any{"SOL"})
+	assert.ErrorIs(t, err, ErrArguments)
+
+	_, err = lookup(clientType(transportGRPC), "Nope")
+	assert.ErrorIs(t, err, ErrUnknownMethod)
+
+	m, err = lookup(clientType(transportWS), "GetOrderbooksStream")
+	require.Nil(t, err)
+	assert.True(t, returnsStream(m))
+}
+
+func TestTable(t *testing.T) {
+	var buf bytes.Buffer
+	p := &printer{w: &buf, format: outputTable}
+
+	err := p.print(&pb.GetAccountBalanceResponse{Tokens: []*pb.TokenBalance{
+		{Symbol: "SOL", SettledAmount: 1.5},
+		{Symbol: "USDC", SettledAmount: 10},
+	}}, false)
+	require.Nil(t, err)
+	assert.Contains(t, buf.String(), "SYMBOL")
+	assert.Contains(t, buf.String(), "USDC")
+
+	buf.Reset()
+	err = p.print(map[string]any{"b": 2, "a": "x"}, false)
+	require.Nil(t, err)
+	assert.Equal(t, "a  x\nb  2\n", buf.String())
+}
@@ -0,0 +1,259 @@
+// Command trader calls the Solana Trader API from the command line, over GRPC, websockets or HTTP. Every method of
+// the clients can be called with `trader call`, and common operations have their own subcommands.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	ConfigFlag = &cli.StringFlag{
+		Name:  "config",
+		Usage: "config file with connection profiles",
+		Value: defaultConfigPath(),
+	}
+	ProfileFlag = &cli.StringFlag{
+		Name:    "profile",
+		Aliases: []string{"p"},
+		Usage:   "profile of the config file to use",
+		EnvVars: []string{"TRADER_PROFILE"},
+		Value:   defaultProfile,
+	}
+	TransportFlag = &cli.StringFlag{
+		Name:    "transport",
+		Aliases: []string{"t"},
+		Usage:   "transport to use (options: grpc, ws, http) (default: grpc)",
+	}
+	EnvFlag = &cli.StringFlag{
+		Name:  "env",
+		Usage: "trader API environment (options: mainnet, mainnet-uk, pump-ny, testnet, devnet, local) (default: mainnet)",
+	}
+	EndpointFlag = &cli.StringFlag{
+		Name:  "endpoint",
+		Usage: "trader API endpoint, instead of the environment's",
+	}
+	AuthHeaderFlag = &cli.StringFlag{
+		Name:  "auth-header",
+		Usage: "trader API auth header (default: AUTH_HEADER environment variable)",
+	}
+	OwnerFlag = &cli.StringFlag{
+		Name:  "owner",
+		Usage: "wallet address (default: the address of the private key, from the profile or PRIVATE_KEY)",
+	}
+	OutputFlag = &cli.StringFlag{
+		Name:    "output",
+		Aliases: []string{"o"},
+		Usage:   "output format (options: json, table) (default: table)",
+	}
+	DryRunFlag = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "build transactions and print them instead of submitting them",
+	}
+	TimeoutFlag = &cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "timeout of requests, streams run until interrupted",
+		Value: 30 * time.Second,
+	}
+)
+
+func main() {
+	app := &cli.App{
+		Name:  "trader",
+		Usage: "Calls the Solana Trader API",
+		Flags: []cli.Flag{
+			ConfigFlag,
+			ProfileFlag,
+			TransportFlag,
+			EnvFlag,
+			EndpointFlag,
+			AuthHeaderFlag,
+			OwnerFlag,
+			OutputFlag,
+			DryRunFlag,
+			TimeoutFlag,
+		},
+		Commands: commands(),
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// session is the client and settings of a command
+type session struct {
+	client    any
+	transport string
+	profile   Profile
+	out       *printer
+	dryRun    bool
+	timeout   time.Duration
+	close     func()
+}
+
+// settings merges the flags, the profile and the environment, in that order
+func settings(c *cli.Context) (Profile, error) {
+	config, err := loadConfig(c.String(ConfigFlag.Name), c.IsSet(ConfigFlag.Name))
+	if err != nil {
+		return Profile{}, err
+	}
+	profile, err := config.profile(c.String(ProfileFlag.Name))
+	if err != nil {
+		return Profile{}, err
+	}
+
+	flags := Profile{
+		Transport:  c.String(TransportFlag.Name),
+		Env:        c.String(EnvFlag.Name),
+		Endpoint:   c.String(EndpointFlag.Name),
+		AuthHeader: c.String(AuthHeaderFlag.Name),
+		Owner:      c.String(OwnerFlag.Name),
+		Output:     c.String(OutputFlag.Name),
+	}
+	defaults := Profile{
+		Transport:  transportGRPC,
+		Env:        "mainnet",
+		AuthHeader: os.Getenv("AUTH_HEADER"),
+		PrivateKey: os.Getenv("PRIVATE_KEY"),
+		Output:     outputTable,
+	}
+	p := flags.merge(profile).merge(defaults)
+
+	switch p.Transport {
+	case transportGRPC, transportWS, transportHTTP:
+	default:
+		return p, fmt.Errorf("unknown transport %v", p.Transport)
+	}
+	if p.Output != outputJSON && p.Output != outputTable {
+		return p, fmt.Errorf("unknown output format %v", p.Output)
+	}
+	return p, nil
+}
+
+func newSession(c *cli.Context) (*session, error) {
+	p, err := settings(c)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := p.rpcOpts()
+	if err != nil {
+		return nil, err
+	}
+	// the client doesn't submit anything, including from methods `call` doesn't recognize as submitting
+	opts.DryRun = c.Bool(DryRunFlag.Name)
+
+	s := &session{
+		transport: p.Transport,
+		profile:   p,
+		out:       &printer{w: os.Stdout, format: p.Output},
+		dryRun:    c.Bool(DryRunFlag.Name),
+		timeout:   c.Duration(TimeoutFlag.Name),
+		close:     func() {},
+	}
+	switch p.Transport {
+	case transportGRPC:
+		s.client, err = provider.NewGRPCClientWithOpts(opts)
+	case transportWS:
+		var ws *provider.WSClient
+		ws, err = provider.NewWSClientWithOpts(opts)
+		s.client, s.close = ws, func() { _ = ws.Close() }
+	case transportHTTP:
+		s.client = provider.NewHTTPClientWithOpts(nil, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to %v: %w", opts.Endpoint, err)
+	}
+	return s, nil
+}
+
+// clientType returns a client of a transport to list methods of, without connecting
+func clientType(transport string) any {
+	switch transport {
+	case transportWS:
+		return (*provider.WSClient)(nil)
+	case transportHTTP:
+		return (*provider.HTTPClient)(nil)
+	}
+	return (*provider.GRPCClient)(nil)
+}
+
+// action runs a command with a connected session, until it's done or interrupted
+func action(run func(ctx context.Context, s *session, c *cli.Context) error) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		s, err := newSession(c)
+		if err != nil {
+			return err
+		}
+		defer s.close()
+		return run(ctx, s, c)
+	}
+}
+
+func (s *session) owner(c *cli.Context) (string, error) {
+	if owner := c.String(OwnerFlag.Name); owner != "" {
+		return owner, nil
+	}
+	if owner := s.profile.owner(); owner != "" {
+		return owner, nil
+	}
+	return "", errors.New("no owner: set --owner, or a private key in the profile or PRIVATE_KEY")
+}
+
+// call calls a method within the timeout, or starts a stream, and prints the results
+func (s *session) call(ctx context.Context, names []string, args ...any) error {
+	m, err := lookup(s.client, names...)
+	if err != nil {
+		return err
+	}
+	if !returnsStream(m) {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	in, err := arguments(ctx, m, args)
+	if err != nil {
+		return err
+	}
+	result, err := invoke(s.client, m, in)
+	var dryRun *provider.DryRunError
+	if errors.As(err, &dryRun) {
+		return s.printDryRun(dryRun)
+	}
+	if err != nil {
+		return err
+	}
+	return s.print(ctx, result)
+}
+
+// print prints a result, or each update of a stream until it ends or the command is interrupted
+func (s *session) print(ctx context.Context, result any) error {
+	updates, ok := result.(stream)
+	if !ok {
+		return s.out.print(result, false)
+	}
+	for {
+		update, err := updates()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err = s.out.print(update, true); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	outputJSON  = "json"
+	outputTable = "table"
+)
+
+// printer writes results as JSON or tables. Stream updates are written as one line of JSON each.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func (p *printer) print(v any, compact bool) error {
+	if p.format == outputJSON {
+		return p.json(v, compact)
+	}
+	return p.table(v)
+}
+
+func (p *printer) json(v any, compact bool) error {
+	var (
+		data []byte
+		err  error
+	)
+	if msg, ok := v.(proto.Message); ok {
+		data, err = protojson.MarshalOptions{Multiline: !compact, Indent: "  "}.Marshal(msg)
+	} else if compact {
+		data, err = json.Marshal(v)
+	} else {
+		data, err = json.MarshalIndent(v, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("could not marshal result: %w", err)
+	}
+	_, err = fmt.Fprintln(p.w, string(data))
+	return err
+}
+
+// table writes a message as rows if its only populated field is a list of messages, and as field and value pairs
+// otherwise
+func (p *printer) table(v any) error {
+	w := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	switch value := v.(type) {
+	case proto.Message:
+		m := value.ProtoReflect()
+		if rows, ok := listField(m); ok {
+			writeRows(w, rows)
+		} else {
+			m.Range(func(field protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+				fmt.Fprintf(w, "%v\t%v\n", field.JSONName(), format(field, v))
+				return true
+			})
+		}
+	case map[string]any:
+		for _, key := range sortedKeys(value) {
+			fmt.Fprintf(w, "%v\t%v\n", key, plain(value[key]))
+		}
+	default:
+		fmt.Fprintln(w, plain(v))
+	}
+	return w.Flush()
+}
+
+func listField(m protoreflect.Message) (protoreflect.List, bool) {
+	var (
+		list  protoreflect.List
+		count int
+	)
+	m.Range(func(field protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		count++
+		if field.IsList() && field.Kind() == protoreflect.MessageKind {
+			list = v.List()
+		}
+		return true
+	})
+	return list, count == 1 && list != nil
+}
+
+func writeRows(w io.Writer, rows protoreflect.List) {
+	if rows.Len() == 0 {
+		return
+	}
+	fields := rows.Get(0).Message().Descriptor().Fields()
+	var header []string
+	for i := 0; i < fields.Len(); i++ {
+		header = append(header, strings.ToUpper(fields.Get(i).JSONName()))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Get(i).Message()
+		var cells []string
+		for j := 0; j < fields.Len(); j++ {
+			field := fields.Get(j)
+			if !row.Has(field) {
+				cells = append(cells, "")
+				continue
+			}
+			cells = append(cells, format(field, row.Get(field)))
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+}
+
+// format writes a field value on one line
+func format(field protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch {
+	case field.IsList():
+		list := v.List()
+		items := make([]string, 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			items = append(items, scalar(field, list.Get(i)))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case field.IsMap():
+		var items []string
+		v.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			items = append(items, fmt.Sprintf("%v=%v", key.Interface(), scalar(field.MapValue(), value)))
+			return true
+		})
+		sort.Strings(items)
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return scalar(field, v)
+}
+
+func scalar(field protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		data, _ := protojson.Marshal(v.Message().Interface())
+		return string(data)
+	case protoreflect.EnumKind:
+		if value := field.Enum().Values().ByNumber(v.Enum()); value != nil {
+			return string(value.Name())
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func plain(v any) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case fmt.Stringer:
+		return value.String()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,22 +1,42 @@
 package connections
 
+import "context"
+
 type Streamer[T any] func() (T, error)
 
+// Channel returns a channel receiving all stream updates, closed once the stream ends. The forwarding goroutine stays
+// blocked if the channel is no longer read: use ChannelWithContext to be able to release it.
 func (s Streamer[T]) Channel(size int) chan T {
+	return s.ChannelWithContext(context.Background(), size)
+}
+
+// Into forwards all stream updates to ch, closing it once the stream ends. The forwarding goroutine stays blocked if
+// ch is no longer read: use IntoWithContext to be able to release it.
+func (s Streamer[T]) Into(ch chan T) {
+	s.IntoWithContext(context.Background(), ch)
+}
+
+// ChannelWithContext is like Channel, but stops forwarding and closes the channel when ctx is canceled
+func (s Streamer[T]) ChannelWithContext(ctx context.Context, size int) chan T {
 	ch := make(chan T, size)
-	s.Into(ch)
+	s.IntoWithContext(ctx, ch)
 	return ch
 }
 
-func (s Streamer[T]) Into(ch chan T) {
+// IntoWithContext is like Into, but stops forwarding and closes ch when ctx is canceled, even if nobody is reading
+func (s Streamer[T]) IntoWithContext(ctx context.Context, ch chan T) {
 	go func() {
+		defer close(ch)
 		for {
 			v, err := s()
 			if err != nil {
-				close(ch)
 				return
 			}
-			ch <- v
+			select {
+			case ch <- v:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
 }
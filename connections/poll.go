@@ -0,0 +1,134 @@
+package connections
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	defaultMinPollInterval = 500 * time.Millisecond
+	defaultMaxPollInterval = 5 * time.Second
+	defaultMaxPollFailures = 3
+)
+
+// PollOpts controls how often a polled stream queries the underlying endpoint. The interval starts at MinInterval,
+// doubles after every poll that produced no changes (up to MaxInterval) and resets once a change is seen.
+type PollOpts struct {
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	// MaxFailures is the number of consecutive failed polls tolerated before the stream returns an error
+	MaxFailures int
+}
+
+func DefaultPollOpts() PollOpts {
+	return PollOpts{
+		MinInterval: defaultMinPollInterval,
+		MaxInterval: defaultMaxPollInterval,
+		MaxFailures: defaultMaxPollFailures,
+	}
+}
+
+// PollSpec describes how to emulate a stream from a request/response endpoint
+type PollSpec[T any] struct {
+	// Poll fetches the current snapshot of all items
+	Poll func(ctx context.Context) ([]T, error)
+	// Key identifies an item across snapshots
+	Key func(T) string
+	// Equal reports whether two items with the same key are unchanged. If nil, items are never re-emitted once seen.
+	Equal func(a, b T) bool
+	// SkipSnapshot suppresses the items returned by the first poll, so only later changes are emitted
+	SkipSnapshot bool
+}
+
+// PollStream emulates a stream by repeatedly polling, diffing each snapshot against the previous one and emitting
+// only new or changed items. An item that drops out of a snapshot and later reappears is emitted again. Polling happens on the caller's goroutine when the streamer is invoked, so nothing is
+// left running once the caller stops reading or ctx is canceled.
+func PollStream[T any](ctx context.Context, spec PollSpec[T], opts PollOpts) Streamer[T] {
+	if opts.MinInterval <= 0 {
+		opts.MinInterval = defaultMinPollInterval
+	}
+	if opts.MaxInterval < opts.MinInterval {
+		opts.MaxInterval = opts.MinInterval
+	}
+	if opts.MaxFailures <= 0 {
+		opts.MaxFailures = defaultMaxPollFailures
+	}
+
+	var (
+		seen     = make(map[string]T)
+		pending  []T
+		interval time.Duration
+		failures int
+		first    = true
+	)
+
+	return func() (T, error) {
+		var zero T
+		for len(pending) == 0 {
+			if interval > 0 {
+				timer := time.NewTimer(interval)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return zero, errors.New("stream context has been closed")
+				case <-timer.C:
+				}
+			} else if ctx.Err() != nil {
+				return zero, errors.New("stream context has been closed")
+			}
+
+			items, err := spec.Poll(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return zero, errors.New("stream context has been closed")
+				}
+				failures++
+				if failures >= opts.MaxFailures {
+					return zero, err
+				}
+				interval = backoff(interval, opts)
+				continue
+			}
+			failures = 0
+
+			// only the keys of the latest snapshot are kept, so items that disappear don't accumulate
+			latest := make(map[string]T, len(items))
+			for _, item := range items {
+				key := spec.Key(item)
+				prev, ok := seen[key]
+				latest[key] = item
+				if ok && (spec.Equal == nil || spec.Equal(prev, item)) {
+					continue
+				}
+				if first && spec.SkipSnapshot {
+					continue
+				}
+				pending = append(pending, item)
+			}
+			seen = latest
+			first = false
+
+			if len(pending) > 0 {
+				interval = opts.MinInterval
+			} else {
+				interval = backoff(interval, opts)
+			}
+		}
+
+		v := pending[0]
+		pending = pending[1:]
+		return v, nil
+	}
+}
+
+func backoff(interval time.Duration, opts PollOpts) time.Duration {
+	if interval < opts.MinInterval {
+		return opts.MinInterval
+	}
+	interval *= 2
+	if interval > opts.MaxInterval {
+		return opts.MaxInterval
+	}
+	return interval
+}
@@ -0,0 +1,169 @@
+package connections
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	key   string
+	value int
+}
+
+// snapshots polls the snapshots in order, then the last one forever
+func snapshots(polls *int, snapshots ...[]item) func(context.Context) ([]item, error) {
+	return func(context.Context) ([]item, error) {
+		i := *polls
+		*polls++
+		if i >= len(snapshots) {
+			i = len(snapshots) - 1
+		}
+		if snapshots[i] == nil {
+			return nil, errors.New("poll failed")
+		}
+		return snapshots[i], nil
+	}
+}
+
+var fastPoll = PollOpts{MinInterval: time.Millisecond, MaxInterval: 4 * time.Millisecond, MaxFailures: 2}
+
+func TestPollStream(t *testing.T) {
+	var polls int
+	stream := PollStream(context.Background(), PollSpec[item]{
+		Poll: snapshots(&polls,
+			[]item{{"a", 1}, {"b", 1}},
+			[]item{{"a", 1}, {"b", 1}},
+			[]item{{"a", 1}, {"b", 2}, {"c", 1}},
+		),
+		Key:   func(v item) string { return v.key },
+		Equal: func(a, b item) bool { return a.value == b.value },
+	}, fastPoll)
+
+	// the snapshot, then new and changed items only
+	var updates []item
+	for len(updates) < 4 {
+		v, err := stream()
+		require.Nil(t, err)
+		updates = append(updates, v)
+	}
+	assert.Equal(t, []item{{"a", 1}, {"b", 1}, {"b", 2}, {"c", 1}}, updates)
+	assert.Equal(t, 3, polls)
+}
+
+func TestPollStreamSkipSnapshot(t *testing.T) {
+	var polls int
+	stream := PollStream(context.Background(), PollSpec[item]{
+		Poll: snapshots(&polls,
+			[]item{{"a", 1}},
+			[]item{{"a", 2}, {"b", 1}},
+		),
+		Key:          func(v item) string { return v.key },
+		SkipSnapshot: true,
+	}, fastPoll)
+
+	// without Equal, items are only emitted when first seen
+	v, err := stream()
+	require.Nil(t, err)
+	assert.Equal(t, item{"b", 1}, v)
+}
+
+func TestPollStreamRemoved(t *testing.T) {
+	var polls int
+	stream := PollStream(context.Background(), PollSpec[item]{
+		Poll: snapshots(&polls,
+			[]item{{"a", 1}, {"b", 1}},
+			[]item{{"b", 1}},
+			[]item{{"a", 1}, {"b", 1}},
+		),
+		Key: func(v item) string { return v.key },
+	}, fastPoll)
+
+	// items missing from a snapshot are forgotten, so they're new again once they reappear
+	var updates []item
+	for len(updates) < 3 {
+		v, err := stream()
+		require.Nil(t, err)
+		updates = append(updates, v)
+	}
+	assert.Equal(t, []item{{"a", 1}, {"b", 1}, {"a", 1}}, updates)
+	assert.Equal(t, 3, polls)
+}
+
+func TestPollStreamFailures(t *testing.T) {
+	var polls int
+	stream := PollStream(context.Background(), PollSpec[item]{
+		Poll: snapshots(&polls, nil, []item{{"a", 1}}, nil, nil),
+		Key:  func(v item) string { return v.key },
+	}, fastPoll)
+
+	// a failure is tolerated, MaxFailures in a row end the stream
+	v, err := stream()
+	require.Nil(t, err)
+	assert.Equal(t, item{"a", 1}, v)
+	_, err = stream()
+	assert.EqualError(t, err, "poll failed")
+	assert.Equal(t, 4, polls)
+}
+
+func TestPollStreamBackoff(t *testing.T) {
+	opts := PollOpts{MinInterval: 10 * time.Millisecond, MaxInterval: 40 * time.Millisecond}
+	assert.Equal(t, 10*time.Millisecond, backoff(0, opts))
+	assert.Equal(t, 20*time.Millisecond, backoff(10*time.Millisecond, opts))
+	assert.Equal(t, 40*time.Millisecond, backoff(20*time.Millisecond, opts))
+	assert.Equal(t, 40*time.Millisecond, backoff(40*time.Millisecond, opts))
+
+	// polls without changes are spaced out, until a change resets the interval
+	var times []time.Time
+	values := []int{1, 1, 1, 1, 2, 3}
+	stream := PollStream(context.Background(), PollSpec[item]{
+		Poll: func(context.Context) ([]item, error) {
+			times = append(times, time.Now())
+			v := values[len(times)-1]
+			return []item{{"a", v}}, nil
+		},
+		Key:   func(v item) string { return v.key },
+		Equal: func(a, b item) bool { return a.value == b.value },
+	}, opts)
+	for i := 0; i < 3; i++ {
+		_, err := stream()
+		require.Nil(t, err)
+	}
+	require.Len(t, times, 6)
+	gaps := []time.Duration{10, 20, 40, 40, 10}
+	for i, gap := range gaps {
+		assert.GreaterOrEqual(t, times[i+1].Sub(times[i]), gap*time.Millisecond, i)
+	}
+	assert.Less(t, times[5].Sub(times[4]), 40*time.Millisecond)
+}
+
+func TestPollStreamCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var polls int
+	stream := PollStream(ctx, PollSpec[item]{
+		Poll: snapshots(&polls, []item{{"a", 1}}),
+		Key:  func(v item) string { return v.key },
+	}, PollOpts{MinInterval: time.Hour})
+
+	_, err := stream()
+	require.Nil(t, err)
+
+	// a stream waiting for its next poll returns once ctx is canceled
+	done := make(chan error)
+	go func() {
+		_, err := stream()
+		done <- err
+	}()
+	cancel()
+	select {
+	case err = <-done:
+		assert.EqualError(t, err, "stream context has been closed")
+	case <-time.After(time.Second):
+		t.Fatal("stream didn't return after cancel")
+	}
+	assert.Equal(t, 1, polls)
+}
@@ -0,0 +1,145 @@
+package connections
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	package_info "github.com/bloXroute-Labs/solana-trader-client-go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	sseContentType = "text/event-stream"
+	sseMaxLineSize = 16 * 1024 * 1024
+)
+
+// ErrSSEUnsupported is returned when the server doesn't provide the requested stream as server-sent events
+var ErrSSEUnsupported = errors.New("server-sent events are not supported for this stream")
+
+// HTTPStreamSSE opens a server-sent events stream by posting the stream parameters to the provided url. Each `data`
+// event is unmarshalled into a new result message. If the server rejects the request or replies with anything other
+// than an event stream, ErrSSEUnsupported is returned so that callers can fall back to another mechanism.
+func HTTPStreamSSE[T proto.Message](ctx context.Context, url string, client *http.Client, streamParams proto.Message, authHeader string, resultInitFn func() T) (Streamer[T], error) {
+	b, err := protojson.Marshal(streamParams)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", authHeader)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", sseContentType)
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("x-sdk", package_info.Name)
+	req.Header.Set("x-sdk-version", package_info.Version)
+
+	httpResp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotAcceptable, http.StatusNotImplemented:
+		_ = httpResp.Body.Close()
+		return nil, ErrSSEUnsupported
+	default:
+		defer func() { _ = httpResp.Body.Close() }()
+		return nil, httpUnmarshalError(httpResp)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(httpResp.Header.Get("Content-Type"))
+	if err != nil || mediaType != sseContentType {
+		_ = httpResp.Body.Close()
+		return nil, ErrSSEUnsupported
+	}
+
+	scanner := bufio.NewScanner(httpResp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), sseMaxLineSize)
+
+	var closed bool
+	return func() (T, error) {
+		var zero T
+		if closed {
+			return zero, errors.New("stream has been closed")
+		}
+
+		event, data, err := readSSEEvent(scanner)
+		if err == nil {
+			switch event {
+			case "", "message":
+				v := resultInitFn()
+				if err = protojson.Unmarshal(data, v); err == nil {
+					return v, nil
+				}
+				err = fmt.Errorf("error unmarshalling message of type %T: %w", v, err)
+			case "error":
+				err = errors.New(string(data))
+			default:
+				err = fmt.Errorf("unexpected event type %v", event)
+			}
+		}
+
+		if ctx.Err() != nil {
+			err = errors.New("stream context has been closed")
+		} else if err == io.EOF {
+			err = fmt.Errorf("stream for url %s ended", url)
+		}
+		closed = true
+		_ = httpResp.Body.Close()
+		return zero, err
+	}, nil
+}
+
+// readSSEEvent consumes lines until a complete event is available, skipping comments (used as keep-alives) and
+// events without data
+func readSSEEvent(scanner *bufio.Scanner) (string, []byte, error) {
+	var (
+		event   string
+		data    bytes.Buffer
+		hasData bool
+	)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if hasData {
+				return event, data.Bytes(), nil
+			}
+			event = ""
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", nil, err
+	}
+	return "", nil, io.EOF
+}
@@ -0,0 +1,132 @@
+package connections
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestReadSSEEvent(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader(": keep-alive\n\n" +
+		"event: ping\n\n" +
+		"data: first\n\n" +
+		"event: error\ndata: line 1\ndata:line 2\n\n" +
+		"id: 3\ndata: {\"a\": 1}\nretry: 10\n\n" +
+		"data: incomplete"))
+
+	event, data, err := readSSEEvent(scanner)
+	require.Nil(t, err)
+	assert.Equal(t, "", event, "events without data are skipped, with their type")
+	assert.Equal(t, "first", string(data))
+
+	event, data, err = readSSEEvent(scanner)
+	require.Nil(t, err)
+	assert.Equal(t, "error", event)
+	assert.Equal(t, "line 1\nline 2", string(data))
+
+	event, data, err = readSSEEvent(scanner)
+	require.Nil(t, err)
+	assert.Equal(t, "", event)
+	assert.Equal(t, `{"a": 1}`, string(data))
+
+	// an event is only complete after a blank line
+	_, _, err = readSSEEvent(scanner)
+	assert.Equal(t, io.EOF, err)
+}
+
+func sseServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) string {
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func openSSE(url string) (Streamer[*wrapperspb.StringValue], error) {
+	return HTTPStreamSSE[*wrapperspb.StringValue](context.Background(), url, http.DefaultClient, wrapperspb.String("params"), "auth",
+		func() *wrapperspb.StringValue { return &wrapperspb.StringValue{} })
+}
+
+func TestHTTPStreamSSE(t *testing.T) {
+	url := sseServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "auth", r.Header.Get("Authorization"))
+		assert.Equal(t, sseContentType, r.Header.Get("Accept"))
+		body, err := io.ReadAll(r.Body)
+		require.Nil(t, err)
+		assert.Equal(t, `"params"`, string(body))
+
+		w.Header().Set("Content-Type", sseContentType+"; charset=utf-8")
+		_, _ = fmt.Fprint(w, ": connected\n\ndata: \"a\"\n\nevent: message\ndata: \"b\"\n\nevent: error\ndata: rate limited\n\n")
+	})
+
+	stream, err := openSSE(url)
+	require.Nil(t, err)
+	v, err := stream()
+	require.Nil(t, err)
+	assert.Equal(t, "a", v.Value)
+	v, err = stream()
+	require.Nil(t, err)
+	assert.Equal(t, "b", v.Value)
+
+	// error events end the stream
+	_, err = stream()
+	assert.EqualError(t, err, "rate limited")
+	_, err = stream()
+	assert.EqualError(t, err, "stream has been closed")
+}
+
+func TestHTTPStreamSSEEnd(t *testing.T) {
+	url := sseServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", sseContentType)
+		_, _ = fmt.Fprint(w, "data: \"a\"\n\ndata: not json\n\n")
+	})
+	stream, err := openSSE(url)
+	require.Nil(t, err)
+	_, err = stream()
+	require.Nil(t, err)
+	_, err = stream()
+	assert.ErrorContains(t, err, "error unmarshalling message")
+
+	url = sseServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", sseContentType)
+	})
+	stream, err = openSSE(url)
+	require.Nil(t, err)
+	_, err = stream()
+	assert.ErrorContains(t, err, "ended")
+}
+
+func TestHTTPStreamSSEUnsupported(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented} {
+		url := sseServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		})
+		_, err := openSSE(url)
+		assert.ErrorIs(t, err, ErrSSEUnsupported, status)
+	}
+
+	// a regular response isn't an event stream
+	url := sseServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		_, _ = fmt.Fprint(w, `"a"`)
+	})
+	_, err := openSSE(url)
+	assert.ErrorIs(t, err, ErrSSEUnsupported)
+
+	// other failures aren't a reason to fall back
+	url = sseServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = fmt.Fprint(w, `{"code": 16, "message": "unauthorized"}`)
+	})
+	_, err = openSSE(url)
+	require.NotNil(t, err)
+	assert.NotErrorIs(t, err, ErrSSEUnsupported)
+}
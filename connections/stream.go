@@ -0,0 +1,386 @@
+package connections
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// The helpers below compose streamers. Transformations that only read from a single source (Map, Filter, Dedupe)
+// run on the caller's goroutine and start nothing in the background. Combinators that need to read ahead (Merge,
+// Tee, Throttle, Batch, Window, FirstOf) start one goroutine per source, which exits once ctx is canceled or the source
+// ends and never blocks on a send without also watching ctx. A source that is blocked waiting for its next update is
+// only released when that source ends, so sources should be opened with ctx (or a context derived from it).
+
+var errStreamContextClosed = errors.New("stream context has been closed")
+
+// Tagged is a stream update annotated with the index of the source it came from and the time it was received
+type Tagged[T any] struct {
+	Source     int
+	Value      T
+	ReceivedAt time.Time
+}
+
+type streamResult[T any] struct {
+	source     int
+	v          T
+	err        error
+	receivedAt time.Time
+}
+
+// FromChannel wraps a channel as a streamer, ending when the channel is closed or ctx is canceled
+func FromChannel[T any](ctx context.Context, ch <-chan T) Streamer[T] {
+	return func() (T, error) {
+		var zero T
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return zero, errors.New("channel has been closed")
+			}
+			return v, nil
+		case <-ctx.Done():
+			return zero, errStreamContextClosed
+		}
+	}
+}
+
+// Map transforms each update of the stream
+func Map[T, U any](ctx context.Context, s Streamer[T], f func(T) U) Streamer[U] {
+	return func() (U, error) {
+		var zero U
+		if ctx.Err() != nil {
+			return zero, errStreamContextClosed
+		}
+		v, err := s()
+		if err != nil {
+			return zero, err
+		}
+		return f(v), nil
+	}
+}
+
+// Filter only keeps updates for which keep returns true
+func Filter[T any](ctx context.Context, s Streamer[T], keep func(T) bool) Streamer[T] {
+	return func() (T, error) {
+		var zero T
+		for {
+			if ctx.Err() != nil {
+				return zero, errStreamContextClosed
+			}
+			v, err := s()
+			if err != nil {
+				return zero, err
+			}
+			if keep(v) {
+				return v, nil
+			}
+		}
+	}
+}
+
+// Dedupe drops updates whose key was already seen among the last capacity distinct keys
+func Dedupe[T any, K comparable](ctx context.Context, s Streamer[T], key func(T) K, capacity int) Streamer[T] {
+	seen := NewKeyCache[K](capacity)
+	return Filter(ctx, s, func(v T) bool {
+		return seen.Add(key(v))
+	})
+}
+
+// Merge fans in updates from all streams in arrival order. The merged stream ends once every source has ended, with
+// the errors of all sources.
+func Merge[T any](ctx context.Context, streams ...Streamer[T]) Streamer[T] {
+	tagged := MergeTagged(ctx, streams...)
+	return Map(ctx, tagged, func(t Tagged[T]) T {
+		return t.Value
+	})
+}
+
+// MergeTagged is like Merge, but annotates each update with its source index and receive time
+func MergeTagged[T any](ctx context.Context, streams ...Streamer[T]) Streamer[Tagged[T]] {
+	ch := make(chan streamResult[T], len(streams))
+	for i, s := range streams {
+		go pump(ctx, s, i, ch)
+	}
+
+	remaining := len(streams)
+	var errs []error
+	return func() (Tagged[T], error) {
+		for remaining > 0 {
+			select {
+			case r := <-ch:
+				if r.err != nil {
+					remaining--
+					errs = append(errs, r.err)
+					continue
+				}
+				return Tagged[T]{Source: r.source, Value: r.v, ReceivedAt: r.receivedAt}, nil
+			case <-ctx.Done():
+				return Tagged[T]{}, errStreamContextClosed
+			}
+		}
+		return Tagged[T]{}, errors.Join(errs...)
+	}
+}
+
+// FirstOf races redundant streams carrying the same updates and emits only the earliest copy of each update, as
+// identified by key. Keys are remembered for the last capacity distinct updates.
+func FirstOf[T any, K comparable](ctx context.Context, key func(T) K, capacity int, streams ...Streamer[T]) Streamer[T] {
+	return Dedupe(ctx, Merge(ctx, streams...), key, capacity)
+}
+
+// Tee splits a stream into n streams that each receive every update. A slow reader holds back all others once its
+// buffer of size updates is full.
+func Tee[T any](ctx context.Context, s Streamer[T], n int, size int) []Streamer[T] {
+	chs := make([]chan streamResult[T], n)
+	streams := make([]Streamer[T], n)
+	for i := range chs {
+		ch := make(chan streamResult[T], size)
+		chs[i] = ch
+		streams[i] = fromResults(ctx, ch)
+	}
+
+	go func() {
+		for {
+			v, err := s()
+			for _, ch := range chs {
+				select {
+				case ch <- streamResult[T]{v: v, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return streams
+}
+
+// Throttle emits at most one update per interval. Updates arriving in between replace each other, so only the most
+// recent one is emitted.
+func Throttle[T any](ctx context.Context, s Streamer[T], interval time.Duration) Streamer[T] {
+	ch := make(chan streamResult[T], 1)
+	go pump(ctx, s, 0, ch)
+
+	var (
+		lastEmit   time.Time
+		pendingErr error
+	)
+	return func() (T, error) {
+		var zero T
+		if pendingErr != nil {
+			return zero, pendingErr
+		}
+
+		var latest T
+		select {
+		case r := <-ch:
+			if r.err != nil {
+				pendingErr = r.err
+				return zero, r.err
+			}
+			latest = r.v
+		case <-ctx.Done():
+			return zero, errStreamContextClosed
+		}
+
+		wait := time.Until(lastEmit.Add(interval))
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+		drain:
+			for {
+				select {
+				case r := <-ch:
+					if r.err != nil {
+						pendingErr = r.err
+						break drain
+					}
+					latest = r.v
+				case <-timer.C:
+					break drain
+				case <-ctx.Done():
+					return zero, errStreamContextClosed
+				}
+			}
+		}
+
+		lastEmit = time.Now()
+		return latest, nil
+	}
+}
+
+// Batch groups updates into slices of up to size updates, emitting a partial batch once maxWait has passed since its
+// first update. A maxWait of 0 waits for full batches.
+func Batch[T any](ctx context.Context, s Streamer[T], size int, maxWait time.Duration) Streamer[[]T] {
+	ch := make(chan streamResult[T], size)
+	go pump(ctx, s, 0, ch)
+
+	var pendingErr error
+	return func() ([]T, error) {
+		if pendingErr != nil {
+			return nil, pendingErr
+		}
+
+		var (
+			batch    []T
+			deadline <-chan time.Time
+		)
+		for size <= 0 || len(batch) < size {
+			select {
+			case r := <-ch:
+				if r.err != nil {
+					pendingErr = r.err
+					if len(batch) > 0 {
+						return batch, nil
+					}
+					return nil, r.err
+				}
+				batch = append(batch, r.v)
+				if len(batch) == 1 && maxWait > 0 {
+					timer := time.NewTimer(maxWait)
+					defer timer.Stop()
+					deadline = timer.C
+				}
+			case <-deadline:
+				return batch, nil
+			case <-ctx.Done():
+				return nil, errStreamContextClosed
+			}
+		}
+		return batch, nil
+	}
+}
+
+// Window groups updates into consecutive windows of the given duration, aligned to the wall clock. Empty windows are
+// skipped.
+func Window[T any](ctx context.Context, s Streamer[T], d time.Duration) Streamer[[]T] {
+	ch := make(chan streamResult[T], 1)
+	go pump(ctx, s, 0, ch)
+
+	var pendingErr error
+	return func() ([]T, error) {
+		if pendingErr != nil {
+			return nil, pendingErr
+		}
+
+		var batch []T
+		for {
+			now := time.Now()
+			timer := time.NewTimer(now.Truncate(d).Add(d).Sub(now))
+		collect:
+			for {
+				select {
+				case r := <-ch:
+					if r.err != nil {
+						timer.Stop()
+						pendingErr = r.err
+						if len(batch) > 0 {
+							return batch, nil
+						}
+						return nil, r.err
+					}
+					batch = append(batch, r.v)
+				case <-timer.C:
+					break collect
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, errStreamContextClosed
+				}
+			}
+			if len(batch) > 0 {
+				return batch, nil
+			}
+		}
+	}
+}
+
+// KeyCache remembers the last capacity distinct keys added to it, with the time they were first added. It's safe for
+// concurrent use.
+type KeyCache[K comparable] struct {
+	m     sync.Mutex
+	keys  map[K]time.Time
+	order []K
+	next  int
+}
+
+func NewKeyCache[K comparable](capacity int) *KeyCache[K] {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &KeyCache[K]{
+		keys:  make(map[K]time.Time, capacity),
+		order: make([]K, 0, capacity),
+	}
+}
+
+// Add records the key, returning false if it was already present
+func (c *KeyCache[K]) Add(key K) bool {
+	_, added := c.AddAt(key, time.Now())
+	return added
+}
+
+// AddAt records the key as added at t. If it was already present, it returns false and the time it was first added.
+func (c *KeyCache[K]) AddAt(key K, t time.Time) (time.Time, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if first, ok := c.keys[key]; ok {
+		return first, false
+	}
+
+	if len(c.order) < cap(c.order) {
+		c.order = append(c.order, key)
+	} else {
+		delete(c.keys, c.order[c.next])
+		c.order[c.next] = key
+		c.next = (c.next + 1) % len(c.order)
+	}
+	c.keys[key] = t
+	return t, true
+}
+
+// Contains reports whether the key is present
+func (c *KeyCache[K]) Contains(key K) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	_, ok := c.keys[key]
+	return ok
+}
+
+func pump[T any](ctx context.Context, s Streamer[T], source int, out chan<- streamResult[T]) {
+	for {
+		v, err := s()
+		select {
+		case out <- streamResult[T]{source: source, v: v, err: err, receivedAt: time.Now()}:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func fromResults[T any](ctx context.Context, ch <-chan streamResult[T]) Streamer[T] {
+	var pendingErr error
+	return func() (T, error) {
+		var zero T
+		if pendingErr != nil {
+			return zero, pendingErr
+		}
+		select {
+		case r := <-ch:
+			if r.err != nil {
+				pendingErr = r.err
+			}
+			return r.v, r.err
+		case <-ctx.Done():
+			return zero, errStreamContextClosed
+		}
+	}
+}
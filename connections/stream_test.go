@@ -0,0 +1,273 @@
+package connections
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sliceStream[T any](values ...T) Streamer[T] {
+	i := 0
+	return func() (T, error) {
+		var zero T
+		if i >= len(values) {
+			return zero, errors.New("done")
+		}
+		v := values[i]
+		i++
+		return v, nil
+	}
+}
+
+func collect[T any](s Streamer[T]) []T {
+	var out []T
+	for {
+		v, err := s()
+		if err != nil {
+			return out
+		}
+		out = append(out, v)
+	}
+}
+
+func TestMapFilterDedupe(t *testing.T) {
+	ctx := context.Background()
+	s := sliceStream(1, 2, 2, 3, 4, 4, 5)
+	s = Dedupe(ctx, s, func(v int) int { return v }, 10)
+	s = Filter(ctx, s, func(v int) bool { return v%2 == 1 })
+	out := collect(Map(ctx, s, func(v int) int { return v * 10 }))
+
+	assert.Equal(t, []int{10, 30, 50}, out)
+}
+
+func TestMergeAndFirstOf(t *testing.T) {
+	ctx := context.Background()
+	merged := collect(Merge(ctx, sliceStream(1, 2, 3), sliceStream(4, 5)))
+	sort.Ints(merged)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, merged)
+
+	first := collect(FirstOf(ctx, func(v int) int { return v }, 10, sliceStream(1, 2, 3), sliceStream(1, 2, 3)))
+	sort.Ints(first)
+	assert.Equal(t, []int{1, 2, 3}, first)
+}
+
+func TestTee(t *testing.T) {
+	ctx := context.Background()
+	streams := Tee(ctx, sliceStream(1, 2, 3), 2, 3)
+	require.Len(t, streams, 2)
+	assert.Equal(t, []int{1, 2, 3}, collect(streams[0]))
+	assert.Equal(t, []int{1, 2, 3}, collect(streams[1]))
+}
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	out := collect(Batch(ctx, sliceStream(1, 2, 3, 4, 5), 2, 0))
+	assert.Equal(t, [][]int{{1, 2}, {3, 4}, {5}}, out)
+}
+
+func TestKeyCacheEviction(t *testing.T) {
+	c := NewKeyCache[int](2)
+	assert.True(t, c.Add(1))
+	assert.True(t, c.Add(2))
+	assert.False(t, c.Add(1))
+	assert.True(t, c.Add(3))
+	assert.False(t, c.Contains(1))
+	assert.True(t, c.Contains(3))
+
+	// copies report when the key was first added
+	at := time.Now()
+	first, added := c.AddAt(4, at)
+	assert.True(t, added)
+	assert.Equal(t, at, first)
+	first, added = c.AddAt(4, at.Add(time.Second))
+	assert.False(t, added)
+	assert.Equal(t, at, first)
+}
+
+func TestIntoWithContextReleasesBlockedSend(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	infinite := Streamer[int](func() (int, error) { return 1, nil })
+
+	ch := make(chan int)
+	infinite.IntoWithContext(ctx, ch)
+	<-ch
+	cancel()
+
+	// the forwarding goroutine must close the channel instead of staying blocked on the send
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("channel was not closed after context cancellation")
+		}
+	}
+}
+
+// chanStream streams the values sent to ch until it's closed, or until ctx is done
+func chanStream[T any](ctx context.Context, ch <-chan T) Streamer[T] {
+	return func() (T, error) {
+		var zero T
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return zero, errors.New("done")
+			}
+			return v, nil
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	ctx := context.Background()
+	src := make(chan int)
+	interval := 50 * time.Millisecond
+	s := Throttle(ctx, chanStream(ctx, src), interval)
+
+	// the first update is emitted right away
+	go func() { src <- 1 }()
+	v, err := s()
+	require.Nil(t, err)
+	assert.Equal(t, 1, v)
+	emitted := time.Now()
+
+	// updates within the interval are replaced by the latest one
+	go func() {
+		src <- 2
+		src <- 3
+	}()
+	v, err = s()
+	require.Nil(t, err)
+	assert.Equal(t, 3, v)
+	assert.GreaterOrEqual(t, time.Since(emitted), interval-time.Millisecond)
+
+	// the end of the source is kept
+	close(src)
+	_, err = s()
+	assert.EqualError(t, err, "done")
+	_, err = s()
+	assert.EqualError(t, err, "done")
+}
+
+func TestThrottleCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	src := make(chan int)
+	defer close(src)
+	s := Throttle(ctx, chanStream(context.Background(), src), time.Hour)
+	src <- 1
+	_, err := s()
+	require.Nil(t, err)
+
+	// a stream waiting for the end of its interval returns once ctx is canceled
+	src <- 2
+	done := make(chan error)
+	go func() {
+		_, err := s()
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case err = <-done:
+		assert.Equal(t, errStreamContextClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("stream didn't return after cancel")
+	}
+}
+
+func TestWindow(t *testing.T) {
+	ctx := context.Background()
+	src := make(chan int)
+	d := 50 * time.Millisecond
+	s := Window(ctx, chanStream(ctx, src), d)
+
+	// windows end on multiples of d, and updates arriving in one window are emitted together
+	go func() {
+		time.Sleep(time.Until(time.Now().Truncate(d).Add(d + 5*time.Millisecond)))
+		src <- 1
+		src <- 2
+	}()
+	batch, err := s()
+	require.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, batch)
+	assert.Less(t, time.Since(time.Now().Truncate(d)), d/2)
+
+	// empty windows are skipped
+	go func() {
+		time.Sleep(2 * d)
+		src <- 3
+	}()
+	start := time.Now()
+	batch, err = s()
+	require.Nil(t, err)
+	assert.Equal(t, []int{3}, batch)
+	assert.GreaterOrEqual(t, time.Since(start), 2*d)
+
+	// the last window is emitted as soon as the source ends, then its error
+	go func() {
+		src <- 4
+		close(src)
+	}()
+	start = time.Now()
+	batch, err = s()
+	require.Nil(t, err)
+	assert.Equal(t, []int{4}, batch)
+	assert.Less(t, time.Since(start), d)
+	_, err = s()
+	assert.EqualError(t, err, "done")
+}
+
+func TestWindowCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	src := make(chan int)
+	defer close(src)
+	s := Window(ctx, chanStream(context.Background(), src), time.Hour)
+
+	done := make(chan error)
+	go func() {
+		_, err := s()
+		done <- err
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		assert.Equal(t, errStreamContextClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("stream didn't return after cancel")
+	}
+}
+
+func TestThrottleWindowGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	// the pumping goroutines end with ctx, even for streams that are never read
+	ctx, cancel := context.WithCancel(context.Background())
+	infinite := Streamer[int](func() (int, error) { return 1, nil })
+	throttled := Throttle(ctx, infinite, time.Millisecond)
+	_, err := throttled()
+	require.Nil(t, err)
+	windowed := Window(ctx, infinite, time.Millisecond)
+	_, err = windowed()
+	require.Nil(t, err)
+	_ = Throttle(ctx, infinite, time.Hour)
+	_ = Window(ctx, chanStream(ctx, make(chan int)), time.Hour)
+	cancel()
+
+	// not with assert.Eventually, which checks from a goroutine of its own
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
@@ -255,6 +255,10 @@ var ExampleEndpoints = map[string]struct {
 		run:         callPriceHTTP,
 		description: "get raydium pools",
 	},
+	"getPricesStream": {
+		run:         callPricesHTTPStream,
+		description: "stream prices over server-sent events or polling",
+	},
 	"getRecentBlockhash": {
 		run:         callGetRecentBlockHashHTTP,
 		description: "get recent blockhash",
@@ -684,6 +688,25 @@ func callPriceHTTP(h *provider.HTTPClient) bool {
 	return false
 }
 
+func callPricesHTTPStream(h *provider.HTTPClient) bool {
+	log.Info("starting prices stream")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	stream, err := h.GetPricesStream(ctx, []pb.Project{pb.Project_P_RAYDIUM}, []string{"So11111111111111111111111111111111111111112"})
+	if err != nil {
+		log.Errorf("error with GetPrices stream request: %v", err)
+		return true
+	}
+
+	if _, ok := <-stream.Channel(0); !ok {
+		return true
+	}
+	log.Info("response received")
+	return false
+}
+
 func callRaydiumPricesHTTP(h *provider.HTTPClient) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
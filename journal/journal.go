@@ -0,0 +1,370 @@
+package journal
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	MethodPostSubmit      = "PostSubmit"
+	MethodPostSubmitBatch = "PostSubmitBatch"
+)
+
+var ErrEntryNotFound = errors.New("journal entry not found")
+
+var (
+	entriesBucket    = []byte("entries")
+	openBucket       = []byte("open")
+	signaturesBucket = []byte("signatures")
+)
+
+// State is how far a journaled transaction has gone
+type State string
+
+const (
+	// StatePending entries were written before their request. If the request failed, Entry.Error tells why, but the
+	// transaction may still have been received.
+	StatePending   State = "pending"
+	StateSubmitted State = "submitted"
+	// StateRejected entries had an error in the submit response
+	StateRejected State = "rejected"
+	// StateSkipped entries weren't submitted, without an error, e.g. after an earlier error with P_ABORT_ON_FIRST_ERROR
+	StateSkipped State = "skipped"
+	// StateLanded entries were confirmed or finalized
+	StateLanded State = "landed"
+	// StateFailed entries landed with an error
+	StateFailed State = "failed"
+	// StateExpired entries weren't found before TrackOpts.Expiry
+	StateExpired State = "expired"
+)
+
+// Final reports whether the entry is no longer tracked
+func (s State) Final() bool {
+	return s != StatePending && s != StateSubmitted
+}
+
+// Params are the parameters of the request submitting a transaction
+type Params struct {
+	Method                 string
+	SkipPreFlight          bool
+	FrontRunningProtection bool
+	UseStakedRPCs          bool
+	UseBundle              bool
+	SubmitStrategy         string
+	IsCleanup              bool
+}
+
+// Entry is a signed transaction and what happened to it
+type Entry struct {
+	ID uint64
+	// Batch is the ID of the first entry of the same request
+	Batch     uint64
+	Signature string
+	// Transaction is the signed transaction, in base64
+	Transaction string
+	Params      Params
+	State       State
+	// Error is the error of the request, of the submit response entry or of the landed transaction
+	Error string
+	// Status and Slot are the last result of GetTransaction
+	Status    string
+	Slot      uint64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Journal is a write-ahead log of submitted transactions, stored in a BoltDB file. It's safe for concurrent use.
+type Journal struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the journal at path
+func Open(path string) (*Journal, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("could not open journal %v: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{entriesBucket, openBucket, signaturesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &Journal{db: db}, nil
+}
+
+func (j *Journal) Close() error {
+	return j.db.Close()
+}
+
+// RecordSubmit writes a signed transaction before PostSubmit
+func (j *Journal) RecordSubmit(tx *pb.TransactionMessage, skipPreFlight, frontRunningProtection, useStakedRPCs bool) (uint64, error) {
+	ids, err := j.record([]*pb.TransactionMessage{tx}, []Params{{
+		Method:                 MethodPostSubmit,
+		SkipPreFlight:          skipPreFlight,
+		FrontRunningProtection: frontRunningProtection,
+		UseStakedRPCs:          useStakedRPCs,
+		IsCleanup:              tx.IsCleanup,
+	}})
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// RecordBatch writes the signed transactions of a request before PostSubmitBatch, returning their IDs in order
+func (j *Journal) RecordBatch(request *pb.PostSubmitBatchRequest) ([]uint64, error) {
+	var transactions []*pb.TransactionMessage
+	var params []Params
+	for _, entry := range request.Entries {
+		transactions = append(transactions, entry.Transaction)
+		params = append(params, Params{
+			Method:                 MethodPostSubmitBatch,
+			SkipPreFlight:          entry.SkipPreFlight,
+			FrontRunningProtection: request.GetFrontRunningProtection(),
+			UseBundle:              request.GetUseBundle(),
+			SubmitStrategy:         request.SubmitStrategy.String(),
+			IsCleanup:              entry.Transaction.IsCleanup,
+		})
+	}
+	return j.record(transactions, params)
+}
+
+func (j *Journal) record(transactions []*pb.TransactionMessage, params []Params) ([]uint64, error) {
+	now := time.Now()
+	ids := make([]uint64, 0, len(transactions))
+	err := j.db.Update(func(tx *bolt.Tx) error {
+		for i, transaction := range transactions {
+			id, err := tx.Bucket(entriesBucket).NextSequence()
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+			entry := Entry{
+				ID:          id,
+				Batch:       ids[0],
+				Signature:   signature(transaction.Content),
+				Transaction: transaction.Content,
+				Params:      params[i],
+				State:       StatePending,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+			if err := put(tx, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not write journal: %w", err)
+	}
+	return ids, nil
+}
+
+// RecordResponse writes the outcome of the request that submitted entries. With err set, the entries stay pending
+// until tracking finds them or they expire.
+func (j *Journal) RecordResponse(ids []uint64, response *pb.PostSubmitBatchResponse, err error) error {
+	return j.updateEntries(ids, func(i int, entry *Entry) {
+		if err != nil {
+			entry.Error = err.Error()
+			return
+		}
+		if response == nil || i >= len(response.Transactions) {
+			return
+		}
+		result := response.Transactions[i]
+		if result.Signature != "" {
+			entry.Signature = result.Signature
+		}
+		entry.Error = result.Error
+		switch {
+		case result.Submitted:
+			entry.State = StateSubmitted
+		case result.Error != "":
+			entry.State = StateRejected
+		default:
+			entry.State = StateSkipped
+		}
+	})
+}
+
+func (j *Journal) updateEntries(ids []uint64, update func(i int, entry *Entry)) error {
+	now := time.Now()
+	return j.db.Update(func(tx *bolt.Tx) error {
+		for i, id := range ids {
+			entry, err := get(tx, id)
+			if err != nil {
+				return err
+			}
+			update(i, &entry)
+			entry.UpdatedAt = now
+			if err := put(tx, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns an entry by ID
+func (j *Journal) Get(id uint64) (Entry, error) {
+	var entry Entry
+	err := j.db.View(func(tx *bolt.Tx) error {
+		var err error
+		entry, err = get(tx, id)
+		return err
+	})
+	return entry, err
+}
+
+// Filter selects entries of Query. Zero fields match every entry.
+type Filter struct {
+	States    []State
+	Signature string
+	Method    string
+	Since     time.Time
+	Until     time.Time
+	// Limit is the maximum number of entries returned, the oldest first
+	Limit int
+}
+
+func (f Filter) match(entry Entry) bool {
+	if len(f.States) > 0 {
+		found := false
+		for _, state := range f.States {
+			found = found || entry.State == state
+		}
+		if !found {
+			return false
+		}
+	}
+	switch {
+	case f.Signature != "" && entry.Signature != f.Signature:
+		return false
+	case f.Method != "" && entry.Params.Method != f.Method:
+		return false
+	case !f.Since.IsZero() && entry.CreatedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !entry.CreatedAt.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// Query returns the entries matching filter, in the order they were recorded
+func (j *Journal) Query(filter Filter) ([]Entry, error) {
+	var entries []Entry
+	err := j.db.View(func(tx *bolt.Tx) error {
+		if filter.Signature != "" {
+			id := tx.Bucket(signaturesBucket).Get([]byte(filter.Signature))
+			if id == nil {
+				return nil
+			}
+			entry, err := get(tx, binary.BigEndian.Uint64(id))
+			if err != nil {
+				return err
+			}
+			if filter.match(entry) {
+				entries = append(entries, entry)
+			}
+			return nil
+		}
+
+		return tx.Bucket(entriesBucket).ForEach(func(_, data []byte) error {
+			if filter.Limit > 0 && len(entries) >= filter.Limit {
+				return nil
+			}
+			var entry Entry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return err
+			}
+			if filter.match(entry) {
+				entries = append(entries, entry)
+			}
+			return nil
+		})
+	})
+	return entries, err
+}
+
+// Unconfirmed returns the pending and submitted entries, in the order they were recorded
+func (j *Journal) Unconfirmed() ([]Entry, error) {
+	var entries []Entry
+	err := j.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(openBucket).ForEach(func(key, _ []byte) error {
+			entry, err := get(tx, binary.BigEndian.Uint64(key))
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	return entries, err
+}
+
+func get(tx *bolt.Tx, id uint64) (Entry, error) {
+	var entry Entry
+	data := tx.Bucket(entriesBucket).Get(key(id))
+	if data == nil {
+		return entry, fmt.Errorf("%w: %v", ErrEntryNotFound, id)
+	}
+	err := json.Unmarshal(data, &entry)
+	return entry, err
+}
+
+// put writes an entry and its indexes
+func put(tx *bolt.Tx, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(entriesBucket).Put(key(entry.ID), data); err != nil {
+		return err
+	}
+	if entry.Signature != "" {
+		if err := tx.Bucket(signaturesBucket).Put([]byte(entry.Signature), key(entry.ID)); err != nil {
+			return err
+		}
+	}
+	if entry.State.Final() {
+		return tx.Bucket(openBucket).Delete(key(entry.ID))
+	}
+	return tx.Bucket(openBucket).Put(key(entry.ID), nil)
+}
+
+// key orders entries by ID
+func key(id uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, id)
+	return b
+}
+
+// signature is the fee payer's signature of a signed transaction
+func signature(txBase64 string) string {
+	data, err := base64.StdEncoding.DecodeString(txBase64)
+	if err != nil {
+		return ""
+	}
+	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(data))
+	if err != nil || len(tx.Signatures) == 0 {
+		return ""
+	}
+	return tx.Signatures[0].String()
+}
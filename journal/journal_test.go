@@ -0,0 +1,161 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signedTx(t *testing.T, lamports uint64) *pb.TransactionMessage {
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	owner := privateKey.PublicKey()
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{system.NewTransferInstruction(lamports, owner, solana.NewWallet().PublicKey()).Build()},
+		solana.MustHashFromBase58("A1xapHMk7Y9tj2NuVKw1ddKASsCce2M5EyD1xXo3RWr1"),
+		solana.TransactionPayer(owner),
+	)
+	require.Nil(t, err)
+	_, err = tx.Sign(func(solana.PublicKey) *solana.PrivateKey { return &privateKey })
+	require.Nil(t, err)
+	txBase64, err := tx.ToBase64()
+	require.Nil(t, err)
+	return &pb.TransactionMessage{Content: txBase64}
+}
+
+// fakeStatuses answers GetTransaction from a map of signatures
+type fakeStatuses map[string]*pb.GetTransactionResponse
+
+func (f fakeStatuses) GetTransaction(_ context.Context, request *pb.GetTransactionRequest) (*pb.GetTransactionResponse, error) {
+	if response, ok := f[request.Signature]; ok {
+		return response, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.db")
+	j, err := Open(path)
+	require.Nil(t, err)
+
+	useBundle := true
+	request := &pb.PostSubmitBatchRequest{
+		Entries:        []*pb.PostSubmitRequestEntry{{Transaction: signedTx(t, 1), SkipPreFlight: true}, {Transaction: signedTx(t, 2)}, {Transaction: signedTx(t, 4)}},
+		SubmitStrategy: pb.SubmitStrategy_P_ABORT_ON_FIRST_ERROR,
+		UseBundle:      &useBundle,
+	}
+	ids, err := j.RecordBatch(request)
+	require.Nil(t, err)
+	require.Len(t, ids, 3)
+
+	entry, err := j.Get(ids[1])
+	require.Nil(t, err)
+	assert.Equal(t, StatePending, entry.State)
+	assert.Equal(t, ids[0], entry.Batch)
+	assert.Equal(t, request.Entries[1].Transaction.Content, entry.Transaction)
+	assert.NotEmpty(t, entry.Signature)
+	assert.Equal(t, Params{Method: MethodPostSubmitBatch, UseBundle: true, SubmitStrategy: "P_ABORT_ON_FIRST_ERROR"}, entry.Params)
+
+	err = j.RecordResponse(ids, &pb.PostSubmitBatchResponse{Transactions: []*pb.PostSubmitBatchResponseEntry{
+		{Signature: entry.Signature, Submitted: true},
+		{Error: "insufficient funds"},
+		{},
+	}}, nil)
+	require.Nil(t, err)
+
+	// a request that failed stays pending, since it may have been received
+	single, err := j.RecordSubmit(signedTx(t, 3), false, true, false)
+	require.Nil(t, err)
+	require.Nil(t, j.RecordResponse([]uint64{single}, nil, errors.New("connection reset")))
+
+	// after a restart, the unconfirmed entries are still there
+	require.Nil(t, j.Close())
+	j, err = Open(path)
+	require.Nil(t, err)
+	defer func() { _ = j.Close() }()
+
+	unconfirmed, err := j.Unconfirmed()
+	require.Nil(t, err)
+	require.Len(t, unconfirmed, 2)
+	assert.Equal(t, StateSubmitted, unconfirmed[0].State)
+	assert.Equal(t, StatePending, unconfirmed[1].State)
+	assert.Equal(t, "connection reset", unconfirmed[1].Error)
+
+	rejected, err := j.Query(Filter{States: []State{StateRejected}})
+	require.Nil(t, err)
+	require.Len(t, rejected, 1)
+	assert.Equal(t, "insufficient funds", rejected[0].Error)
+	skipped, err := j.Query(Filter{States: []State{StateSkipped}})
+	require.Nil(t, err)
+	require.Len(t, skipped, 1)
+	assert.Equal(t, ids[2], skipped[0].ID)
+
+	found, err := j.Query(Filter{Signature: unconfirmed[1].Signature})
+	require.Nil(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, single, found[0].ID)
+
+	all, err := j.Query(Filter{Method: MethodPostSubmitBatch, Since: time.Now().Add(-time.Minute)})
+	require.Nil(t, err)
+	assert.Len(t, all, 3)
+
+	_, err = j.Get(100)
+	require.ErrorIs(t, err, ErrEntryNotFound)
+}
+
+func TestJournalSync(t *testing.T) {
+	j, err := Open(filepath.Join(t.TempDir(), "journal.db"))
+	require.Nil(t, err)
+	defer func() { _ = j.Close() }()
+
+	var ids []uint64
+	for i := uint64(1); i <= 4; i++ {
+		id, err := j.RecordSubmit(signedTx(t, i), false, false, false)
+		require.Nil(t, err)
+		ids = append(ids, id)
+	}
+	var entries []Entry
+	for _, id := range ids {
+		entry, err := j.Get(id)
+		require.Nil(t, err)
+		entries = append(entries, entry)
+	}
+
+	client := fakeStatuses{
+		entries[0].Signature: {Status: "CONFIRMED", Slot: 10},
+		entries[1].Signature: {Status: "processed", Slot: 11},
+		entries[2].Signature: {Status: "finalized", Metadata: &pb.TransactionMeta{Errored: true, Err: "custom program error"}},
+	}
+	var updates []Entry
+	opts := TrackOpts{OnUpdate: func(entry Entry) { updates = append(updates, entry) }}
+	remaining, err := j.Sync(context.Background(), client, opts)
+	require.Nil(t, err)
+	assert.Equal(t, 2, remaining)
+	require.Len(t, updates, 2)
+	assert.Equal(t, StateLanded, updates[0].State)
+	assert.Equal(t, uint64(10), updates[0].Slot)
+	assert.Equal(t, StateFailed, updates[1].State)
+	assert.Equal(t, "custom program error", updates[1].Error)
+
+	entry, err := j.Get(ids[1])
+	require.Nil(t, err)
+	assert.Equal(t, StatePending, entry.State)
+	assert.Equal(t, "processed", entry.Status)
+
+	// entries that can't be found expire
+	opts.Expiry = time.Nanosecond
+	remaining, err = j.Sync(context.Background(), client, opts)
+	require.Nil(t, err)
+	assert.Equal(t, 1, remaining)
+	entry, err = j.Get(ids[3])
+	require.Nil(t, err)
+	assert.Equal(t, StateExpired, entry.State)
+}
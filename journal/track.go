@@ -0,0 +1,126 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPollInterval = 2 * time.Second
+	defaultExpiry       = 2 * time.Minute
+)
+
+var errNoSignature = errors.New("transaction has no signature")
+
+// StatusClient is implemented by all clients
+type StatusClient interface {
+	GetTransaction(ctx context.Context, request *pb.GetTransactionRequest) (*pb.GetTransactionResponse, error)
+}
+
+type TrackOpts struct {
+	// PollInterval is how often Track syncs the unconfirmed entries (default 2s)
+	PollInterval time.Duration
+	// Expiry is how long after being recorded an entry that can't be found expires, longer than a block hash is valid
+	// (default 2m)
+	Expiry time.Duration
+
+	// OnUpdate is called after an entry changes state
+	OnUpdate func(Entry)
+}
+
+func (o *TrackOpts) setDefaults() {
+	if o.PollInterval <= 0 {
+		o.PollInterval = defaultPollInterval
+	}
+	if o.Expiry <= 0 {
+		o.Expiry = defaultExpiry
+	}
+}
+
+// Track syncs the unconfirmed entries until ctx is done. Run it after a restart to resume tracking the transactions
+// that were in flight.
+func (j *Journal) Track(ctx context.Context, client StatusClient, opts TrackOpts) error {
+	opts.setDefaults()
+	ticker := time.NewTicker(opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.Sync(ctx, client, opts); err != nil && ctx.Err() == nil {
+			log.Errorf("could not sync journal: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Sync looks up the unconfirmed entries once, and returns how many are still unconfirmed
+func (j *Journal) Sync(ctx context.Context, client StatusClient, opts TrackOpts) (int, error) {
+	opts.setDefaults()
+	entries, err := j.Unconfirmed()
+	if err != nil {
+		return 0, err
+	}
+
+	remaining := 0
+	for _, entry := range entries {
+		// entries without a signature can't be found, and expire
+		var response *pb.GetTransactionResponse
+		err := errNoSignature
+		if entry.Signature != "" {
+			response, err = client.GetTransaction(ctx, &pb.GetTransactionRequest{Signature: entry.Signature})
+		}
+		if ctx.Err() != nil {
+			return remaining, ctx.Err()
+		}
+
+		updated := entry
+		switch {
+		case err != nil:
+			if time.Since(entry.CreatedAt) > opts.Expiry {
+				updated.State = StateExpired
+			}
+		case response.Metadata != nil && response.Metadata.Errored:
+			updated.State, updated.Error = StateFailed, response.Metadata.Err
+		case landed(response.Status):
+			updated.State = StateLanded
+		}
+		if err == nil {
+			updated.Status, updated.Slot = response.Status, response.Slot
+		}
+		if updated == entry {
+			remaining++
+			continue
+		}
+
+		if err := j.updateEntries([]uint64{entry.ID}, func(_ int, e *Entry) {
+			e.State, e.Error, e.Status, e.Slot = updated.State, updated.Error, updated.Status, updated.Slot
+		}); err != nil {
+			return remaining, err
+		}
+		if !updated.State.Final() {
+			remaining++
+		}
+		if updated.State != entry.State && opts.OnUpdate != nil {
+			updated, err := j.Get(entry.ID)
+			if err != nil {
+				return remaining, err
+			}
+			opts.OnUpdate(updated)
+		}
+	}
+	return remaining, nil
+}
+
+// landed reports whether a GetTransaction status is confirmed or finalized
+func landed(status string) bool {
+	status = strings.ToLower(status)
+	return strings.Contains(status, "confirm") || strings.Contains(status, "final")
+}
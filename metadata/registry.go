@@ -0,0 +1,530 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/amount"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	SOLMint  = "So11111111111111111111111111111111111111112"
+	USDCMint = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+	USDTMint = "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCeBvSuN9rS"
+
+	defaultRefreshInterval = 10 * time.Minute
+)
+
+var (
+	ErrUnknownToken     = errors.New("unknown token")
+	ErrAmbiguousSymbol  = errors.New("symbol matches multiple mints")
+	ErrUnknownDecimals  = errors.New("token decimals are unknown")
+	ErrAmountOutOfRange = amount.ErrOutOfRange
+)
+
+// well known tokens are always registered, and take priority when resolving their symbols
+var wellKnownTokens = []Token{
+	{Mint: SOLMint, Symbol: "SOL", Decimals: 9, DecimalsKnown: true},
+	{Mint: USDCMint, Symbol: "USDC", Decimals: 6, DecimalsKnown: true},
+	{Mint: USDTMint, Symbol: "USDT", Decimals: 6, DecimalsKnown: true},
+}
+
+var symbolAliases = map[string]string{
+	"WSOL": "SOL",
+}
+
+// Client is the subset of the Trader API used to load metadata. It's implemented by all provider clients.
+type Client interface {
+	GetMarkets(ctx context.Context) (*pb.GetMarketsResponse, error)
+	GetPools(ctx context.Context, projects []pb.Project) (*pb.GetPoolsResponse, error)
+	GetRaydiumPools(ctx context.Context, request *pb.GetRaydiumPoolsRequest) (*pb.GetRaydiumPoolsResponse, error)
+}
+
+// clients that provide Openbook V2 markets additionally implement one of these: the websocket client returns the
+// markets as a GetMarketsResponse
+type marketsV2Client interface {
+	GetMarketsV2(ctx context.Context) (*pb.GetMarketsResponseV2, error)
+}
+
+type wsMarketsV2Client interface {
+	GetMarketsV2(ctx context.Context) (*pb.GetMarketsResponse, error)
+}
+
+type Token struct {
+	Mint          string
+	Symbol        string
+	Decimals      int
+	DecimalsKnown bool
+}
+
+type Market struct {
+	Name      string
+	Address   string
+	BaseMint  string
+	QuoteMint string
+	Project   pb.Project
+}
+
+type Pool struct {
+	Name       string
+	Address    string
+	Project    pb.Project
+	PoolType   string
+	Token1Mint string
+	Token2Mint string
+}
+
+type Opts struct {
+	// RefreshInterval between background refreshes started by Run (default 10 minutes)
+	RefreshInterval time.Duration
+	// Projects to load pools for (default all)
+	Projects []pb.Project
+	// SkipPools disables loading GetPools and GetRaydiumPools, which are large responses
+	SkipPools bool
+}
+
+// Registry caches token, market and pool metadata loaded from the Trader API, resolves symbols to mints and converts
+// between raw and UI token amounts
+type Registry struct {
+	client Client
+	opts   Opts
+
+	m           sync.RWMutex
+	tokens      map[string]Token
+	symbols     map[string][]string
+	markets     map[string]Market
+	pools       map[string]Pool
+	poolsByMint map[string][]string
+	overrides   map[string]Token
+	refreshedAt time.Time
+}
+
+func NewRegistry(client Client, opts Opts) *Registry {
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = defaultRefreshInterval
+	}
+	if len(opts.Projects) == 0 {
+		opts.Projects = []pb.Project{pb.Project_P_ALL}
+	}
+
+	r := &Registry{
+		client:    client,
+		opts:      opts,
+		overrides: make(map[string]Token),
+	}
+	r.swap(newSnapshot(r.overrides))
+	return r
+}
+
+// Run loads the registry, then refreshes it until ctx is canceled. Failed refreshes keep the previous metadata.
+func (r *Registry) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Errorf("could not refresh token metadata: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Refresh reloads all metadata from the API
+func (r *Registry) Refresh(ctx context.Context) error {
+	r.m.RLock()
+	overrides := make(map[string]Token, len(r.overrides))
+	for k, v := range r.overrides {
+		overrides[k] = v
+	}
+	r.m.RUnlock()
+
+	s := newSnapshot(overrides)
+
+	markets, err := r.client.GetMarkets(ctx)
+	if err != nil {
+		return fmt.Errorf("could not load markets: %w", err)
+	}
+	for _, market := range markets.Markets {
+		s.addMarket(market.Market, market.Address, market.BaseMint, market.QuotedMint,
+			market.BaseDecimals, market.QuoteDecimals, market.Project)
+	}
+
+	switch v2 := r.client.(type) {
+	case marketsV2Client:
+		marketsV2, err := v2.GetMarketsV2(ctx)
+		if err != nil {
+			return fmt.Errorf("could not load openbook v2 markets: %w", err)
+		}
+		for _, market := range marketsV2.Markets {
+			s.addMarket(market.Market, market.Address, market.BaseMint, market.QuotedMint,
+				market.BaseDecimals, market.QuoteDecimals, pb.Project_P_OPENBOOK)
+		}
+	case wsMarketsV2Client:
+		marketsV2, err := v2.GetMarketsV2(ctx)
+		if err != nil {
+			return fmt.Errorf("could not load openbook v2 markets: %w", err)
+		}
+		for _, market := range marketsV2.Markets {
+			s.addMarket(market.Market, market.Address, market.BaseMint, market.QuotedMint,
+				market.BaseDecimals, market.QuoteDecimals, pb.Project_P_OPENBOOK)
+		}
+	}
+
+	if !r.opts.SkipPools {
+		pools, err := r.client.GetPools(ctx, r.opts.Projects)
+		if err != nil {
+			return fmt.Errorf("could not load pools: %w", err)
+		}
+		for _, project := range pools.Projects {
+			for _, pool := range project.Pools {
+				s.addPool(pool, project.Project)
+			}
+		}
+
+		raydiumPools, err := r.client.GetRaydiumPools(ctx, &pb.GetRaydiumPoolsRequest{})
+		if err != nil {
+			return fmt.Errorf("could not load raydium pools: %w", err)
+		}
+		for _, pool := range raydiumPools.Pools {
+			s.addPool(pool, pb.Project_P_RAYDIUM)
+		}
+	}
+
+	s.refreshedAt = time.Now()
+	r.swap(s)
+	return nil
+}
+
+// SetToken registers or overrides a token, e.g. to provide decimals for mints not traded on any market. Overrides are
+// kept across refreshes.
+func (r *Registry) SetToken(token Token) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.overrides[token.Mint] = token
+	r.tokens[token.Mint] = token
+	if token.Symbol != "" {
+		r.symbols[normalizeSymbol(token.Symbol)] = prependUnique(r.symbols[normalizeSymbol(token.Symbol)], token.Mint)
+	}
+}
+
+// RefreshedAt returns the time of the last successful refresh
+func (r *Registry) RefreshedAt() time.Time {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	return r.refreshedAt
+}
+
+// Token looks up a token by mint or symbol
+func (r *Registry) Token(mintOrSymbol string) (Token, error) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	return r.token(mintOrSymbol)
+}
+
+// ResolveMint returns the mint address of a symbol (e.g. "SOL", "USDC"). Mint addresses are returned as is.
+func (r *Registry) ResolveMint(mintOrSymbol string) (string, error) {
+	token, err := r.Token(mintOrSymbol)
+	if err != nil {
+		return "", err
+	}
+	return token.Mint, nil
+}
+
+// Decimals returns the number of decimals of a token
+func (r *Registry) Decimals(mintOrSymbol string) (int, error) {
+	token, err := r.Token(mintOrSymbol)
+	if err != nil {
+		return 0, err
+	}
+	if !token.DecimalsKnown {
+		return 0, fmt.Errorf("%w: %v", ErrUnknownDecimals, mintOrSymbol)
+	}
+	return token.Decimals, nil
+}
+
+// ToUIAmount converts a raw amount (e.g. lamports) to the nearest UI amount (e.g. SOL)
+func (r *Registry) ToUIAmount(mintOrSymbol string, raw uint64) (float64, error) {
+	a, err := r.Amount(mintOrSymbol, raw)
+	if err != nil {
+		return 0, err
+	}
+	return a.Float64(), nil
+}
+
+// ToRawAmount converts a UI amount (e.g. SOL) to a raw amount (e.g. lamports), rounding to the nearest unit
+func (r *Registry) ToRawAmount(mintOrSymbol string, ui float64) (uint64, error) {
+	decimals, err := r.Decimals(mintOrSymbol)
+	if err != nil {
+		return 0, err
+	}
+	a, err := amount.FromFloat64(ui, decimals)
+	if err != nil {
+		return 0, fmt.Errorf("could not convert %v %v: %w", ui, mintOrSymbol, err)
+	}
+	return a.Raw(), nil
+}
+
+// Amount creates an exact amount of a token from its raw value (e.g. lamports)
+func (r *Registry) Amount(mintOrSymbol string, raw uint64) (amount.Amount, error) {
+	decimals, err := r.Decimals(mintOrSymbol)
+	if err != nil {
+		return amount.Amount{}, err
+	}
+	return amount.New(raw, decimals)
+}
+
+// ParseAmount reads a decimal string (e.g. "1.5") as an exact amount of a token
+func (r *Registry) ParseAmount(mintOrSymbol string, s string) (amount.Amount, error) {
+	decimals, err := r.Decimals(mintOrSymbol)
+	if err != nil {
+		return amount.Amount{}, err
+	}
+	return amount.Parse(s, decimals)
+}
+
+// Market looks up a market by name (e.g. "SOL/USDC") or address
+func (r *Registry) Market(nameOrAddress string) (Market, bool) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	market, ok := r.markets[nameOrAddress]
+	if !ok {
+		market, ok = r.markets[strings.ToUpper(nameOrAddress)]
+	}
+	return market, ok
+}
+
+// Pool looks up a pool by address
+func (r *Registry) Pool(address string) (Pool, bool) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	pool, ok := r.pools[address]
+	return pool, ok
+}
+
+// PoolsForPair returns all pools trading the two tokens, given as mints or symbols
+func (r *Registry) PoolsForPair(tokenA, tokenB string) ([]Pool, error) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	a, err := r.token(tokenA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.token(tokenB)
+	if err != nil {
+		return nil, err
+	}
+
+	var pools []Pool
+	for _, address := range r.poolsByMint[a.Mint] {
+		pool := r.pools[address]
+		if (pool.Token1Mint == a.Mint && pool.Token2Mint == b.Mint) || (pool.Token1Mint == b.Mint && pool.Token2Mint == a.Mint) {
+			pools = append(pools, pool)
+		}
+	}
+	return pools, nil
+}
+
+// Tokens returns all known tokens
+func (r *Registry) Tokens() []Token {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	tokens := make([]Token, 0, len(r.tokens))
+	for _, token := range r.tokens {
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func (r *Registry) token(mintOrSymbol string) (Token, error) {
+	if token, ok := r.tokens[mintOrSymbol]; ok {
+		return token, nil
+	}
+
+	mints := r.symbols[normalizeSymbol(mintOrSymbol)]
+	switch len(mints) {
+	case 0:
+		return Token{}, fmt.Errorf("%w: %v", ErrUnknownToken, mintOrSymbol)
+	case 1:
+		return r.tokens[mints[0]], nil
+	}
+
+	// well known and manually registered tokens are placed first and win over any other mint using the same symbol
+	first := mints[0]
+	if _, ok := r.overrides[first]; ok || isWellKnown(first) {
+		return r.tokens[first], nil
+	}
+	return Token{}, fmt.Errorf("%w: %v (%v)", ErrAmbiguousSymbol, mintOrSymbol, strings.Join(mints, ", "))
+}
+
+func (r *Registry) swap(s *snapshot) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.tokens = s.tokens
+	r.symbols = s.symbols
+	r.markets = s.markets
+	r.pools = s.pools
+	r.poolsByMint = s.poolsByMint
+	if !s.refreshedAt.IsZero() {
+		r.refreshedAt = s.refreshedAt
+	}
+}
+
+// snapshot is built off-lock during a refresh and swapped in at once
+type snapshot struct {
+	tokens      map[string]Token
+	symbols     map[string][]string
+	markets     map[string]Market
+	pools       map[string]Pool
+	poolsByMint map[string][]string
+	refreshedAt time.Time
+}
+
+func newSnapshot(overrides map[string]Token) *snapshot {
+	s := &snapshot{
+		tokens:      make(map[string]Token),
+		symbols:     make(map[string][]string),
+		markets:     make(map[string]Market),
+		pools:       make(map[string]Pool),
+		poolsByMint: make(map[string][]string),
+	}
+	for _, token := range wellKnownTokens {
+		s.addToken(token)
+	}
+	for _, token := range overrides {
+		s.tokens[token.Mint] = token
+		if token.Symbol != "" {
+			sym := normalizeSymbol(token.Symbol)
+			s.symbols[sym] = prependUnique(s.symbols[sym], token.Mint)
+		}
+	}
+	return s
+}
+
+func (s *snapshot) addToken(token Token) {
+	if token.Mint == "" {
+		return
+	}
+
+	existing, ok := s.tokens[token.Mint]
+	if ok {
+		// never overwrite known information with missing information
+		if existing.Symbol == "" {
+			existing.Symbol = token.Symbol
+		}
+		if !existing.DecimalsKnown && token.DecimalsKnown {
+			existing.Decimals = token.Decimals
+			existing.DecimalsKnown = true
+		}
+		token = existing
+	}
+	s.tokens[token.Mint] = token
+
+	if token.Symbol != "" {
+		sym := normalizeSymbol(token.Symbol)
+		if !contains(s.symbols[sym], token.Mint) {
+			s.symbols[sym] = append(s.symbols[sym], token.Mint)
+		}
+	}
+}
+
+func (s *snapshot) addMarket(name, address, baseMint, quoteMint string, baseDecimals, quoteDecimals int64, project pb.Project) {
+	var baseSymbol, quoteSymbol string
+	if parts := strings.Split(name, "/"); len(parts) == 2 {
+		baseSymbol, quoteSymbol = parts[0], parts[1]
+	}
+	s.addToken(Token{Mint: baseMint, Symbol: baseSymbol, Decimals: int(baseDecimals), DecimalsKnown: true})
+	s.addToken(Token{Mint: quoteMint, Symbol: quoteSymbol, Decimals: int(quoteDecimals), DecimalsKnown: true})
+
+	market := Market{
+		Name:      name,
+		Address:   address,
+		BaseMint:  baseMint,
+		QuoteMint: quoteMint,
+		Project:   project,
+	}
+	s.markets[address] = market
+	if name != "" {
+		s.markets[strings.ToUpper(name)] = market
+	}
+}
+
+func (s *snapshot) addPool(pool *pb.ProjectPool, project pb.Project) {
+	s.addToken(Token{Mint: pool.Token1MintAddress, Symbol: pool.Token1MintSymbol})
+	s.addToken(Token{Mint: pool.Token2MintAddress, Symbol: pool.Token2MintSymbol})
+
+	if _, ok := s.pools[pool.PoolAddress]; !ok {
+		s.poolsByMint[pool.Token1MintAddress] = append(s.poolsByMint[pool.Token1MintAddress], pool.PoolAddress)
+		s.poolsByMint[pool.Token2MintAddress] = append(s.poolsByMint[pool.Token2MintAddress], pool.PoolAddress)
+	}
+	s.pools[pool.PoolAddress] = Pool{
+		Name:       pool.Pool,
+		Address:    pool.PoolAddress,
+		Project:    project,
+		PoolType:   pool.PoolType,
+		Token1Mint: pool.Token1MintAddress,
+		Token2Mint: pool.Token2MintAddress,
+	}
+}
+
+func normalizeSymbol(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if alias, ok := symbolAliases[symbol]; ok {
+		return alias
+	}
+	return symbol
+}
+
+func isWellKnown(mint string) bool {
+	for _, token := range wellKnownTokens {
+		if token.Mint == mint {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func prependUnique(s []string, v string) []string {
+	out := []string{v}
+	for _, e := range s {
+		if e != v {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+var (
+	_ Client            = (*provider.GRPCClient)(nil)
+	_ Client            = (*provider.WSClient)(nil)
+	_ Client            = (*provider.HTTPClient)(nil)
+	_ marketsV2Client   = (*provider.GRPCClient)(nil)
+	_ wsMarketsV2Client = (*provider.WSClient)(nil)
+	_ marketsV2Client   = (*provider.HTTPClient)(nil)
+)
@@ -0,0 +1,168 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/amount"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	rayMint  = "4k3Dyjzvzp8eMZWUXbBCjEvwSkkk59S5iCNLY3QrkX6R"
+	fakeUSDC = "FakeUSDC1111111111111111111111111111111111111"
+)
+
+type fakeClient struct {
+	err   error
+	loads atomic.Int32
+}
+
+func (f *fakeClient) GetMarkets(context.Context) (*pb.GetMarketsResponse, error) {
+	f.loads.Add(1)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &pb.GetMarketsResponse{Markets: map[string]*pb.Market{
+		"SOL/USDC": {Market: "SOL/USDC", Address: "solusdc", BaseMint: SOLMint, QuotedMint: USDCMint, BaseDecimals: 9, QuoteDecimals: 6, Project: pb.Project_P_OPENBOOK},
+	}}, nil
+}
+
+func (f *fakeClient) GetPools(context.Context, []pb.Project) (*pb.GetPoolsResponse, error) {
+	return &pb.GetPoolsResponse{Projects: []*pb.ProjectPools{{
+		Project: pb.Project_P_RAYDIUM,
+		Pools: []*pb.ProjectPool{
+			{Pool: "RAY/USDC", PoolAddress: "rayusdc", Token1MintAddress: rayMint, Token1MintSymbol: "RAY", Token2MintAddress: USDCMint, Token2MintSymbol: "USDC"},
+			{Pool: "SOL/USDC", PoolAddress: "fakeusdc", Token1MintAddress: SOLMint, Token1MintSymbol: "SOL", Token2MintAddress: fakeUSDC, Token2MintSymbol: "USDC"},
+		},
+	}}}, nil
+}
+
+func (f *fakeClient) GetRaydiumPools(context.Context, *pb.GetRaydiumPoolsRequest) (*pb.GetRaydiumPoolsResponse, error) {
+	return &pb.GetRaydiumPoolsResponse{Pools: []*pb.ProjectPool{
+		{Pool: "RAY/SOL", PoolAddress: "raysol", Token1MintAddress: rayMint, Token1MintSymbol: "RAY", Token2MintAddress: SOLMint, Token2MintSymbol: "SOL"},
+	}}, nil
+}
+
+// fakeWSClient returns its Openbook V2 markets like the websocket client
+type fakeWSClient struct {
+	fakeClient
+}
+
+func (f *fakeWSClient) GetMarketsV2(context.Context) (*pb.GetMarketsResponse, error) {
+	return &pb.GetMarketsResponse{Markets: map[string]*pb.Market{
+		"RAY/USDC": {Market: "RAY/USDC", Address: "rayusdcv2", BaseMint: rayMint, QuotedMint: USDCMint, BaseDecimals: 6, QuoteDecimals: 6},
+	}}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeClient{}
+	r := NewRegistry(client, Opts{})
+
+	// well known tokens are available before loading
+	mint, err := r.ResolveMint("wsol")
+	require.Nil(t, err)
+	assert.Equal(t, SOLMint, mint)
+	_, err = r.Token("RAY")
+	assert.ErrorIs(t, err, ErrUnknownToken)
+
+	require.Nil(t, r.Refresh(ctx))
+	assert.False(t, r.RefreshedAt().IsZero())
+
+	market, ok := r.Market("sol/usdc")
+	require.True(t, ok)
+	assert.Equal(t, "solusdc", market.Address)
+	assert.Equal(t, USDCMint, market.QuoteMint)
+
+	// pools provide symbols, but not decimals
+	mint, err = r.ResolveMint("RAY")
+	require.Nil(t, err)
+	assert.Equal(t, rayMint, mint)
+	_, err = r.Decimals("RAY")
+	assert.ErrorIs(t, err, ErrUnknownDecimals)
+
+	// well known tokens win over other mints with their symbol
+	mint, err = r.ResolveMint("USDC")
+	require.Nil(t, err)
+	assert.Equal(t, USDCMint, mint)
+	pools, err := r.PoolsForPair("SOL", fakeUSDC)
+	require.Nil(t, err)
+	require.Len(t, pools, 1)
+	assert.Equal(t, "fakeusdc", pools[0].Address)
+	pools, err = r.PoolsForPair("RAY", "SOL")
+	require.Nil(t, err)
+	require.Len(t, pools, 1)
+	assert.Equal(t, pb.Project_P_RAYDIUM, pools[0].Project)
+
+	// overrides are kept across refreshes, and failed refreshes keep the previous metadata
+	r.SetToken(Token{Mint: rayMint, Symbol: "RAY", Decimals: 6, DecimalsKnown: true})
+	client.err = errors.New("unavailable")
+	require.NotNil(t, r.Refresh(ctx))
+	client.err = nil
+	require.Nil(t, r.Refresh(ctx))
+	decimals, err := r.Decimals("RAY")
+	require.Nil(t, err)
+	assert.Equal(t, 6, decimals)
+	_, ok = r.Pool("raysol")
+	assert.True(t, ok)
+}
+
+func TestRegistryAmounts(t *testing.T) {
+	r := NewRegistry(&fakeClient{}, Opts{})
+
+	raw, err := r.ToRawAmount("USDC", 0.1)
+	require.Nil(t, err)
+	assert.Equal(t, uint64(100_000), raw)
+	raw, err = r.ToRawAmount("SOL", 1.0000000005)
+	require.Nil(t, err)
+	assert.Equal(t, uint64(1_000_000_001), raw)
+	ui, err := r.ToUIAmount("SOL", 1_500_000_001)
+	require.Nil(t, err)
+	assert.Equal(t, 1.500000001, ui)
+
+	_, err = r.ToRawAmount("USDC", -1)
+	assert.ErrorIs(t, err, ErrAmountOutOfRange)
+	_, err = r.ToRawAmount("USDC", 1e20)
+	assert.ErrorIs(t, err, ErrAmountOutOfRange)
+	_, err = r.ToRawAmount("RAY", 1)
+	assert.ErrorIs(t, err, ErrUnknownToken)
+
+	a, err := r.ParseAmount("USDC", "2.5")
+	require.Nil(t, err)
+	assert.Equal(t, amount.MustParse("2.5", 6), a)
+}
+
+func TestRegistryWSMarketsV2(t *testing.T) {
+	r := NewRegistry(&fakeWSClient{}, Opts{SkipPools: true})
+	require.Nil(t, r.Refresh(context.Background()))
+
+	market, ok := r.Market("rayusdcv2")
+	require.True(t, ok)
+	assert.Equal(t, pb.Project_P_OPENBOOK, market.Project)
+	decimals, err := r.Decimals("RAY")
+	require.Nil(t, err)
+	assert.Equal(t, 6, decimals)
+}
+
+func TestRegistryRun(t *testing.T) {
+	client := &fakeClient{}
+	r := NewRegistry(client, Opts{RefreshInterval: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	// the registry is loaded on start, not after the first interval
+	require.Eventually(t, func() bool { return !r.RefreshedAt().IsZero() }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), client.loads.Load())
+	cancel()
+	<-done
+}
@@ -0,0 +1,592 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultAckTimeout = 30 * time.Second
+	defaultOrderType  = "limit"
+)
+
+var (
+	ErrUnknownOrder      = errors.New("unknown order")
+	ErrDuplicateOrder    = errors.New("client order ID is already tracked")
+	ErrOrderClosed       = errors.New("order is already closed")
+	ErrNotAcknowledged   = errors.New("order has not been acknowledged yet")
+	ErrAckTimeout        = errors.New("timed out waiting for acknowledgement")
+	ErrStreamClosed      = errors.New("order status stream has ended")
+	ErrNotStarted        = errors.New("order manager has not been started")
+	ErrUnsupportedClient = errors.New("client does not support SubmitOrderV2")
+)
+
+// Client is implemented by all clients. Clients must also provide SubmitOrderV2, whose signature varies.
+type Client interface {
+	GetOrderStatusStream(ctx context.Context, market, ownerAddress string, project pb.Project) (connections.Streamer[*pb.GetOrderStatusStreamResponse], error)
+	SubmitReplaceOrderV2(ctx context.Context, orderID, owner, payer, market string, side string, orderType string, amount, price float64, opts provider.PostOrderOpts) (string, error)
+	SubmitCancelByClientOrderID(ctx context.Context, clientOrderID uint64, owner, market, openOrders string, project pb.Project, skipPreFlight bool) (string, error)
+}
+
+// orderSubmitter is implemented by the WS and HTTP clients
+type orderSubmitter interface {
+	SubmitOrderV2(ctx context.Context, owner, payer, market string, side string, orderType string, amount, price float64, opts provider.PostOrderOpts) (string, error)
+}
+
+// orderSubmitterWithTip is implemented by the GRPC client
+type orderSubmitterWithTip interface {
+	SubmitOrderV2(ctx context.Context, owner, payer, market string, side string, orderType string, amount, price float64, bundleTip *uint64, opts provider.PostOrderOpts) (string, error)
+}
+
+type submitFunc func(ctx context.Context, owner, payer, market, side, orderType string, amount, price float64, opts provider.PostOrderOpts) (string, error)
+
+// orderCancelerV2 is implemented by the GRPC and HTTP clients
+type orderCancelerV2 interface {
+	SubmitCancelOrderV2(ctx context.Context, orderID string, clientOrderID uint64, side string, owner, market, openOrders string, opts provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error)
+}
+
+// wsOrderCancelerV2 is implemented by the WS client
+type wsOrderCancelerV2 interface {
+	SubmitCancelOrderV2(ctx context.Context, request *pb.PostCancelOrderRequestV2, skipPreFlight bool) (*pb.PostSubmitBatchResponse, error)
+}
+
+type cancelFunc func(ctx context.Context, orderID string, clientOrderID uint64, side string) (*pb.PostSubmitBatchResponse, error)
+
+type Opts struct {
+	Owner string
+	// Payer defaults to Owner
+	Payer             string
+	Market            string
+	OpenOrdersAddress string
+	// Project is used for the order status stream and cancels (default P_OPENBOOK)
+	Project pb.Project
+	// AckTimeout bounds how long Place, Cancel and Amend wait for the order status stream (default 30s)
+	AckTimeout time.Duration
+	// SkipPreFlight defaults to true, like the clients' submit methods
+	SkipPreFlight *bool
+
+	// OnFill is called for each fill, from the goroutine following the order status stream
+	OnFill func(Fill)
+	// OnUpdate is called with a snapshot of the order after each change
+	OnUpdate func(Order)
+}
+
+// PlaceRequest describes a new order. Side is "bid" or "ask", and Type defaults to "limit".
+type PlaceRequest struct {
+	Side  string
+	Type  string
+	Size  float64
+	Price float64
+	// ClientOrderID is generated randomly if 0
+	ClientOrderID uint64
+}
+
+type trackedOrder struct {
+	Order
+	amending        bool
+	replacedOrderID string
+	amendSize       float64
+	amendPrice      float64
+	// changed is closed and replaced whenever the order changes
+	changed chan struct{}
+	// placedV2 is set for orders placed with SubmitOrderV2, which are cancelled with SubmitCancelOrderV2
+	placedV2 bool
+}
+
+// Manager tracks the orders of an owner on a market by client order ID, from submission until they are filled,
+// cancelled or rejected. Order state is driven by the order status stream and by submit results.
+type Manager struct {
+	client Client
+	submit submitFunc
+	// cancelV2 is nil if the client doesn't provide SubmitCancelOrderV2
+	cancelV2 cancelFunc
+	opts     Opts
+
+	m      sync.Mutex
+	orders map[uint64]*trackedOrder
+
+	done      chan struct{}
+	streamErr error
+}
+
+func NewManager(client Client, opts Opts) (*Manager, error) {
+	var submit submitFunc
+	switch c := client.(type) {
+	case orderSubmitter:
+		submit = c.SubmitOrderV2
+	case orderSubmitterWithTip:
+		submit = func(ctx context.Context, owner, payer, market, side, orderType string, amount, price float64, opts provider.PostOrderOpts) (string, error) {
+			return c.SubmitOrderV2(ctx, owner, payer, market, side, orderType, amount, price, nil, opts)
+		}
+	default:
+		return nil, ErrUnsupportedClient
+	}
+
+	if opts.Payer == "" {
+		opts.Payer = opts.Owner
+	}
+	if opts.Project == pb.Project_P_UNKNOWN {
+		opts.Project = pb.Project_P_OPENBOOK
+	}
+	if opts.AckTimeout <= 0 {
+		opts.AckTimeout = defaultAckTimeout
+	}
+	if opts.SkipPreFlight == nil {
+		skipPreFlight := true
+		opts.SkipPreFlight = &skipPreFlight
+	}
+
+	var cancelV2 cancelFunc
+	switch c := client.(type) {
+	case orderCancelerV2:
+		cancelV2 = func(ctx context.Context, orderID string, clientOrderID uint64, side string) (*pb.PostSubmitBatchResponse, error) {
+			return c.SubmitCancelOrderV2(ctx, orderID, clientOrderID, side, opts.Owner, opts.Market, opts.OpenOrdersAddress, provider.SubmitOpts{
+				SubmitStrategy: pb.SubmitStrategy_P_SUBMIT_ALL,
+				SkipPreFlight:  opts.SkipPreFlight,
+			})
+		}
+	case wsOrderCancelerV2:
+		cancelV2 = func(ctx context.Context, orderID string, clientOrderID uint64, side string) (*pb.PostSubmitBatchResponse, error) {
+			return c.SubmitCancelOrderV2(ctx, &pb.PostCancelOrderRequestV2{
+				OrderID:           orderID,
+				ClientOrderID:     clientOrderID,
+				Side:              side,
+				MarketAddress:     opts.Market,
+				OwnerAddress:      opts.Owner,
+				OpenOrdersAddress: opts.OpenOrdersAddress,
+			}, *opts.SkipPreFlight)
+		}
+	}
+
+	return &Manager{
+		client:   client,
+		submit:   submit,
+		cancelV2: cancelV2,
+		opts:     opts,
+		orders:   make(map[uint64]*trackedOrder),
+	}, nil
+}
+
+// Start opens the order status stream and follows it in the background until ctx is canceled or the stream ends. It
+// must be called before placing orders, so that their acknowledgements aren't missed.
+func (m *Manager) Start(ctx context.Context) error {
+	stream, err := m.client.GetOrderStatusStream(ctx, m.opts.Market, m.opts.Owner, m.opts.Project)
+	if err != nil {
+		return fmt.Errorf("could not open order status stream: %w", err)
+	}
+
+	done := make(chan struct{})
+	m.m.Lock()
+	m.done = done
+	m.m.Unlock()
+
+	go func() {
+		for {
+			update, err := stream()
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Errorf("order status stream for market %v ended: %v", m.opts.Market, err)
+				}
+				m.m.Lock()
+				m.streamErr = err
+				m.m.Unlock()
+				close(done)
+				return
+			}
+			m.apply(update)
+		}
+	}()
+	return nil
+}
+
+// Done is closed once the order status stream has ended
+func (m *Manager) Done() <-chan struct{} {
+	m.m.Lock()
+	defer m.m.Unlock()
+	return m.done
+}
+
+// Place submits an order and waits until it's acknowledged on the order status stream
+func (m *Manager) Place(ctx context.Context, req PlaceRequest) (Order, error) {
+	if req.Type == "" {
+		req.Type = defaultOrderType
+	}
+
+	m.m.Lock()
+	if m.done == nil {
+		m.m.Unlock()
+		return Order{}, ErrNotStarted
+	}
+	if req.ClientOrderID == 0 {
+		req.ClientOrderID = m.unusedClientOrderID()
+	}
+	id := req.ClientOrderID
+	if _, ok := m.orders[id]; ok {
+		m.m.Unlock()
+		return Order{}, fmt.Errorf("%w: %v", ErrDuplicateOrder, id)
+	}
+	// the order is tracked before it's submitted, since it might show up on the stream before the submit returns
+	o := &trackedOrder{
+		Order: Order{
+			ClientOrderID: id,
+			Market:        m.opts.Market,
+			Side:          req.Side,
+			Type:          req.Type,
+			Size:          req.Size,
+			Price:         req.Price,
+			Remaining:     req.Size,
+			State:         StatePendingSubmit,
+			UpdatedAt:     time.Now(),
+		},
+		changed:  make(chan struct{}),
+		placedV2: true,
+	}
+	m.orders[id] = o
+	m.m.Unlock()
+
+	signature, err := m.submit(ctx, m.opts.Owner, m.opts.Payer, m.opts.Market, req.Side, req.Type, req.Size, req.Price, provider.PostOrderOpts{
+		OpenOrdersAddress: m.opts.OpenOrdersAddress,
+		ClientOrderID:     id,
+		SkipPreFlight:     m.opts.SkipPreFlight,
+	})
+	if err != nil {
+		order := m.update(id, func(o *trackedOrder) {
+			if o.State == StatePendingSubmit {
+				o.State = StateRejected
+				o.Err = err
+			}
+		})
+		return order, fmt.Errorf("order %v rejected: %w", id, err)
+	}
+	m.update(id, func(o *trackedOrder) {
+		o.Signature = signature
+	})
+
+	return m.wait(ctx, id, func(o *trackedOrder) bool {
+		return o.State != StatePendingSubmit
+	})
+}
+
+// unusedClientOrderID must be called with the lock held
+func (m *Manager) unusedClientOrderID() uint64 {
+	for {
+		id := rand.Uint64()
+		if _, ok := m.orders[id]; !ok && id != 0 {
+			return id
+		}
+	}
+}
+
+// Cancel cancels an order and waits until it's closed. The order may end up filled instead of cancelled. Orders
+// placed by the manager are cancelled with SubmitCancelOrderV2 if the client provides it, and others by client order
+// ID.
+func (m *Manager) Cancel(ctx context.Context, clientOrderID uint64) (Order, error) {
+	m.m.Lock()
+	o, ok := m.orders[clientOrderID]
+	if !ok {
+		m.m.Unlock()
+		return Order{}, fmt.Errorf("%w: %v", ErrUnknownOrder, clientOrderID)
+	}
+	order, placedV2 := o.Order, o.placedV2
+	m.m.Unlock()
+	if order.State.Terminal() {
+		return order, fmt.Errorf("%w: %v is %v", ErrOrderClosed, clientOrderID, order.State)
+	}
+
+	var (
+		signature string
+		err       error
+	)
+	if placedV2 && m.cancelV2 != nil {
+		var response *pb.PostSubmitBatchResponse
+		response, err = m.cancelV2(ctx, order.OrderID, clientOrderID, order.Side)
+		if err == nil {
+			signature, err = submittedSignature(response)
+		}
+	} else {
+		signature, err = m.client.SubmitCancelByClientOrderID(ctx, clientOrderID, m.opts.Owner, m.opts.Market, m.opts.OpenOrdersAddress, m.opts.Project, *m.opts.SkipPreFlight)
+	}
+	if err != nil {
+		return order, fmt.Errorf("could not cancel order %v: %w", clientOrderID, err)
+	}
+	m.update(clientOrderID, func(o *trackedOrder) {
+		o.Signature = signature
+	})
+
+	return m.wait(ctx, clientOrderID, func(o *trackedOrder) bool {
+		return o.State.Terminal()
+	})
+}
+
+// submittedSignature returns the signature of the last transaction of a batch, failing if any wasn't submitted
+func submittedSignature(response *pb.PostSubmitBatchResponse) (string, error) {
+	var signature string
+	for _, entry := range response.GetTransactions() {
+		if !entry.Submitted {
+			return "", fmt.Errorf("not submitted: %v", entry.Error)
+		}
+		signature = entry.Signature
+	}
+	if signature == "" {
+		return "", errors.New("no transactions submitted")
+	}
+	return signature, nil
+}
+
+// Amend replaces an open order with a new size and price, keeping its client order ID, and waits until the
+// replacement is acknowledged
+func (m *Manager) Amend(ctx context.Context, clientOrderID uint64, size, price float64) (Order, error) {
+	m.m.Lock()
+	o, ok := m.orders[clientOrderID]
+	if !ok {
+		m.m.Unlock()
+		return Order{}, fmt.Errorf("%w: %v", ErrUnknownOrder, clientOrderID)
+	}
+	order := o.Order
+	switch {
+	case o.State.Terminal():
+		m.m.Unlock()
+		return order, fmt.Errorf("%w: %v is %v", ErrOrderClosed, clientOrderID, o.State)
+	case o.OrderID == "" || o.amending:
+		m.m.Unlock()
+		return order, fmt.Errorf("%w: %v", ErrNotAcknowledged, clientOrderID)
+	}
+	o.amending = true
+	o.replacedOrderID = o.OrderID
+	o.amendSize = size
+	o.amendPrice = price
+	m.m.Unlock()
+
+	signature, err := m.client.SubmitReplaceOrderV2(ctx, order.OrderID, m.opts.Owner, m.opts.Payer, m.opts.Market, order.Side, order.Type, size, price, provider.PostOrderOpts{
+		OpenOrdersAddress: m.opts.OpenOrdersAddress,
+		ClientOrderID:     clientOrderID,
+		SkipPreFlight:     m.opts.SkipPreFlight,
+	})
+	if err != nil {
+		order = m.update(clientOrderID, func(o *trackedOrder) {
+			o.amending = false
+		})
+		return order, fmt.Errorf("could not amend order %v: %w", clientOrderID, err)
+	}
+	m.update(clientOrderID, func(o *trackedOrder) {
+		o.Signature = signature
+	})
+
+	return m.wait(ctx, clientOrderID, func(o *trackedOrder) bool {
+		return !o.amending
+	})
+}
+
+// Order returns a snapshot of an order
+func (m *Manager) Order(clientOrderID uint64) (Order, bool) {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	o, ok := m.orders[clientOrderID]
+	if !ok {
+		return Order{}, false
+	}
+	return o.Order, true
+}
+
+// Orders returns snapshots of all tracked orders
+func (m *Manager) Orders() []Order {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	orders := make([]Order, 0, len(m.orders))
+	for _, o := range m.orders {
+		orders = append(orders, o.Order)
+	}
+	return orders
+}
+
+// OpenOrders returns snapshots of all orders that haven't reached a terminal state
+func (m *Manager) OpenOrders() []Order {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	var orders []Order
+	for _, o := range m.orders {
+		if !o.State.Terminal() {
+			orders = append(orders, o.Order)
+		}
+	}
+	return orders
+}
+
+// Forget stops tracking an order, e.g. once it's closed and has been accounted for
+func (m *Manager) Forget(clientOrderID uint64) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	delete(m.orders, clientOrderID)
+}
+
+func (m *Manager) apply(update *pb.GetOrderStatusStreamResponse) {
+	info := update.GetOrderInfo()
+	if info == nil || info.OrderStatus == pb.OrderStatus_OS_UNKNOWN {
+		return
+	}
+	ts := time.Now()
+	if update.Timestamp != nil {
+		ts = update.Timestamp.AsTime()
+	}
+
+	m.m.Lock()
+	o, ok := m.orders[info.ClientOrderID]
+	if !ok || o.State.Terminal() {
+		m.m.Unlock()
+		return
+	}
+
+	// while amending, updates of the replaced order only matter if it was filled before being replaced
+	replaced := o.amending && info.OrderID == o.replacedOrderID
+	remaining := float64(info.QuantityRemaining)
+	if info.OrderStatus == pb.OrderStatus_OS_FILLED {
+		remaining = 0
+	}
+
+	// the replacement starts at the amended size, and may fill on its first update if it crosses the book
+	if o.amending && !replaced {
+		o.Remaining = o.amendSize
+	}
+
+	var fill *Fill
+	if info.OrderStatus == pb.OrderStatus_OS_PARTIAL_FILL || info.OrderStatus == pb.OrderStatus_OS_FILLED {
+		if quantity := o.Remaining - remaining; quantity > 0 {
+			o.Filled += quantity
+			fill = &Fill{
+				ClientOrderID: o.ClientOrderID,
+				OrderID:       info.OrderID,
+				Market:        o.Market,
+				Side:          o.Side,
+				Quantity:      quantity,
+				Price:         float64(info.FillPrice),
+				Remaining:     remaining,
+				Slot:          update.Slot,
+				Time:          ts,
+			}
+		}
+	}
+
+	switch {
+	case replaced && info.OrderStatus == pb.OrderStatus_OS_FILLED:
+		o.amending = false
+		o.State = StateFilled
+		o.Remaining = 0
+	case replaced && info.OrderStatus == pb.OrderStatus_OS_PARTIAL_FILL:
+		o.Remaining = remaining
+	case replaced:
+	default:
+		if o.amending {
+			o.amending = false
+			o.Size = o.amendSize
+			o.Price = o.amendPrice
+		}
+		if info.OrderID != "" {
+			o.OrderID = info.OrderID
+		}
+		o.Remaining = remaining
+		switch info.OrderStatus {
+		case pb.OrderStatus_OS_OPEN:
+			o.State = StateOpen
+		case pb.OrderStatus_OS_PARTIAL_FILL:
+			o.State = StatePartiallyFilled
+		case pb.OrderStatus_OS_FILLED:
+			o.State = StateFilled
+		case pb.OrderStatus_OS_CANCELLED:
+			o.State = StateCancelled
+			o.Remaining = 0
+		}
+	}
+	order := m.changed(o, ts)
+	m.m.Unlock()
+
+	if fill != nil && m.opts.OnFill != nil {
+		m.opts.OnFill(*fill)
+	}
+	if m.opts.OnUpdate != nil {
+		m.opts.OnUpdate(order)
+	}
+}
+
+// update applies a change to an order and notifies waiters, returning the updated snapshot
+func (m *Manager) update(clientOrderID uint64, f func(o *trackedOrder)) Order {
+	m.m.Lock()
+	o, ok := m.orders[clientOrderID]
+	if !ok {
+		m.m.Unlock()
+		return Order{}
+	}
+	f(o)
+	order := m.changed(o, time.Now())
+	m.m.Unlock()
+
+	if m.opts.OnUpdate != nil {
+		m.opts.OnUpdate(order)
+	}
+	return order
+}
+
+// changed must be called with the lock held
+func (m *Manager) changed(o *trackedOrder, ts time.Time) Order {
+	o.UpdatedAt = ts
+	close(o.changed)
+	o.changed = make(chan struct{})
+	return o.Order
+}
+
+func (m *Manager) wait(ctx context.Context, clientOrderID uint64, done func(o *trackedOrder) bool) (Order, error) {
+	timer := time.NewTimer(m.opts.AckTimeout)
+	defer timer.Stop()
+
+	for {
+		m.m.Lock()
+		o, ok := m.orders[clientOrderID]
+		if !ok {
+			m.m.Unlock()
+			return Order{}, fmt.Errorf("%w: %v", ErrUnknownOrder, clientOrderID)
+		}
+		order := o.Order
+		if done(o) {
+			m.m.Unlock()
+			return order, nil
+		}
+		changed, streamDone := o.changed, m.done
+		m.m.Unlock()
+
+		select {
+		case <-changed:
+		case <-streamDone:
+			m.m.Lock()
+			err := m.streamErr
+			m.m.Unlock()
+			return order, fmt.Errorf("%w: %v", ErrStreamClosed, err)
+		case <-timer.C:
+			return order, fmt.Errorf("%w: order %v", ErrAckTimeout, clientOrderID)
+		case <-ctx.Done():
+			return order, ctx.Err()
+		}
+	}
+}
+
+var (
+	_ Client                = (*provider.GRPCClient)(nil)
+	_ Client                = (*provider.WSClient)(nil)
+	_ Client                = (*provider.HTTPClient)(nil)
+	_ orderSubmitterWithTip = (*provider.GRPCClient)(nil)
+	_ orderSubmitter        = (*provider.WSClient)(nil)
+	_ orderSubmitter        = (*provider.HTTPClient)(nil)
+	_ orderCancelerV2       = (*provider.GRPCClient)(nil)
+	_ orderCancelerV2       = (*provider.HTTPClient)(nil)
+	_ wsOrderCancelerV2     = (*provider.WSClient)(nil)
+)
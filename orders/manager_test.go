@@ -0,0 +1,359 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeClient acknowledges submits on its order status stream, like the exchange would
+type fakeClient struct {
+	updates chan *pb.GetOrderStatusStreamResponse
+	m       sync.Mutex
+	nextID  int
+	orders  map[uint64]*pb.GetOrderStatusResponse
+	reject  error
+	// crossing is filled immediately by replacements
+	crossing float32
+	// block holds new orders until closed, counting them in submitting
+	block      chan struct{}
+	submitting int
+	// cancelled are the client order IDs cancelled with SubmitCancelByClientOrderID
+	cancelled []uint64
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		updates: make(chan *pb.GetOrderStatusStreamResponse, 100),
+		orders:  make(map[uint64]*pb.GetOrderStatusResponse),
+	}
+}
+
+func (f *fakeClient) GetOrderStatusStream(ctx context.Context, _, _ string, _ pb.Project) (connections.Streamer[*pb.GetOrderStatusStreamResponse], error) {
+	return connections.FromChannel(ctx, f.updates), nil
+}
+
+func (f *fakeClient) SubmitOrderV2(_ context.Context, _, _, market string, _ string, _ string, amount, price float64, opts provider.PostOrderOpts) (string, error) {
+	if f.reject != nil {
+		return "", f.reject
+	}
+	f.m.Lock()
+	block := f.block
+	f.submitting++
+	f.m.Unlock()
+	if block != nil {
+		<-block
+	}
+	f.open(market, opts.ClientOrderID, amount, price, 0)
+	return "sig", nil
+}
+
+func (f *fakeClient) SubmitReplaceOrderV2(_ context.Context, _, _, _, market string, _ string, _ string, amount, price float64, opts provider.PostOrderOpts) (string, error) {
+	f.status(opts.ClientOrderID, pb.OrderStatus_OS_CANCELLED, 0)
+	f.open(market, opts.ClientOrderID, amount, price, f.crossing)
+	return "sig", nil
+}
+
+func (f *fakeClient) SubmitCancelByClientOrderID(_ context.Context, clientOrderID uint64, _, _, _ string, _ pb.Project, _ bool) (string, error) {
+	f.m.Lock()
+	f.cancelled = append(f.cancelled, clientOrderID)
+	f.m.Unlock()
+	f.status(clientOrderID, pb.OrderStatus_OS_CANCELLED, 0)
+	return "sig", nil
+}
+
+// open acknowledges a new order, filling filled of it right away
+func (f *fakeClient) open(market string, clientOrderID uint64, amount, price float64, filled float32) {
+	f.m.Lock()
+	f.nextID++
+	info := &pb.GetOrderStatusResponse{
+		Market:            market,
+		OrderID:           fmt.Sprint(f.nextID),
+		ClientOrderID:     clientOrderID,
+		QuantityRemaining: float32(amount) - filled,
+		OrderPrice:        float32(price),
+		OrderStatus:       pb.OrderStatus_OS_OPEN,
+	}
+	if filled > 0 {
+		info.OrderStatus = pb.OrderStatus_OS_PARTIAL_FILL
+		info.FillPrice = info.OrderPrice
+	}
+	f.orders[clientOrderID] = info
+	f.m.Unlock()
+	f.updates <- &pb.GetOrderStatusStreamResponse{OrderInfo: info}
+}
+
+func (f *fakeClient) status(clientOrderID uint64, status pb.OrderStatus, remaining float32) {
+	f.m.Lock()
+	info := proto.Clone(f.orders[clientOrderID]).(*pb.GetOrderStatusResponse)
+	f.m.Unlock()
+	info.OrderStatus = status
+	info.QuantityRemaining = remaining
+	info.FillPrice = info.OrderPrice
+	f.updates <- &pb.GetOrderStatusStreamResponse{OrderInfo: info}
+}
+
+func TestManagerLifecycle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := newFakeClient()
+	var (
+		fillsM sync.Mutex
+		fills  []Fill
+	)
+	m, err := NewManager(client, Opts{Owner: "owner", Market: "SOLUSDC", AckTimeout: time.Second, OnFill: func(fill Fill) {
+		fillsM.Lock()
+		defer fillsM.Unlock()
+		fills = append(fills, fill)
+	}})
+	require.Nil(t, err)
+	require.Nil(t, m.Start(ctx))
+
+	order, err := m.Place(ctx, PlaceRequest{Side: "bid", Size: 10, Price: 100, ClientOrderID: 1})
+	require.Nil(t, err)
+	assert.Equal(t, StateOpen, order.State)
+	assert.Equal(t, "1", order.OrderID)
+
+	order, err = m.Amend(ctx, 1, 8, 101)
+	require.Nil(t, err)
+	assert.Equal(t, StateOpen, order.State)
+	assert.Equal(t, "2", order.OrderID)
+	assert.Equal(t, 101.0, order.Price)
+	assert.Equal(t, 8.0, order.Remaining)
+
+	client.status(1, pb.OrderStatus_OS_PARTIAL_FILL, 5)
+	client.status(1, pb.OrderStatus_OS_FILLED, 0)
+	require.Eventually(t, func() bool {
+		order, _ = m.Order(1)
+		return order.State == StateFilled
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 8.0, order.Filled)
+
+	fillsM.Lock()
+	require.Len(t, fills, 2)
+	assert.Equal(t, 3.0, fills[0].Quantity)
+	assert.Equal(t, 5.0, fills[1].Quantity)
+	fillsM.Unlock()
+
+	_, err = m.Cancel(ctx, 1)
+	assert.ErrorIs(t, err, ErrOrderClosed)
+
+	order, err = m.Place(ctx, PlaceRequest{Side: "ask", Size: 1, Price: 200})
+	require.Nil(t, err)
+	order, err = m.Cancel(ctx, order.ClientOrderID)
+	require.Nil(t, err)
+	assert.Equal(t, StateCancelled, order.State)
+	assert.Empty(t, m.OpenOrders())
+}
+
+// fakeClientV2 also provides SubmitCancelOrderV2, like the GRPC and HTTP clients
+type fakeClientV2 struct {
+	*fakeClient
+	// cancelledV2 are the order IDs cancelled with SubmitCancelOrderV2
+	cancelledV2 []string
+}
+
+func (f *fakeClientV2) SubmitCancelOrderV2(_ context.Context, orderID string, clientOrderID uint64, _ string, _, _, _ string, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	f.m.Lock()
+	f.cancelledV2 = append(f.cancelledV2, orderID)
+	f.m.Unlock()
+	f.status(clientOrderID, pb.OrderStatus_OS_CANCELLED, 0)
+	return &pb.PostSubmitBatchResponse{Transactions: []*pb.PostSubmitBatchResponseEntry{{Signature: "sig", Submitted: true}}}, nil
+}
+
+func TestManagerCancelV2(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &fakeClientV2{fakeClient: newFakeClient()}
+	m, err := NewManager(client, Opts{Owner: "owner", Market: "SOLUSDC", AckTimeout: time.Second})
+	require.Nil(t, err)
+	require.Nil(t, m.Start(ctx))
+
+	// orders placed by the manager are cancelled as V2 orders
+	order, err := m.Place(ctx, PlaceRequest{Side: "bid", Size: 1, Price: 100, ClientOrderID: 1})
+	require.Nil(t, err)
+	order, err = m.Cancel(ctx, order.ClientOrderID)
+	require.Nil(t, err)
+	assert.Equal(t, StateCancelled, order.State)
+	assert.Equal(t, "sig", order.Signature)
+
+	// adopted orders weren't placed by the manager, so they're cancelled by client order ID
+	client.open("SOLUSDC", 2, 1, 100, 0)
+	m.adopt(RemoteOrder{OrderID: "2", ClientOrderID: 2, Side: "ask", Price: 100, RemainingSize: 1})
+	order, err = m.Cancel(ctx, 2)
+	require.Nil(t, err)
+	assert.Equal(t, StateCancelled, order.State)
+
+	client.m.Lock()
+	defer client.m.Unlock()
+	assert.Equal(t, []string{"1"}, client.cancelledV2)
+	assert.Equal(t, []uint64{2}, client.cancelled)
+}
+
+func TestManagerAmendCrossing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := newFakeClient()
+	fills := make(chan Fill, 10)
+	m, err := NewManager(client, Opts{Owner: "owner", Market: "SOLUSDC", AckTimeout: time.Second, OnFill: func(fill Fill) {
+		fills <- fill
+	}})
+	require.Nil(t, err)
+	require.Nil(t, m.Start(ctx))
+
+	_, err = m.Place(ctx, PlaceRequest{Side: "bid", Size: 10, Price: 100, ClientOrderID: 1})
+	require.Nil(t, err)
+
+	// the replacement of 4 fills 3 right away, not 10 - 1
+	client.crossing = 3
+	order, err := m.Amend(ctx, 1, 4, 120)
+	require.Nil(t, err)
+	assert.Equal(t, StatePartiallyFilled, order.State)
+	assert.Equal(t, 4.0, order.Size)
+	assert.Equal(t, 1.0, order.Remaining)
+	assert.Equal(t, 3.0, order.Filled)
+
+	fill := <-fills
+	assert.Equal(t, "2", fill.OrderID)
+	assert.Equal(t, 3.0, fill.Quantity)
+	assert.Equal(t, 120.0, fill.Price)
+	assert.Empty(t, fills)
+}
+
+func TestManagerRejected(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := newFakeClient()
+	client.reject = errors.New("insufficient funds")
+	m, err := NewManager(client, Opts{Owner: "owner", Market: "SOLUSDC"})
+	require.Nil(t, err)
+
+	_, err = m.Place(ctx, PlaceRequest{Side: "bid", Size: 1, Price: 1})
+	assert.ErrorIs(t, err, ErrNotStarted)
+
+	require.Nil(t, m.Start(ctx))
+	order, err := m.Place(ctx, PlaceRequest{Side: "bid", Size: 1, Price: 1})
+	assert.ErrorIs(t, err, client.reject)
+	assert.Equal(t, StateRejected, order.State)
+}
+
+func (f *fakeClient) GetOpenOrdersV2(_ context.Context, market string, _ string, _ string, _ string, _ uint64) (*pb.GetOpenOrdersResponse, error) {
+	return &pb.GetOpenOrdersResponse{Orders: []*pb.Order{
+		{OrderID: "99", ClientOrderID: "99", Market: market, Side: pb.Side_S_ASK, Price: 150, RemainingSize: 2},
+	}}, nil
+}
+
+func (f *fakeClient) GetUnsettledV2(_ context.Context, market string, _ string) (*pb.GetUnsettledResponse, error) {
+	return &pb.GetUnsettledResponse{Market: market, Unsettled: []*pb.UnsettledAccount{
+		{Account: "oo", BaseToken: &pb.UnsettledAccountToken{Amount: 1}, QuoteToken: &pb.UnsettledAccountToken{}},
+	}}, nil
+}
+
+func (f *fakeClient) SubmitCancelAll(context.Context, string, string, []string, pb.Project, provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return &pb.PostSubmitBatchResponse{}, nil
+}
+
+func TestReconcile(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := newFakeClient()
+	m, err := NewManager(client, Opts{Owner: "owner", Market: "SOLUSDC", OpenOrdersAddress: "oo"})
+	require.Nil(t, err)
+	require.Nil(t, m.Start(ctx))
+	_, err = m.Place(ctx, PlaceRequest{Side: "bid", Size: 1, Price: 100, ClientOrderID: 1})
+	require.Nil(t, err)
+
+	// local orders are only compared after the grace period
+	r, err := NewReconciler(client, ReconcileOpts{}, m)
+	require.Nil(t, err)
+	discrepancies, err := r.Reconcile(ctx)
+	require.Nil(t, err)
+	require.NotEmpty(t, discrepancies)
+	for _, d := range discrepancies {
+		assert.NotEqual(t, DiscrepancyMissing, d.Kind)
+		assert.False(t, d.Repaired)
+	}
+
+	r, err = NewReconciler(client, ReconcileOpts{Policy: RepairPolicy{Orphans: OrphanAdopt}}, m)
+	require.Nil(t, err)
+	r.now = func() time.Time { return time.Now().Add(defaultReconcileGrace) }
+	discrepancies, err = r.Reconcile(ctx)
+	require.Nil(t, err)
+	kinds := make(map[DiscrepancyKind]Discrepancy)
+	for _, d := range discrepancies {
+		kinds[d.Kind] = d
+	}
+	require.Len(t, kinds, 3)
+	assert.Equal(t, uint64(1), kinds[DiscrepancyMissing].Local.ClientOrderID)
+	assert.False(t, kinds[DiscrepancyMissing].Repaired)
+	assert.Equal(t, uint64(99), kinds[DiscrepancyOrphaned].Remote.ClientOrderID)
+	assert.True(t, kinds[DiscrepancyOrphaned].Repaired)
+	assert.Equal(t, 1.0, kinds[DiscrepancyUnsettled].Unsettled.BaseAmount)
+
+	adopted, ok := m.Order(99)
+	require.True(t, ok)
+	assert.Equal(t, StateOpen, adopted.State)
+	assert.Equal(t, "ask", adopted.Side)
+}
+
+func TestReconcileReplaceMissing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := newFakeClient()
+	m, err := NewManager(client, Opts{Owner: "owner", Market: "SOLUSDC", OpenOrdersAddress: "oo"})
+	require.Nil(t, err)
+	require.Nil(t, m.Start(ctx))
+	for id := uint64(1); id <= 3; id++ {
+		_, err = m.Place(ctx, PlaceRequest{Side: "bid", Size: 1, Price: 100, ClientOrderID: id})
+		require.Nil(t, err)
+	}
+
+	r, err := NewReconciler(client, ReconcileOpts{Policy: RepairPolicy{ReplaceMissing: true}}, m)
+	require.Nil(t, err)
+	r.now = func() time.Time { return time.Now().Add(defaultReconcileGrace) }
+
+	// the replacements are submitted together, without waiting for each other's acknowledgement
+	client.m.Lock()
+	client.block = make(chan struct{})
+	client.submitting = 0
+	client.m.Unlock()
+	done := make(chan []Discrepancy)
+	go func() {
+		discrepancies, err := r.Reconcile(ctx)
+		assert.Nil(t, err)
+		done <- discrepancies
+	}()
+	require.Eventually(t, func() bool {
+		client.m.Lock()
+		defer client.m.Unlock()
+		return client.submitting == 3
+	}, time.Second, time.Millisecond)
+	close(client.block)
+
+	var replaced int
+	for _, d := range <-done {
+		if d.Kind == DiscrepancyMissing {
+			assert.True(t, d.Repaired)
+			assert.Nil(t, d.RepairErr)
+			replaced++
+		}
+	}
+	assert.Equal(t, 3, replaced)
+	assert.Len(t, m.OpenOrders(), 3)
+}
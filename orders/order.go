@@ -0,0 +1,78 @@
+package orders
+
+import (
+	"time"
+)
+
+// State is the lifecycle state of an order
+type State int
+
+const (
+	// StatePendingSubmit orders have been submitted, but haven't been seen on the order status stream yet
+	StatePendingSubmit State = iota
+	StateOpen
+	StatePartiallyFilled
+	StateFilled
+	StateCancelled
+	// StateRejected orders could not be submitted
+	StateRejected
+)
+
+func (s State) String() string {
+	switch s {
+	case StatePendingSubmit:
+		return "pending-submit"
+	case StateOpen:
+		return "open"
+	case StatePartiallyFilled:
+		return "partially-filled"
+	case StateFilled:
+		return "filled"
+	case StateCancelled:
+		return "cancelled"
+	case StateRejected:
+		return "rejected"
+	}
+	return "unknown"
+}
+
+// Terminal returns true for states an order can't leave anymore
+func (s State) Terminal() bool {
+	return s == StateFilled || s == StateCancelled || s == StateRejected
+}
+
+// Order is a snapshot of a tracked order
+type Order struct {
+	ClientOrderID uint64
+	// OrderID is the exchange order ID, known once the order has been seen on the order status stream
+	OrderID string
+	Market  string
+	Side    string
+	Type    string
+	Size    float64
+	Price   float64
+	// Filled is the cumulative filled quantity, across amendments
+	Filled float64
+	// Remaining is the quantity still resting on the book
+	Remaining float64
+	State     State
+	// Signature is the signature of the last transaction submitted for the order
+	Signature string
+	// Err is the error that rejected the order
+	Err       error
+	UpdatedAt time.Time
+}
+
+// Fill is a (partial) execution of an order
+type Fill struct {
+	ClientOrderID uint64
+	OrderID       string
+	Market        string
+	Side          string
+	Quantity      float64
+	Price         float64
+	// Remaining is the quantity still resting on the book after this fill
+	Remaining float64
+	Slot      int64
+	Time      time.Time
+}
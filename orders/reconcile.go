@@ -0,0 +1,502 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultReconcileInterval = time.Minute
+	defaultReconcileGrace    = 10 * time.Second
+	defaultMaxReplacing      = 4
+	// stream quantities are float32, so sizes are only compared up to this relative difference
+	sizeTolerance = 1e-5
+)
+
+var ErrMissingOrder = errors.New("order is no longer on the book")
+
+// ReconcileClient is implemented by all clients. Clients must also provide GetOpenOrdersV2, whose return type varies.
+type ReconcileClient interface {
+	GetUnsettledV2(ctx context.Context, market string, ownerAddress string) (*pb.GetUnsettledResponse, error)
+	SubmitCancelByClientOrderID(ctx context.Context, clientOrderID uint64, owner, market, openOrders string, project pb.Project, skipPreFlight bool) (string, error)
+	SubmitCancelAll(ctx context.Context, market, owner string, openOrdersAddresses []string, project pb.Project, opts provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error)
+}
+
+// openOrdersClient is implemented by the WS and HTTP clients
+type openOrdersClient interface {
+	GetOpenOrdersV2(ctx context.Context, market string, owner string, openOrdersAddress string, orderID string, clientOrderID uint64) (*pb.GetOpenOrdersResponse, error)
+}
+
+// openOrdersV2Client is implemented by the GRPC client
+type openOrdersV2Client interface {
+	GetOpenOrdersV2(ctx context.Context, market string, owner string, openOrdersAddress string, orderID string, clientOrderID uint64) (*pb.GetOpenOrdersResponseV2, error)
+}
+
+type DiscrepancyKind int
+
+const (
+	// DiscrepancyOrphaned is an order resting on the book that isn't tracked locally
+	DiscrepancyOrphaned DiscrepancyKind = iota
+	// DiscrepancyMissing is a locally open order that's no longer on the book
+	DiscrepancyMissing
+	// DiscrepancyMismatched is an order whose price or remaining size differs from the book
+	DiscrepancyMismatched
+	// DiscrepancyUnsettled reports funds waiting to be settled in an open orders account
+	DiscrepancyUnsettled
+)
+
+func (k DiscrepancyKind) String() string {
+	switch k {
+	case DiscrepancyOrphaned:
+		return "orphaned"
+	case DiscrepancyMissing:
+		return "missing"
+	case DiscrepancyMismatched:
+		return "mismatched"
+	case DiscrepancyUnsettled:
+		return "unsettled"
+	}
+	return "unknown"
+}
+
+// RemoteOrder is an open order as reported by the API
+type RemoteOrder struct {
+	OrderID           string
+	ClientOrderID     uint64
+	Market            string
+	Side              string
+	Type              string
+	Price             float64
+	RemainingSize     float64
+	OpenOrdersAddress string
+}
+
+// Unsettled is the amount of funds waiting to be settled in an open orders account
+type Unsettled struct {
+	OpenOrdersAddress string
+	BaseMint          string
+	BaseAmount        float64
+	QuoteMint         string
+	QuoteAmount       float64
+}
+
+type Discrepancy struct {
+	Kind              DiscrepancyKind
+	Market            string
+	Owner             string
+	OpenOrdersAddress string
+	// Local is unset for orphaned orders
+	Local *Order
+	// Remote is unset for missing orders
+	Remote    *RemoteOrder
+	Unsettled *Unsettled
+	// Repaired is true if the policy repaired the discrepancy, and RepairErr is set if it failed to
+	Repaired  bool
+	RepairErr error
+}
+
+type OrphanPolicy int
+
+const (
+	OrphanIgnore OrphanPolicy = iota
+	// OrphanCancel cancels orphaned orders
+	OrphanCancel
+	// OrphanAdopt starts tracking orphaned orders
+	OrphanAdopt
+)
+
+// RepairPolicy decides which discrepancies are repaired automatically. The zero value only reports them.
+type RepairPolicy struct {
+	Orphans OrphanPolicy
+	// ReplaceMissing closes missing orders locally and places them again with their remaining size. A missing order
+	// might have been filled while the order status stream was down, so only enable this if re-placing a filled order
+	// is acceptable.
+	ReplaceMissing bool
+	// MaxReplacing bounds how many missing orders are placed again at once, as each placement waits for its
+	// acknowledgement (default 4)
+	MaxReplacing int
+	// SyncMismatched updates the local price and remaining size of mismatched orders from the book
+	SyncMismatched bool
+}
+
+type ReconcileOpts struct {
+	// Interval between reconciliations in Run (default 1m)
+	Interval time.Duration
+	// Grace ignores local orders changed this recently, since the book may not reflect them yet (default 10s)
+	Grace  time.Duration
+	Policy RepairPolicy
+	// OnDiscrepancy is called for each discrepancy, after any repair
+	OnDiscrepancy func(Discrepancy)
+}
+
+type openOrdersFunc func(ctx context.Context, market, owner, openOrdersAddress string) ([]RemoteOrder, error)
+
+// Reconciler periodically compares the orders tracked by managers with the open orders and unsettled funds reported
+// by the API, for the market, owner and open orders account of each manager
+type Reconciler struct {
+	client     ReconcileClient
+	openOrders openOrdersFunc
+	opts       ReconcileOpts
+	managers   []*Manager
+	// now is the clock the grace period is measured with
+	now func() time.Time
+}
+
+func NewReconciler(client ReconcileClient, opts ReconcileOpts, managers ...*Manager) (*Reconciler, error) {
+	var openOrders openOrdersFunc
+	switch c := client.(type) {
+	case openOrdersClient:
+		openOrders = func(ctx context.Context, market, owner, openOrdersAddress string) ([]RemoteOrder, error) {
+			resp, err := c.GetOpenOrdersV2(ctx, market, owner, openOrdersAddress, "", 0)
+			if err != nil {
+				return nil, err
+			}
+			orders := make([]RemoteOrder, 0, len(resp.Orders))
+			for _, o := range resp.Orders {
+				orders = append(orders, remoteOrder(o.OrderID, o.ClientOrderID, o.Market, sideName(o.Side), "", o.Price, o.RemainingSize, o.OpenOrderAccount))
+			}
+			return orders, nil
+		}
+	case openOrdersV2Client:
+		openOrders = func(ctx context.Context, market, owner, openOrdersAddress string) ([]RemoteOrder, error) {
+			resp, err := c.GetOpenOrdersV2(ctx, market, owner, openOrdersAddress, "", 0)
+			if err != nil {
+				return nil, err
+			}
+			orders := make([]RemoteOrder, 0, len(resp.Orders))
+			for _, o := range resp.Orders {
+				orders = append(orders, remoteOrder(o.OrderID, o.ClientOrderID, o.Market, o.Side, o.Type, o.Price, o.RemainingSize, o.OpenOrderAccount))
+			}
+			return orders, nil
+		}
+	default:
+		return nil, errors.New("client does not support GetOpenOrdersV2")
+	}
+
+	if opts.Interval <= 0 {
+		opts.Interval = defaultReconcileInterval
+	}
+	if opts.Grace <= 0 {
+		opts.Grace = defaultReconcileGrace
+	}
+	if opts.Policy.MaxReplacing <= 0 {
+		opts.Policy.MaxReplacing = defaultMaxReplacing
+	}
+	return &Reconciler{
+		client:     client,
+		openOrders: openOrders,
+		opts:       opts,
+		managers:   managers,
+		now:        time.Now,
+	}, nil
+}
+
+func remoteOrder(orderID, clientOrderID, market, side, orderType string, price, remaining float64, openOrdersAddress string) RemoteOrder {
+	// client order IDs are reported as strings, and are empty or 0 for orders placed without one
+	id, _ := strconv.ParseUint(clientOrderID, 10, 64)
+	return RemoteOrder{
+		OrderID:           orderID,
+		ClientOrderID:     id,
+		Market:            market,
+		Side:              side,
+		Type:              orderType,
+		Price:             price,
+		RemainingSize:     remaining,
+		OpenOrdersAddress: openOrdersAddress,
+	}
+}
+
+// Run reconciles until ctx is canceled
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := r.Reconcile(ctx); err != nil {
+				log.Errorf("could not reconcile orders: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Reconcile runs a single reconciliation across all managers and returns the discrepancies found. Managers whose
+// orders could not be fetched are skipped and reported in the error.
+func (r *Reconciler) Reconcile(ctx context.Context) ([]Discrepancy, error) {
+	var (
+		discrepancies []Discrepancy
+		errs          []error
+	)
+	for _, m := range r.managers {
+		d, err := r.reconcile(ctx, m)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("market %v: %w", m.opts.Market, err))
+		}
+		discrepancies = append(discrepancies, d...)
+	}
+	return discrepancies, errors.Join(errs...)
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, m *Manager) ([]Discrepancy, error) {
+	remote, err := r.openOrders(ctx, m.opts.Market, m.opts.Owner, m.opts.OpenOrdersAddress)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch open orders: %w", err)
+	}
+	unsettled, err := r.client.GetUnsettledV2(ctx, m.opts.Market, m.opts.Owner)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch unsettled funds: %w", err)
+	}
+
+	newDiscrepancy := func(kind DiscrepancyKind, local *Order, remote *RemoteOrder) Discrepancy {
+		return Discrepancy{Kind: kind, Market: m.opts.Market, Owner: m.opts.Owner, OpenOrdersAddress: m.opts.OpenOrdersAddress, Local: local, Remote: remote}
+	}
+
+	var discrepancies []Discrepancy
+	cutoff := r.now().Add(-r.opts.Grace)
+	local := m.trackedOrders()
+	matched := make(map[uint64]bool)
+	for i := range remote {
+		o := &remote[i]
+		l, ok := matchLocal(local, o)
+		if !ok || l.State.Terminal() {
+			if ok && l.UpdatedAt.After(cutoff) {
+				continue
+			}
+			discrepancies = append(discrepancies, newDiscrepancy(DiscrepancyOrphaned, nil, o))
+			continue
+		}
+		matched[l.ClientOrderID] = true
+		if l.UpdatedAt.After(cutoff) || l.amending {
+			continue
+		}
+		if l.OrderID != o.OrderID || !sameQuantity(l.Price, o.Price) || !sameQuantity(l.Remaining, o.RemainingSize) {
+			order := l.Order
+			discrepancies = append(discrepancies, newDiscrepancy(DiscrepancyMismatched, &order, o))
+		}
+	}
+	for _, l := range local {
+		if matched[l.ClientOrderID] || l.State.Terminal() || l.amending || l.UpdatedAt.After(cutoff) {
+			continue
+		}
+		order := l.Order
+		discrepancies = append(discrepancies, newDiscrepancy(DiscrepancyMissing, &order, nil))
+	}
+	for _, account := range unsettled.GetUnsettled() {
+		if m.opts.OpenOrdersAddress != "" && account.Account != m.opts.OpenOrdersAddress {
+			continue
+		}
+		u := &Unsettled{
+			OpenOrdersAddress: account.Account,
+			BaseMint:          account.GetBaseToken().GetAddress(),
+			BaseAmount:        account.GetBaseToken().GetAmount(),
+			QuoteMint:         account.GetQuoteToken().GetAddress(),
+			QuoteAmount:       account.GetQuoteToken().GetAmount(),
+		}
+		if u.BaseAmount > 0 || u.QuoteAmount > 0 {
+			d := newDiscrepancy(DiscrepancyUnsettled, nil, nil)
+			d.Unsettled = u
+			discrepancies = append(discrepancies, d)
+		}
+	}
+
+	r.repair(ctx, m, discrepancies)
+	if r.opts.OnDiscrepancy != nil {
+		for _, d := range discrepancies {
+			r.opts.OnDiscrepancy(d)
+		}
+	}
+	return discrepancies, nil
+}
+
+func (r *Reconciler) repair(ctx context.Context, m *Manager, discrepancies []Discrepancy) {
+	policy := r.opts.Policy
+
+	// orphans without a client order ID can only be cancelled with everything else in their account, which is only
+	// safe when no tracked orders are resting there
+	cancelAll := make(map[string]bool)
+	var missing []*Discrepancy
+	for i := range discrepancies {
+		d := &discrepancies[i]
+		switch {
+		case d.Kind == DiscrepancyOrphaned && policy.Orphans == OrphanAdopt:
+			m.adopt(*d.Remote)
+			d.Repaired = true
+		case d.Kind == DiscrepancyOrphaned && policy.Orphans == OrphanCancel && d.Remote.ClientOrderID != 0:
+			_, d.RepairErr = r.client.SubmitCancelByClientOrderID(ctx, d.Remote.ClientOrderID, m.opts.Owner, m.opts.Market, d.Remote.OpenOrdersAddress, m.opts.Project, *m.opts.SkipPreFlight)
+			d.Repaired = d.RepairErr == nil
+		case d.Kind == DiscrepancyOrphaned && policy.Orphans == OrphanCancel:
+			cancelAll[d.Remote.OpenOrdersAddress] = true
+		case d.Kind == DiscrepancyMissing && policy.ReplaceMissing:
+			missing = append(missing, d)
+		case d.Kind == DiscrepancyMismatched && policy.SyncMismatched:
+			m.sync(d.Local.ClientOrderID, *d.Remote)
+			d.Repaired = true
+		}
+	}
+
+	r.replaceAll(ctx, m, missing)
+
+	for account := range cancelAll {
+		var err error
+		if m.hasOpenOrders() {
+			err = fmt.Errorf("open orders account %v has tracked orders, not cancelling all its orders", account)
+		} else {
+			_, err = r.client.SubmitCancelAll(ctx, m.opts.Market, m.opts.Owner, []string{account}, m.opts.Project, provider.SubmitOpts{SkipPreFlight: m.opts.SkipPreFlight})
+		}
+		for i := range discrepancies {
+			d := &discrepancies[i]
+			if d.Kind == DiscrepancyOrphaned && d.Remote.ClientOrderID == 0 && d.Remote.OpenOrdersAddress == account {
+				d.RepairErr = err
+				d.Repaired = err == nil
+			}
+		}
+	}
+}
+
+// replaceAll places the missing orders again concurrently, up to MaxReplacing at a time
+func (r *Reconciler) replaceAll(ctx context.Context, m *Manager, missing []*Discrepancy) {
+	var (
+		wg    sync.WaitGroup
+		slots = make(chan struct{}, r.opts.Policy.MaxReplacing)
+	)
+	for _, d := range missing {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(d *Discrepancy) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			d.RepairErr = r.replaceMissing(ctx, m, d.Local)
+			d.Repaired = d.RepairErr == nil
+		}(d)
+	}
+	wg.Wait()
+}
+
+func (r *Reconciler) replaceMissing(ctx context.Context, m *Manager, local *Order) error {
+	m.update(local.ClientOrderID, func(o *trackedOrder) {
+		o.State = StateCancelled
+		o.Err = ErrMissingOrder
+	})
+	if local.Remaining <= 0 {
+		return nil
+	}
+	_, err := m.Place(ctx, PlaceRequest{Side: local.Side, Type: local.Type, Size: local.Remaining, Price: local.Price})
+	return err
+}
+
+// sideName converts a side to the names used by V2 orders
+func sideName(side pb.Side) string {
+	switch side {
+	case pb.Side_S_ASK:
+		return "ask"
+	case pb.Side_S_BID:
+		return "bid"
+	}
+	return ""
+}
+
+func matchLocal(local map[uint64]trackedOrder, remote *RemoteOrder) (trackedOrder, bool) {
+	if remote.ClientOrderID != 0 {
+		l, ok := local[remote.ClientOrderID]
+		return l, ok
+	}
+	for _, l := range local {
+		if l.OrderID == remote.OrderID {
+			return l, true
+		}
+	}
+	return trackedOrder{}, false
+}
+
+func sameQuantity(a, b float64) bool {
+	return math.Abs(a-b) <= sizeTolerance*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// trackedOrders returns copies of all tracked orders, including their amendment state
+func (m *Manager) trackedOrders() map[uint64]trackedOrder {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	orders := make(map[uint64]trackedOrder, len(m.orders))
+	for id, o := range m.orders {
+		orders[id] = *o
+	}
+	return orders
+}
+
+func (m *Manager) hasOpenOrders() bool {
+	return len(m.OpenOrders()) > 0
+}
+
+// adopt starts tracking an order that was placed elsewhere
+func (m *Manager) adopt(remote RemoteOrder) {
+	id := remote.ClientOrderID
+	m.m.Lock()
+	if id == 0 {
+		// orders placed without a client order ID are tracked under a generated one, but can only be cancelled with
+		// everything else in their account
+		id = m.unusedClientOrderID()
+	}
+	if existing, ok := m.orders[id]; !ok || existing.State.Terminal() {
+		m.orders[id] = &trackedOrder{
+			Order: Order{
+				ClientOrderID: id,
+				OrderID:       remote.OrderID,
+				Market:        m.opts.Market,
+				Side:          remote.Side,
+				Type:          remote.Type,
+				Size:          remote.RemainingSize,
+				Price:         remote.Price,
+				Remaining:     remote.RemainingSize,
+				State:         StateOpen,
+				UpdatedAt:     time.Now(),
+			},
+			changed: make(chan struct{}),
+		}
+	}
+	order := m.orders[id].Order
+	m.m.Unlock()
+
+	if m.opts.OnUpdate != nil {
+		m.opts.OnUpdate(order)
+	}
+}
+
+// sync overwrites the local price and remaining size of an order with the book's
+func (m *Manager) sync(clientOrderID uint64, remote RemoteOrder) {
+	m.update(clientOrderID, func(o *trackedOrder) {
+		o.OrderID = remote.OrderID
+		o.Price = remote.Price
+		if o.State == StatePendingSubmit {
+			o.State = StateOpen
+		}
+		if remote.RemainingSize < o.Remaining {
+			o.Filled += o.Remaining - remote.RemainingSize
+			o.State = StatePartiallyFilled
+		}
+		o.Remaining = remote.RemainingSize
+	})
+}
+
+var (
+	_ ReconcileClient    = (*provider.GRPCClient)(nil)
+	_ ReconcileClient    = (*provider.WSClient)(nil)
+	_ ReconcileClient    = (*provider.HTTPClient)(nil)
+	_ openOrdersV2Client = (*provider.GRPCClient)(nil)
+	_ openOrdersClient   = (*provider.WSClient)(nil)
+	_ openOrdersClient   = (*provider.HTTPClient)(nil)
+)
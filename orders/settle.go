@@ -0,0 +1,336 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/metadata"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultSettleInterval = 30 * time.Second
+
+// SettleClient is implemented by all clients
+type SettleClient interface {
+	GetUnsettledV2(ctx context.Context, market string, ownerAddress string) (*pb.GetUnsettledResponse, error)
+	PostSettleV2(ctx context.Context, owner, market, baseTokenWallet, quoteTokenWallet, openOrdersAccount string) (*pb.PostSettleResponse, error)
+	SignAndSubmitBatch(ctx context.Context, transactions []*pb.TransactionMessage, useBundle bool, opts provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error)
+}
+
+type SettleOpts struct {
+	Owner   string
+	Markets []string
+	// Thresholds maps token mints (or symbols, if Registry is set) to the unsettled amount that triggers a settlement.
+	// Tokens without a threshold are settled as soon as any amount is unsettled.
+	Thresholds map[string]float64
+	// Interval between checks of the unsettled funds in Run (default 30s)
+	Interval time.Duration
+	// Schedule settles all unsettled funds at this interval in Run, regardless of thresholds. It's disabled if 0.
+	Schedule time.Duration
+	// Wallets maps token mints to the token accounts funds are settled to. Other tokens are settled to the owner's
+	// associated token account, or to the owner itself for SOL.
+	Wallets map[string]string
+	// Registry is used to find the mints of a market when the API doesn't report them, and to resolve symbols
+	Registry      *metadata.Registry
+	SkipPreFlight bool
+	// OnSettle is called after each settlement attempt
+	OnSettle func(Settlement)
+}
+
+// Settlement reports the funds settled from an open orders account
+type Settlement struct {
+	Market            string
+	OpenOrdersAddress string
+	BaseMint          string
+	BaseAmount        float64
+	QuoteMint         string
+	QuoteAmount       float64
+	Signature         string
+	Err               error
+	Time              time.Time
+}
+
+// Settler settles unsettled Openbook funds of an owner across markets, once they exceed per-token thresholds or on a
+// schedule
+type Settler struct {
+	client  SettleClient
+	opts    SettleOpts
+	trigger chan struct{}
+
+	m         sync.Mutex
+	settled   map[string]float64
+	triggered map[string]bool
+}
+
+func NewSettler(client SettleClient, opts SettleOpts) *Settler {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultSettleInterval
+	}
+	return &Settler{
+		client:    client,
+		opts:      opts,
+		trigger:   make(chan struct{}, 1),
+		settled:   make(map[string]float64),
+		triggered: make(map[string]bool),
+	}
+}
+
+// Run checks for unsettled funds until ctx is canceled
+func (s *Settler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	var schedule <-chan time.Time
+	if s.opts.Schedule > 0 {
+		scheduleTicker := time.NewTicker(s.opts.Schedule)
+		defer scheduleTicker.Stop()
+		schedule = scheduleTicker.C
+	}
+
+	for {
+		var err error
+		select {
+		case <-ticker.C:
+			_, err = s.Settle(ctx, false)
+		case <-schedule:
+			_, err = s.Settle(ctx, true)
+		case <-s.trigger:
+			_, err = s.settleMarkets(ctx, s.takeTriggered(), false)
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			log.Errorf("could not settle funds: %v", err)
+		}
+	}
+}
+
+// Trigger asks Run to check a market for unsettled funds right away, e.g. after a fill. Markets triggered while Run is
+// busy are checked together once it's done.
+func (s *Settler) Trigger(market string) {
+	s.m.Lock()
+	s.triggered[market] = true
+	s.m.Unlock()
+
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// takeTriggered returns the markets triggered since the last call
+func (s *Settler) takeTriggered() []string {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	markets := make([]string, 0, len(s.triggered))
+	for market := range s.triggered {
+		markets = append(markets, market)
+	}
+	s.triggered = make(map[string]bool)
+	return markets
+}
+
+// OnFill triggers a check of the fill's market. It can be used as the order manager's OnFill callback.
+func (s *Settler) OnFill(fill Fill) {
+	s.Trigger(fill.Market)
+}
+
+// Settle checks all markets once and settles the open orders accounts whose unsettled funds exceed their
+// thresholds, or all of them if force is set. The settlements are submitted in one batch.
+func (s *Settler) Settle(ctx context.Context, force bool) ([]Settlement, error) {
+	return s.settleMarkets(ctx, s.opts.Markets, force)
+}
+
+// Settled returns the total amounts settled so far by mint
+func (s *Settler) Settled() map[string]float64 {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	settled := make(map[string]float64, len(s.settled))
+	for mint, amount := range s.settled {
+		settled[mint] = amount
+	}
+	return settled
+}
+
+func (s *Settler) settleMarkets(ctx context.Context, markets []string, force bool) ([]Settlement, error) {
+	var (
+		settlements  []Settlement
+		transactions []*pb.TransactionMessage
+		errs         []error
+	)
+	for _, market := range markets {
+		due, err := s.due(ctx, market, force)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, settlement := range due {
+			tx, err := s.build(ctx, &settlement)
+			if err != nil {
+				settlement.Err = err
+			}
+			settlements = append(settlements, settlement)
+			transactions = append(transactions, tx)
+		}
+	}
+
+	s.submit(ctx, settlements, transactions)
+	for i := range settlements {
+		settlement := &settlements[i]
+		settlement.Time = time.Now()
+		if settlement.Err != nil {
+			errs = append(errs, fmt.Errorf("could not settle open orders account %v on market %v: %w", settlement.OpenOrdersAddress, settlement.Market, settlement.Err))
+		} else {
+			s.m.Lock()
+			s.settled[settlement.BaseMint] += settlement.BaseAmount
+			s.settled[settlement.QuoteMint] += settlement.QuoteAmount
+			s.m.Unlock()
+		}
+
+		if s.opts.OnSettle != nil {
+			s.opts.OnSettle(*settlement)
+		}
+	}
+	return settlements, errors.Join(errs...)
+}
+
+// due returns the settlements of a market whose unsettled funds exceed their thresholds, or all of them if force is set
+func (s *Settler) due(ctx context.Context, market string, force bool) ([]Settlement, error) {
+	unsettled, err := s.client.GetUnsettledV2(ctx, market, s.opts.Owner)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch unsettled funds for market %v: %w", market, err)
+	}
+
+	var settlements []Settlement
+	for _, account := range unsettled.GetUnsettled() {
+		settlement := Settlement{
+			Market:            market,
+			OpenOrdersAddress: account.Account,
+			BaseMint:          account.GetBaseToken().GetAddress(),
+			BaseAmount:        account.GetBaseToken().GetAmount(),
+			QuoteMint:         account.GetQuoteToken().GetAddress(),
+			QuoteAmount:       account.GetQuoteToken().GetAmount(),
+		}
+		if settlement.BaseAmount <= 0 && settlement.QuoteAmount <= 0 {
+			continue
+		}
+		if !force && !s.exceeds(settlement.BaseMint, settlement.BaseAmount) && !s.exceeds(settlement.QuoteMint, settlement.QuoteAmount) {
+			continue
+		}
+		settlements = append(settlements, settlement)
+	}
+	return settlements, nil
+}
+
+// build returns the transaction settling the funds of a settlement
+func (s *Settler) build(ctx context.Context, settlement *Settlement) (*pb.TransactionMessage, error) {
+	if (settlement.BaseMint == "" || settlement.QuoteMint == "") && s.opts.Registry != nil {
+		if market, ok := s.opts.Registry.Market(settlement.Market); ok {
+			settlement.BaseMint, settlement.QuoteMint = market.BaseMint, market.QuoteMint
+		}
+	}
+
+	baseWallet, err := s.wallet(settlement.BaseMint)
+	if err != nil {
+		return nil, err
+	}
+	quoteWallet, err := s.wallet(settlement.QuoteMint)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.client.PostSettleV2(ctx, s.opts.Owner, settlement.Market, baseWallet, quoteWallet, settlement.OpenOrdersAddress)
+	if err != nil {
+		return nil, err
+	}
+	return response.Transaction, nil
+}
+
+// submit submits the transactions of the settlements that were built in one batch, setting their signature or error
+func (s *Settler) submit(ctx context.Context, settlements []Settlement, transactions []*pb.TransactionMessage) {
+	var (
+		batch   []*pb.TransactionMessage
+		indexes []int
+	)
+	for i, tx := range transactions {
+		if settlements[i].Err == nil {
+			batch = append(batch, tx)
+			indexes = append(indexes, i)
+		}
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	skipPreFlight := s.opts.SkipPreFlight
+	response, err := s.client.SignAndSubmitBatch(ctx, batch, false, provider.SubmitOpts{
+		SubmitStrategy: pb.SubmitStrategy_P_SUBMIT_ALL,
+		SkipPreFlight:  &skipPreFlight,
+	})
+	if err == nil && len(response.GetTransactions()) != len(batch) {
+		err = fmt.Errorf("%v results for %v settlements", len(response.GetTransactions()), len(batch))
+	}
+	for n, i := range indexes {
+		switch {
+		case err != nil:
+			settlements[i].Err = err
+		case !response.Transactions[n].Submitted:
+			settlements[i].Err = fmt.Errorf("not submitted: %v", response.Transactions[n].Error)
+		default:
+			settlements[i].Signature = response.Transactions[n].Signature
+		}
+	}
+}
+
+// exceeds returns true if the amount reaches the token's threshold
+func (s *Settler) exceeds(mint string, amount float64) bool {
+	if amount <= 0 {
+		return false
+	}
+	threshold, ok := s.opts.Thresholds[mint]
+	if !ok && s.opts.Registry != nil {
+		if token, err := s.opts.Registry.Token(mint); err == nil {
+			threshold = s.opts.Thresholds[token.Symbol]
+		}
+	}
+	return amount >= threshold
+}
+
+// wallet resolves the token account funds of a mint are settled to
+func (s *Settler) wallet(mint string) (string, error) {
+	if wallet, ok := s.opts.Wallets[mint]; ok {
+		return wallet, nil
+	}
+	if mint == "" {
+		return "", errors.New("token mint is unknown, set a Registry or the settlement wallets")
+	}
+	if mint == metadata.SOLMint {
+		return s.opts.Owner, nil
+	}
+
+	owner, err := solana.PublicKeyFromBase58(s.opts.Owner)
+	if err != nil {
+		return "", fmt.Errorf("invalid owner %v: %w", s.opts.Owner, err)
+	}
+	mintKey, err := solana.PublicKeyFromBase58(mint)
+	if err != nil {
+		return "", fmt.Errorf("invalid mint %v: %w", mint, err)
+	}
+	wallet, _, err := solana.FindAssociatedTokenAddress(owner, mintKey)
+	if err != nil {
+		return "", fmt.Errorf("could not find associated token account for mint %v: %w", mint, err)
+	}
+	return wallet.String(), nil
+}
+
+var (
+	_ SettleClient = (*provider.GRPCClient)(nil)
+	_ SettleClient = (*provider.WSClient)(nil)
+	_ SettleClient = (*provider.HTTPClient)(nil)
+)
@@ -0,0 +1,131 @@
+package orders
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/metadata"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSettleClient struct {
+	m       sync.Mutex
+	settled [][2]string
+	batches [][]string
+	// rejected open orders accounts aren't submitted
+	rejected map[string]bool
+}
+
+func (f *fakeSettleClient) GetUnsettledV2(_ context.Context, market string, _ string) (*pb.GetUnsettledResponse, error) {
+	return &pb.GetUnsettledResponse{Market: market, Unsettled: []*pb.UnsettledAccount{
+		{
+			Account:    "oo-" + market,
+			BaseToken:  &pb.UnsettledAccountToken{Address: metadata.SOLMint, Amount: 1},
+			QuoteToken: &pb.UnsettledAccountToken{Address: metadata.USDCMint, Amount: 0},
+		},
+	}}, nil
+}
+
+func (f *fakeSettleClient) PostSettleV2(_ context.Context, _, _, baseTokenWallet, quoteTokenWallet, openOrdersAccount string) (*pb.PostSettleResponse, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	f.settled = append(f.settled, [2]string{baseTokenWallet, quoteTokenWallet})
+	return &pb.PostSettleResponse{Transaction: &pb.TransactionMessage{Content: openOrdersAccount}}, nil
+}
+
+func (f *fakeSettleClient) SignAndSubmitBatch(_ context.Context, transactions []*pb.TransactionMessage, _ bool, opts provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	var (
+		batch    []string
+		response pb.PostSubmitBatchResponse
+	)
+	for _, tx := range transactions {
+		batch = append(batch, tx.Content)
+		entry := &pb.PostSubmitBatchResponseEntry{Signature: "sig-" + tx.Content, Submitted: true}
+		if f.rejected[tx.Content] {
+			entry = &pb.PostSubmitBatchResponseEntry{Error: "rejected"}
+		}
+		response.Transactions = append(response.Transactions, entry)
+	}
+	f.batches = append(f.batches, batch)
+	return &response, nil
+}
+
+func (f *fakeSettleClient) batchCount() int {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return len(f.batches)
+}
+
+func TestSettler(t *testing.T) {
+	ctx := context.Background()
+	owner := "AFT8VayE7qr8MoQsW3wHsDS83HhEvhGWdbNSHRKeUDfQ"
+
+	client := &fakeSettleClient{}
+	s := NewSettler(client, SettleOpts{
+		Owner:      owner,
+		Markets:    []string{"SOLUSDC"},
+		Thresholds: map[string]float64{metadata.SOLMint: 2},
+	})
+
+	settlements, err := s.Settle(ctx, false)
+	require.Nil(t, err)
+	assert.Empty(t, settlements)
+	assert.Empty(t, client.batches)
+
+	settlements, err = s.Settle(ctx, true)
+	require.Nil(t, err)
+	require.Len(t, settlements, 1)
+	assert.Equal(t, "sig-oo-SOLUSDC", settlements[0].Signature)
+	assert.Equal(t, 1.0, s.Settled()[metadata.SOLMint])
+
+	// SOL is settled to the owner, other tokens to the owner's associated token account
+	require.Len(t, client.settled, 1)
+	assert.Equal(t, owner, client.settled[0][0])
+	assert.NotEqual(t, owner, client.settled[0][1])
+	assert.NotEmpty(t, client.settled[0][1])
+}
+
+func TestSettlerBatch(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeSettleClient{rejected: map[string]bool{"oo-RAYUSDC": true}}
+	s := NewSettler(client, SettleOpts{
+		Owner:   "AFT8VayE7qr8MoQsW3wHsDS83HhEvhGWdbNSHRKeUDfQ",
+		Markets: []string{"SOLUSDC", "RAYUSDC", "SOLUSDT"},
+	})
+
+	// the accounts of all markets are settled in one batch, and rejections are reported for their account only
+	settlements, err := s.Settle(ctx, false)
+	require.NotNil(t, err)
+	require.Len(t, settlements, 3)
+	assert.Equal(t, [][]string{{"oo-SOLUSDC", "oo-RAYUSDC", "oo-SOLUSDT"}}, client.batches)
+	assert.Nil(t, settlements[0].Err)
+	assert.NotNil(t, settlements[1].Err)
+	assert.Empty(t, settlements[1].Signature)
+	assert.Equal(t, "sig-oo-SOLUSDT", settlements[2].Signature)
+	assert.Equal(t, 2.0, s.Settled()[metadata.SOLMint])
+
+	// markets triggered while Run is busy are coalesced into one check
+	client.batches = nil
+	for _, market := range []string{"SOLUSDC", "SOLUSDT", "SOLUSDC"} {
+		s.Trigger(market)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.Run(ctx)
+
+	require.Eventually(t, func() bool { return client.batchCount() == 1 }, time.Second, time.Millisecond)
+	client.m.Lock()
+	assert.ElementsMatch(t, []string{"oo-SOLUSDC", "oo-SOLUSDT"}, client.batches[0])
+	client.m.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 1, client.batchCount())
+}
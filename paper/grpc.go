@@ -0,0 +1,309 @@
+package paper
+
+import (
+	"context"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/amount"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/bloXroute-Labs/solana-trader-proto/common"
+	"github.com/gagliardetto/solana-go"
+)
+
+// GRPCClient is a provider.GRPCClient that simulates its submissions
+type GRPCClient struct {
+	*provider.GRPCClient
+	sim *Simulator
+}
+
+func NewGRPCClient(client *provider.GRPCClient, sim *Simulator) *GRPCClient {
+	return &GRPCClient{GRPCClient: client, sim: sim}
+}
+
+// Simulator returns the simulator behind the client
+func (g *GRPCClient) Simulator() *Simulator {
+	return g.sim
+}
+
+// GetAccountBalance reports the virtual balances, whatever the owner
+func (g *GRPCClient) GetAccountBalance(context.Context, string) (*pb.GetAccountBalanceResponse, error) {
+	return g.sim.AccountBalance(), nil
+}
+
+// SignAndSubmit simulates an opaque transaction
+func (g *GRPCClient) SignAndSubmit(context.Context, *pb.TransactionMessage, bool, bool, bool) (string, error) {
+	return g.sim.transaction(KindTransaction)
+}
+
+// SignAndSubmitBatch simulates each transaction of the batch
+func (g *GRPCClient) SignAndSubmitBatch(_ context.Context, transactions []*pb.TransactionMessage, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return g.sim.transactions(len(transactions))
+}
+
+// PostSubmit simulates an opaque transaction
+func (g *GRPCClient) PostSubmit(context.Context, *pb.TransactionMessage, bool, bool, bool) (*pb.PostSubmitResponse, error) {
+	sig, err := g.sim.transaction(KindTransaction)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PostSubmitResponse{Signature: sig}, nil
+}
+
+// PostSubmitV2 simulates an opaque transaction
+func (g *GRPCClient) PostSubmitV2(ctx context.Context, tx *pb.TransactionMessage, skipPreFlight bool, frontRunningProtection bool, _ uint32) (*pb.PostSubmitResponse, error) {
+	return g.PostSubmit(ctx, tx, skipPreFlight, frontRunningProtection, false)
+}
+
+// PostSubmitBatch simulates each transaction of the batch
+func (g *GRPCClient) PostSubmitBatch(_ context.Context, request *pb.PostSubmitBatchRequest) (*pb.PostSubmitBatchResponse, error) {
+	return g.sim.transactions(len(request.Entries))
+}
+
+// SubmitBatch simulates the transactions as generic transactions
+func (g *GRPCClient) SubmitBatch(_ context.Context, transactions []*pb.TransactionMessage, _ provider.BatchOpts) (*provider.BatchResult, error) {
+	resp, err := g.sim.transactions(len(transactions))
+	if err != nil {
+		return nil, err
+	}
+	return provider.NewBatchResult(resp), nil
+}
+
+// PostSubmitBatchV2 simulates each transaction of the batch
+func (g *GRPCClient) PostSubmitBatchV2(ctx context.Context, request *pb.PostSubmitBatchRequest) (*pb.PostSubmitBatchResponse, error) {
+	return g.PostSubmitBatch(ctx, request)
+}
+
+// SubmitTradeSwap simulates the swap at the quote of PostTradeSwap
+func (g *GRPCClient) SubmitTradeSwap(ctx context.Context, ownerAddress, inToken, outToken string, inAmount, slippage float64, project pb.Project, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := g.GRPCClient.PostTradeSwap(ctx, ownerAddress, inToken, outToken, inAmount, slippage, project)
+	if err != nil {
+		return nil, err
+	}
+	return batch(g.sim.quotedSwap(inToken, outToken, inAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitTradeSwapWithAmount is like SubmitTradeSwap, with an exact input amount
+func (g *GRPCClient) SubmitTradeSwapWithAmount(ctx context.Context, ownerAddress, inToken, outToken string, inAmount amount.Amount, slippage float64, project pb.Project, opts provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	in, err := inAmount.ExactFloat64()
+	if err != nil {
+		return nil, err
+	}
+	return g.SubmitTradeSwap(ctx, ownerAddress, inToken, outToken, in, slippage, project, opts)
+}
+
+// SubmitRouteTradeSwap simulates the route at the quote of PostRouteTradeSwap
+func (g *GRPCClient) SubmitRouteTradeSwap(ctx context.Context, request *pb.RouteTradeSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := g.GRPCClient.PostRouteTradeSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(routeSwap(g.sim, request.Steps, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumSwap simulates the swap at the quote of PostRaydiumSwap
+func (g *GRPCClient) SubmitRaydiumSwap(ctx context.Context, request *pb.PostRaydiumSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := g.GRPCClient.PostRaydiumSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(g.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumCLMMSwap simulates the swap at the quote of PostRaydiumCLMMSwap
+func (g *GRPCClient) SubmitRaydiumCLMMSwap(ctx context.Context, request *pb.PostRaydiumSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := g.GRPCClient.PostRaydiumCLMMSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(g.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumSwapCPMM simulates the swap at the quote of PostRaydiumSwapCPMM
+func (g *GRPCClient) SubmitRaydiumSwapCPMM(ctx context.Context, request *pb.PostRaydiumCPMMSwapRequest) (string, error) {
+	resp, err := g.GRPCClient.PostRaydiumSwapCPMM(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	return g.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin)
+}
+
+// SubmitRaydiumRouteSwap simulates the route at the quote of PostRaydiumRouteSwap
+func (g *GRPCClient) SubmitRaydiumRouteSwap(ctx context.Context, request *pb.PostRaydiumRouteSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := g.GRPCClient.PostRaydiumRouteSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(routeSwap(g.sim, request.Steps, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumCLMMRouteSwap simulates the route at the quote of PostRaydiumCLMMRouteSwap
+func (g *GRPCClient) SubmitRaydiumCLMMRouteSwap(ctx context.Context, request *pb.PostRaydiumRouteSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := g.GRPCClient.PostRaydiumCLMMRouteSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(routeSwap(g.sim, request.Steps, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumSwapInstructions simulates the swap at the quote of PostRaydiumSwapInstructions
+func (g *GRPCClient) SubmitRaydiumSwapInstructions(ctx context.Context, request *pb.PostRaydiumSwapInstructionsRequest, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := g.GRPCClient.PostRaydiumSwapInstructions(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(g.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitJupiterSwap simulates the swap at the quote of PostJupiterSwap
+func (g *GRPCClient) SubmitJupiterSwap(ctx context.Context, request *pb.PostJupiterSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := g.GRPCClient.PostJupiterSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(g.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitJupiterSwapInstructions simulates the swap at the quote of PostJupiterSwapInstructions
+func (g *GRPCClient) SubmitJupiterSwapInstructions(ctx context.Context, request *pb.PostJupiterSwapInstructionsRequest, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := g.GRPCClient.PostJupiterSwapInstructions(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(g.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitComposed checks the composed transaction builds, and simulates it as a generic transaction: its swaps aren't
+// applied to the balances
+func (g *GRPCClient) SubmitComposed(_ context.Context, composer *transaction.Composer, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if _, err := composer.Build(solana.Hash{}); err != nil {
+		return nil, err
+	}
+	return g.sim.transactions(1)
+}
+
+// SubmitTxBuilder checks the transaction builds, and simulates it as a generic transaction
+func (g *GRPCClient) SubmitTxBuilder(ctx context.Context, builder *transaction.TxBuilder, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if _, err := builder.Build(ctx); err != nil {
+		return nil, err
+	}
+	return g.sim.transactions(1)
+}
+
+// CreateTokenAccounts simulates the account creations as a generic transaction
+func (g *GRPCClient) CreateTokenAccounts(context.Context, []solana.PublicKey, provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return g.sim.transactions(1)
+}
+
+// WrapSOL simulates a generic transaction, SOL and wrapped SOL being the same virtual balance
+func (g *GRPCClient) WrapSOL(context.Context, uint64, provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return g.sim.transactions(1)
+}
+
+// UnwrapSOL simulates a generic transaction, SOL and wrapped SOL being the same virtual balance
+func (g *GRPCClient) UnwrapSOL(context.Context, provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return g.sim.transactions(1)
+}
+
+// CloseEmptyTokenAccounts simulates the account closes as a generic transaction
+func (g *GRPCClient) CloseEmptyTokenAccounts(context.Context, provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return g.sim.transactions(1)
+}
+
+// SweepDust simulates the swaps of the virtual balances selected by DustOpts.IsDust
+func (g *GRPCClient) SweepDust(ctx context.Context, opts provider.DustOpts) ([]provider.SweepResult, error) {
+	owner, err := g.GRPCClient.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return provider.SweepDustAccounts(ctx, owner.String(), g.sim.tokenAccounts(), opts, g.SubmitTradeSwap)
+}
+
+// SubmitJupiterRouteSwap simulates the route at the quote of PostJupiterRouteSwap
+func (g *GRPCClient) SubmitJupiterRouteSwap(ctx context.Context, request *pb.PostJupiterRouteSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := g.GRPCClient.PostJupiterRouteSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(routeSwap(g.sim, request.Steps, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitPostPumpFunSwap simulates the swap at the quotes of GetPumpFunQuotes
+func (g *GRPCClient) SubmitPostPumpFunSwap(ctx context.Context, request *pb.PostPumpFunSwapRequest) (string, error) {
+	return g.sim.pumpFunSwap(ctx, g, request)
+}
+
+// SubmitOrder simulates the order against the live orderbook
+func (g *GRPCClient) SubmitOrder(ctx context.Context, _, _, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts provider.PostOrderOpts) (string, error) {
+	return g.sim.orderV1(ctx, g, market, side, types, amount, price, project, opts.ClientOrderID)
+}
+
+// SubmitOrderWithAmounts is like SubmitOrder, with an exact size and price
+func (g *GRPCClient) SubmitOrderWithAmounts(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, size, price amount.Amount, project pb.Project, opts provider.PostOrderOpts) (string, error) {
+	sizeF, priceF, err := exactOrder(size, price)
+	if err != nil {
+		return "", err
+	}
+	return g.SubmitOrder(ctx, owner, payer, market, side, types, sizeF, priceF, project, opts)
+}
+
+// SubmitCancelOrder cancels a paper order
+func (g *GRPCClient) SubmitCancelOrder(_ context.Context, orderID string, _ pb.Side, _, market, _ string, _ pb.Project, _ bool) (string, error) {
+	return g.sim.cancelOrder(market, orderID, 0)
+}
+
+// SubmitCancelByClientOrderID cancels a paper order
+func (g *GRPCClient) SubmitCancelByClientOrderID(_ context.Context, clientOrderID uint64, _, market, _ string, _ pb.Project, _ bool) (string, error) {
+	return g.sim.cancelOrder(market, "", clientOrderID)
+}
+
+// SubmitCancelAll cancels the paper orders of a market
+func (g *GRPCClient) SubmitCancelAll(_ context.Context, market, _ string, _ []string, _ pb.Project, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return batch(g.sim.cancelAll(market))
+}
+
+// SubmitSettle simulates a settlement. Paper fills are settled right away.
+func (g *GRPCClient) SubmitSettle(context.Context, string, string, string, string, string, pb.Project, bool) (string, error) {
+	return g.sim.transaction(KindTransaction)
+}
+
+// SubmitReplaceByClientOrderID replaces the paper order with the client order ID of opts
+func (g *GRPCClient) SubmitReplaceByClientOrderID(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts provider.PostOrderOpts) (string, error) {
+	return g.sim.replace(market, "", opts.ClientOrderID, func() (string, error) {
+		return g.SubmitOrder(ctx, owner, payer, market, side, types, amount, price, project, opts)
+	})
+}
+
+// SubmitReplaceOrder replaces a paper order
+func (g *GRPCClient) SubmitReplaceOrder(ctx context.Context, orderID, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts provider.PostOrderOpts) (string, error) {
+	return g.sim.replace(market, orderID, 0, func() (string, error) {
+		return g.SubmitOrder(ctx, owner, payer, market, side, types, amount, price, project, opts)
+	})
+}
+
+// SubmitOrderV2 simulates the order against the live orderbook
+func (g *GRPCClient) SubmitOrderV2(ctx context.Context, _, _, market string, side string, orderType string, amount, price float64, _ *uint64, opts provider.PostOrderOpts) (string, error) {
+	return g.sim.orderV2(ctx, g, market, side, orderType, amount, price, opts.ClientOrderID)
+}
+
+// SubmitOrderV2WithPriorityFee simulates the order against the live orderbook
+func (g *GRPCClient) SubmitOrderV2WithPriorityFee(ctx context.Context, owner, payer, market string, side string, orderType string, amount, price float64, _ uint32, _ uint64, bundleTip *uint64, opts provider.PostOrderOpts) (string, error) {
+	return g.SubmitOrderV2(ctx, owner, payer, market, side, orderType, amount, price, bundleTip, opts)
+}
+
+// SubmitCancelOrderV2 cancels a paper order
+func (g *GRPCClient) SubmitCancelOrderV2(_ context.Context, orderID string, clientOrderID uint64, _ string, _, market, _ string, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return batch(g.sim.cancelOrder(market, orderID, clientOrderID))
+}
+
+// SubmitSettleV2 simulates a settlement. Paper fills are settled right away.
+func (g *GRPCClient) SubmitSettleV2(context.Context, string, string, string, string, string, bool) (string, error) {
+	return g.sim.transaction(KindTransaction)
+}
+
+// SubmitReplaceOrderV2 replaces a paper order
+func (g *GRPCClient) SubmitReplaceOrderV2(ctx context.Context, orderID, owner, payer, market string, side string, orderType string, amount, price float64, opts provider.PostOrderOpts) (string, error) {
+	return g.sim.replace(market, orderID, opts.ClientOrderID, func() (string, error) {
+		return g.SubmitOrderV2(ctx, owner, payer, market, side, orderType, amount, price, nil, opts)
+	})
+}
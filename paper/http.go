@@ -0,0 +1,309 @@
+package paper
+
+import (
+	"context"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/amount"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/bloXroute-Labs/solana-trader-proto/common"
+	"github.com/gagliardetto/solana-go"
+)
+
+// HTTPClient is a provider.HTTPClient that simulates its submissions
+type HTTPClient struct {
+	*provider.HTTPClient
+	sim *Simulator
+}
+
+func NewHTTPClient(client *provider.HTTPClient, sim *Simulator) *HTTPClient {
+	return &HTTPClient{HTTPClient: client, sim: sim}
+}
+
+// Simulator returns the simulator behind the client
+func (h *HTTPClient) Simulator() *Simulator {
+	return h.sim
+}
+
+// GetAccountBalance reports the virtual balances, whatever the owner
+func (h *HTTPClient) GetAccountBalance(context.Context, string) (*pb.GetAccountBalanceResponse, error) {
+	return h.sim.AccountBalance(), nil
+}
+
+// SignAndSubmit simulates an opaque transaction
+func (h *HTTPClient) SignAndSubmit(context.Context, *pb.TransactionMessage, bool, bool, bool) (string, error) {
+	return h.sim.transaction(KindTransaction)
+}
+
+// SignAndSubmitBatch simulates each transaction of the batch
+func (h *HTTPClient) SignAndSubmitBatch(_ context.Context, transactions []*pb.TransactionMessage, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return h.sim.transactions(len(transactions))
+}
+
+// PostSubmit simulates an opaque transaction
+func (h *HTTPClient) PostSubmit(context.Context, string, bool, bool, bool) (*pb.PostSubmitResponse, error) {
+	sig, err := h.sim.transaction(KindTransaction)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PostSubmitResponse{Signature: sig}, nil
+}
+
+// PostSubmitV2 simulates an opaque transaction
+func (h *HTTPClient) PostSubmitV2(ctx context.Context, txBase64 string, skipPreFlight bool, frontRunningProtection bool, useStakedRPCs bool) (*pb.PostSubmitResponse, error) {
+	return h.PostSubmit(ctx, txBase64, skipPreFlight, frontRunningProtection, useStakedRPCs)
+}
+
+// PostSubmitBatch simulates each transaction of the batch
+func (h *HTTPClient) PostSubmitBatch(_ context.Context, request *pb.PostSubmitBatchRequest) (*pb.PostSubmitBatchResponse, error) {
+	return h.sim.transactions(len(request.Entries))
+}
+
+// SubmitBatch simulates the transactions as generic transactions
+func (h *HTTPClient) SubmitBatch(_ context.Context, transactions []*pb.TransactionMessage, _ provider.BatchOpts) (*provider.BatchResult, error) {
+	resp, err := h.sim.transactions(len(transactions))
+	if err != nil {
+		return nil, err
+	}
+	return provider.NewBatchResult(resp), nil
+}
+
+// PostSubmitBatchV2 simulates each transaction of the batch
+func (h *HTTPClient) PostSubmitBatchV2(ctx context.Context, request *pb.PostSubmitBatchRequest) (*pb.PostSubmitBatchResponse, error) {
+	return h.PostSubmitBatch(ctx, request)
+}
+
+// SubmitTradeSwap simulates the swap at the quote of PostTradeSwap
+func (h *HTTPClient) SubmitTradeSwap(ctx context.Context, ownerAddress, inToken, outToken string, inAmount, slippage float64, project pb.Project, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := h.HTTPClient.PostTradeSwap(ctx, ownerAddress, inToken, outToken, inAmount, slippage, project)
+	if err != nil {
+		return nil, err
+	}
+	return batch(h.sim.quotedSwap(inToken, outToken, inAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitTradeSwapWithAmount is like SubmitTradeSwap, with an exact input amount
+func (h *HTTPClient) SubmitTradeSwapWithAmount(ctx context.Context, ownerAddress, inToken, outToken string, inAmount amount.Amount, slippage float64, project pb.Project, opts provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	in, err := inAmount.ExactFloat64()
+	if err != nil {
+		return nil, err
+	}
+	return h.SubmitTradeSwap(ctx, ownerAddress, inToken, outToken, in, slippage, project, opts)
+}
+
+// SubmitRouteTradeSwap simulates the route at the quote of PostRouteTradeSwap
+func (h *HTTPClient) SubmitRouteTradeSwap(ctx context.Context, request *pb.RouteTradeSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := h.HTTPClient.PostRouteTradeSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(routeSwap(h.sim, request.Steps, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumSwap simulates the swap at the quote of PostRaydiumSwap
+func (h *HTTPClient) SubmitRaydiumSwap(ctx context.Context, request *pb.PostRaydiumSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := h.HTTPClient.PostRaydiumSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(h.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumCLMMSwap simulates the swap at the quote of PostRaydiumCLMMSwap
+func (h *HTTPClient) SubmitRaydiumCLMMSwap(ctx context.Context, request *pb.PostRaydiumSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := h.HTTPClient.PostRaydiumCLMMSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(h.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumSwapCPMM simulates the swap at the quote of PostRaydiumCPMMSwap
+func (h *HTTPClient) SubmitRaydiumSwapCPMM(ctx context.Context, request *pb.PostRaydiumCPMMSwapRequest) (string, error) {
+	resp, err := h.HTTPClient.PostRaydiumCPMMSwap(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	return h.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin)
+}
+
+// SubmitRaydiumRouteSwap simulates the route at the quote of PostRaydiumRouteSwap
+func (h *HTTPClient) SubmitRaydiumRouteSwap(ctx context.Context, request *pb.PostRaydiumRouteSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := h.HTTPClient.PostRaydiumRouteSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(routeSwap(h.sim, request.Steps, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumCLMMRouteSwap simulates the route at the quote of PostRaydiumCLMMRouteSwap
+func (h *HTTPClient) SubmitRaydiumCLMMRouteSwap(ctx context.Context, request *pb.PostRaydiumRouteSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := h.HTTPClient.PostRaydiumCLMMRouteSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(routeSwap(h.sim, request.Steps, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumSwapInstructions simulates the swap at the quote of PostRaydiumSwapInstructions
+func (h *HTTPClient) SubmitRaydiumSwapInstructions(ctx context.Context, request *pb.PostRaydiumSwapInstructionsRequest, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := h.HTTPClient.PostRaydiumSwapInstructions(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(h.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitJupiterSwap simulates the swap at the quote of PostJupiterSwap
+func (h *HTTPClient) SubmitJupiterSwap(ctx context.Context, request *pb.PostJupiterSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := h.HTTPClient.PostJupiterSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(h.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitJupiterSwapInstructions simulates the swap at the quote of PostJupiterSwapInstructions
+func (h *HTTPClient) SubmitJupiterSwapInstructions(ctx context.Context, request *pb.PostJupiterSwapInstructionsRequest, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := h.HTTPClient.PostJupiterSwapInstructions(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(h.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitComposed checks the composed transaction builds, and simulates it as a generic transaction: its swaps aren't
+// applied to the balances
+func (h *HTTPClient) SubmitComposed(_ context.Context, composer *transaction.Composer, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if _, err := composer.Build(solana.Hash{}); err != nil {
+		return nil, err
+	}
+	return h.sim.transactions(1)
+}
+
+// SubmitTxBuilder checks the transaction builds, and simulates it as a generic transaction
+func (h *HTTPClient) SubmitTxBuilder(ctx context.Context, builder *transaction.TxBuilder, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if _, err := builder.Build(ctx); err != nil {
+		return nil, err
+	}
+	return h.sim.transactions(1)
+}
+
+// CreateTokenAccounts simulates the account creations as a generic transaction
+func (h *HTTPClient) CreateTokenAccounts(context.Context, []solana.PublicKey, provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return h.sim.transactions(1)
+}
+
+// WrapSOL simulates a generic transaction, SOL and wrapped SOL being the same virtual balance
+func (h *HTTPClient) WrapSOL(context.Context, uint64, provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return h.sim.transactions(1)
+}
+
+// UnwrapSOL simulates a generic transaction, SOL and wrapped SOL being the same virtual balance
+func (h *HTTPClient) UnwrapSOL(context.Context, provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return h.sim.transactions(1)
+}
+
+// CloseEmptyTokenAccounts simulates the account closes as a generic transaction
+func (h *HTTPClient) CloseEmptyTokenAccounts(context.Context, provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return h.sim.transactions(1)
+}
+
+// SweepDust simulates the swaps of the virtual balances selected by DustOpts.IsDust
+func (h *HTTPClient) SweepDust(ctx context.Context, opts provider.DustOpts) ([]provider.SweepResult, error) {
+	owner, err := h.HTTPClient.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return provider.SweepDustAccounts(ctx, owner.String(), h.sim.tokenAccounts(), opts, h.SubmitTradeSwap)
+}
+
+// SubmitJupiterRouteSwap simulates the route at the quote of PostJupiterRouteSwap
+func (h *HTTPClient) SubmitJupiterRouteSwap(ctx context.Context, request *pb.PostJupiterRouteSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := h.HTTPClient.PostJupiterRouteSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(routeSwap(h.sim, request.Steps, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitPostPumpFunSwap simulates the swap at the quotes of GetPumpFunQuotes
+func (h *HTTPClient) SubmitPostPumpFunSwap(ctx context.Context, request *pb.PostPumpFunSwapRequest) (string, error) {
+	return h.sim.pumpFunSwap(ctx, h, request)
+}
+
+// SubmitOrder simulates the order against the live orderbook
+func (h *HTTPClient) SubmitOrder(ctx context.Context, _, _, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts provider.PostOrderOpts) (string, error) {
+	return h.sim.orderV1(ctx, h, market, side, types, amount, price, project, opts.ClientOrderID)
+}
+
+// SubmitOrderWithAmounts is like SubmitOrder, with an exact size and price
+func (h *HTTPClient) SubmitOrderWithAmounts(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, size, price amount.Amount, project pb.Project, opts provider.PostOrderOpts) (string, error) {
+	sizeF, priceF, err := exactOrder(size, price)
+	if err != nil {
+		return "", err
+	}
+	return h.SubmitOrder(ctx, owner, payer, market, side, types, sizeF, priceF, project, opts)
+}
+
+// SubmitCancelOrder cancels a paper order
+func (h *HTTPClient) SubmitCancelOrder(_ context.Context, orderID string, _ pb.Side, _, market, _ string, _ pb.Project, _ bool) (string, error) {
+	return h.sim.cancelOrder(market, orderID, 0)
+}
+
+// SubmitCancelByClientOrderID cancels a paper order
+func (h *HTTPClient) SubmitCancelByClientOrderID(_ context.Context, clientOrderID uint64, _, market, _ string, _ pb.Project, _ bool) (string, error) {
+	return h.sim.cancelOrder(market, "", clientOrderID)
+}
+
+// SubmitCancelAll cancels the paper orders of a market
+func (h *HTTPClient) SubmitCancelAll(_ context.Context, market, _ string, _ []string, _ pb.Project, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return batch(h.sim.cancelAll(market))
+}
+
+// SubmitSettle simulates a settlement. Paper fills are settled right away.
+func (h *HTTPClient) SubmitSettle(context.Context, string, string, string, string, string, pb.Project, bool) (string, error) {
+	return h.sim.transaction(KindTransaction)
+}
+
+// SubmitReplaceByClientOrderID replaces the paper order with the client order ID of opts
+func (h *HTTPClient) SubmitReplaceByClientOrderID(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts provider.PostOrderOpts) (string, error) {
+	return h.sim.replace(market, "", opts.ClientOrderID, func() (string, error) {
+		return h.SubmitOrder(ctx, owner, payer, market, side, types, amount, price, project, opts)
+	})
+}
+
+// SubmitReplaceOrder replaces a paper order
+func (h *HTTPClient) SubmitReplaceOrder(ctx context.Context, orderID, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts provider.PostOrderOpts) (string, error) {
+	return h.sim.replace(market, orderID, 0, func() (string, error) {
+		return h.SubmitOrder(ctx, owner, payer, market, side, types, amount, price, project, opts)
+	})
+}
+
+// SubmitOrderV2 simulates the order against the live orderbook
+func (h *HTTPClient) SubmitOrderV2(ctx context.Context, _, _, market string, side string, orderType string, amount, price float64, opts provider.PostOrderOpts) (string, error) {
+	return h.sim.orderV2(ctx, h, market, side, orderType, amount, price, opts.ClientOrderID)
+}
+
+// SubmitOrderV2WithPriorityFee simulates the order against the live orderbook
+func (h *HTTPClient) SubmitOrderV2WithPriorityFee(ctx context.Context, owner, payer, market string, side string, orderType string, amount, price float64, _ uint32, _ uint64, opts provider.PostOrderOpts) (string, error) {
+	return h.SubmitOrderV2(ctx, owner, payer, market, side, orderType, amount, price, opts)
+}
+
+// SubmitCancelOrderV2 cancels a paper order
+func (h *HTTPClient) SubmitCancelOrderV2(_ context.Context, orderID string, clientOrderID uint64, _ string, _, market, _ string, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return batch(h.sim.cancelOrder(market, orderID, clientOrderID))
+}
+
+// SubmitSettleV2 simulates a settlement. Paper fills are settled right away.
+func (h *HTTPClient) SubmitSettleV2(context.Context, string, string, string, string, string, bool) (string, error) {
+	return h.sim.transaction(KindTransaction)
+}
+
+// SubmitReplaceOrderV2 replaces a paper order
+func (h *HTTPClient) SubmitReplaceOrderV2(ctx context.Context, orderID, owner, payer, market string, side string, orderType string, amount, price float64, opts provider.PostOrderOpts) (string, error) {
+	return h.sim.replace(market, orderID, opts.ClientOrderID, func() (string, error) {
+		return h.SubmitOrderV2(ctx, owner, payer, market, side, orderType, amount, price, opts)
+	})
+}
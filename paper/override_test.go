@@ -0,0 +1,44 @@
+package paper
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	"github.com/stretchr/testify/assert"
+)
+
+// promoted reports whether a method is promoted from an embedded type, by the wrapper the compiler generates for it
+func promoted(method reflect.Method) bool {
+	pc := method.Func.Pointer()
+	file, _ := runtime.FuncForPC(pc).FileLine(pc)
+	return file == "<autogenerated>"
+}
+
+// TestSubmitMethodsOverridden fails if a paper client would submit through a method promoted from its provider client
+func TestSubmitMethodsOverridden(t *testing.T) {
+	clients := map[reflect.Type]reflect.Type{
+		reflect.TypeOf(&provider.GRPCClient{}): reflect.TypeOf(&GRPCClient{}),
+		reflect.TypeOf(&provider.HTTPClient{}): reflect.TypeOf(&HTTPClient{}),
+		reflect.TypeOf(&provider.WSClient{}):   reflect.TypeOf(&WSClient{}),
+	}
+	for live, paper := range clients {
+		for i := 0; i < live.NumMethod(); i++ {
+			name := live.Method(i).Name
+			// the methods of pb.UnimplementedApiServer don't submit anything
+			if promoted(live.Method(i)) {
+				continue
+			}
+			if !strings.HasPrefix(name, "Submit") && !strings.HasPrefix(name, "SignAndSubmit") && !strings.HasPrefix(name, "PostSubmit") {
+				continue
+			}
+			method, ok := paper.MethodByName(name)
+			if !assert.True(t, ok, "%v.%v", paper, name) {
+				continue
+			}
+			assert.False(t, promoted(method), "%v.%v is not simulated", paper, name)
+		}
+	}
+}
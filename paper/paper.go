@@ -0,0 +1,233 @@
+// Package paper runs trading code against the live API without submitting transactions. The paper clients wrap the
+// provider clients: Get* and stream calls go through to the API, while every Submit*, SignAndSubmit* and PostSubmit*
+// call is simulated by a Simulator from the current quotes and orderbooks and answered with realistic signatures and
+// batch responses. GetAccountBalance reports the virtual balance sheet.
+//
+// Swaps are built with the matching Post* call, which validates them and quotes their output. Pump.fun swaps are
+// quoted with GetPumpFunQuotes. Orders fill against the live orderbook and the remainder rests virtually, filling
+// when Simulator.Refresh finds the book crossing it; the API's open orders don't include them. Opaque transactions
+// passed to SignAndSubmit* and PostSubmit* only cost the network fee.
+package paper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/amount"
+	"github.com/bloXroute-Labs/solana-trader-client-go/metadata"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/bloXroute-Labs/solana-trader-proto/common"
+)
+
+// marketClient is the market data shared by the provider clients
+type marketClient interface {
+	GetOrderbook(ctx context.Context, market string, limit uint32, project pb.Project) (*pb.GetOrderbookResponse, error)
+	GetOrderbookV2(ctx context.Context, market string, limit uint32) (*pb.GetOrderbookResponseV2, error)
+	GetPumpFunQuotes(ctx context.Context, request *pb.GetPumpFunQuotesRequest) (*pb.GetPumpFunQuotesResponse, error)
+}
+
+// pumpFunSwap simulates a Pump.fun swap. Buys pay the quoted SOL cost of the tokens and fail above the SOL threshold,
+// sells fail below it.
+func (s *Simulator) pumpFunSwap(ctx context.Context, client marketClient, request *pb.PostPumpFunSwapRequest) (string, error) {
+	if !request.IsBuy {
+		quote, err := client.GetPumpFunQuotes(ctx, &pb.GetPumpFunQuotesRequest{
+			QuoteType:           "sell",
+			MintAddress:         request.TokenAddress,
+			BondingCurveAddress: request.BondingCurveAddress,
+			Amount:              request.TokenAmount,
+		})
+		if err != nil {
+			return "", err
+		}
+		return s.quotedSwap(request.TokenAddress, metadata.SOLMint, request.TokenAmount, quote.OutAmount, request.SolThreshold)
+	}
+
+	// the quote is for spending the whole threshold, the cost of fewer tokens is interpolated
+	quote, err := client.GetPumpFunQuotes(ctx, &pb.GetPumpFunQuotesRequest{
+		QuoteType:           "buy",
+		MintAddress:         request.TokenAddress,
+		BondingCurveAddress: request.BondingCurveAddress,
+		Amount:              request.SolThreshold,
+	})
+	if err != nil {
+		return "", err
+	}
+	cost := request.SolThreshold
+	if quote.OutAmount > 0 {
+		cost = request.SolThreshold * request.TokenAmount / quote.OutAmount * (1 + s.opts.Slippage/100)
+	}
+	var failure error
+	if quote.OutAmount < request.TokenAmount || cost > request.SolThreshold {
+		failure = fmt.Errorf("%w: %v SOL above threshold %v", ErrSlippageExceeded, cost, request.SolThreshold)
+	}
+	return s.swap(metadata.SOLMint, request.TokenAddress, cost, request.TokenAmount, failure)
+}
+
+// orderV1 simulates an order on a market of the given project
+func (s *Simulator) orderV1(ctx context.Context, client marketClient, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, clientOrderID uint64) (string, error) {
+	req := orderRequest{market: market, side: sideName(side), amount: amount, price: price, clientOrderID: clientOrderID}
+	for _, t := range types {
+		switch t {
+		case common.OrderType_OT_MARKET, common.OrderType_OT_IOC:
+			req.immediate = true
+		case common.OrderType_OT_POST:
+			req.postOnly = true
+		}
+	}
+	book := func(ctx context.Context, market string) ([]level, []level, error) {
+		resp, err := client.GetOrderbook(ctx, market, bookDepth, project)
+		if err != nil {
+			return nil, nil, err
+		}
+		return levels(resp.Bids), levels(resp.Asks), nil
+	}
+	return s.order(ctx, book, req)
+}
+
+// orderV2 simulates an Openbook V2 order
+func (s *Simulator) orderV2(ctx context.Context, client marketClient, market, side, orderType string, amount, price float64, clientOrderID uint64) (string, error) {
+	orderType = strings.ToLower(orderType)
+	req := orderRequest{
+		market:        market,
+		side:          side,
+		amount:        amount,
+		price:         price,
+		clientOrderID: clientOrderID,
+		immediate:     strings.Contains(orderType, "market") || strings.Contains(orderType, "ioc"),
+		postOnly:      strings.Contains(orderType, "post"),
+	}
+	book := func(ctx context.Context, market string) ([]level, []level, error) {
+		resp, err := client.GetOrderbookV2(ctx, market, bookDepth)
+		if err != nil {
+			return nil, nil, err
+		}
+		return levelsV2(resp.Bids), levelsV2(resp.Asks), nil
+	}
+	return s.order(ctx, book, req)
+}
+
+// cancelOrder cancels the resting orders of a market with an order ID or client order ID
+func (s *Simulator) cancelOrder(market, orderID string, clientOrderID uint64) (string, error) {
+	return s.cancel(s.orderMatcher(market, orderID, clientOrderID))
+}
+
+// cancelAll cancels the resting orders of a market
+func (s *Simulator) cancelAll(market string) (string, error) {
+	return s.cancel(func(o *Order) bool {
+		return s.sameMarket(o.Market, market)
+	})
+}
+
+// replace removes the resting orders of a market with an order ID or client order ID and places a new one, in a single
+// transaction. If the new order is rejected, the old orders are kept.
+func (s *Simulator) replace(market, orderID string, clientOrderID uint64, place func() (string, error)) (string, error) {
+	removed := s.remove(s.orderMatcher(market, orderID, clientOrderID))
+	signature, err := place()
+	if err != nil {
+		s.restore(removed)
+		return "", err
+	}
+	return signature, nil
+}
+
+func (s *Simulator) orderMatcher(market, orderID string, clientOrderID uint64) func(o *Order) bool {
+	return func(o *Order) bool {
+		if market != "" && !s.sameMarket(o.Market, market) {
+			return false
+		}
+		return (orderID != "" && o.OrderID == orderID) || (clientOrderID != 0 && o.ClientOrderID == clientOrderID)
+	}
+}
+
+func (s *Simulator) sameMarket(name, nameOrAddress string) bool {
+	if name == nameOrAddress {
+		return true
+	}
+	market, ok := s.market(nameOrAddress)
+	return ok && market.Name == name
+}
+
+// transactions simulates n opaque transactions, for batch submissions
+func (s *Simulator) transactions(n int) (*pb.PostSubmitBatchResponse, error) {
+	resp := &pb.PostSubmitBatchResponse{}
+	for i := 0; i < n; i++ {
+		sig, err := s.transaction(KindTransaction)
+		if err != nil {
+			resp.Transactions = append(resp.Transactions, &pb.PostSubmitBatchResponseEntry{Error: err.Error()})
+			continue
+		}
+		resp.Transactions = append(resp.Transactions, &pb.PostSubmitBatchResponseEntry{Signature: sig, Submitted: true})
+	}
+	return resp, nil
+}
+
+// tokenAccounts lists the virtual balances as token accounts
+func (s *Simulator) tokenAccounts() []*pb.TokenAccount {
+	var accounts []*pb.TokenAccount
+	for _, token := range s.AccountBalance().Tokens {
+		accounts = append(accounts, &pb.TokenAccount{Symbol: token.Symbol, TokenMint: token.TokenMint, Amount: token.SettledAmount})
+	}
+	return accounts
+}
+
+// batch wraps the signature of a simulated transaction in a batch response
+func batch(sig string, err error) (*pb.PostSubmitBatchResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PostSubmitBatchResponse{
+		Transactions: []*pb.PostSubmitBatchResponseEntry{{Signature: sig, Submitted: true}},
+	}, nil
+}
+
+// routeSwap simulates a multi step swap from the first step's input to the last step's output
+func routeSwap[T interface {
+	GetInToken() string
+	GetInAmount() float64
+	GetOutToken() string
+}](s *Simulator, steps []T, outAmount, minOut float64) (string, error) {
+	if len(steps) == 0 {
+		return "", fmt.Errorf("%w: route without steps", ErrInvalidOrder)
+	}
+	first, last := steps[0], steps[len(steps)-1]
+	return s.quotedSwap(first.GetInToken(), last.GetOutToken(), first.GetInAmount(), outAmount, minOut)
+}
+
+func exactOrder(size, price amount.Amount) (float64, float64, error) {
+	sizeF, err := size.ExactFloat64()
+	if err != nil {
+		return 0, 0, fmt.Errorf("amount: %w", err)
+	}
+	priceF, err := price.ExactFloat64()
+	if err != nil {
+		return 0, 0, fmt.Errorf("price: %w", err)
+	}
+	return sizeF, priceF, nil
+}
+
+func sideName(side pb.Side) string {
+	if side == pb.Side_S_ASK {
+		return "ask"
+	}
+	if side == pb.Side_S_BID {
+		return "bid"
+	}
+	return side.String()
+}
+
+func levels(items []*pb.OrderbookItem) []level {
+	l := make([]level, 0, len(items))
+	for _, item := range items {
+		l = append(l, level{price: item.Price, size: item.Size})
+	}
+	return l
+}
+
+func levelsV2(items []*pb.OrderbookItemV2) []level {
+	l := make([]level, 0, len(items))
+	for _, item := range items {
+		l = append(l, level{price: item.Price, size: item.Size})
+	}
+	return l
+}
@@ -0,0 +1,217 @@
+package paper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/metadata"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/bloXroute-Labs/solana-trader-proto/common"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMarket = "SOL/USDC"
+
+type testClient struct {
+	book  *pb.GetOrderbookResponse
+	quote *pb.GetPumpFunQuotesResponse
+}
+
+func (c *testClient) GetOrderbook(context.Context, string, uint32, pb.Project) (*pb.GetOrderbookResponse, error) {
+	return c.book, nil
+}
+
+func (c *testClient) GetOrderbookV2(context.Context, string, uint32) (*pb.GetOrderbookResponseV2, error) {
+	resp := &pb.GetOrderbookResponseV2{}
+	for _, item := range c.book.Bids {
+		resp.Bids = append(resp.Bids, &pb.OrderbookItemV2{Price: item.Price, Size: item.Size})
+	}
+	for _, item := range c.book.Asks {
+		resp.Asks = append(resp.Asks, &pb.OrderbookItemV2{Price: item.Price, Size: item.Size})
+	}
+	return resp, nil
+}
+
+func (c *testClient) GetPumpFunQuotes(context.Context, *pb.GetPumpFunQuotesRequest) (*pb.GetPumpFunQuotesResponse, error) {
+	return c.quote, nil
+}
+
+func newTestSimulator(t *testing.T) *Simulator {
+	sim, err := NewSimulator(Opts{
+		Balances:    map[string]float64{"SOL": 10, "USDC": 1000},
+		Markets:     []metadata.Market{{Name: testMarket, Address: "market", BaseMint: metadata.SOLMint, QuoteMint: metadata.USDCMint}},
+		TakerFeeBps: 10,
+		NetworkFee:  0.001,
+	})
+	require.Nil(t, err)
+	return sim
+}
+
+func TestSwap(t *testing.T) {
+	sim := newTestSimulator(t)
+
+	sig, err := sim.quotedSwap("USDC", "SOL", 100, 0.5, 0.49)
+	require.Nil(t, err)
+	_, err = solana.SignatureFromBase58(sig)
+	require.Nil(t, err)
+	assert.InDelta(t, 900, sim.Balance("USDC"), 1e-9)
+	assert.InDelta(t, 10.499, sim.Balance(metadata.SOLMint), 1e-9)
+
+	// preflight rejects unfunded swaps
+	_, err = sim.quotedSwap("USDC", "SOL", 5000, 25, 24)
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// swaps below their minimum land and fail
+	sim.opts.Slippage = 5
+	sig, err = sim.quotedSwap("USDC", "SOL", 100, 0.5, 0.49)
+	require.Nil(t, err)
+	trades := sim.Trades()
+	require.Len(t, trades, 2)
+	assert.Equal(t, sig, trades[1].Signature)
+	assert.ErrorIs(t, trades[1].Err, ErrSlippageExceeded)
+	assert.InDelta(t, 900, sim.Balance("USDC"), 1e-9)
+	assert.InDelta(t, 10.498, sim.Balance(metadata.SOLMint), 1e-9)
+}
+
+func TestPumpFunSwap(t *testing.T) {
+	sim := newTestSimulator(t)
+	client := &testClient{quote: &pb.GetPumpFunQuotesResponse{OutAmount: 2000}}
+
+	_, err := sim.pumpFunSwap(context.Background(), client, &pb.PostPumpFunSwapRequest{
+		TokenAddress: "token",
+		TokenAmount:  1000,
+		SolThreshold: 1,
+		IsBuy:        true,
+	})
+	require.Nil(t, err)
+	assert.InDelta(t, 1000, sim.Balance("token"), 1e-9)
+	assert.InDelta(t, 9.499, sim.Balance(metadata.SOLMint), 1e-9)
+
+	client.quote.OutAmount = 0.4
+	_, err = sim.pumpFunSwap(context.Background(), client, &pb.PostPumpFunSwapRequest{
+		TokenAddress: "token",
+		TokenAmount:  1000,
+		SolThreshold: 0.45,
+	})
+	require.Nil(t, err)
+	trades := sim.Trades()
+	assert.ErrorIs(t, trades[len(trades)-1].Err, ErrSlippageExceeded)
+	assert.InDelta(t, 1000, sim.Balance("token"), 1e-9)
+}
+
+func TestOrders(t *testing.T) {
+	sim := newTestSimulator(t)
+	client := &testClient{book: &pb.GetOrderbookResponse{
+		Bids: []*pb.OrderbookItem{{Price: 99, Size: 5}},
+		Asks: []*pb.OrderbookItem{{Price: 100, Size: 1}, {Price: 101, Size: 5}},
+	}}
+	ctx := context.Background()
+
+	// fills 1 at 100 and rests the remaining 1 at 100.5
+	_, err := sim.orderV1(ctx, client, testMarket, pb.Side_S_BID, []common.OrderType{common.OrderType_OT_LIMIT}, 2, 100.5, pb.Project_P_OPENBOOK, 7)
+	require.Nil(t, err)
+	assert.InDelta(t, 10.999, sim.Balance("SOL"), 1e-9)
+	assert.InDelta(t, 1000-100-0.1-100.5, sim.Balance("USDC"), 1e-9)
+	orders := sim.OpenOrders()
+	require.Len(t, orders, 1)
+	assert.Equal(t, uint64(7), orders[0].ClientOrderID)
+	assert.Equal(t, 1.0, orders[0].Remaining)
+
+	balance := sim.AccountBalance()
+	require.Len(t, balance.Tokens, 2)
+	for _, token := range balance.Tokens {
+		if token.TokenMint == metadata.USDCMint {
+			assert.Equal(t, "USDC", token.Symbol)
+			assert.InDelta(t, 100.5, token.OpenOrdersAmount, 1e-9)
+		}
+	}
+
+	// the book moves through the resting order, which fills at its price
+	client.book.Asks = []*pb.OrderbookItem{{Price: 100.2, Size: 3}}
+	require.Nil(t, sim.Refresh(ctx))
+	assert.Empty(t, sim.OpenOrders())
+	assert.InDelta(t, 11.999, sim.Balance("SOL"), 1e-9)
+
+	// post only orders can't cross, and cancels return the locked funds
+	_, err = sim.orderV2(ctx, client, testMarket, "ask", "postOnly", 1, 99, 0)
+	assert.ErrorIs(t, err, ErrWouldCross)
+	_, err = sim.orderV2(ctx, client, testMarket, "ask", "limit", 1, 110, 0)
+	require.Nil(t, err)
+	assert.InDelta(t, 10.998, sim.Balance("SOL"), 1e-9)
+	orders = sim.OpenOrders()
+	require.Len(t, orders, 1)
+	_, err = sim.cancelOrder("market", orders[0].OrderID, 0)
+	require.Nil(t, err)
+	assert.Empty(t, sim.OpenOrders())
+	assert.InDelta(t, 11.997, sim.Balance("SOL"), 1e-9)
+}
+
+func TestOrderSlippage(t *testing.T) {
+	ctx := context.Background()
+	book := func() *testClient {
+		return &testClient{book: &pb.GetOrderbookResponse{
+			Asks: []*pb.OrderbookItem{{Price: 100, Size: 1}, {Price: 101, Size: 5}},
+		}}
+	}
+
+	// limit orders fill at the book price
+	sim := newTestSimulator(t)
+	sim.opts.Slippage = 1
+	_, err := sim.orderV2(ctx, book(), testMarket, "bid", "limit", 1, 100.5, 0)
+	require.Nil(t, err)
+	assert.InDelta(t, 1000-100-0.1, sim.Balance("USDC"), 1e-9)
+
+	// IOC orders slip, but never past their limit price
+	sim = newTestSimulator(t)
+	sim.opts.Slippage = 1
+	_, err = sim.orderV2(ctx, book(), testMarket, "bid", "ioc", 2, 101.5, 0)
+	require.Nil(t, err)
+	assert.InDelta(t, 1000-101-0.101-101.5-0.1015, sim.Balance("USDC"), 1e-9)
+	assert.InDelta(t, 11.999, sim.Balance("SOL"), 1e-9)
+	assert.Empty(t, sim.OpenOrders())
+}
+
+func TestReplace(t *testing.T) {
+	sim := newTestSimulator(t)
+	client := &testClient{book: &pb.GetOrderbookResponse{}}
+	ctx := context.Background()
+
+	_, err := sim.orderV2(ctx, client, testMarket, "ask", "limit", 1, 110, 3)
+	require.Nil(t, err)
+	orderID := sim.OpenOrders()[0].OrderID
+
+	// a rejected replacement keeps the old order and its locked funds
+	_, err = sim.replace(testMarket, orderID, 0, func() (string, error) {
+		return sim.orderV2(ctx, client, testMarket, "ask", "limit", 0, 120, 3)
+	})
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+	orders := sim.OpenOrders()
+	require.Len(t, orders, 1)
+	assert.Equal(t, orderID, orders[0].OrderID)
+	assert.Equal(t, 1.0, orders[0].Remaining)
+	assert.InDelta(t, 8.999, sim.Balance("SOL"), 1e-9)
+
+	_, err = sim.replace(testMarket, orderID, 0, func() (string, error) {
+		return sim.orderV2(ctx, client, testMarket, "ask", "limit", 2, 120, 3)
+	})
+	require.Nil(t, err)
+	orders = sim.OpenOrders()
+	require.Len(t, orders, 1)
+	assert.Equal(t, 120.0, orders[0].Price)
+	assert.InDelta(t, 7.998, sim.Balance("SOL"), 1e-9)
+}
+
+func TestTransactions(t *testing.T) {
+	sim := newTestSimulator(t)
+
+	resp, err := sim.transactions(3)
+	require.Nil(t, err)
+	require.Len(t, resp.Transactions, 3)
+	for _, entry := range resp.Transactions {
+		assert.True(t, entry.Submitted)
+		assert.NotEmpty(t, entry.Signature)
+	}
+	assert.InDelta(t, 9.997, sim.Balance("SOL"), 1e-9)
+}
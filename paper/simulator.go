@@ -0,0 +1,575 @@
+package paper
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/metadata"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// bookDepth is the number of levels per side fetched to fill orders
+const bookDepth = 50
+
+var (
+	ErrInsufficientFunds = errors.New("insufficient paper funds")
+	ErrSlippageExceeded  = errors.New("slippage exceeded")
+	ErrUnknownMarket     = errors.New("unknown market")
+	ErrInvalidOrder      = errors.New("invalid order")
+	ErrWouldCross        = errors.New("post only order would cross the book")
+)
+
+type Opts struct {
+	// Balances to start with, by mint or symbol
+	Balances map[string]float64
+	// Registry resolves symbols and markets. By default, only the well-known tokens are known.
+	Registry *metadata.Registry
+	// Markets in addition to those of the registry, needed to place orders
+	Markets []metadata.Market
+	// Slippage is an adverse price move in percent applied to every swap and to the taker fills of market and IOC
+	// orders, on top of the quoted price. Fills never slip past the limit price of the order.
+	Slippage float64
+	// TakerFeeBps and MakerFeeBps are charged in the quote token on order fills
+	TakerFeeBps float64
+	MakerFeeBps float64
+	// NetworkFee in SOL is charged for every simulated transaction
+	NetworkFee float64
+	// OnTrade is called with every simulated trade, without holding the simulator's lock
+	OnTrade func(Trade)
+}
+
+// TradeKind is the kind of simulated transaction
+type TradeKind string
+
+const (
+	KindSwap        TradeKind = "swap"
+	KindFill        TradeKind = "fill"
+	KindCancel      TradeKind = "cancel"
+	KindTransaction TradeKind = "transaction"
+)
+
+// Trade is a simulated transaction. Fills of resting orders are trades without a signature of their own.
+type Trade struct {
+	Signature string
+	Kind      TradeKind
+	Market    string
+	OrderID   string
+	InMint    string
+	InAmount  float64
+	OutMint   string
+	OutAmount float64
+	// Fee of a fill in the quote token. Swap fees are included in the quotes.
+	Fee  float64
+	Time time.Time
+	// Err is set for transactions that would have landed and failed, e.g. with ErrSlippageExceeded
+	Err error
+}
+
+// Order is a paper order resting on a market
+type Order struct {
+	OrderID       string
+	ClientOrderID uint64
+	Market        string
+	// Side is "bid" or "ask"
+	Side      string
+	Price     float64
+	Size      float64
+	Remaining float64
+	Signature string
+	Time      time.Time
+}
+
+// level of an orderbook side
+type level struct {
+	price float64
+	size  float64
+}
+
+// bookFunc fetches the current bids and asks of a market
+type bookFunc func(ctx context.Context, market string) ([]level, []level, error)
+
+type restingOrder struct {
+	Order
+	market metadata.Market
+	book   bookFunc
+	// immediate orders fill against the book or not at all, and slip
+	immediate bool
+}
+
+func (o *restingOrder) bid() bool {
+	return o.Side == "bid"
+}
+
+// orderRequest is a new order, with the Openbook order types reduced to their effect
+type orderRequest struct {
+	market        string
+	side          string
+	amount        float64
+	price         float64
+	clientOrderID uint64
+	immediate     bool
+	postOnly      bool
+}
+
+// Simulator keeps the virtual balance sheet and orders behind the paper clients. Swaps execute at the quotes of the
+// live API, and orders fill against its live orderbooks.
+type Simulator struct {
+	opts     Opts
+	registry *metadata.Registry
+	markets  map[string]metadata.Market
+
+	m        sync.Mutex
+	balances map[string]float64
+	locked   map[string]float64
+	orders   []*restingOrder
+	trades   []Trade
+	orderID  uint64
+}
+
+func NewSimulator(opts Opts) (*Simulator, error) {
+	if opts.Registry == nil {
+		opts.Registry = metadata.NewRegistry(nil, metadata.Opts{SkipPools: true})
+	}
+
+	s := &Simulator{
+		opts:     opts,
+		registry: opts.Registry,
+		markets:  make(map[string]metadata.Market),
+		balances: make(map[string]float64),
+		locked:   make(map[string]float64),
+		orderID:  uint64(time.Now().UnixNano()),
+	}
+	for _, market := range opts.Markets {
+		s.markets[market.Name] = market
+		s.markets[market.Address] = market
+	}
+	for token, balance := range opts.Balances {
+		s.balances[s.resolve(token)] += balance
+	}
+	return s, nil
+}
+
+// Balance returns the available balance of a mint or symbol, without the funds locked in open orders
+func (s *Simulator) Balance(token string) float64 {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.balances[s.resolve(token)]
+}
+
+// Balances returns the available balances by mint
+func (s *Simulator) Balances() map[string]float64 {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	balances := make(map[string]float64, len(s.balances))
+	for mint, balance := range s.balances {
+		balances[mint] = balance
+	}
+	return balances
+}
+
+// OpenOrders returns the orders resting on the books, oldest first
+func (s *Simulator) OpenOrders() []Order {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	orders := make([]Order, 0, len(s.orders))
+	for _, order := range s.orders {
+		orders = append(orders, order.Order)
+	}
+	return orders
+}
+
+// Trades returns the simulated trades, oldest first
+func (s *Simulator) Trades() []Trade {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return append([]Trade(nil), s.trades...)
+}
+
+// AccountBalance reports the virtual balances in the format of GetAccountBalance
+func (s *Simulator) AccountBalance() *pb.GetAccountBalanceResponse {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	mints := make(map[string]bool)
+	for mint := range s.balances {
+		mints[mint] = true
+	}
+	for mint := range s.locked {
+		mints[mint] = true
+	}
+	sorted := make([]string, 0, len(mints))
+	for mint := range mints {
+		sorted = append(sorted, mint)
+	}
+	sort.Strings(sorted)
+
+	resp := &pb.GetAccountBalanceResponse{}
+	for _, mint := range sorted {
+		var symbol string
+		if token, err := s.registry.Token(mint); err == nil {
+			symbol = token.Symbol
+		}
+		resp.Tokens = append(resp.Tokens, &pb.TokenBalance{
+			Symbol:           symbol,
+			TokenMint:        mint,
+			SettledAmount:    s.balances[mint],
+			OpenOrdersAmount: s.locked[mint],
+		})
+	}
+	return resp
+}
+
+// Refresh fills the resting orders that the current books cross, as maker
+func (s *Simulator) Refresh(ctx context.Context) error {
+	s.m.Lock()
+	markets := make(map[string]bookFunc)
+	for _, order := range s.orders {
+		markets[order.Market] = order.book
+	}
+	s.m.Unlock()
+
+	for market, book := range markets {
+		bids, asks, err := book(ctx, market)
+		if err != nil {
+			return fmt.Errorf("could not fetch orderbook of %v: %w", market, err)
+		}
+
+		s.m.Lock()
+		var trades []Trade
+		remaining := s.orders[:0]
+		for _, order := range s.orders {
+			if order.Market == market {
+				if order.bid() {
+					trades = append(trades, s.match(order, &asks, true)...)
+				} else {
+					trades = append(trades, s.match(order, &bids, true)...)
+				}
+			}
+			if order.Remaining > 0 {
+				remaining = append(remaining, order)
+			}
+		}
+		s.orders = remaining
+		s.m.Unlock()
+		s.notify(trades...)
+	}
+	return nil
+}
+
+// Run refreshes the resting orders every interval until the context is done
+func (s *Simulator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				log.Errorf("could not refresh paper orders: %v", err)
+			}
+		}
+	}
+}
+
+// quotedSwap exchanges inAmount for a quoted output, less the configured slippage, failing below minOut
+func (s *Simulator) quotedSwap(inToken, outToken string, inAmount, quoted, minOut float64) (string, error) {
+	out := quoted * (1 - s.opts.Slippage/100)
+	var failure error
+	if minOut > 0 && out < minOut {
+		failure = fmt.Errorf("%w: %v below minimum %v", ErrSlippageExceeded, out, minOut)
+	}
+	return s.swap(inToken, outToken, inAmount, out, failure)
+}
+
+// swap exchanges inAmount for out. With a failure, the swap is recorded as failed without moving the balances, like a
+// transaction that lands and fails, and still returns a signature. Swaps that couldn't be funded are rejected, as
+// preflight checks would.
+func (s *Simulator) swap(inToken, outToken string, inAmount, out float64, failure error) (string, error) {
+	if inAmount <= 0 || out < 0 {
+		return "", fmt.Errorf("%w: %v for %v", ErrInvalidOrder, inAmount, out)
+	}
+	inMint, outMint := s.resolve(inToken), s.resolve(outToken)
+
+	s.m.Lock()
+	if err := s.charge(inMint, inAmount); err != nil {
+		s.m.Unlock()
+		return "", err
+	}
+	trade := Trade{
+		Signature: newSignature(),
+		Kind:      KindSwap,
+		InMint:    inMint,
+		InAmount:  inAmount,
+		OutMint:   outMint,
+		OutAmount: out,
+		Time:      time.Now(),
+		Err:       failure,
+	}
+	if failure != nil {
+		trade.OutAmount = 0
+	} else {
+		s.balances[inMint] -= inAmount
+		s.balances[outMint] += out
+	}
+	s.balances[metadata.SOLMint] -= s.opts.NetworkFee
+	s.trades = append(s.trades, trade)
+	s.m.Unlock()
+
+	s.notify(trade)
+	return trade.Signature, nil
+}
+
+// transaction simulates a transaction with no effect on the balances other than the network fee
+func (s *Simulator) transaction(kind TradeKind) (string, error) {
+	s.m.Lock()
+	if err := s.charge("", 0); err != nil {
+		s.m.Unlock()
+		return "", err
+	}
+	trade := Trade{Signature: newSignature(), Kind: kind, Time: time.Now()}
+	s.balances[metadata.SOLMint] -= s.opts.NetworkFee
+	s.trades = append(s.trades, trade)
+	s.m.Unlock()
+
+	s.notify(trade)
+	return trade.Signature, nil
+}
+
+// order fills a new order against the current book and rests the remainder, unless it's immediate
+func (s *Simulator) order(ctx context.Context, book bookFunc, req orderRequest) (string, error) {
+	market, ok := s.market(req.market)
+	if !ok {
+		return "", fmt.Errorf("%w: %v", ErrUnknownMarket, req.market)
+	}
+	side := strings.ToLower(req.side)
+	if req.amount <= 0 || req.price <= 0 || (side != "bid" && side != "ask") {
+		return "", fmt.Errorf("%w: %v %v at %v", ErrInvalidOrder, req.side, req.amount, req.price)
+	}
+	bids, asks, err := book(ctx, req.market)
+	if err != nil {
+		return "", fmt.Errorf("could not fetch orderbook of %v: %w", req.market, err)
+	}
+
+	s.m.Lock()
+	order := &restingOrder{
+		Order: Order{
+			OrderID:       s.nextOrderID(),
+			ClientOrderID: req.clientOrderID,
+			Market:        market.Name,
+			Side:          side,
+			Price:         req.price,
+			Size:          req.amount,
+			Remaining:     req.amount,
+			Signature:     newSignature(),
+			Time:          time.Now(),
+		},
+		market:    market,
+		book:      book,
+		immediate: req.immediate,
+	}
+	levels := &asks
+	lockMint, lockAmount := market.QuoteMint, req.amount*req.price
+	if !order.bid() {
+		levels = &bids
+		lockMint, lockAmount = market.BaseMint, req.amount
+	}
+	if req.postOnly && len(*levels) > 0 && crosses(order, (*levels)[0].price) {
+		s.m.Unlock()
+		return "", ErrWouldCross
+	}
+	if err = s.charge(lockMint, lockAmount); err != nil {
+		s.m.Unlock()
+		return "", err
+	}
+	s.balances[lockMint] -= lockAmount
+	s.locked[lockMint] += lockAmount
+	s.balances[metadata.SOLMint] -= s.opts.NetworkFee
+
+	trades := s.match(order, levels, false)
+	switch {
+	case order.Remaining <= 0:
+	case req.immediate:
+		s.unlock(order)
+	default:
+		s.orders = append(s.orders, order)
+	}
+	s.m.Unlock()
+
+	s.notify(trades...)
+	return order.Signature, nil
+}
+
+// cancel removes the resting orders selected by match and returns their funds, in a simulated transaction
+func (s *Simulator) cancel(match func(o *Order) bool) (string, error) {
+	s.remove(match)
+	return s.transaction(KindCancel)
+}
+
+// remove removes the resting orders selected by match and returns their funds. The removed orders are returned as
+// they were before removal, to restore them.
+func (s *Simulator) remove(match func(o *Order) bool) []*restingOrder {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	var removed []*restingOrder
+	remaining := s.orders[:0]
+	for _, order := range s.orders {
+		if match(&order.Order) {
+			o := *order
+			removed = append(removed, &o)
+			s.unlock(order)
+			continue
+		}
+		remaining = append(remaining, order)
+	}
+	s.orders = remaining
+	return removed
+}
+
+// restore locks the funds of removed orders again and rests them
+func (s *Simulator) restore(orders []*restingOrder) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for _, order := range orders {
+		mint, amount := order.market.BaseMint, order.Remaining
+		if order.bid() {
+			mint, amount = order.market.QuoteMint, order.Remaining*order.Price
+		}
+		s.balances[mint] -= amount
+		s.locked[mint] += amount
+		s.orders = append(s.orders, order)
+	}
+}
+
+// match fills an order against the crossing levels of the opposite side, consuming them. Makers fill at their own
+// price, and only immediate takers slip, up to their limit price. The lock must be held.
+func (s *Simulator) match(order *restingOrder, levels *[]level, maker bool) []Trade {
+	var trades []Trade
+	for len(*levels) > 0 && order.Remaining > 0 {
+		l := &(*levels)[0]
+		if !crosses(order, l.price) {
+			break
+		}
+
+		quantity := math.Min(order.Remaining, l.size)
+		price, feeBps := l.price, s.opts.TakerFeeBps
+		if maker {
+			price, feeBps = order.Price, s.opts.MakerFeeBps
+		}
+		if !maker && order.immediate {
+			if order.bid() {
+				price = math.Min(price*(1+s.opts.Slippage/100), order.Price)
+			} else {
+				price = math.Max(price*(1-s.opts.Slippage/100), order.Price)
+			}
+		}
+		fee := quantity * price * feeBps / 10000
+
+		trade := Trade{Kind: KindFill, Market: order.Market, OrderID: order.OrderID, Fee: fee, Time: time.Now()}
+		base, quote := order.market.BaseMint, order.market.QuoteMint
+		if order.bid() {
+			// the locked quote covers the order price, the difference is returned
+			s.locked[quote] -= quantity * order.Price
+			s.balances[quote] += quantity*order.Price - quantity*price - fee
+			s.balances[base] += quantity
+			trade.InMint, trade.InAmount, trade.OutMint, trade.OutAmount = quote, quantity*price+fee, base, quantity
+		} else {
+			s.locked[base] -= quantity
+			s.balances[quote] += quantity*price - fee
+			trade.InMint, trade.InAmount, trade.OutMint, trade.OutAmount = base, quantity, quote, quantity*price-fee
+		}
+		order.Remaining -= quantity
+		l.size -= quantity
+		if l.size <= 0 {
+			*levels = (*levels)[1:]
+		}
+		trades = append(trades, trade)
+	}
+	s.trades = append(s.trades, trades...)
+	return trades
+}
+
+// unlock returns the funds locked for the unfilled part of an order. The lock must be held.
+func (s *Simulator) unlock(order *restingOrder) {
+	mint, amount := order.market.BaseMint, order.Remaining
+	if order.bid() {
+		mint, amount = order.market.QuoteMint, order.Remaining*order.Price
+	}
+	s.locked[mint] -= amount
+	s.balances[mint] += amount
+	order.Remaining = 0
+}
+
+// charge checks that amount of mint and the network fee are available. The lock must be held.
+func (s *Simulator) charge(mint string, amount float64) error {
+	if mint == metadata.SOLMint {
+		amount += s.opts.NetworkFee
+	} else if s.balances[metadata.SOLMint] < s.opts.NetworkFee {
+		return fmt.Errorf("%w: %v SOL for the network fee", ErrInsufficientFunds, s.opts.NetworkFee)
+	}
+	if mint != "" && s.balances[mint] < amount {
+		return fmt.Errorf("%w: %v of %v available, %v needed", ErrInsufficientFunds, s.balances[mint], mint, amount)
+	}
+	return nil
+}
+
+func (s *Simulator) notify(trades ...Trade) {
+	if s.opts.OnTrade == nil {
+		return
+	}
+	for _, trade := range trades {
+		s.opts.OnTrade(trade)
+	}
+}
+
+// nextOrderID returns a unique order ID. The lock must be held.
+func (s *Simulator) nextOrderID() string {
+	s.orderID++
+	return strconv.FormatUint(s.orderID, 10)
+}
+
+func (s *Simulator) resolve(mintOrSymbol string) string {
+	mint, err := s.registry.ResolveMint(mintOrSymbol)
+	if err != nil {
+		return mintOrSymbol
+	}
+	return mint
+}
+
+func (s *Simulator) market(nameOrAddress string) (metadata.Market, bool) {
+	if market, ok := s.markets[nameOrAddress]; ok {
+		return market, true
+	}
+	return s.registry.Market(nameOrAddress)
+}
+
+func crosses(order *restingOrder, price float64) bool {
+	if order.bid() {
+		return price <= order.Price
+	}
+	return price >= order.Price
+}
+
+// newSignature returns a random transaction signature, indistinguishable in format from a real one
+func newSignature() string {
+	var sig solana.Signature
+	_, _ = rand.Read(sig[:])
+	return sig.String()
+}
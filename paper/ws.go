@@ -0,0 +1,315 @@
+package paper
+
+import (
+	"context"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/amount"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/bloXroute-Labs/solana-trader-proto/common"
+	"github.com/gagliardetto/solana-go"
+)
+
+// WSClient is a provider.WSClient that simulates its submissions
+type WSClient struct {
+	*provider.WSClient
+	sim *Simulator
+}
+
+func NewWSClient(client *provider.WSClient, sim *Simulator) *WSClient {
+	return &WSClient{WSClient: client, sim: sim}
+}
+
+// Simulator returns the simulator behind the client
+func (w *WSClient) Simulator() *Simulator {
+	return w.sim
+}
+
+// GetAccountBalance reports the virtual balances, whatever the owner
+func (w *WSClient) GetAccountBalance(context.Context, string) (*pb.GetAccountBalanceResponse, error) {
+	return w.sim.AccountBalance(), nil
+}
+
+// SignAndSubmit simulates an opaque transaction
+func (w *WSClient) SignAndSubmit(context.Context, *pb.TransactionMessage, bool, bool, bool) (string, error) {
+	return w.sim.transaction(KindTransaction)
+}
+
+// SignAndSubmitBatch simulates each transaction of the batch
+func (w *WSClient) SignAndSubmitBatch(_ context.Context, transactions []*pb.TransactionMessage, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return w.sim.transactions(len(transactions))
+}
+
+// PostSubmit simulates an opaque transaction
+func (w *WSClient) PostSubmit(context.Context, string, bool, bool, bool) (*pb.PostSubmitResponse, error) {
+	sig, err := w.sim.transaction(KindTransaction)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PostSubmitResponse{Signature: sig}, nil
+}
+
+// PostSubmitV2 simulates an opaque transaction
+func (w *WSClient) PostSubmitV2(ctx context.Context, txBase64 string, skipPreFlight bool, useBundle bool, useStakedRPCs bool) (*pb.PostSubmitResponse, error) {
+	return w.PostSubmit(ctx, txBase64, skipPreFlight, useBundle, useStakedRPCs)
+}
+
+// PostSubmitBatch simulates each transaction of the batch
+func (w *WSClient) PostSubmitBatch(_ context.Context, request *pb.PostSubmitBatchRequest) (*pb.PostSubmitBatchResponse, error) {
+	return w.sim.transactions(len(request.Entries))
+}
+
+// SubmitBatch simulates the transactions as generic transactions
+func (w *WSClient) SubmitBatch(_ context.Context, transactions []*pb.TransactionMessage, _ provider.BatchOpts) (*provider.BatchResult, error) {
+	resp, err := w.sim.transactions(len(transactions))
+	if err != nil {
+		return nil, err
+	}
+	return provider.NewBatchResult(resp), nil
+}
+
+// PostSubmitBatchV2 simulates each transaction of the batch
+func (w *WSClient) PostSubmitBatchV2(ctx context.Context, request *pb.PostSubmitBatchRequest) (*pb.PostSubmitBatchResponse, error) {
+	return w.PostSubmitBatch(ctx, request)
+}
+
+// SubmitTradeSwap simulates the swap at the quote of PostTradeSwap
+func (w *WSClient) SubmitTradeSwap(ctx context.Context, owner, inToken, outToken string, inAmount, slippage float64, project string, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := w.WSClient.PostTradeSwap(ctx, owner, inToken, outToken, inAmount, slippage, project)
+	if err != nil {
+		return nil, err
+	}
+	return batch(w.sim.quotedSwap(inToken, outToken, inAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitTradeSwapWithPriorityFee simulates the swap at the quote of PostTradeSwapWithPriorityFee
+func (w *WSClient) SubmitTradeSwapWithPriorityFee(ctx context.Context, owner, inToken, outToken string, inAmount, slippage float64, project string, computeLimit uint32, computePrice uint64, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := w.WSClient.PostTradeSwapWithPriorityFee(ctx, owner, inToken, outToken, inAmount, slippage, computeLimit, computePrice, project)
+	if err != nil {
+		return nil, err
+	}
+	return batch(w.sim.quotedSwap(inToken, outToken, inAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitTradeSwapWithAmount is like SubmitTradeSwap, with an exact input amount
+func (w *WSClient) SubmitTradeSwapWithAmount(ctx context.Context, ownerAddress, inToken, outToken string, inAmount amount.Amount, slippage float64, project string, opts provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	in, err := inAmount.ExactFloat64()
+	if err != nil {
+		return nil, err
+	}
+	return w.SubmitTradeSwap(ctx, ownerAddress, inToken, outToken, in, slippage, project, opts)
+}
+
+// SubmitRouteTradeSwap simulates the route at the quote of PostRouteTradeSwap
+func (w *WSClient) SubmitRouteTradeSwap(ctx context.Context, request *pb.RouteTradeSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := w.WSClient.PostRouteTradeSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(routeSwap(w.sim, request.Steps, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumSwap simulates the swap at the quote of PostRaydiumSwap
+func (w *WSClient) SubmitRaydiumSwap(ctx context.Context, request *pb.PostRaydiumSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := w.WSClient.PostRaydiumSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(w.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumCLMMSwap simulates the swap at the quote of PostRaydiumCLMMSwap
+func (w *WSClient) SubmitRaydiumCLMMSwap(ctx context.Context, request *pb.PostRaydiumSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := w.WSClient.PostRaydiumCLMMSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(w.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumSwapCPMM simulates the swap at the quote of PostRaydiumSwapCPMM
+func (w *WSClient) SubmitRaydiumSwapCPMM(ctx context.Context, request *pb.PostRaydiumCPMMSwapRequest) (string, error) {
+	resp, err := w.WSClient.PostRaydiumSwapCPMM(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	return w.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin)
+}
+
+// SubmitRaydiumRouteSwap simulates the route at the quote of PostRaydiumRouteSwap
+func (w *WSClient) SubmitRaydiumRouteSwap(ctx context.Context, request *pb.PostRaydiumRouteSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := w.WSClient.PostRaydiumRouteSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(routeSwap(w.sim, request.Steps, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumCLMMRouteSwap simulates the route at the quote of PostRaydiumCLMMRouteSwap
+func (w *WSClient) SubmitRaydiumCLMMRouteSwap(ctx context.Context, request *pb.PostRaydiumRouteSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := w.WSClient.PostRaydiumCLMMRouteSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(routeSwap(w.sim, request.Steps, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitRaydiumSwapInstructions simulates the swap at the quote of PostRaydiumSwapInstructions
+func (w *WSClient) SubmitRaydiumSwapInstructions(ctx context.Context, request *pb.PostRaydiumSwapInstructionsRequest, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := w.WSClient.PostRaydiumSwapInstructions(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(w.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitJupiterSwap simulates the swap at the quote of PostJupiterSwap
+func (w *WSClient) SubmitJupiterSwap(ctx context.Context, request *pb.PostJupiterSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := w.WSClient.PostJupiterSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(w.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitJupiterSwapInstructions simulates the swap at the quote of PostJupiterSwapInstructions
+func (w *WSClient) SubmitJupiterSwapInstructions(ctx context.Context, request *pb.PostJupiterSwapInstructionsRequest, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := w.WSClient.PostJupiterSwapInstructions(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(w.sim.quotedSwap(request.InToken, request.OutToken, request.InAmount, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitComposed checks the composed transaction builds, and simulates it as a generic transaction: its swaps aren't
+// applied to the balances
+func (w *WSClient) SubmitComposed(_ context.Context, composer *transaction.Composer, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if _, err := composer.Build(solana.Hash{}); err != nil {
+		return nil, err
+	}
+	return w.sim.transactions(1)
+}
+
+// SubmitTxBuilder checks the transaction builds, and simulates it as a generic transaction
+func (w *WSClient) SubmitTxBuilder(ctx context.Context, builder *transaction.TxBuilder, _ bool, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if _, err := builder.Build(ctx); err != nil {
+		return nil, err
+	}
+	return w.sim.transactions(1)
+}
+
+// CreateTokenAccounts simulates the account creations as a generic transaction
+func (w *WSClient) CreateTokenAccounts(context.Context, []solana.PublicKey, provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return w.sim.transactions(1)
+}
+
+// WrapSOL simulates a generic transaction, SOL and wrapped SOL being the same virtual balance
+func (w *WSClient) WrapSOL(context.Context, uint64, provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return w.sim.transactions(1)
+}
+
+// UnwrapSOL simulates a generic transaction, SOL and wrapped SOL being the same virtual balance
+func (w *WSClient) UnwrapSOL(context.Context, provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return w.sim.transactions(1)
+}
+
+// CloseEmptyTokenAccounts simulates the account closes as a generic transaction
+func (w *WSClient) CloseEmptyTokenAccounts(context.Context, provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return w.sim.transactions(1)
+}
+
+// SweepDust simulates the swaps of the virtual balances selected by DustOpts.IsDust
+func (w *WSClient) SweepDust(ctx context.Context, opts provider.DustOpts) ([]provider.SweepResult, error) {
+	owner, err := w.WSClient.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return provider.SweepDustAccounts(ctx, owner.String(), w.sim.tokenAccounts(), opts, func(ctx context.Context, owner, inToken, outToken string, inAmount, slippage float64, project pb.Project, opts provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+		return w.SubmitTradeSwap(ctx, owner, inToken, outToken, inAmount, slippage, provider.ProjectToString(project), opts)
+	})
+}
+
+// SubmitJupiterRouteSwap simulates the route at the quote of PostJupiterRouteSwap
+func (w *WSClient) SubmitJupiterRouteSwap(ctx context.Context, request *pb.PostJupiterRouteSwapRequest, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	resp, err := w.WSClient.PostJupiterRouteSwap(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return batch(routeSwap(w.sim, request.Steps, resp.OutAmount, resp.OutAmountMin))
+}
+
+// SubmitPostPumpFunSwap simulates the swap at the quotes of GetPumpFunQuotes
+func (w *WSClient) SubmitPostPumpFunSwap(ctx context.Context, request *pb.PostPumpFunSwapRequest) (string, error) {
+	return w.sim.pumpFunSwap(ctx, w, request)
+}
+
+// SubmitOrder simulates the order against the live orderbook
+func (w *WSClient) SubmitOrder(ctx context.Context, _, _, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts provider.PostOrderOpts) (string, error) {
+	return w.sim.orderV1(ctx, w, market, side, types, amount, price, project, opts.ClientOrderID)
+}
+
+// SubmitOrderWithAmounts is like SubmitOrder, with an exact size and price
+func (w *WSClient) SubmitOrderWithAmounts(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, size, price amount.Amount, project pb.Project, opts provider.PostOrderOpts) (string, error) {
+	sizeF, priceF, err := exactOrder(size, price)
+	if err != nil {
+		return "", err
+	}
+	return w.SubmitOrder(ctx, owner, payer, market, side, types, sizeF, priceF, project, opts)
+}
+
+// SubmitCancelOrder cancels a paper order
+func (w *WSClient) SubmitCancelOrder(_ context.Context, request *pb.PostCancelOrderRequest, _ bool) (string, error) {
+	return w.sim.cancelOrder(request.MarketAddress, request.OrderID, 0)
+}
+
+// SubmitCancelByClientOrderID cancels a paper order
+func (w *WSClient) SubmitCancelByClientOrderID(_ context.Context, clientOrderID uint64, _, market, _ string, _ pb.Project, _ bool) (string, error) {
+	return w.sim.cancelOrder(market, "", clientOrderID)
+}
+
+// SubmitCancelAll cancels the paper orders of a market
+func (w *WSClient) SubmitCancelAll(_ context.Context, market, _ string, _ []string, _ pb.Project, _ provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return batch(w.sim.cancelAll(market))
+}
+
+// SubmitSettle simulates a settlement. Paper fills are settled right away.
+func (w *WSClient) SubmitSettle(context.Context, string, string, string, string, string, pb.Project, bool) (string, error) {
+	return w.sim.transaction(KindTransaction)
+}
+
+// SubmitReplaceByClientOrderID replaces the paper order with the client order ID of opts
+func (w *WSClient) SubmitReplaceByClientOrderID(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts provider.PostOrderOpts) (string, error) {
+	return w.sim.replace(market, "", opts.ClientOrderID, func() (string, error) {
+		return w.SubmitOrder(ctx, owner, payer, market, side, types, amount, price, project, opts)
+	})
+}
+
+// SubmitReplaceOrder replaces a paper order
+func (w *WSClient) SubmitReplaceOrder(ctx context.Context, orderID, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts provider.PostOrderOpts) (string, error) {
+	return w.sim.replace(market, orderID, 0, func() (string, error) {
+		return w.SubmitOrder(ctx, owner, payer, market, side, types, amount, price, project, opts)
+	})
+}
+
+// SubmitOrderV2 simulates the order against the live orderbook
+func (w *WSClient) SubmitOrderV2(ctx context.Context, _, _, market string, side string, orderType string, amount, price float64, opts provider.PostOrderOpts) (string, error) {
+	return w.sim.orderV2(ctx, w, market, side, orderType, amount, price, opts.ClientOrderID)
+}
+
+// SubmitCancelOrderV2 cancels a paper order
+func (w *WSClient) SubmitCancelOrderV2(_ context.Context, request *pb.PostCancelOrderRequestV2, _ bool) (*pb.PostSubmitBatchResponse, error) {
+	return batch(w.sim.cancelOrder(request.MarketAddress, request.OrderID, request.ClientOrderID))
+}
+
+// SubmitSettleV2 simulates a settlement. Paper fills are settled right away.
+func (w *WSClient) SubmitSettleV2(context.Context, string, string, string, string, string, bool) (string, error) {
+	return w.sim.transaction(KindTransaction)
+}
+
+// SubmitReplaceOrderV2 replaces a paper order
+func (w *WSClient) SubmitReplaceOrderV2(ctx context.Context, orderID, owner, payer, market string, side string, orderType string, amount, price float64, opts provider.PostOrderOpts) (string, error) {
+	return w.sim.replace(market, orderID, opts.ClientOrderID, func() (string, error) {
+		return w.SubmitOrderV2(ctx, owner, payer, market, side, orderType, amount, price, opts)
+	})
+}
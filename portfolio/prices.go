@@ -0,0 +1,140 @@
+package portfolio
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const priceStreamRetryInterval = 5 * time.Second
+
+// PricesStreamer is implemented by all clients
+type PricesStreamer interface {
+	GetPricesStream(ctx context.Context, projects []pb.Project, tokens []string) (connections.Streamer[*pb.GetPricesStreamResponse], error)
+}
+
+// MidPrice returns the midpoint of a token's buy and sell prices, or whichever is available
+func MidPrice(price *pb.TokenPrice) (float64, bool) {
+	switch {
+	case price.GetBuy() > 0 && price.GetSell() > 0:
+		return (price.Buy + price.Sell) / 2, true
+	case price.GetBuy() > 0:
+		return price.Buy, true
+	case price.GetSell() > 0:
+		return price.Sell, true
+	}
+	return 0, false
+}
+
+// priceFeed keeps a prices stream subscribed to a growing set of mints, resubscribing whenever mints are added
+type priceFeed struct {
+	client   PricesStreamer
+	projects []pb.Project
+	onPrice  func(mint string, price *pb.TokenPrice, ts time.Time)
+	changed  chan struct{}
+
+	m     sync.Mutex
+	mints map[string]bool
+}
+
+func newPriceFeed(client PricesStreamer, projects []pb.Project, onPrice func(mint string, price *pb.TokenPrice, ts time.Time)) *priceFeed {
+	return &priceFeed{
+		client:   client,
+		projects: projects,
+		onPrice:  onPrice,
+		changed:  make(chan struct{}, 1),
+		mints:    make(map[string]bool),
+	}
+}
+
+func (f *priceFeed) add(mints ...string) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	added := false
+	for _, mint := range mints {
+		if mint != "" && !f.mints[mint] {
+			f.mints[mint] = true
+			added = true
+		}
+	}
+	if added {
+		select {
+		case f.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (f *priceFeed) list() []string {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	mints := make([]string, 0, len(f.mints))
+	for mint := range f.mints {
+		mints = append(mints, mint)
+	}
+	sort.Strings(mints)
+	return mints
+}
+
+// run follows prices until ctx is canceled, reopening the stream when mints are added or it ends
+func (f *priceFeed) run(ctx context.Context) {
+	for {
+		mints := f.list()
+		streamCtx, cancel := context.WithCancel(ctx)
+		ended := make(chan struct{})
+		if len(mints) > 0 {
+			stream, err := f.client.GetPricesStream(streamCtx, f.projects, mints)
+			if err != nil {
+				log.Errorf("could not open prices stream: %v", err)
+				close(ended)
+			} else {
+				go func() {
+					defer close(ended)
+					for {
+						update, err := stream()
+						if err != nil {
+							if streamCtx.Err() == nil {
+								log.Errorf("prices stream ended: %v", err)
+							}
+							return
+						}
+						if update.GetPrice() == nil {
+							continue
+						}
+						mint := update.Price.TokenAddress
+						if mint == "" {
+							mint = update.Price.Token
+						}
+						ts := time.Now()
+						if update.Timestamp != nil {
+							ts = update.Timestamp.AsTime()
+						}
+						f.onPrice(mint, update.Price, ts)
+					}
+				}()
+			}
+		}
+
+		select {
+		case <-f.changed:
+			cancel()
+		case <-ended:
+			cancel()
+			select {
+			case <-time.After(priceStreamRetryInterval):
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			cancel()
+			return
+		}
+	}
+}
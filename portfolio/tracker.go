@@ -0,0 +1,526 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/metadata"
+	"github.com/bloXroute-Labs/solana-trader-client-go/orders"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultReconcileInterval = 5 * time.Minute
+	// signatures are remembered to skip trades reported by several sources, e.g. a submission and the swaps stream
+	seenTradesCapacity = 10000
+	// quantities closer than this (relative to the balance) are considered equal when reconciling
+	quantityTolerance = 1e-9
+)
+
+var ErrUnknownMarket = errors.New("unknown market")
+
+// Client is implemented by all clients
+type Client interface {
+	PricesStreamer
+	BalancesClient
+	GetSwapsStream(ctx context.Context, projects []pb.Project, markets []string, includeFailed bool) (connections.Streamer[*pb.GetSwapsStreamResponse], error)
+}
+
+type TrackerOpts struct {
+	// Owners are the wallets whose positions are tracked
+	Owners []string
+	// QuoteMints are valued at 1 and used to price trades (default USDC and USDT)
+	QuoteMints []string
+	// Projects for the prices and swaps streams (default all)
+	Projects []pb.Project
+	// SwapPools are followed on the swaps stream by Run for swaps by the owners. Swaps aren't followed if empty.
+	SwapPools []string
+	// ReconcileInterval between balance reconciliations in Run (default 5m)
+	ReconcileInterval time.Duration
+	// Registry resolves the mints of Openbook markets for fills
+	Registry *metadata.Registry
+	// OnUpdate is called with the updated position after each trade or adjustment
+	OnUpdate func(Position)
+}
+
+// Trade exchanges InAmount of InMint for OutAmount of OutMint. Trades with a signature are only applied once.
+type Trade struct {
+	InMint    string
+	InAmount  float64
+	OutMint   string
+	OutAmount float64
+	Signature string
+	Time      time.Time
+}
+
+// Position is the holding of a token. Costs and PnL are in quote currency (USD).
+type Position struct {
+	Mint     string
+	Quantity float64
+	// AverageCost is the average unit cost of the open lots
+	AverageCost   float64
+	CostBasis     float64
+	RealizedPnL   float64
+	Price         float64
+	PriceKnown    bool
+	MarketValue   float64
+	UnrealizedPnL float64
+	// CostKnown is false if part of the position was acquired while neither of the trade's tokens had a price, so its
+	// cost (and PnL) is excluded
+	CostKnown bool
+	UpdatedAt time.Time
+}
+
+// Adjustment is a correction of a tracked quantity to the balance reported by the API. Added quantities are costed at
+// the current price, and removed quantities don't realize PnL.
+type Adjustment struct {
+	Mint    string
+	Tracked float64
+	Actual  float64
+}
+
+type lot struct {
+	quantity float64
+	unitCost float64
+	known    bool
+}
+
+type position struct {
+	Position
+	lots []lot
+}
+
+// Tracker keeps per-token positions of a set of wallets from their trades, with FIFO lots for realized PnL and
+// unrealized PnL marked by the prices stream
+type Tracker struct {
+	client Client
+	opts   TrackerOpts
+	quotes map[string]bool
+	owners map[string]bool
+	seen   *connections.KeyCache[string]
+	feed   *priceFeed
+
+	m         sync.Mutex
+	positions map[string]*position
+	prices    map[string]float64
+}
+
+func NewTracker(client Client, opts TrackerOpts) *Tracker {
+	if len(opts.QuoteMints) == 0 {
+		opts.QuoteMints = []string{metadata.USDCMint, metadata.USDTMint}
+	}
+	if len(opts.Projects) == 0 {
+		opts.Projects = []pb.Project{pb.Project_P_ALL}
+	}
+	if opts.ReconcileInterval <= 0 {
+		opts.ReconcileInterval = defaultReconcileInterval
+	}
+
+	t := &Tracker{
+		client:    client,
+		opts:      opts,
+		quotes:    make(map[string]bool),
+		owners:    make(map[string]bool),
+		seen:      connections.NewKeyCache[string](seenTradesCapacity),
+		positions: make(map[string]*position),
+		prices:    make(map[string]float64),
+	}
+	for _, mint := range opts.QuoteMints {
+		t.quotes[mint] = true
+	}
+	for _, owner := range opts.Owners {
+		t.owners[owner] = true
+	}
+	t.feed = newPriceFeed(client, opts.Projects, func(mint string, price *pb.TokenPrice, _ time.Time) {
+		if mid, ok := MidPrice(price); ok {
+			t.SetPrice(mint, mid)
+		}
+	})
+	return t
+}
+
+// Run reconciles balances on start and on a schedule, marks positions with the prices stream and follows swaps until
+// ctx is canceled
+func (t *Tracker) Run(ctx context.Context) {
+	if _, err := t.Reconcile(ctx); err != nil {
+		log.Errorf("could not reconcile positions: %v", err)
+	}
+	go t.feed.run(ctx)
+	if len(t.opts.SwapPools) > 0 {
+		go t.followSwaps(ctx)
+	}
+
+	ticker := time.NewTicker(t.opts.ReconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := t.Reconcile(ctx); err != nil {
+				log.Errorf("could not reconcile positions: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t *Tracker) followSwaps(ctx context.Context) {
+	for {
+		stream, err := t.client.GetSwapsStream(ctx, t.opts.Projects, t.opts.SwapPools, false)
+		if err == nil {
+			for {
+				update, streamErr := stream()
+				if streamErr != nil {
+					err = streamErr
+					break
+				}
+				t.ApplySwap(update)
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Errorf("swaps stream ended, reopening in %v: %v", priceStreamRetryInterval, err)
+		select {
+		case <-time.After(priceStreamRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ApplySwap applies a successful swap by one of the owners from the swaps stream. The stream only reports the
+// minimum output amount, which is used as the received amount until the next reconciliation.
+func (t *Tracker) ApplySwap(update *pb.GetSwapsStreamResponse) bool {
+	swap := update.GetSwap()
+	if swap == nil || !swap.Success || !t.owners[swap.OwnerAccount] {
+		return false
+	}
+	ts := time.Now()
+	if update.Timestamp != nil {
+		ts = update.Timestamp.AsTime()
+	}
+	return t.ApplyTrade(Trade{
+		InMint:    swap.InTokenAddress,
+		InAmount:  swap.InAmount,
+		OutMint:   swap.OutTokenAddress,
+		OutAmount: swap.OutAmountMin,
+		Signature: swap.Signature,
+		Time:      ts,
+	})
+}
+
+// ApplyFill applies an Openbook fill, using the registry to find the market's mints
+func (t *Tracker) ApplyFill(fill orders.Fill) error {
+	if t.opts.Registry == nil {
+		return fmt.Errorf("%w: %v (no registry)", ErrUnknownMarket, fill.Market)
+	}
+	market, ok := t.opts.Registry.Market(fill.Market)
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrUnknownMarket, fill.Market)
+	}
+
+	trade := Trade{Time: fill.Time}
+	quoteAmount := fill.Quantity * fill.Price
+	if fill.Side == "bid" {
+		trade.InMint, trade.InAmount = market.QuoteMint, quoteAmount
+		trade.OutMint, trade.OutAmount = market.BaseMint, fill.Quantity
+	} else {
+		trade.InMint, trade.InAmount = market.BaseMint, fill.Quantity
+		trade.OutMint, trade.OutAmount = market.QuoteMint, quoteAmount
+	}
+	t.ApplyTrade(trade)
+	return nil
+}
+
+// ApplyTrade applies a trade, e.g. from a submission result. It returns false if the trade was already applied.
+func (t *Tracker) ApplyTrade(trade Trade) bool {
+	if trade.Signature != "" && !t.seen.Add(trade.Signature) {
+		return false
+	}
+	if trade.Time.IsZero() {
+		trade.Time = time.Now()
+	}
+
+	t.m.Lock()
+	value, known := t.tradeValue(trade)
+	in := t.dispose(trade.InMint, trade.InAmount, value, known, true, trade.Time)
+	out := t.acquire(trade.OutMint, trade.OutAmount, value, known, trade.Time)
+	t.m.Unlock()
+
+	t.feed.add(t.unquoted(trade.InMint, trade.OutMint)...)
+	t.notify(in, out)
+	return true
+}
+
+// SetPrice marks a token at a new price
+func (t *Tracker) SetPrice(mint string, price float64) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	t.prices[mint] = price
+	if p, ok := t.positions[mint]; ok {
+		t.recompute(p)
+	}
+}
+
+// Reconcile adjusts tracked quantities to the balances reported by the API, summed over all owners
+func (t *Tracker) Reconcile(ctx context.Context) ([]Adjustment, error) {
+	actual, _, err := fetchBalances(ctx, t.client, t.opts.Owners)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var (
+		adjustments []Adjustment
+		updated     []Position
+	)
+	t.m.Lock()
+	for mint := range t.positions {
+		if _, ok := actual[mint]; !ok {
+			actual[mint] = 0
+		}
+	}
+	for mint, quantity := range actual {
+		tracked := 0.0
+		if p, ok := t.positions[mint]; ok {
+			tracked = p.Quantity
+		}
+		delta := quantity - tracked
+		if math.Abs(delta) <= quantityTolerance*math.Max(1, math.Abs(quantity)) {
+			continue
+		}
+
+		adjustments = append(adjustments, Adjustment{Mint: mint, Tracked: tracked, Actual: quantity})
+		price, known := t.price(mint)
+		if delta > 0 {
+			updated = append(updated, t.acquire(mint, delta, delta*price, known, now))
+		} else {
+			updated = append(updated, t.dispose(mint, -delta, 0, false, false, now))
+		}
+	}
+	t.m.Unlock()
+
+	mints := make([]string, 0, len(actual))
+	for mint := range actual {
+		mints = append(mints, mint)
+	}
+	t.feed.add(t.unquoted(mints...)...)
+	t.notify(updated...)
+	sort.Slice(adjustments, func(i, j int) bool {
+		return adjustments[i].Mint < adjustments[j].Mint
+	})
+	return adjustments, nil
+}
+
+// Position returns the position in a token
+func (t *Tracker) Position(mint string) (Position, bool) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	p, ok := t.positions[mint]
+	if !ok {
+		return Position{}, false
+	}
+	return p.Position, true
+}
+
+// Positions returns all positions, including closed ones with realized PnL
+func (t *Tracker) Positions() []Position {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	positions := make([]Position, 0, len(t.positions))
+	for _, p := range t.positions {
+		positions = append(positions, p.Position)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].Mint < positions[j].Mint
+	})
+	return positions
+}
+
+// PnL returns the total realized and unrealized PnL across positions
+func (t *Tracker) PnL() (realized, unrealized float64) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	for _, p := range t.positions {
+		realized += p.RealizedPnL
+		unrealized += p.UnrealizedPnL
+	}
+	return realized, unrealized
+}
+
+// tradeValue values a trade in quote currency, preferring quote tokens over marked prices. It must be called with the
+// lock held.
+func (t *Tracker) tradeValue(trade Trade) (float64, bool) {
+	switch {
+	case t.quotes[trade.InMint]:
+		return trade.InAmount, true
+	case t.quotes[trade.OutMint]:
+		return trade.OutAmount, true
+	}
+	if price, ok := t.prices[trade.InMint]; ok {
+		return trade.InAmount * price, true
+	}
+	if price, ok := t.prices[trade.OutMint]; ok {
+		return trade.OutAmount * price, true
+	}
+	return 0, false
+}
+
+// price must be called with the lock held
+func (t *Tracker) price(mint string) (float64, bool) {
+	if t.quotes[mint] {
+		return 1, true
+	}
+	price, ok := t.prices[mint]
+	return price, ok
+}
+
+// acquire adds a lot to a position. It must be called with the lock held.
+func (t *Tracker) acquire(mint string, quantity, cost float64, known bool, ts time.Time) Position {
+	p := t.position(mint)
+	if quantity > 0 {
+		p.lots = append(p.lots, lot{quantity: quantity, unitCost: cost / quantity, known: known})
+	}
+	p.Quantity += quantity
+	p.UpdatedAt = ts
+	t.recompute(p)
+	return p.Position
+}
+
+// dispose removes quantity from a position's lots in FIFO order, realizing PnL against the proceeds if realize is set.
+// It must be called with the lock held.
+func (t *Tracker) dispose(mint string, quantity, proceeds float64, known, realize bool, ts time.Time) Position {
+	p := t.position(mint)
+	remaining := quantity
+	for remaining > 0 && len(p.lots) > 0 {
+		l := &p.lots[0]
+		take := math.Min(l.quantity, remaining)
+		if realize && known && l.known && quantity > 0 {
+			p.RealizedPnL += take * (proceeds/quantity - l.unitCost)
+		}
+		l.quantity -= take
+		remaining -= take
+		if l.quantity <= 0 {
+			p.lots = p.lots[1:]
+		}
+	}
+	p.Quantity -= quantity
+	p.UpdatedAt = ts
+	t.recompute(p)
+	return p.Position
+}
+
+// position must be called with the lock held
+func (t *Tracker) position(mint string) *position {
+	p, ok := t.positions[mint]
+	if !ok {
+		p = &position{Position: Position{Mint: mint, CostKnown: true}}
+		t.positions[mint] = p
+	}
+	return p
+}
+
+// recompute updates a position's derived values from its lots and price. It must be called with the lock held.
+func (t *Tracker) recompute(p *position) {
+	var lotQuantity, costBasis, knownQuantity float64
+	p.CostKnown = true
+	for _, l := range p.lots {
+		lotQuantity += l.quantity
+		if l.known {
+			costBasis += l.quantity * l.unitCost
+			knownQuantity += l.quantity
+		} else {
+			p.CostKnown = false
+		}
+	}
+	p.CostBasis = costBasis
+	p.AverageCost = 0
+	if knownQuantity > 0 {
+		p.AverageCost = costBasis / knownQuantity
+	}
+
+	p.Price, p.PriceKnown = t.price(p.Mint)
+	p.MarketValue = p.Quantity * p.Price
+	p.UnrealizedPnL = 0
+	if p.PriceKnown {
+		p.UnrealizedPnL = knownQuantity*p.Price - costBasis
+	}
+}
+
+// BalancesClient is implemented by all clients
+type BalancesClient interface {
+	GetAccountBalance(ctx context.Context, owner string) (*pb.GetAccountBalanceResponse, error)
+	GetTokenAccounts(ctx context.Context, req *pb.GetTokenAccountsRequest) (*pb.GetTokenAccountsResponse, error)
+}
+
+// fetchBalances returns the total balance of each mint over all owners, including unsettled and open orders amounts,
+// and the symbols reported for the mints
+func fetchBalances(ctx context.Context, client BalancesClient, owners []string) (map[string]float64, map[string]string, error) {
+	balances := make(map[string]float64)
+	symbols := make(map[string]string)
+	for _, owner := range owners {
+		resp, err := client.GetAccountBalance(ctx, owner)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not fetch balance of %v: %w", owner, err)
+		}
+		seen := make(map[string]bool)
+		for _, token := range resp.GetTokens() {
+			balances[token.TokenMint] += token.SettledAmount + token.UnsettledAmount + token.OpenOrdersAmount
+			symbols[token.TokenMint] = token.Symbol
+			seen[token.TokenMint] = true
+		}
+
+		// token accounts cover tokens the balance doesn't report
+		accounts, err := client.GetTokenAccounts(ctx, &pb.GetTokenAccountsRequest{OwnerAddress: owner})
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not fetch token accounts of %v: %w", owner, err)
+		}
+		for _, account := range accounts.GetAccounts() {
+			if !seen[account.TokenMint] {
+				balances[account.TokenMint] += account.Amount
+				if _, ok := symbols[account.TokenMint]; !ok {
+					symbols[account.TokenMint] = account.Symbol
+				}
+			}
+		}
+	}
+	return balances, symbols, nil
+}
+
+func (t *Tracker) unquoted(mints ...string) []string {
+	var unquoted []string
+	for _, mint := range mints {
+		if !t.quotes[mint] {
+			unquoted = append(unquoted, mint)
+		}
+	}
+	return unquoted
+}
+
+func (t *Tracker) notify(positions ...Position) {
+	if t.opts.OnUpdate == nil {
+		return
+	}
+	for _, p := range positions {
+		t.opts.OnUpdate(p)
+	}
+}
+
+var (
+	_ Client = (*provider.GRPCClient)(nil)
+	_ Client = (*provider.WSClient)(nil)
+	_ Client = (*provider.HTTPClient)(nil)
+)
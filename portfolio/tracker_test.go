@@ -0,0 +1,86 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/metadata"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClient struct {
+	balances    map[string][]*pb.TokenBalance
+	balancesErr error
+}
+
+func (f *fakeClient) GetPricesStream(context.Context, []pb.Project, []string) (connections.Streamer[*pb.GetPricesStreamResponse], error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeClient) GetAccountBalance(_ context.Context, owner string) (*pb.GetAccountBalanceResponse, error) {
+	if f.balancesErr != nil {
+		return nil, f.balancesErr
+	}
+	return &pb.GetAccountBalanceResponse{Tokens: f.balances[owner]}, nil
+}
+
+func (f *fakeClient) GetTokenAccounts(context.Context, *pb.GetTokenAccountsRequest) (*pb.GetTokenAccountsResponse, error) {
+	return &pb.GetTokenAccountsResponse{}, nil
+}
+
+func (f *fakeClient) GetSwapsStream(context.Context, []pb.Project, []string, bool) (connections.Streamer[*pb.GetSwapsStreamResponse], error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestTrackerFIFO(t *testing.T) {
+	tracker := NewTracker(&fakeClient{}, TrackerOpts{Owners: []string{"owner"}})
+
+	assert.True(t, tracker.ApplyTrade(Trade{InMint: metadata.USDCMint, InAmount: 1000, OutMint: metadata.SOLMint, OutAmount: 10, Signature: "a"}))
+	assert.False(t, tracker.ApplyTrade(Trade{InMint: metadata.USDCMint, InAmount: 1000, OutMint: metadata.SOLMint, OutAmount: 10, Signature: "a"}))
+	tracker.ApplyTrade(Trade{InMint: metadata.USDCMint, InAmount: 1200, OutMint: metadata.SOLMint, OutAmount: 10, Signature: "b"})
+	tracker.ApplyTrade(Trade{InMint: metadata.SOLMint, InAmount: 15, OutMint: metadata.USDCMint, OutAmount: 1950, Signature: "c"})
+
+	sol, ok := tracker.Position(metadata.SOLMint)
+	require.True(t, ok)
+	assert.InDelta(t, 5, sol.Quantity, 1e-9)
+	assert.InDelta(t, 350, sol.RealizedPnL, 1e-9)
+	assert.InDelta(t, 120, sol.AverageCost, 1e-9)
+	assert.False(t, sol.PriceKnown)
+
+	tracker.SetPrice(metadata.SOLMint, 140)
+	sol, _ = tracker.Position(metadata.SOLMint)
+	assert.InDelta(t, 100, sol.UnrealizedPnL, 1e-9)
+	assert.InDelta(t, 700, sol.MarketValue, 1e-9)
+
+	realized, unrealized := tracker.PnL()
+	assert.InDelta(t, 350, realized, 1e-9)
+	assert.InDelta(t, 100, unrealized, 1e-9)
+}
+
+func TestTrackerReconcile(t *testing.T) {
+	client := &fakeClient{balances: map[string][]*pb.TokenBalance{
+		"owner": {
+			{TokenMint: metadata.SOLMint, SettledAmount: 8, UnsettledAmount: 1, OpenOrdersAmount: 1},
+			{TokenMint: metadata.USDCMint, SettledAmount: 500},
+		},
+	}}
+	tracker := NewTracker(client, TrackerOpts{Owners: []string{"owner"}})
+	tracker.SetPrice(metadata.SOLMint, 100)
+	tracker.ApplyTrade(Trade{InMint: metadata.USDCMint, InAmount: 1200, OutMint: metadata.SOLMint, OutAmount: 12})
+
+	adjustments, err := tracker.Reconcile(context.Background())
+	require.Nil(t, err)
+	require.Len(t, adjustments, 2)
+
+	sol, _ := tracker.Position(metadata.SOLMint)
+	assert.InDelta(t, 10, sol.Quantity, 1e-9)
+	// removed quantities don't realize PnL
+	assert.Equal(t, 0.0, sol.RealizedPnL)
+
+	usdc, _ := tracker.Position(metadata.USDCMint)
+	assert.InDelta(t, 500, usdc.Quantity, 1e-9)
+}
@@ -0,0 +1,362 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/metadata"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultBalanceInterval = 30 * time.Second
+
+var ErrAlreadyStreaming = errors.New("valuation is already streaming")
+
+// ValuationClient is implemented by all clients
+type ValuationClient interface {
+	PricesStreamer
+	BalancesClient
+}
+
+type ValuationOpts struct {
+	// Owners are the wallets whose holdings are valued
+	Owners []string
+	// QuoteMints are valued at 1 (default USDC and USDT)
+	QuoteMints []string
+	// Projects for the prices stream (default all)
+	Projects []pb.Project
+	// BalanceInterval between balance refreshes (default 30s)
+	BalanceInterval time.Duration
+	// MaxPriceAge marks prices older than this as stale, streaming a snapshot when a price becomes stale. Stale prices
+	// are still valued. It's disabled if 0.
+	MaxPriceAge time.Duration
+	// MinLiquidity is the USD value at the best buy or sell price below which a token is illiquid. Illiquid tokens
+	// are excluded from the total. It's disabled if 0, and tokens whose prices don't report sizes are never illiquid.
+	MinLiquidity float64
+}
+
+type PriceStatus int
+
+const (
+	// PriceMissing means no price has been received for the token
+	PriceMissing PriceStatus = iota
+	PriceLive
+	PriceStale
+	PriceIlliquid
+)
+
+func (s PriceStatus) String() string {
+	switch s {
+	case PriceMissing:
+		return "missing"
+	case PriceLive:
+		return "live"
+	case PriceStale:
+		return "stale"
+	case PriceIlliquid:
+		return "illiquid"
+	}
+	return fmt.Sprintf("PriceStatus(%d)", int(s))
+}
+
+// TokenValue is the valuation of a token held by the owners. Value and Share are 0 unless Status is PriceLive or
+// PriceStale.
+type TokenValue struct {
+	Mint      string
+	Symbol    string
+	Quantity  float64
+	Price     float64
+	PriceTime time.Time
+	Status    PriceStatus
+	Value     float64
+	// Share of the total value, between 0 and 1
+	Share float64
+}
+
+// PortfolioSnapshot is the value of the owners' holdings at a point in time. Tokens are sorted by value.
+type PortfolioSnapshot struct {
+	Tokens []TokenValue
+	// Total is the USD value of the tokens with a live or stale price
+	Total float64
+	// IlliquidValue is the USD value of the illiquid tokens at their quoted price, excluded from Total
+	IlliquidValue float64
+	// Unpriced are the mints of the tokens without a price
+	Unpriced []string
+	Time     time.Time
+}
+
+type tokenPrice struct {
+	price     float64
+	liquidity float64
+	time      time.Time
+}
+
+// Valuation values the holdings of a set of wallets with the prices stream, re-valuing on each price or balance change
+type Valuation struct {
+	client ValuationClient
+	opts   ValuationOpts
+	quotes map[string]bool
+	feed   *priceFeed
+
+	m        sync.Mutex
+	balances map[string]float64
+	symbols  map[string]string
+	prices   map[string]tokenPrice
+	snapshot PortfolioSnapshot
+	updates  chan PortfolioSnapshot
+	// staleAt is when the next live price becomes stale, zero if none will
+	staleAt time.Time
+}
+
+func NewValuation(client ValuationClient, opts ValuationOpts) *Valuation {
+	if len(opts.QuoteMints) == 0 {
+		opts.QuoteMints = []string{metadata.USDCMint, metadata.USDTMint}
+	}
+	if len(opts.Projects) == 0 {
+		opts.Projects = []pb.Project{pb.Project_P_ALL}
+	}
+	if opts.BalanceInterval <= 0 {
+		opts.BalanceInterval = defaultBalanceInterval
+	}
+
+	v := &Valuation{
+		client:   client,
+		opts:     opts,
+		quotes:   make(map[string]bool),
+		balances: make(map[string]float64),
+		symbols:  make(map[string]string),
+		prices:   make(map[string]tokenPrice),
+	}
+	for _, mint := range opts.QuoteMints {
+		v.quotes[mint] = true
+	}
+	v.feed = newPriceFeed(client, opts.Projects, v.setPrice)
+	return v
+}
+
+// Stream fetches the balances, then follows prices and refreshes balances until ctx is canceled, streaming a snapshot
+// on each change. Snapshots not read in time are replaced by newer ones.
+func (v *Valuation) Stream(ctx context.Context) (connections.Streamer[PortfolioSnapshot], error) {
+	v.m.Lock()
+	if v.updates != nil {
+		v.m.Unlock()
+		return nil, ErrAlreadyStreaming
+	}
+	v.updates = make(chan PortfolioSnapshot, 1)
+	updates := v.updates
+	v.m.Unlock()
+
+	if err := v.Refresh(ctx); err != nil {
+		v.m.Lock()
+		v.updates = nil
+		v.m.Unlock()
+		return nil, err
+	}
+
+	go v.feed.run(ctx)
+	go v.run(ctx, updates)
+	return connections.FromChannel[PortfolioSnapshot](ctx, updates), nil
+}
+
+// run refreshes the balances, and re-values the portfolio when prices become stale, until ctx is canceled. It then
+// stops streaming to updates, so Stream can be called again.
+func (v *Valuation) run(ctx context.Context, updates chan PortfolioSnapshot) {
+	ticker := time.NewTicker(v.opts.BalanceInterval)
+	defer ticker.Stop()
+
+	// the staleness timer is only started with MaxPriceAge, its channel stays nil otherwise
+	var (
+		staleness *time.Timer
+		stale     <-chan time.Time
+	)
+	if v.opts.MaxPriceAge > 0 {
+		staleness = time.NewTimer(v.untilStale())
+		defer staleness.Stop()
+		stale = staleness.C
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := v.Refresh(ctx); err != nil {
+				log.Errorf("could not refresh balances: %v", err)
+			}
+		case <-stale:
+			v.m.Lock()
+			if !v.staleAt.IsZero() && !time.Now().Before(v.staleAt) {
+				v.revalue()
+			}
+			v.m.Unlock()
+			staleness.Reset(v.untilStale())
+		case <-ctx.Done():
+			v.m.Lock()
+			if v.updates == updates {
+				v.updates = nil
+			}
+			v.m.Unlock()
+			return
+		}
+	}
+}
+
+// untilStale is how long until the staleness of prices is checked again: when the next live price becomes stale,
+// and at most MaxPriceAge, as prices received later become stale after it
+func (v *Valuation) untilStale() time.Duration {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	if v.staleAt.IsZero() {
+		return v.opts.MaxPriceAge
+	}
+	wait := time.Until(v.staleAt)
+	if wait < time.Millisecond {
+		wait = time.Millisecond
+	}
+	if wait > v.opts.MaxPriceAge {
+		wait = v.opts.MaxPriceAge
+	}
+	return wait
+}
+
+// Refresh fetches the balances and re-values the portfolio if they changed
+func (v *Valuation) Refresh(ctx context.Context) error {
+	balances, symbols, err := fetchBalances(ctx, v.client, v.opts.Owners)
+	if err != nil {
+		return err
+	}
+
+	var mints []string
+	for mint := range balances {
+		if !v.quotes[mint] {
+			mints = append(mints, mint)
+		}
+	}
+	v.feed.add(mints...)
+
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	changed := v.snapshot.Time.IsZero() || len(balances) != len(v.balances)
+	for mint, quantity := range balances {
+		if previous, ok := v.balances[mint]; !ok || previous != quantity {
+			changed = true
+		}
+	}
+	v.balances = balances
+	v.symbols = symbols
+	if changed {
+		v.revalue()
+	}
+	return nil
+}
+
+// Snapshot returns the latest valuation
+func (v *Valuation) Snapshot() PortfolioSnapshot {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	snapshot := v.snapshot
+	snapshot.Tokens = append([]TokenValue(nil), v.snapshot.Tokens...)
+	snapshot.Unpriced = append([]string(nil), v.snapshot.Unpriced...)
+	return snapshot
+}
+
+func (v *Valuation) setPrice(mint string, update *pb.TokenPrice, ts time.Time) {
+	price, ok := MidPrice(update)
+	if !ok {
+		return
+	}
+	liquidity := math.Max(update.GetBuySize()*update.GetBuy(), update.GetSellSize()*update.GetSell())
+
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	v.prices[mint] = tokenPrice{price: price, liquidity: liquidity, time: ts}
+	if _, held := v.balances[mint]; held {
+		v.revalue()
+	}
+}
+
+// revalue computes a snapshot and publishes it. The lock must be held.
+func (v *Valuation) revalue() {
+	now := time.Now()
+	snapshot := PortfolioSnapshot{Time: now}
+	v.staleAt = time.Time{}
+	for mint, quantity := range v.balances {
+		if quantity == 0 {
+			continue
+		}
+
+		token := TokenValue{Mint: mint, Symbol: v.symbols[mint], Quantity: quantity}
+		if v.quotes[mint] {
+			token.Price, token.PriceTime, token.Status = 1, now, PriceLive
+		} else if price, ok := v.prices[mint]; ok {
+			token.Price, token.PriceTime, token.Status = price.price, price.time, PriceLive
+			switch {
+			case v.opts.MinLiquidity > 0 && price.liquidity > 0 && price.liquidity < v.opts.MinLiquidity:
+				token.Status = PriceIlliquid
+			case v.opts.MaxPriceAge > 0 && now.Sub(price.time) > v.opts.MaxPriceAge:
+				token.Status = PriceStale
+			case v.opts.MaxPriceAge > 0:
+				if staleAt := price.time.Add(v.opts.MaxPriceAge); v.staleAt.IsZero() || staleAt.Before(v.staleAt) {
+					v.staleAt = staleAt
+				}
+			}
+		}
+
+		switch token.Status {
+		case PriceLive, PriceStale:
+			token.Value = quantity * token.Price
+			snapshot.Total += token.Value
+		case PriceIlliquid:
+			snapshot.IlliquidValue += quantity * token.Price
+		case PriceMissing:
+			snapshot.Unpriced = append(snapshot.Unpriced, mint)
+		}
+		snapshot.Tokens = append(snapshot.Tokens, token)
+	}
+
+	if snapshot.Total > 0 {
+		for i := range snapshot.Tokens {
+			snapshot.Tokens[i].Share = snapshot.Tokens[i].Value / snapshot.Total
+		}
+	}
+	sort.Slice(snapshot.Tokens, func(i, j int) bool {
+		if snapshot.Tokens[i].Value != snapshot.Tokens[j].Value {
+			return snapshot.Tokens[i].Value > snapshot.Tokens[j].Value
+		}
+		return snapshot.Tokens[i].Mint < snapshot.Tokens[j].Mint
+	})
+	sort.Strings(snapshot.Unpriced)
+	v.snapshot = snapshot
+
+	if v.updates == nil {
+		return
+	}
+	// replace the pending snapshot if the reader is behind
+	for {
+		select {
+		case v.updates <- snapshot:
+			return
+		default:
+		}
+		select {
+		case <-v.updates:
+		default:
+		}
+	}
+}
+
+var (
+	_ ValuationClient = (*provider.GRPCClient)(nil)
+	_ ValuationClient = (*provider.WSClient)(nil)
+	_ ValuationClient = (*provider.HTTPClient)(nil)
+)
@@ -0,0 +1,105 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/metadata"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValuation(t *testing.T) {
+	client := &fakeClient{balances: map[string][]*pb.TokenBalance{
+		"owner": {
+			{TokenMint: metadata.SOLMint, Symbol: "SOL", SettledAmount: 10},
+			{TokenMint: metadata.USDCMint, Symbol: "USDC", SettledAmount: 1000},
+			{TokenMint: "illiquid", SettledAmount: 100},
+			{TokenMint: "unpriced", SettledAmount: 5},
+		},
+	}}
+	v := NewValuation(client, ValuationOpts{Owners: []string{"owner"}, MinLiquidity: 10})
+	require.Nil(t, v.Refresh(context.Background()))
+
+	snapshot := v.Snapshot()
+	assert.Equal(t, 1000.0, snapshot.Total)
+	assert.Equal(t, []string{metadata.SOLMint, "illiquid", "unpriced"}, snapshot.Unpriced)
+
+	v.setPrice(metadata.SOLMint, &pb.TokenPrice{Buy: 150, Sell: 150}, time.Now())
+	v.setPrice("illiquid", &pb.TokenPrice{Buy: 2, BuySize: 1}, time.Now())
+
+	snapshot = v.Snapshot()
+	assert.Equal(t, 2500.0, snapshot.Total)
+	assert.Equal(t, 200.0, snapshot.IlliquidValue)
+	assert.Equal(t, []string{"unpriced"}, snapshot.Unpriced)
+
+	require.Len(t, snapshot.Tokens, 4)
+	assert.Equal(t, metadata.SOLMint, snapshot.Tokens[0].Mint)
+	assert.Equal(t, PriceLive, snapshot.Tokens[0].Status)
+	assert.InDelta(t, 0.6, snapshot.Tokens[0].Share, 1e-9)
+	assert.InDelta(t, 0.4, snapshot.Tokens[1].Share, 1e-9)
+	assert.Equal(t, PriceIlliquid, snapshot.Tokens[2].Status)
+	assert.Equal(t, 0.0, snapshot.Tokens[2].Share)
+	assert.Equal(t, PriceMissing, snapshot.Tokens[3].Status)
+}
+
+func TestValuationStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a failed first refresh doesn't prevent streaming later
+	client := &fakeClient{balancesErr: errors.New("unavailable"), balances: map[string][]*pb.TokenBalance{
+		"owner": {{TokenMint: metadata.SOLMint, Symbol: "SOL", SettledAmount: 10}},
+	}}
+	v := NewValuation(client, ValuationOpts{Owners: []string{"owner"}, MaxPriceAge: 20 * time.Millisecond})
+	_, err := v.Stream(ctx)
+	require.ErrorIs(t, err, client.balancesErr)
+
+	client.balancesErr = nil
+	stream, err := v.Stream(ctx)
+	require.Nil(t, err)
+	_, err = v.Stream(ctx)
+	require.ErrorIs(t, err, ErrAlreadyStreaming)
+	snapshot, err := stream()
+	require.Nil(t, err)
+	assert.Equal(t, []string{metadata.SOLMint}, snapshot.Unpriced)
+
+	// the price becomes stale without any other update
+	v.setPrice(metadata.SOLMint, &pb.TokenPrice{Buy: 150, Sell: 150}, time.Now())
+	snapshot, err = stream()
+	require.Nil(t, err)
+	assert.Equal(t, PriceLive, snapshot.Tokens[0].Status)
+	snapshot, err = stream()
+	require.Nil(t, err)
+	assert.Equal(t, PriceStale, snapshot.Tokens[0].Status)
+	assert.Equal(t, 1500.0, snapshot.Total)
+}
+
+func TestValuationStreamRestart(t *testing.T) {
+	client := &fakeClient{balances: map[string][]*pb.TokenBalance{
+		"owner": {{TokenMint: metadata.SOLMint, Symbol: "SOL", SettledAmount: 10}},
+	}}
+	v := NewValuation(client, ValuationOpts{Owners: []string{"owner"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := v.Stream(ctx)
+	require.Nil(t, err)
+	cancel()
+	client.balances["owner"][0].SettledAmount = 12
+
+	// once the canceled stream has stopped, another one can be started
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	var stream connections.Streamer[PortfolioSnapshot]
+	require.Eventually(t, func() bool {
+		stream, err = v.Stream(ctx)
+		return err == nil
+	}, time.Second, time.Millisecond)
+	snapshot, err := stream()
+	require.Nil(t, err)
+	assert.Equal(t, 12.0, snapshot.Tokens[0].Quantity)
+}
@@ -0,0 +1,234 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/amount"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/bloXroute-Labs/solana-trader-proto/common"
+)
+
+// The methods and request builders below accept exact amounts instead of float64. Requests still carry amounts as
+// float64, so each amount is checked to convert back to exactly the same raw amount, and rejected with
+// amount.ErrInexactFloat otherwise (e.g. more than ~15 significant digits).
+
+func exactFloat(name string, a amount.Amount) (float64, error) {
+	f, err := a.ExactFloat64()
+	if err != nil {
+		return 0, fmt.Errorf("%v: %w", name, err)
+	}
+	return f, nil
+}
+
+func exactOrder(size, price amount.Amount) (float64, float64, error) {
+	sizeF, err := exactFloat("amount", size)
+	if err != nil {
+		return 0, 0, err
+	}
+	priceF, err := exactFloat("price", price)
+	if err != nil {
+		return 0, 0, err
+	}
+	return sizeF, priceF, nil
+}
+
+// NewRaydiumSwapRequest creates a Raydium swap request for an exact input amount
+func NewRaydiumSwapRequest(owner, inToken, outToken string, inAmount amount.Amount, slippage float64) (*pb.PostRaydiumSwapRequest, error) {
+	in, err := exactFloat("inAmount", inAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PostRaydiumSwapRequest{OwnerAddress: owner, InToken: inToken, OutToken: outToken, InAmount: in, Slippage: slippage}, nil
+}
+
+// NewRaydiumCPMMSwapRequest creates a Raydium CPMM swap request for an exact input amount
+func NewRaydiumCPMMSwapRequest(owner, pool, inToken, outToken string, inAmount amount.Amount, slippage float64) (*pb.PostRaydiumCPMMSwapRequest, error) {
+	in, err := exactFloat("inAmount", inAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PostRaydiumCPMMSwapRequest{OwnerAddress: owner, PoolAddress: pool, InToken: inToken, OutToken: outToken, InAmount: in, Slippage: slippage}, nil
+}
+
+// NewRaydiumQuotesRequest creates a Raydium quotes request for an exact input amount
+func NewRaydiumQuotesRequest(inToken, outToken string, inAmount amount.Amount, slippage float64) (*pb.GetRaydiumQuotesRequest, error) {
+	in, err := exactFloat("inAmount", inAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetRaydiumQuotesRequest{InToken: inToken, OutToken: outToken, InAmount: in, Slippage: slippage}, nil
+}
+
+// NewJupiterSwapRequest creates a Jupiter swap request for an exact input amount
+func NewJupiterSwapRequest(owner, inToken, outToken string, inAmount amount.Amount, slippage float64) (*pb.PostJupiterSwapRequest, error) {
+	in, err := exactFloat("inAmount", inAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PostJupiterSwapRequest{OwnerAddress: owner, InToken: inToken, OutToken: outToken, InAmount: in, Slippage: slippage}, nil
+}
+
+// NewJupiterQuotesRequest creates a Jupiter quotes request for an exact input amount
+func NewJupiterQuotesRequest(inToken, outToken string, inAmount amount.Amount, slippage float64) (*pb.GetJupiterQuotesRequest, error) {
+	in, err := exactFloat("inAmount", inAmount)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetJupiterQuotesRequest{InToken: inToken, OutToken: outToken, InAmount: in, Slippage: slippage}, nil
+}
+
+// NewPumpFunSwapRequest creates a pumpfun swap request for an exact token amount and SOL threshold
+func NewPumpFunSwapRequest(user, bondingCurve, token string, tokenAmount, solThreshold amount.Amount, isBuy bool) (*pb.PostPumpFunSwapRequest, error) {
+	tokens, err := exactFloat("tokenAmount", tokenAmount)
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := exactFloat("solThreshold", solThreshold)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PostPumpFunSwapRequest{
+		UserAddress:         user,
+		BondingCurveAddress: bondingCurve,
+		TokenAddress:        token,
+		TokenAmount:         tokens,
+		SolThreshold:        threshold,
+		IsBuy:               isBuy,
+	}, nil
+}
+
+// GetQuotesWithAmount is like GetQuotes, with an exact input amount
+func (g *GRPCClient) GetQuotesWithAmount(ctx context.Context, inToken, outToken string, inAmount amount.Amount, slippage float64, limit int32, projects []pb.Project) (*pb.GetQuotesResponse, error) {
+	in, err := exactFloat("inAmount", inAmount)
+	if err != nil {
+		return nil, err
+	}
+	return g.GetQuotes(ctx, inToken, outToken, in, slippage, limit, projects)
+}
+
+// PostTradeSwapWithAmount is like PostTradeSwap, with an exact input amount
+func (g *GRPCClient) PostTradeSwapWithAmount(ctx context.Context, ownerAddress, inToken, outToken string, inAmount amount.Amount, slippage float64, project pb.Project) (*pb.TradeSwapResponse, error) {
+	in, err := exactFloat("inAmount", inAmount)
+	if err != nil {
+		return nil, err
+	}
+	return g.PostTradeSwap(ctx, ownerAddress, inToken, outToken, in, slippage, project)
+}
+
+// SubmitTradeSwapWithAmount is like SubmitTradeSwap, with an exact input amount
+func (g *GRPCClient) SubmitTradeSwapWithAmount(ctx context.Context, ownerAddress, inToken, outToken string, inAmount amount.Amount, slippage float64, project pb.Project, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	in, err := exactFloat("inAmount", inAmount)
+	if err != nil {
+		return nil, err
+	}
+	return g.SubmitTradeSwap(ctx, ownerAddress, inToken, outToken, in, slippage, project, opts)
+}
+
+// PostOrderWithAmounts is like PostOrder, with an exact size and price
+func (g *GRPCClient) PostOrderWithAmounts(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, size, price amount.Amount, project pb.Project, opts PostOrderOpts) (*pb.PostOrderResponse, error) {
+	sizeF, priceF, err := exactOrder(size, price)
+	if err != nil {
+		return nil, err
+	}
+	return g.PostOrder(ctx, owner, payer, market, side, types, sizeF, priceF, project, opts)
+}
+
+// SubmitOrderWithAmounts is like SubmitOrder, with an exact size and price
+func (g *GRPCClient) SubmitOrderWithAmounts(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, size, price amount.Amount, project pb.Project, opts PostOrderOpts) (string, error) {
+	sizeF, priceF, err := exactOrder(size, price)
+	if err != nil {
+		return "", err
+	}
+	return g.SubmitOrder(ctx, owner, payer, market, side, types, sizeF, priceF, project, opts)
+}
+
+// GetQuotesWithAmount is like GetQuotes, with an exact input amount
+func (w *WSClient) GetQuotesWithAmount(ctx context.Context, inToken, outToken string, inAmount amount.Amount, slippage float64, limit int32, projects []pb.Project) (*pb.GetQuotesResponse, error) {
+	in, err := exactFloat("inAmount", inAmount)
+	if err != nil {
+		return nil, err
+	}
+	return w.GetQuotes(ctx, inToken, outToken, in, slippage, limit, projects)
+}
+
+// PostTradeSwapWithAmount is like PostTradeSwap, with an exact input amount
+func (w *WSClient) PostTradeSwapWithAmount(ctx context.Context, ownerAddress, inToken, outToken string, inAmount amount.Amount, slippage float64, projectStr string) (*pb.TradeSwapResponse, error) {
+	in, err := exactFloat("inAmount", inAmount)
+	if err != nil {
+		return nil, err
+	}
+	return w.PostTradeSwap(ctx, ownerAddress, inToken, outToken, in, slippage, projectStr)
+}
+
+// SubmitTradeSwapWithAmount is like SubmitTradeSwap, with an exact input amount
+func (w *WSClient) SubmitTradeSwapWithAmount(ctx context.Context, owner, inToken, outToken string, inAmount amount.Amount, slippage float64, project string, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	in, err := exactFloat("inAmount", inAmount)
+	if err != nil {
+		return nil, err
+	}
+	return w.SubmitTradeSwap(ctx, owner, inToken, outToken, in, slippage, project, opts)
+}
+
+// PostOrderWithAmounts is like PostOrder, with an exact size and price
+func (w *WSClient) PostOrderWithAmounts(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, size, price amount.Amount, project pb.Project, opts PostOrderOpts) (*pb.PostOrderResponse, error) {
+	sizeF, priceF, err := exactOrder(size, price)
+	if err != nil {
+		return nil, err
+	}
+	return w.PostOrder(ctx, owner, payer, market, side, types, sizeF, priceF, project, opts)
+}
+
+// SubmitOrderWithAmounts is like SubmitOrder, with an exact size and price
+func (w *WSClient) SubmitOrderWithAmounts(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, size, price amount.Amount, project pb.Project, opts PostOrderOpts) (string, error) {
+	sizeF, priceF, err := exactOrder(size, price)
+	if err != nil {
+		return "", err
+	}
+	return w.SubmitOrder(ctx, owner, payer, market, side, types, sizeF, priceF, project, opts)
+}
+
+// GetQuotesWithAmount is like GetQuotes, with an exact input amount
+func (h *HTTPClient) GetQuotesWithAmount(ctx context.Context, inToken, outToken string, inAmount amount.Amount, slippage float64, limit int32, projects []pb.Project) (*pb.GetQuotesResponse, error) {
+	in, err := exactFloat("inAmount", inAmount)
+	if err != nil {
+		return nil, err
+	}
+	return h.GetQuotes(ctx, inToken, outToken, in, slippage, limit, projects)
+}
+
+// PostTradeSwapWithAmount is like PostTradeSwap, with an exact input amount
+func (h *HTTPClient) PostTradeSwapWithAmount(ctx context.Context, ownerAddress, inToken, outToken string, inAmount amount.Amount, slippage float64, project pb.Project) (*pb.TradeSwapResponse, error) {
+	in, err := exactFloat("inAmount", inAmount)
+	if err != nil {
+		return nil, err
+	}
+	return h.PostTradeSwap(ctx, ownerAddress, inToken, outToken, in, slippage, project)
+}
+
+// SubmitTradeSwapWithAmount is like SubmitTradeSwap, with an exact input amount
+func (h *HTTPClient) SubmitTradeSwapWithAmount(ctx context.Context, owner, inToken, outToken string, inAmount amount.Amount, slippage float64, project pb.Project, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	in, err := exactFloat("inAmount", inAmount)
+	if err != nil {
+		return nil, err
+	}
+	return h.SubmitTradeSwap(ctx, owner, inToken, outToken, in, slippage, project, opts)
+}
+
+// PostOrderWithAmounts is like PostOrder, with an exact size and price
+func (h *HTTPClient) PostOrderWithAmounts(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, size, price amount.Amount, project pb.Project, opts PostOrderOpts) (*pb.PostOrderResponse, error) {
+	sizeF, priceF, err := exactOrder(size, price)
+	if err != nil {
+		return nil, err
+	}
+	return h.PostOrder(ctx, owner, payer, market, side, types, sizeF, priceF, project, opts)
+}
+
+// SubmitOrderWithAmounts is like SubmitOrder, with an exact size and price
+func (h *HTTPClient) SubmitOrderWithAmounts(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, size, price amount.Amount, project pb.Project, opts PostOrderOpts) (string, error) {
+	sizeF, priceF, err := exactOrder(size, price)
+	if err != nil {
+		return "", err
+	}
+	return h.SubmitOrder(ctx, owner, payer, market, side, types, sizeF, priceF, project, opts)
+}
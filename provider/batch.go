@@ -0,0 +1,301 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+// ErrRefreshSigners is returned when a failed transaction signed by other keys needs BatchOpts.Refresh to be retried
+var ErrRefreshSigners = errors.New("transaction is signed by other keys and can't be given a new block hash")
+
+// BatchOutcome is what happened to a transaction of a batch
+type BatchOutcome string
+
+const (
+	BatchAccepted  BatchOutcome = "accepted"
+	BatchRejected  BatchOutcome = "rejected"
+	BatchDuplicate BatchOutcome = "duplicate"
+	// BatchSkipped entries were not submitted, e.g. after an earlier entry failed with P_ABORT_ON_FIRST_ERROR
+	BatchSkipped BatchOutcome = "skipped"
+)
+
+// duplicateErrors are the errors of transactions that were already submitted
+var duplicateErrors = []string{"already processed", "alreadyprocessed", "already been processed", "duplicate"}
+
+// retryableErrors are the errors of transactions whose block hash is unknown or expired, which can't land anymore
+var retryableErrors = []string{"blockhash not found", "block hash not found", "blockhashnotfound", "blockhash expired",
+	"block hash expired", "block height exceeded"}
+
+// BatchEntryResult is the outcome of a transaction of a batch
+type BatchEntryResult struct {
+	// Index is the position of the transaction in the batch
+	Index     int
+	Signature string
+	Outcome   BatchOutcome
+	// Reason is the error of a rejected or skipped transaction
+	Reason string
+	// Attempts is how many times the transaction was submitted
+	Attempts int
+}
+
+// Failed reports whether the transaction wasn't accepted. A duplicate was accepted by an earlier submission.
+func (e BatchEntryResult) Failed() bool {
+	return e.Outcome == BatchRejected || e.Outcome == BatchSkipped
+}
+
+// BatchResult is the outcome of each transaction of a batch, in the order of the batch
+type BatchResult struct {
+	Entries []BatchEntryResult
+}
+
+// NewBatchResult classifies the entries of a PostSubmitBatch response
+func NewBatchResult(response *pb.PostSubmitBatchResponse) *BatchResult {
+	result := &BatchResult{}
+	for i, entry := range response.Transactions {
+		entryResult := classifyEntry(i, entry)
+		if entryResult.Outcome != BatchSkipped {
+			entryResult.Attempts = 1
+		}
+		result.Entries = append(result.Entries, entryResult)
+	}
+	return result
+}
+
+// Failed lists the entries that weren't accepted
+func (r *BatchResult) Failed() []BatchEntryResult {
+	var failed []BatchEntryResult
+	for _, entry := range r.Entries {
+		if entry.Failed() {
+			failed = append(failed, entry)
+		}
+	}
+	return failed
+}
+
+// Succeeded reports whether every transaction was accepted
+func (r *BatchResult) Succeeded() bool {
+	return len(r.Failed()) == 0
+}
+
+// Signatures are the signatures of the accepted and duplicate transactions
+func (r *BatchResult) Signatures() []string {
+	var signatures []string
+	for _, entry := range r.Entries {
+		if !entry.Failed() {
+			signatures = append(signatures, entry.Signature)
+		}
+	}
+	return signatures
+}
+
+func classifyEntry(index int, entry *pb.PostSubmitBatchResponseEntry) BatchEntryResult {
+	result := BatchEntryResult{Index: index, Signature: entry.Signature, Reason: entry.Error}
+	switch {
+	case entry.Error != "" && isDuplicate(entry.Error):
+		result.Outcome = BatchDuplicate
+	case entry.Error != "":
+		result.Outcome = BatchRejected
+	case entry.Submitted:
+		result.Outcome = BatchAccepted
+	default:
+		result.Outcome, result.Reason = BatchSkipped, "not submitted"
+	}
+	return result
+}
+
+func isDuplicate(reason string) bool {
+	return containsAny(reason, duplicateErrors)
+}
+
+// retryable reports whether a failed transaction can be submitted again without landing twice: it wasn't submitted, or
+// was rejected for its block hash
+func (e BatchEntryResult) retryable() bool {
+	return e.Outcome == BatchSkipped || (e.Outcome == BatchRejected && containsAny(e.Reason, retryableErrors))
+}
+
+func containsAny(reason string, substrings []string) bool {
+	reason = strings.ToLower(reason)
+	for _, substring := range substrings {
+		if strings.Contains(reason, substring) {
+			return true
+		}
+	}
+	return false
+}
+
+// RefreshFunc rebuilds a failed transaction of a batch with a new block hash, before it's signed again
+type RefreshFunc func(ctx context.Context, index int, tx *pb.TransactionMessage, blockHash solana.Hash) (*pb.TransactionMessage, error)
+
+// BatchOpts configures SubmitBatch
+type BatchOpts struct {
+	SubmitOpts
+	UseBundle bool
+	// MaxRetries is how many times failed transactions are given a fresh block hash and submitted again. Only the
+	// transactions rejected for an unknown or expired block hash, or not submitted, are retried: others may still land.
+	// The transactions of a bundle are retried together, if all of its failures are retryable.
+	MaxRetries int
+	// Refresh rebuilds failed transactions, by default replacing the block hash of messages signed only by the client
+	Refresh RefreshFunc
+}
+
+// batchClient is what SubmitBatch needs from a client
+type batchClient struct {
+	submit     func(ctx context.Context, request *pb.PostSubmitBatchRequest) (*pb.PostSubmitBatchResponse, error)
+	blockHash  func(ctx context.Context) (*pb.GetRecentBlockHashResponse, error)
+	privateKey solana.PrivateKey
+	policy     *transaction.Policy
+	dryRun     bool
+}
+
+// submitBatch signs and submits transactions, retrying the failed ones. Without a bundle, P_ABORT_ON_FIRST_ERROR and
+// P_WAIT_FOR_CONFIRMATION submit the transactions one by one and skip the rest after a failure. The result is returned
+// with the error of a failed request, for the transactions submitted before it.
+func submitBatch(ctx context.Context, c batchClient, transactions []*pb.TransactionMessage, opts BatchOpts) (*BatchResult, error) {
+	if opts.DryRun || c.dryRun {
+		request, err := buildBatchRequest(transactions, c.privateKey, opts.UseBundle, opts.SubmitOpts, c.policy)
+		if err != nil {
+			return nil, err
+		}
+		return nil, newDryRunError(request)
+	}
+	if opts.Refresh == nil {
+		opts.Refresh = refreshBlockHash(c.privateKey.PublicKey())
+	}
+
+	result := &BatchResult{}
+	for i := range transactions {
+		result.Entries = append(result.Entries, BatchEntryResult{Index: i, Outcome: BatchSkipped, Reason: "not submitted"})
+	}
+	pending := append([]*pb.TransactionMessage{}, transactions...)
+
+	strategy := opts.SubmitStrategy
+	if !opts.UseBundle && (strategy == pb.SubmitStrategy_P_ABORT_ON_FIRST_ERROR || strategy == pb.SubmitStrategy_P_WAIT_FOR_CONFIRMATION) {
+		for i := range pending {
+			succeeded, err := c.submitWithRetries(ctx, result, pending, []int{i}, opts)
+			if err != nil || !succeeded {
+				return result, err
+			}
+		}
+		return result, nil
+	}
+
+	indexes := make([]int, len(pending))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	_, err := c.submitWithRetries(ctx, result, pending, indexes, opts)
+	return result, err
+}
+
+// submitWithRetries submits the transactions at indexes, then retries the failed ones, and reports whether all were
+// accepted
+func (c batchClient) submitWithRetries(ctx context.Context, result *BatchResult, transactions []*pb.TransactionMessage, indexes []int, opts BatchOpts) (bool, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			response, err := c.blockHash(ctx)
+			if err != nil {
+				return false, fmt.Errorf("could not retrieve block hash: %w", err)
+			}
+			hash, err := solana.HashFromBase58(response.BlockHash)
+			if err != nil {
+				return false, err
+			}
+			for _, index := range indexes {
+				if transactions[index], err = opts.Refresh(ctx, index, transactions[index], hash); err != nil {
+					return false, err
+				}
+			}
+		}
+
+		var batch []*pb.TransactionMessage
+		for _, index := range indexes {
+			batch = append(batch, transactions[index])
+		}
+		request, err := buildBatchRequest(batch, c.privateKey, opts.UseBundle, opts.SubmitOpts, c.policy)
+		if err != nil {
+			return false, err
+		}
+		response, err := c.submit(ctx, request)
+		if err != nil {
+			return false, err
+		}
+
+		var failed []int
+		terminal := false
+		for i, index := range indexes {
+			entry := BatchEntryResult{Outcome: BatchSkipped, Reason: "not submitted"}
+			if i < len(response.Transactions) {
+				entry = classifyEntry(index, response.Transactions[i])
+			}
+			entry.Index = index
+			entry.Attempts = result.Entries[index].Attempts + 1
+			if entry.Signature == "" {
+				entry.Signature = txSignature(request.Entries[i].Transaction.Content)
+			}
+			result.Entries[index] = entry
+			if !entry.Failed() {
+				continue
+			}
+			if entry.retryable() {
+				failed = append(failed, index)
+			} else {
+				terminal = true
+			}
+		}
+
+		if len(failed) == 0 && !terminal {
+			return true, nil
+		}
+		if attempt >= opts.MaxRetries || len(failed) == 0 || (terminal && opts.UseBundle) {
+			return false, nil
+		}
+		if !opts.UseBundle {
+			indexes = failed
+		}
+	}
+}
+
+// refreshBlockHash replaces the block hash of a transaction that only the owner signs
+func refreshBlockHash(owner solana.PublicKey) RefreshFunc {
+	return func(_ context.Context, _ int, tx *pb.TransactionMessage, blockHash solana.Hash) (*pb.TransactionMessage, error) {
+		ins, err := transaction.Inspect(tx.Content, transaction.InspectOpts{})
+		if err != nil {
+			return nil, err
+		}
+		solanaTx := ins.Transaction()
+		for i, signature := range solanaTx.Signatures {
+			if !signature.IsZero() && i < len(solanaTx.Message.AccountKeys) && !solanaTx.Message.AccountKeys[i].Equals(owner) {
+				return nil, ErrRefreshSigners
+			}
+		}
+
+		solanaTx.Message.RecentBlockhash = blockHash
+		solanaTx.Signatures = make([]solana.Signature, solanaTx.Message.Header.NumRequiredSignatures)
+		txBase64, err := solanaTx.ToBase64()
+		if err != nil {
+			return nil, err
+		}
+		return &pb.TransactionMessage{Content: txBase64, IsCleanup: tx.IsCleanup}, nil
+	}
+}
+
+// txSignature is the fee payer's signature of a signed transaction, which identifies it
+func txSignature(txBase64 string) string {
+	data, err := base64.StdEncoding.DecodeString(txBase64)
+	if err != nil {
+		return ""
+	}
+	tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(data))
+	if err != nil || len(tx.Signatures) == 0 {
+		return ""
+	}
+	return tx.Signatures[0].String()
+}
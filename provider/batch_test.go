@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var freshBlockHash = solana.MustHashFromBase58("9Gz6wMqqcgeZgQKqjVbKpnuNqzGoG4oDWySPYiTYaVGb")
+
+// fakeBatchServer rejects the transactions of the listed amounts, and counts the requests
+type fakeBatchServer struct {
+	t        *testing.T
+	rejected map[uint64]string
+	requests []*pb.PostSubmitBatchRequest
+}
+
+func (s *fakeBatchServer) client(privateKey solana.PrivateKey) batchClient {
+	return batchClient{
+		submit: s.submit,
+		blockHash: func(context.Context) (*pb.GetRecentBlockHashResponse, error) {
+			return &pb.GetRecentBlockHashResponse{BlockHash: freshBlockHash.String()}, nil
+		},
+		privateKey: privateKey,
+	}
+}
+
+func (s *fakeBatchServer) submit(_ context.Context, request *pb.PostSubmitBatchRequest) (*pb.PostSubmitBatchResponse, error) {
+	s.requests = append(s.requests, request)
+	response := &pb.PostSubmitBatchResponse{}
+	for _, entry := range request.Entries {
+		ins, err := transaction.Inspect(entry.Transaction.Content, transaction.InspectOpts{})
+		require.Nil(s.t, err)
+		tx := ins.Transaction()
+		require.Nil(s.t, tx.VerifySignatures())
+
+		amount := ins.Transfers[0].Amount
+		if reason, ok := s.rejected[amount]; ok && tx.Message.RecentBlockhash.Equals(testBlockHash) {
+			response.Transactions = append(response.Transactions, &pb.PostSubmitBatchResponseEntry{Error: reason})
+			continue
+		}
+		response.Transactions = append(response.Transactions, &pb.PostSubmitBatchResponseEntry{Signature: tx.Signatures[0].String(), Submitted: true})
+	}
+	return response, nil
+}
+
+func outcomes(result *BatchResult) []BatchOutcome {
+	var outcomes []BatchOutcome
+	for _, entry := range result.Entries {
+		outcomes = append(outcomes, entry.Outcome)
+	}
+	return outcomes
+}
+
+func TestSubmitBatch(t *testing.T) {
+	ctx := context.Background()
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	owner := privateKey.PublicKey()
+	transactions := []*pb.TransactionMessage{unsignedTx(t, owner, 1), unsignedTx(t, owner, 2), unsignedTx(t, owner, 3)}
+
+	// the rejected transaction is retried alone, with the fresh block hash
+	server := &fakeBatchServer{t: t, rejected: map[uint64]string{2: "blockhash not found"}}
+	result, err := submitBatch(ctx, server.client(privateKey), transactions, BatchOpts{MaxRetries: 1})
+	require.Nil(t, err)
+	assert.True(t, result.Succeeded())
+	assert.Len(t, result.Signatures(), 3)
+	require.Len(t, server.requests, 2)
+	assert.Len(t, server.requests[1].Entries, 1)
+	assert.Equal(t, []int{1, 2, 1}, []int{result.Entries[0].Attempts, result.Entries[1].Attempts, result.Entries[2].Attempts})
+
+	// without retries, the reason is kept
+	server = &fakeBatchServer{t: t, rejected: map[uint64]string{2: "blockhash not found", 3: "Transaction already processed"}}
+	result, err = submitBatch(ctx, server.client(privateKey), transactions, BatchOpts{})
+	require.Nil(t, err)
+	assert.Equal(t, []BatchOutcome{BatchAccepted, BatchRejected, BatchDuplicate}, outcomes(result))
+	assert.Equal(t, "blockhash not found", result.Entries[1].Reason)
+	assert.NotEmpty(t, result.Entries[1].Signature)
+	require.Len(t, result.Failed(), 1)
+	assert.Equal(t, 1, result.Failed()[0].Index)
+
+	// the rest isn't submitted after the first failure
+	server = &fakeBatchServer{t: t, rejected: map[uint64]string{2: "insufficient funds"}}
+	opts := BatchOpts{SubmitOpts: SubmitOpts{SubmitStrategy: pb.SubmitStrategy_P_ABORT_ON_FIRST_ERROR}}
+	result, err = submitBatch(ctx, server.client(privateKey), transactions, opts)
+	require.Nil(t, err)
+	assert.Equal(t, []BatchOutcome{BatchAccepted, BatchRejected, BatchSkipped}, outcomes(result))
+	assert.Len(t, server.requests, 2)
+
+	// terminal rejections aren't retried, since they may still land
+	server = &fakeBatchServer{t: t, rejected: map[uint64]string{2: "blockhash not found", 3: "insufficient funds"}}
+	result, err = submitBatch(ctx, server.client(privateKey), transactions, BatchOpts{MaxRetries: 2})
+	require.Nil(t, err)
+	assert.Equal(t, []BatchOutcome{BatchAccepted, BatchAccepted, BatchRejected}, outcomes(result))
+	assert.Equal(t, 1, result.Entries[2].Attempts)
+	require.Len(t, server.requests, 2)
+	assert.Len(t, server.requests[1].Entries, 1)
+
+	server = &fakeBatchServer{t: t, rejected: map[uint64]string{2: "blockhash not found", 3: "insufficient funds"}}
+	result, err = submitBatch(ctx, server.client(privateKey), transactions, BatchOpts{UseBundle: true, MaxRetries: 2})
+	require.Nil(t, err)
+	assert.False(t, result.Succeeded())
+	assert.Len(t, server.requests, 1)
+
+	// bundles are retried whole
+	server = &fakeBatchServer{t: t, rejected: map[uint64]string{3: "Blockhash expired"}}
+	result, err = submitBatch(ctx, server.client(privateKey), transactions, BatchOpts{UseBundle: true, MaxRetries: 2})
+	require.Nil(t, err)
+	assert.True(t, result.Succeeded())
+	require.Len(t, server.requests, 2)
+	assert.Len(t, server.requests[1].Entries, 3)
+}
+
+func TestSubmitBatchRefreshSigners(t *testing.T) {
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	other, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	owner := privateKey.PublicKey()
+
+	tx, err := transaction.NewTxBuilder(owner, nil).
+		WithBlockHash(testBlockHash).
+		AddInstructions(system.NewTransferInstruction(2, other.PublicKey(), owner).Build()).
+		WithSigners(other).
+		BuildBase64(context.Background())
+	require.Nil(t, err)
+
+	server := &fakeBatchServer{t: t, rejected: map[uint64]string{2: "blockhash not found"}}
+	result, err := submitBatch(context.Background(), server.client(privateKey), []*pb.TransactionMessage{{Content: tx}}, BatchOpts{MaxRetries: 1})
+	require.ErrorIs(t, err, ErrRefreshSigners)
+	assert.Equal(t, BatchRejected, result.Entries[0].Outcome)
+}
@@ -1,8 +1,11 @@
 package provider
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/journal"
 	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
 	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
 	"os"
@@ -79,6 +82,15 @@ type PostOrderOpts struct {
 type SubmitOpts struct {
 	SubmitStrategy pb.SubmitStrategy
 	SkipPreFlight  *bool
+
+	// Policy is checked before signing each transaction, in addition to the client's RPCOpts.Policy and the policy the
+	// Submit* methods derive from their parameters
+	Policy *transaction.Policy
+	// DryRun builds and signs the transactions, then returns a *DryRunError instead of submitting them
+	DryRun bool
+
+	// request is the policy derived from the parameters of the Submit* method
+	request *transaction.Policy
 }
 
 type RPCOpts struct {
@@ -89,6 +101,24 @@ type RPCOpts struct {
 	AuthHeader     string
 	CacheBlockHash bool
 	BlockHashTtl   time.Duration
+
+	// HTTPStreamMode, HTTPPollOpts and HTTPSSEEndpoint only apply to HTTPClient streams
+	HTTPStreamMode HTTPStreamMode
+	HTTPPollOpts   connections.PollOpts
+	// HTTPSSEEndpoint is the base URL of a gateway serving the streams as server-sent events, each at
+	// <HTTPSSEEndpoint>/<stream name> (e.g. /GetPricesStream). The Trader API doesn't provide one, so streams are polled
+	// unless it's set.
+	HTTPSSEEndpoint string
+
+	// Policy is checked before signing every transaction. Its owner defaults to the private key's address.
+	Policy *transaction.Policy
+	// DryRun sets SubmitOpts.DryRun for every request, including the methods without SubmitOpts
+	DryRun bool
+	// Journal records every signed transaction before it's submitted, and the response after
+	Journal *journal.Journal
+	// AccountFetcher loads mint accounts, so the token account helpers use the program owning each mint, e.g.
+	// *rpc.Client. Without it, mints without a Token-2022 account of the key are treated as token program mints.
+	AccountFetcher transaction.AccountFetcher
 }
 
 func DefaultRPCOpts(endpoint string) RPCOpts {
@@ -119,11 +149,31 @@ func ProjectFromString(project string) (pb.Project, error) {
 	return pb.Project_P_UNKNOWN, fmt.Errorf("could not find project %s", project)
 }
 
-func buildBatchRequest(transactions []*pb.TransactionMessage, privateKey solana.PrivateKey, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchRequest, error) {
+// ProjectToString is the name of a project accepted by ProjectFromString
+func ProjectToString(project pb.Project) string {
+	for name, apiProject := range stringToAmm {
+		if apiProject == project {
+			return name
+		}
+	}
+	return project.String()
+}
+
+func publicKey(privateKey *solana.PrivateKey) (solana.PublicKey, error) {
+	if privateKey == nil {
+		return solana.PublicKey{}, ErrPrivateKeyNotFound
+	}
+	return privateKey.PublicKey(), nil
+}
+
+func buildBatchRequest(transactions []*pb.TransactionMessage, privateKey solana.PrivateKey, useBundle bool, opts SubmitOpts, policy *transaction.Policy) (*pb.PostSubmitBatchRequest, error) {
 	batchRequest := pb.PostSubmitBatchRequest{}
 	batchRequest.SubmitStrategy = opts.SubmitStrategy
 
 	for _, tx := range transactions {
+		if err := verifyTx(tx.Content, privateKey, policy, opts.Policy, opts.request); err != nil {
+			return nil, err
+		}
 		request, err := createBatchRequestEntry(opts, tx.Content, privateKey)
 		if err != nil {
 			return nil, err
@@ -138,6 +188,45 @@ func buildBatchRequest(transactions []*pb.TransactionMessage, privateKey solana.
 	return &batchRequest, nil
 }
 
+// composedTx builds the transaction of a composer for the sign and submit path
+func composedTx(composer *transaction.Composer, blockHash string) ([]*pb.TransactionMessage, error) {
+	hash, err := solana.HashFromBase58(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	txBase64, err := composer.BuildBase64(hash)
+	if err != nil {
+		return nil, err
+	}
+	return []*pb.TransactionMessage{{Content: txBase64}}, nil
+}
+
+func builtTx(ctx context.Context, builder *transaction.TxBuilder) ([]*pb.TransactionMessage, error) {
+	txBase64, err := builder.BuildBase64(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []*pb.TransactionMessage{{Content: txBase64}}, nil
+}
+
+// verifyTx checks a transaction against each policy before it's signed, returning a *transaction.VerificationError
+// for a mismatch
+func verifyTx(txBase64 string, privateKey solana.PrivateKey, policies ...*transaction.Policy) error {
+	for _, policy := range policies {
+		if policy == nil {
+			continue
+		}
+		p := *policy
+		if p.Owner.IsZero() {
+			p.Owner = privateKey.PublicKey()
+		}
+		if _, err := transaction.VerifyTx(txBase64, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func createBatchRequestEntry(opts SubmitOpts, txBase64 string, privateKey solana.PrivateKey) (*pb.PostSubmitRequestEntry, error) {
 	oneRequest := pb.PostSubmitRequestEntry{}
 	if opts.SkipPreFlight == nil {
@@ -0,0 +1,59 @@
+package provider
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+// ErrDryRun is wrapped by the *DryRunError returned instead of submitting transactions in dry-run mode
+var ErrDryRun = errors.New("dry run: transactions were not submitted")
+
+// DryRunError holds what a Submit* method would have sent, with SubmitOpts.DryRun or RPCOpts.DryRun set. Since it's
+// returned as an error, callers must check for it with errors.As before handling other errors as failures. SubmitBatch
+// returns it before any attempt, so it's never retried, and queue jobs returning it are done.
+type DryRunError struct {
+	// Request is the signed request of PostSubmitBatch. Methods submitting a single transaction with SignAndSubmit
+	// would have sent its only entry to PostSubmit.
+	Request *pb.PostSubmitBatchRequest
+	// Transactions are the signed transactions of the entries
+	Transactions []*solana.Transaction
+}
+
+func (e *DryRunError) Error() string {
+	return fmt.Sprintf("%v: %v transaction(s)", ErrDryRun, len(e.Transactions))
+}
+
+func (e *DryRunError) Unwrap() error {
+	return ErrDryRun
+}
+
+// newDryRunError decodes the transactions of a request built in dry-run mode
+func newDryRunError(request *pb.PostSubmitBatchRequest) error {
+	dryRun := &DryRunError{Request: request}
+	for _, entry := range request.Entries {
+		data, err := base64.StdEncoding.DecodeString(entry.Transaction.Content)
+		if err != nil {
+			return fmt.Errorf("could not decode dry run transaction: %w", err)
+		}
+		tx, err := solana.TransactionFromDecoder(bin.NewBinDecoder(data))
+		if err != nil {
+			return fmt.Errorf("could not decode dry run transaction: %w", err)
+		}
+		dryRun.Transactions = append(dryRun.Transactions, tx)
+	}
+	return dryRun
+}
+
+// dryRunSubmit is the error of SignAndSubmit in dry-run mode, for a transaction already signed
+func dryRunSubmit(tx *pb.TransactionMessage, skipPreFlight bool) error {
+	useBundle := false
+	return newDryRunError(&pb.PostSubmitBatchRequest{
+		Entries:   []*pb.PostSubmitRequestEntry{{Transaction: tx, SkipPreFlight: skipPreFlight}},
+		UseBundle: &useBundle,
+	})
+}
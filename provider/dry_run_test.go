@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var testBlockHash = solana.MustHashFromBase58("A1xapHMk7Y9tj2NuVKw1ddKASsCce2M5EyD1xXo3RWr1")
+
+func unsignedTx(t *testing.T, owner solana.PublicKey, lamports uint64) *pb.TransactionMessage {
+	txBase64, err := transaction.NewTxBuilder(owner, nil).
+		WithBlockHash(testBlockHash).
+		AddInstructions(system.NewTransferInstruction(lamports, owner, solana.NewWallet().PublicKey()).Build()).
+		BuildBase64(context.Background())
+	require.Nil(t, err)
+	return &pb.TransactionMessage{Content: txBase64}
+}
+
+func requireDryRun(t *testing.T, err error) *DryRunError {
+	require.ErrorIs(t, err, ErrDryRun)
+	var dryRun *DryRunError
+	require.True(t, errors.As(err, &dryRun))
+	for _, tx := range dryRun.Transactions {
+		require.Nil(t, tx.VerifySignatures())
+	}
+	return dryRun
+}
+
+func TestDryRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %v", r.URL)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	owner := privateKey.PublicKey()
+	opts := RPCOpts{Endpoint: server.URL, PrivateKey: &privateKey}
+
+	h := NewHTTPClientWithOpts(nil, opts)
+	transactions := []*pb.TransactionMessage{unsignedTx(t, owner, 1), unsignedTx(t, owner, 2)}
+	resp, err := h.SignAndSubmitBatch(ctx, transactions, true, SubmitOpts{DryRun: true, SubmitStrategy: pb.SubmitStrategy_P_ABORT_ON_FIRST_ERROR})
+	assert.Nil(t, resp)
+	dryRun := requireDryRun(t, err)
+	require.Len(t, dryRun.Request.Entries, 2)
+	require.Len(t, dryRun.Transactions, 2)
+	assert.True(t, *dryRun.Request.UseBundle)
+	assert.True(t, dryRun.Request.Entries[0].SkipPreFlight)
+	assert.Equal(t, pb.SubmitStrategy_P_ABORT_ON_FIRST_ERROR, dryRun.Request.SubmitStrategy)
+	assert.Equal(t, owner, dryRun.Transactions[1].Message.AccountKeys[0])
+
+	// the policy is still checked
+	_, err = h.SignAndSubmitBatch(ctx, transactions, false, SubmitOpts{DryRun: true, Policy: &transaction.Policy{MaxInput: map[solana.PublicKey]uint64{solana.SolMint: 1}}})
+	require.ErrorIs(t, err, transaction.ErrUnsafeTransaction)
+
+	// client-wide, single transactions are signed for PostSubmit
+	opts.DryRun = true
+	h = NewHTTPClientWithOpts(nil, opts)
+	signature, err := h.SignAndSubmit(ctx, transactions[0], false, false, false)
+	assert.Empty(t, signature)
+	dryRun = requireDryRun(t, err)
+	require.Len(t, dryRun.Transactions, 1)
+	assert.False(t, dryRun.Request.Entries[0].SkipPreFlight)
+
+	// the websocket client signs PostSubmitV2 transactions itself, and doesn't send them either
+	w := &WSClient{privateKey: &privateKey, dryRun: true}
+	_, err = w.PostSubmitV2(ctx, transactions[0].Content, true, false, false)
+	dryRun = requireDryRun(t, err)
+	assert.True(t, dryRun.Request.Entries[0].SkipPreFlight)
+}
+
+func TestSubmitSwapInstructions(t *testing.T) {
+	program := solana.NewWallet().PublicKey()
+	var blockHashAvailable atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response []byte
+		var err error
+		switch r.URL.Path {
+		case "/api/v2/jupiter/swap-instructions":
+			response, err = protojson.Marshal(&pb.PostJupiterSwapInstructionsResponse{Instructions: []*pb.InstructionJupiter{
+				{ProgramID: program.String(), Data: []byte{1}},
+			}})
+		case "/api/v1/system/blockhash":
+			if !blockHashAvailable.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			response, err = protojson.Marshal(&pb.GetRecentBlockHashResponse{BlockHash: testBlockHash.String()})
+		default:
+			t.Errorf("unexpected request to %v", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		require.Nil(t, err)
+		_, _ = w.Write(response)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	h := NewHTTPClientWithOpts(nil, RPCOpts{Endpoint: server.URL, PrivateKey: &privateKey})
+	request := &pb.PostJupiterSwapInstructionsRequest{OwnerAddress: privateKey.PublicKey().String(), InToken: "SOL", OutToken: "USDC", InAmount: 0.01}
+
+	// a failed block hash fetch is returned
+	_, err = h.SubmitJupiterSwapInstructions(ctx, request, false, SubmitOpts{DryRun: true})
+	require.NotNil(t, err)
+	assert.NotErrorIs(t, err, ErrDryRun)
+
+	// the swap is built with the Trader API memo and the compute budget
+	blockHashAvailable.Store(true)
+	_, err = h.SubmitJupiterSwapInstructions(ctx, request, false, SubmitOpts{DryRun: true})
+	dryRun := requireDryRun(t, err)
+	require.Len(t, dryRun.Transactions, 1)
+	tx := dryRun.Transactions[0]
+	assert.Equal(t, testBlockHash, tx.Message.RecentBlockhash)
+	assert.Contains(t, tx.Message.AccountKeys, program)
+	assert.Contains(t, tx.Message.AccountKeys, transaction.TraderAPIMemoProgram)
+	assert.Contains(t, tx.Message.AccountKeys, solana.ComputeBudget)
+}
@@ -7,8 +7,8 @@ import (
 
 	package_info "github.com/bloXroute-Labs/solana-trader-client-go"
 	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/journal"
 	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
-	"github.com/bloXroute-Labs/solana-trader-client-go/utils"
 	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
 	"github.com/bloXroute-Labs/solana-trader-proto/common"
 	"github.com/gagliardetto/solana-go"
@@ -23,6 +23,10 @@ type GRPCClient struct {
 	apiClient pb.ApiClient
 
 	privateKey           *solana.PrivateKey
+	policy               *transaction.Policy
+	dryRun               bool
+	journal              *journal.Journal
+	accountFetcher       transaction.AccountFetcher
 	recentBlockHashStore *recentBlockHashStore
 }
 
@@ -100,8 +104,12 @@ func NewGRPCClientWithOpts(opts RPCOpts, dialOpts ...grpc.DialOption) (*GRPCClie
 	}
 
 	client := &GRPCClient{
-		apiClient:  pb.NewApiClient(conn),
-		privateKey: opts.PrivateKey,
+		apiClient:      pb.NewApiClient(conn),
+		privateKey:     opts.PrivateKey,
+		policy:         opts.Policy,
+		dryRun:         opts.DryRun,
+		journal:        opts.Journal,
+		accountFetcher: opts.AccountFetcher,
 	}
 
 	client.recentBlockHashStore = newRecentBlockHashStore(
@@ -176,20 +184,22 @@ func (g *GRPCClient) GetRaydiumPrices(ctx context.Context, request *pb.GetRaydiu
 
 // SubmitRaydiumCLMMSwap builds a Raydium Swap transaction then signs it, and submits to the network.
 func (g *GRPCClient) SubmitRaydiumCLMMSwap(ctx context.Context, request *pb.PostRaydiumSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = g.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
 	resp, err := g.PostRaydiumCLMMSwap(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	return g.signAndSubmitBatch(ctx, resp.Transactions, false, opts)
+	return g.SignAndSubmitBatch(ctx, resp.Transactions, false, opts)
 }
 
 // SubmitRaydiumCLMMRouteSwap builds a Raydium RouteSwap transaction then signs it, and submits to the network.
 func (g *GRPCClient) SubmitRaydiumCLMMRouteSwap(ctx context.Context, request *pb.PostRaydiumRouteSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = routePolicy(ctx, g.requests(), request.OwnerAddress, request.Tip, request.Steps)
 	resp, err := g.PostRaydiumCLMMRouteSwap(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	return g.signAndSubmitBatch(ctx, resp.Transactions, false, opts)
+	return g.SignAndSubmitBatch(ctx, resp.Transactions, false, opts)
 }
 
 // PostRaydiumSwap returns a partially signed transaction(s) for submitting a swap request on Raydium
@@ -307,37 +317,78 @@ func (g *GRPCClient) GetQuotes(ctx context.Context, inToken, outToken string, in
 	return g.apiClient.GetQuotes(ctx, &pb.GetQuotesRequest{InToken: inToken, OutToken: outToken, InAmount: inAmount, Slippage: slippage, Limit: limit, Projects: projects})
 }
 
+// PublicKey returns the address of the client's private key
+func (g *GRPCClient) PublicKey() (solana.PublicKey, error) {
+	return publicKey(g.privateKey)
+}
+
 // SignAndSubmit signs the given transaction and submits it.
 func (g *GRPCClient) SignAndSubmit(ctx context.Context, tx *pb.TransactionMessage,
 	skipPreFlight bool, frontRunningProtection bool, useStakedRPCs bool) (string, error) {
 	if g.privateKey == nil {
 		return "", ErrPrivateKeyNotFound
 	}
+	if err := verifyTx(tx.Content, *g.privateKey, g.policy); err != nil {
+		return "", err
+	}
 	txBase64, err := transaction.SignTxWithPrivateKey(tx.Content, *g.privateKey)
 	if err != nil {
 		return "", err
 	}
+	if g.dryRun {
+		return "", dryRunSubmit(&pb.TransactionMessage{Content: txBase64, IsCleanup: tx.IsCleanup}, skipPreFlight)
+	}
 
-	response, err := g.PostSubmit(ctx, &pb.TransactionMessage{
-		Content:   txBase64,
-		IsCleanup: tx.IsCleanup,
-	}, skipPreFlight, frontRunningProtection, useStakedRPCs)
-	if err != nil {
+	signed := &pb.TransactionMessage{Content: txBase64, IsCleanup: tx.IsCleanup}
+	return journalSubmit(ctx, g.journal, signed, skipPreFlight, frontRunningProtection, useStakedRPCs, func(ctx context.Context) (string, error) {
+		response, err := g.PostSubmit(ctx, signed, skipPreFlight, frontRunningProtection, useStakedRPCs)
+		if err != nil {
+			return "", err
+		}
+		return response.Signature, nil
+	})
+}
+
+// signAndSubmitRequest checks the transaction of a Submit* method against the policy derived from its parameters, then
+// signs and submits it
+func (g *GRPCClient) signAndSubmitRequest(ctx context.Context, tx *pb.TransactionMessage, skipPreFlight bool, policy *transaction.Policy) (string, error) {
+	if g.privateKey == nil {
+		return "", ErrPrivateKeyNotFound
+	}
+	if err := verifyTx(tx.Content, *g.privateKey, policy); err != nil {
 		return "", err
 	}
+	return g.SignAndSubmit(ctx, tx, skipPreFlight, false, false)
+}
 
-	return response.Signature, nil
+func (g *GRPCClient) requests() requestPolicy {
+	return requestPolicy{fetcher: g.accountFetcher}
 }
 
-// signAndSubmitBatch signs the given transactions and submits them.
-func (g *GRPCClient) signAndSubmitBatch(ctx context.Context, transactions []*pb.TransactionMessage, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+// SignAndSubmitBatch signs the given transactions and submits them.
+func (g *GRPCClient) SignAndSubmitBatch(ctx context.Context, transactions []*pb.TransactionMessage, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
 	if g.privateKey == nil {
 		return nil, ErrPrivateKeyNotFound
 	}
 
+	if opts.DryRun || g.dryRun {
+		batchRequest, err := buildBatchRequest(transactions, *g.privateKey, useBundle, opts, g.policy)
+		if err != nil {
+			return nil, err
+		}
+		return nil, newDryRunError(batchRequest)
+	}
+
 	if len(transactions) == 1 {
+		if err := verifyTx(transactions[0].Content, *g.privateKey, opts.Policy, opts.request); err != nil {
+			return nil, err
+		}
 		println("here")
-		signature, err := g.SignAndSubmit(ctx, transactions[0], *opts.SkipPreFlight, false, false)
+		skipPreFlight := true
+		if opts.SkipPreFlight != nil {
+			skipPreFlight = *opts.SkipPreFlight
+		}
+		signature, err := g.SignAndSubmit(ctx, transactions[0], skipPreFlight, false, false)
 		if err != nil {
 			return nil, err
 		}
@@ -352,12 +403,27 @@ func (g *GRPCClient) signAndSubmitBatch(ctx context.Context, transactions []*pb.
 		}, nil
 	}
 
-	batchRequest, err := buildBatchRequest(transactions, *g.privateKey, useBundle, opts)
+	batchRequest, err := buildBatchRequest(transactions, *g.privateKey, useBundle, opts, g.policy)
 	if err != nil {
 		return nil, err
 	}
 
-	return g.PostSubmitBatch(ctx, batchRequest)
+	return journalBatch(g.journal, g.PostSubmitBatch)(ctx, batchRequest)
+}
+
+// SubmitBatch signs and submits transactions, returning the outcome of each. Failed transactions are retried with a
+// fresh block hash up to BatchOpts.MaxRetries times.
+func (g *GRPCClient) SubmitBatch(ctx context.Context, transactions []*pb.TransactionMessage, opts BatchOpts) (*BatchResult, error) {
+	if g.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
+	}
+	return submitBatch(ctx, batchClient{
+		submit:     journalBatch(g.journal, g.PostSubmitBatch),
+		blockHash:  g.RecentBlockHash,
+		privateKey: *g.privateKey,
+		policy:     g.policy,
+		dryRun:     g.dryRun,
+	}, transactions, opts)
 }
 
 // PostTradeSwap returns a partially signed transaction for submitting a swap request
@@ -424,6 +490,7 @@ func (g *GRPCClient) PostSubmitBatchV2(ctx context.Context, request *pb.PostSubm
 
 // SubmitTradeSwap builds a TradeSwap transaction then signs it, and submits to the network.
 func (g *GRPCClient) SubmitTradeSwap(ctx context.Context, ownerAddress, inToken, outToken string, inAmount, slippage float64, project pb.Project, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = g.requests().swap(ctx, ownerAddress, inAmount, nil, inToken, outToken)
 	resp, err := g.apiClient.PostTradeSwap(ctx, &pb.TradeSwapRequest{
 		OwnerAddress: ownerAddress,
 		InToken:      inToken,
@@ -435,25 +502,27 @@ func (g *GRPCClient) SubmitTradeSwap(ctx context.Context, ownerAddress, inToken,
 	if err != nil {
 		return nil, err
 	}
-	return g.signAndSubmitBatch(ctx, resp.Transactions, false, opts)
+	return g.SignAndSubmitBatch(ctx, resp.Transactions, false, opts)
 }
 
 // SubmitRouteTradeSwap builds a RouteTradeSwap transaction then signs it, and submits to the network.
 func (g *GRPCClient) SubmitRouteTradeSwap(ctx context.Context, request *pb.RouteTradeSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = routePolicy(ctx, g.requests(), request.OwnerAddress, request.Tip, request.Steps)
 	resp, err := g.PostRouteTradeSwap(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	return g.signAndSubmitBatch(ctx, resp.Transactions, false, opts)
+	return g.SignAndSubmitBatch(ctx, resp.Transactions, false, opts)
 }
 
 // SubmitRaydiumSwap builds a Raydium Swap transaction then signs it, and submits to the network.
 func (g *GRPCClient) SubmitRaydiumSwap(ctx context.Context, request *pb.PostRaydiumSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = g.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
 	resp, err := g.PostRaydiumSwap(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	return g.signAndSubmitBatch(ctx, resp.Transactions, false, opts)
+	return g.SignAndSubmitBatch(ctx, resp.Transactions, false, opts)
 }
 
 // SubmitPostPumpFunSwap builds a pumpfun Swap transaction then signs it, and submits to the network.
@@ -462,9 +531,9 @@ func (g *GRPCClient) SubmitPostPumpFunSwap(ctx context.Context, request *pb.Post
 	if err != nil {
 		return "", err
 	}
-	return g.SignAndSubmit(ctx, &pb.TransactionMessage{
+	return g.signAndSubmitRequest(ctx, &pb.TransactionMessage{
 		Content: resp.Transaction.Content,
-	}, false, false, false)
+	}, false, g.requests().pumpFunSwap(ctx, request))
 }
 
 // SubmitRaydiumSwapCPMM builds a Raydium Swap transaction then signs it, and submits to the network.
@@ -474,7 +543,8 @@ func (g *GRPCClient) SubmitRaydiumSwapCPMM(ctx context.Context, request *pb.Post
 		return "", err
 	}
 
-	sig, err := g.SignAndSubmit(ctx, resp.Transaction, true, false, false)
+	policy := g.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
+	sig, err := g.signAndSubmitRequest(ctx, resp.Transaction, true, policy)
 	if err != nil {
 		return "", err
 	}
@@ -484,147 +554,98 @@ func (g *GRPCClient) SubmitRaydiumSwapCPMM(ctx context.Context, request *pb.Post
 
 // SubmitRaydiumRouteSwap builds a Raydium RouteSwap transaction then signs it, and submits to the network.
 func (g *GRPCClient) SubmitRaydiumRouteSwap(ctx context.Context, request *pb.PostRaydiumRouteSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = routePolicy(ctx, g.requests(), request.OwnerAddress, request.Tip, request.Steps)
 	resp, err := g.PostRaydiumRouteSwap(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	return g.signAndSubmitBatch(ctx, resp.Transactions, false, opts)
+	return g.SignAndSubmitBatch(ctx, resp.Transactions, false, opts)
 }
 
 // SubmitJupiterSwap builds a Jupiter Swap transaction then signs it, and submits to the network.
 func (g *GRPCClient) SubmitJupiterSwap(ctx context.Context, request *pb.PostJupiterSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = g.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
 	resp, err := g.PostJupiterSwap(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	return g.signAndSubmitBatch(ctx, resp.Transactions, false, opts)
+	return g.SignAndSubmitBatch(ctx, resp.Transactions, false, opts)
 }
 
 // SubmitJupiterSwapInstructions builds a Jupiter Swap transaction then signs it, and submits to the network.
 func (g *GRPCClient) SubmitJupiterSwapInstructions(ctx context.Context, request *pb.PostJupiterSwapInstructionsRequest, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
-	swapInstructions, err := g.PostJupiterSwapInstructions(ctx, request)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder := solana.NewTransactionBuilder()
-
-	addressLookupTable, err := utils.ConvertProtoAddressLookupTable(swapInstructions.AddressLookupTableAddresses)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder.WithOpt(solana.TransactionAddressTables(addressLookupTable))
-
-	instructions, err := utils.ConvertJupiterInstructions(swapInstructions.Instructions)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, inst := range instructions {
-		txBuilder.AddInstruction(inst)
-	}
-
-	txBuilder.SetFeePayer(g.privateKey.PublicKey())
-	blockHash, err := g.RecentBlockHash(ctx)
-
-	if err != nil {
-		panic(fmt.Errorf("server error: could not retrieve block hash: %w", err))
-	}
-
-	hash, err := solana.HashFromBase58(blockHash.BlockHash)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder.SetRecentBlockHash(hash)
-	tx, err := txBuilder.Build()
-	if err != nil {
-		return nil, err
+	if g.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
 	}
-
-	err = transaction.PartialSign(tx, g.privateKey.PublicKey(), make(map[solana.PublicKey]solana.PrivateKey))
+	opts.request = g.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
+	swapInstructions, err := g.PostJupiterSwapInstructions(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	var txToBeSigned []*pb.TransactionMessage
-
-	txBase64, err := tx.ToBase64()
+	transactions, err := builtTx(ctx, transaction.NewTxBuilder(g.privateKey.PublicKey(), g).AddJupiterSwap(swapInstructions))
 	if err != nil {
 		return nil, err
 	}
-
-	txToBeSigned = append(txToBeSigned, &pb.TransactionMessage{
-		Content:   txBase64,
-		IsCleanup: false,
-	})
-
-	return g.signAndSubmitBatch(ctx, txToBeSigned, useBundle, opts)
+	return g.SignAndSubmitBatch(ctx, transactions, useBundle, opts)
 }
 
 // SubmitRaydiumSwapInstructions builds a Raydium Swap transaction then signs it, and submits to the network.
 func (g *GRPCClient) SubmitRaydiumSwapInstructions(ctx context.Context, request *pb.PostRaydiumSwapInstructionsRequest, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if g.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
+	}
+	opts.request = g.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
 	swapInstructions, err := g.PostRaydiumSwapInstructions(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	instructions, err := utils.ConvertRaydiumInstructions(swapInstructions.Instructions)
+	transactions, err := builtTx(ctx, transaction.NewTxBuilder(g.privateKey.PublicKey(), g).AddRaydiumSwap(swapInstructions))
 	if err != nil {
 		return nil, err
 	}
-	txBuilder := solana.NewTransactionBuilder()
+	return g.SignAndSubmitBatch(ctx, transactions, useBundle, opts)
+}
 
-	for _, inst := range instructions {
-		txBuilder.AddInstruction(inst)
+// SubmitComposed builds the transaction of a composer, merging several swaps and instructions, then signs it and
+// submits it to the network.
+func (g *GRPCClient) SubmitComposed(ctx context.Context, composer *transaction.Composer, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if g.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
 	}
-
-	txBuilder.SetFeePayer(g.privateKey.PublicKey())
 	blockHash, err := g.RecentBlockHash(ctx)
-
-	if err != nil {
-		panic(fmt.Errorf("server error: could not retrieve block hash: %w", err))
-	}
-
-	hash, err := solana.HashFromBase58(blockHash.BlockHash)
 	if err != nil {
 		return nil, err
 	}
-
-	txBuilder.SetRecentBlockHash(hash)
-	tx, err := txBuilder.Build()
+	transactions, err := composedTx(composer, blockHash.BlockHash)
 	if err != nil {
 		return nil, err
 	}
+	return g.SignAndSubmitBatch(ctx, transactions, useBundle, opts)
+}
 
-	err = transaction.PartialSign(tx, g.privateKey.PublicKey(), make(map[solana.PublicKey]solana.PrivateKey))
-	if err != nil {
-		return nil, err
+// SubmitTxBuilder builds the transaction of a builder, with the Trader API memo, compute budget and tip, then signs it
+// and submits it to the network.
+func (g *GRPCClient) SubmitTxBuilder(ctx context.Context, builder *transaction.TxBuilder, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if g.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
 	}
-
-	var txToBeSigned []*pb.TransactionMessage
-
-	txBase64, err := tx.ToBase64()
+	transactions, err := builtTx(ctx, builder)
 	if err != nil {
 		return nil, err
 	}
-
-	txToBeSigned = append(txToBeSigned, &pb.TransactionMessage{
-		Content:   txBase64,
-		IsCleanup: false,
-	})
-
-	return g.signAndSubmitBatch(ctx, txToBeSigned, useBundle, opts)
+	return g.SignAndSubmitBatch(ctx, transactions, useBundle, opts)
 }
 
 // SubmitJupiterRouteSwap builds a Jupiter RouteSwap transaction then signs it, and submits to the network.
 func (g *GRPCClient) SubmitJupiterRouteSwap(ctx context.Context, request *pb.PostJupiterRouteSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = routePolicy(ctx, g.requests(), request.OwnerAddress, request.Tip, request.Steps)
 	resp, err := g.PostJupiterRouteSwap(ctx, request)
 	if err != nil {
 		return nil, err
 	}
-	return g.signAndSubmitBatch(ctx, resp.Transactions, false, opts)
+	return g.SignAndSubmitBatch(ctx, resp.Transactions, false, opts)
 }
 
 // SubmitOrder builds a Serum market order, signs it, and submits to the network.
@@ -637,7 +658,7 @@ func (g *GRPCClient) SubmitOrder(ctx context.Context, owner, payer, market strin
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	return g.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return g.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, g.requests().owner(owner))
 }
 
 // PostCancelOrder builds a Serum cancel order.
@@ -676,7 +697,7 @@ func (g *GRPCClient) SubmitCancelOrder(
 		return "", err
 	}
 
-	return g.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return g.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, g.requests().owner(owner))
 }
 
 // PostCancelByClientOrderID builds a Serum cancel order by client ID.
@@ -713,7 +734,7 @@ func (g *GRPCClient) SubmitCancelByClientOrderID(
 		return "", err
 	}
 
-	return g.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return g.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, g.requests().owner(owner))
 }
 
 func (g *GRPCClient) PostCancelAll(ctx context.Context, market, owner string, openOrders []string, project pb.Project) (*pb.PostCancelAllResponse, error) {
@@ -726,11 +747,12 @@ func (g *GRPCClient) PostCancelAll(ctx context.Context, market, owner string, op
 }
 
 func (g *GRPCClient) SubmitCancelAll(ctx context.Context, market, owner string, openOrdersAddresses []string, project pb.Project, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = g.requests().owner(owner)
 	orders, err := g.PostCancelAll(ctx, market, owner, openOrdersAddresses, project)
 	if err != nil {
 		return nil, err
 	}
-	return g.signAndSubmitBatch(ctx, orders.Transactions, false, opts)
+	return g.SignAndSubmitBatch(ctx, orders.Transactions, false, opts)
 }
 
 // PostSettle returns a partially signed transaction for settling market funds. Typically, you want to use SubmitSettle instead of this.
@@ -752,7 +774,7 @@ func (g *GRPCClient) SubmitSettle(ctx context.Context, owner, market, baseTokenW
 		return "", err
 	}
 
-	return g.SignAndSubmit(ctx, order.Transaction, skipPreflight, false, false)
+	return g.signAndSubmitRequest(ctx, order.Transaction, skipPreflight, g.requests().owner(owner))
 }
 
 func (g *GRPCClient) PostReplaceByClientOrderID(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts PostOrderOpts) (*pb.PostOrderResponse, error) {
@@ -779,7 +801,7 @@ func (g *GRPCClient) SubmitReplaceByClientOrderID(ctx context.Context, owner, pa
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	return g.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return g.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, g.requests().owner(owner))
 }
 
 func (g *GRPCClient) PostReplaceOrder(ctx context.Context, orderID, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts PostOrderOpts) (*pb.PostOrderResponse, error) {
@@ -807,7 +829,7 @@ func (g *GRPCClient) SubmitReplaceOrder(ctx context.Context, orderID, owner, pay
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	return g.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return g.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, g.requests().owner(owner))
 }
 
 // GetOrderbookStream subscribes to a stream for changes to the requested market updates (e.g. asks and bids. Set limit to 0 for all bids/ asks).
@@ -1090,7 +1112,7 @@ func (g *GRPCClient) SubmitOrderV2(ctx context.Context, owner, payer, market str
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	return g.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return g.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, g.requests().owner(owner))
 }
 
 // SubmitOrderV2WithPriorityFee builds a Serum market order, signs it, and submits to the network with specified computeLimit and computePrice
@@ -1105,7 +1127,7 @@ func (g *GRPCClient) SubmitOrderV2WithPriorityFee(ctx context.Context, owner, pa
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	return g.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return g.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, g.requests().owner(owner))
 }
 
 // PostCancelOrderV2 builds a Serum cancel order.
@@ -1139,12 +1161,13 @@ func (g *GRPCClient) SubmitCancelOrderV2(
 	openOrders string,
 	opts SubmitOpts,
 ) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = g.requests().owner(owner)
 	order, err := g.PostCancelOrderV2(ctx, orderID, clientOrderID, side, owner, market, openOrders)
 	if err != nil {
 		return nil, err
 	}
 
-	return g.signAndSubmitBatch(ctx, order.Transactions, false, opts)
+	return g.SignAndSubmitBatch(ctx, order.Transactions, false, opts)
 }
 
 // PostSettleV2 returns a partially signed transaction for settling market funds. Typically, you want to use SubmitSettle instead of this.
@@ -1165,7 +1188,7 @@ func (g *GRPCClient) SubmitSettleV2(ctx context.Context, owner, market, baseToke
 		return "", err
 	}
 
-	return g.SignAndSubmit(ctx, order.Transaction, skipPreflight, false, false)
+	return g.signAndSubmitRequest(ctx, order.Transaction, skipPreflight, g.requests().owner(owner))
 }
 
 func (g *GRPCClient) PostReplaceOrderV2(ctx context.Context, orderID, owner, payer, market string, side string, orderType string, amount, price float64, opts PostOrderOpts) (*pb.PostOrderResponse, error) {
@@ -1192,5 +1215,5 @@ func (g *GRPCClient) SubmitReplaceOrderV2(ctx context.Context, orderID, owner, p
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	return g.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return g.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, g.requests().owner(owner))
 }
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/journal"
 	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
 	"github.com/bloXroute-Labs/solana-trader-client-go/utils"
 	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
@@ -16,11 +18,22 @@ import (
 type HTTPClient struct {
 	pb.UnimplementedApiServer
 
-	baseURL    string
-	httpClient *http.Client
-	requestID  utils.RequestID
-	privateKey *solana.PrivateKey
-	authHeader string
+	baseURL        string
+	httpClient     *http.Client
+	requestID      utils.RequestID
+	privateKey     *solana.PrivateKey
+	policy         *transaction.Policy
+	dryRun         bool
+	journal        *journal.Journal
+	accountFetcher transaction.AccountFetcher
+	authHeader     string
+
+	streamMode     HTTPStreamMode
+	pollOpts       connections.PollOpts
+	sseEndpoint    string
+	sseUnsupported *utils.LockedMap[string, bool]
+
+	recentBlockHashStore *recentBlockHashStore
 }
 
 // NewHTTPClient connects to Mainnet Trader API
@@ -60,12 +73,39 @@ func NewHTTPClientWithOpts(client *http.Client, opts RPCOpts) *HTTPClient {
 		client = &http.Client{}
 	}
 
-	return &HTTPClient{
-		baseURL:    opts.Endpoint,
-		httpClient: client,
-		privateKey: opts.PrivateKey,
-		authHeader: opts.AuthHeader,
+	pollOpts := opts.HTTPPollOpts
+	if pollOpts == (connections.PollOpts{}) {
+		pollOpts = connections.DefaultPollOpts()
+	}
+
+	h := &HTTPClient{
+		baseURL:        opts.Endpoint,
+		httpClient:     client,
+		privateKey:     opts.PrivateKey,
+		policy:         opts.Policy,
+		dryRun:         opts.DryRun,
+		journal:        opts.Journal,
+		accountFetcher: opts.AccountFetcher,
+		authHeader:     opts.AuthHeader,
+		streamMode:     opts.HTTPStreamMode,
+		pollOpts:       pollOpts,
+		sseEndpoint:    strings.TrimSuffix(opts.HTTPSSEEndpoint, "/"),
+		sseUnsupported: utils.NewLockedMap[string, bool](),
+	}
+	h.recentBlockHashStore = newRecentBlockHashStore(
+		h.GetRecentBlockHash,
+		h.GetRecentBlockHashStream,
+		opts,
+	)
+	if opts.CacheBlockHash {
+		go h.recentBlockHashStore.run(context.Background())
 	}
+	return h
+}
+
+// RecentBlockHash returns a recent block hash from the client's store, kept up to date when RPCOpts.CacheBlockHash is set
+func (h *HTTPClient) RecentBlockHash(ctx context.Context) (*pb.GetRecentBlockHashResponse, error) {
+	return h.recentBlockHashStore.get(ctx)
 }
 
 // GetRaydiumCLMMQuotes returns the CLMM quotes on Raydium
@@ -210,6 +250,7 @@ func (h *HTTPClient) GetRaydiumPrices(ctx context.Context, request *pb.GetRaydiu
 
 // SubmitRaydiumCLMMSwap builds a Raydium CLMM Swap transaction then signs it, and submits to the network.
 func (h *HTTPClient) SubmitRaydiumCLMMSwap(ctx context.Context, request *pb.PostRaydiumSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = h.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
 	resp, err := h.PostRaydiumCLMMSwap(ctx, request)
 	if err != nil {
 		return nil, err
@@ -219,6 +260,7 @@ func (h *HTTPClient) SubmitRaydiumCLMMSwap(ctx context.Context, request *pb.Post
 
 // SubmitRaydiumCLMMRouteSwap builds a Raydium CLMM RouteSwap transaction then signs it, and submits to the network.
 func (h *HTTPClient) SubmitRaydiumCLMMRouteSwap(ctx context.Context, request *pb.PostRaydiumRouteSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = routePolicy(ctx, h.requests(), request.OwnerAddress, request.Tip, request.Steps)
 	resp, err := h.PostRaydiumCLMMRouteSwap(ctx, request)
 	if err != nil {
 		return nil, err
@@ -555,23 +597,52 @@ func (h *HTTPClient) PostSubmitBatchV2(ctx context.Context, request *pb.PostSubm
 	return &response, nil
 }
 
+// PublicKey returns the address of the client's private key
+func (h *HTTPClient) PublicKey() (solana.PublicKey, error) {
+	return publicKey(h.privateKey)
+}
+
 // SignAndSubmit signs the given transaction and submits it.
 func (h *HTTPClient) SignAndSubmit(ctx context.Context, tx *pb.TransactionMessage,
 	skipPreFlight bool, frontRunningProtection bool, useStakedRPCs bool) (string, error) {
 	if h.privateKey == nil {
 		return "", ErrPrivateKeyNotFound
 	}
+	if err := verifyTx(tx.Content, *h.privateKey, h.policy); err != nil {
+		return "", err
+	}
 	txBase64, err := transaction.SignTxWithPrivateKey(tx.Content, *h.privateKey)
 	if err != nil {
 		return "", err
 	}
+	if h.dryRun {
+		return "", dryRunSubmit(&pb.TransactionMessage{Content: txBase64, IsCleanup: tx.IsCleanup}, skipPreFlight)
+	}
 
-	response, err := h.PostSubmit(ctx, txBase64, skipPreFlight, frontRunningProtection, useStakedRPCs)
-	if err != nil {
+	signed := &pb.TransactionMessage{Content: txBase64, IsCleanup: tx.IsCleanup}
+	return journalSubmit(ctx, h.journal, signed, skipPreFlight, frontRunningProtection, useStakedRPCs, func(ctx context.Context) (string, error) {
+		response, err := h.PostSubmit(ctx, txBase64, skipPreFlight, frontRunningProtection, useStakedRPCs)
+		if err != nil {
+			return "", err
+		}
+		return response.Signature, nil
+	})
+}
+
+// signAndSubmitRequest checks the transaction of a Submit* method against the policy derived from its parameters, then
+// signs and submits it
+func (h *HTTPClient) signAndSubmitRequest(ctx context.Context, tx *pb.TransactionMessage, skipPreFlight bool, policy *transaction.Policy) (string, error) {
+	if h.privateKey == nil {
+		return "", ErrPrivateKeyNotFound
+	}
+	if err := verifyTx(tx.Content, *h.privateKey, policy); err != nil {
 		return "", err
 	}
+	return h.SignAndSubmit(ctx, tx, skipPreFlight, false, false)
+}
 
-	return response.Signature, nil
+func (h *HTTPClient) requests() requestPolicy {
+	return requestPolicy{fetcher: h.accountFetcher}
 }
 
 // SignAndSubmitBatch signs the given transactions and submits them.
@@ -581,8 +652,23 @@ func (h *HTTPClient) SignAndSubmitBatch(ctx context.Context, transactions []*pb.
 		return nil, ErrPrivateKeyNotFound
 	}
 
+	if opts.DryRun || h.dryRun {
+		batchRequest, err := buildBatchRequest(transactions, *h.privateKey, useBundle, opts, h.policy)
+		if err != nil {
+			return nil, err
+		}
+		return nil, newDryRunError(batchRequest)
+	}
+
 	if len(transactions) == 1 {
-		signature, err := h.SignAndSubmit(ctx, transactions[0], *opts.SkipPreFlight, false, false)
+		if err := verifyTx(transactions[0].Content, *h.privateKey, opts.Policy, opts.request); err != nil {
+			return nil, err
+		}
+		skipPreFlight := true
+		if opts.SkipPreFlight != nil {
+			skipPreFlight = *opts.SkipPreFlight
+		}
+		signature, err := h.SignAndSubmit(ctx, transactions[0], skipPreFlight, false, false)
 		if err != nil {
 			return nil, err
 		}
@@ -597,11 +683,26 @@ func (h *HTTPClient) SignAndSubmitBatch(ctx context.Context, transactions []*pb.
 		}, nil
 	}
 
-	batchRequest, err := buildBatchRequest(transactions, *h.privateKey, useBundle, opts)
+	batchRequest, err := buildBatchRequest(transactions, *h.privateKey, useBundle, opts, h.policy)
 	if err != nil {
 		return nil, err
 	}
-	return h.PostSubmitBatch(ctx, batchRequest)
+	return journalBatch(h.journal, h.PostSubmitBatch)(ctx, batchRequest)
+}
+
+// SubmitBatch signs and submits transactions, returning the outcome of each. Failed transactions are retried with a
+// fresh block hash up to BatchOpts.MaxRetries times.
+func (h *HTTPClient) SubmitBatch(ctx context.Context, transactions []*pb.TransactionMessage, opts BatchOpts) (*BatchResult, error) {
+	if h.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
+	}
+	return submitBatch(ctx, batchClient{
+		submit:     journalBatch(h.journal, h.PostSubmitBatch),
+		blockHash:  h.RecentBlockHash,
+		privateKey: *h.privateKey,
+		policy:     h.policy,
+		dryRun:     h.dryRun,
+	}, transactions, opts)
 }
 
 // PostTradeSwap returns a partially signed transaction for submitting a swap request
@@ -626,6 +727,7 @@ func (h *HTTPClient) PostTradeSwap(ctx context.Context, ownerAddress, inToken, o
 
 // SubmitTradeSwap builds a TradeSwap transaction then signs it, and submits to the network.
 func (h *HTTPClient) SubmitTradeSwap(ctx context.Context, owner, inToken, outToken string, inAmount, slippage float64, project pb.Project, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = h.requests().swap(ctx, owner, inAmount, nil, inToken, outToken)
 	resp, err := h.PostTradeSwap(ctx, owner, inToken, outToken, inAmount, slippage, project)
 	if err != nil {
 		return nil, err
@@ -647,6 +749,7 @@ func (h *HTTPClient) PostRouteTradeSwap(ctx context.Context, request *pb.RouteTr
 
 // SubmitRouteTradeSwap builds a RouteTradeSwap transaction then signs it, and submits to the network.
 func (h *HTTPClient) SubmitRouteTradeSwap(ctx context.Context, request *pb.RouteTradeSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = routePolicy(ctx, h.requests(), request.OwnerAddress, request.Tip, request.Steps)
 	resp, err := h.PostRouteTradeSwap(ctx, request)
 	if err != nil {
 		return nil, err
@@ -656,6 +759,7 @@ func (h *HTTPClient) SubmitRouteTradeSwap(ctx context.Context, request *pb.Route
 
 // SubmitRaydiumSwap builds a Raydium Swap transaction then signs it, and submits to the network.
 func (h *HTTPClient) SubmitRaydiumSwap(ctx context.Context, request *pb.PostRaydiumSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = h.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
 	resp, err := h.PostRaydiumSwap(ctx, request)
 	if err != nil {
 		return nil, err
@@ -670,7 +774,8 @@ func (h *HTTPClient) SubmitRaydiumSwapCPMM(ctx context.Context, request *pb.Post
 		return "", err
 	}
 
-	sig, err := h.SignAndSubmit(ctx, resp.Transaction, true, false, false)
+	policy := h.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
+	sig, err := h.signAndSubmitRequest(ctx, resp.Transaction, true, policy)
 	if err != nil {
 		return "", err
 	}
@@ -684,13 +789,14 @@ func (h *HTTPClient) SubmitPostPumpFunSwap(ctx context.Context, request *pb.Post
 	if err != nil {
 		return "", err
 	}
-	return h.SignAndSubmit(ctx, &pb.TransactionMessage{
+	return h.signAndSubmitRequest(ctx, &pb.TransactionMessage{
 		Content: resp.Transaction.Content,
-	}, false, false, false)
+	}, false, h.requests().pumpFunSwap(ctx, request))
 }
 
 // SubmitRaydiumRouteSwap builds a Raydium RouteSwap transaction then signs it, and submits to the network.
 func (h *HTTPClient) SubmitRaydiumRouteSwap(ctx context.Context, request *pb.PostRaydiumRouteSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = routePolicy(ctx, h.requests(), request.OwnerAddress, request.Tip, request.Steps)
 	resp, err := h.PostRaydiumRouteSwap(ctx, request)
 	if err != nil {
 		return nil, err
@@ -700,6 +806,7 @@ func (h *HTTPClient) SubmitRaydiumRouteSwap(ctx context.Context, request *pb.Pos
 
 // SubmitJupiterSwap builds a Jupiter Swap transaction then signs it, and submits to the network.
 func (h *HTTPClient) SubmitJupiterSwap(ctx context.Context, request *pb.PostJupiterSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = h.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
 	resp, err := h.PostJupiterSwap(ctx, request)
 	if err != nil {
 		return nil, err
@@ -709,124 +816,73 @@ func (h *HTTPClient) SubmitJupiterSwap(ctx context.Context, request *pb.PostJupi
 
 // SubmitJupiterSwapInstructions builds a Jupiter Swap transaction then signs it, and submits to the network.
 func (h *HTTPClient) SubmitJupiterSwapInstructions(ctx context.Context, request *pb.PostJupiterSwapInstructionsRequest, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
-	swapInstructions, err := h.PostJupiterSwapInstructions(ctx, request)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder := solana.NewTransactionBuilder()
-
-	addressLookupTable, err := utils.ConvertProtoAddressLookupTable(swapInstructions.AddressLookupTableAddresses)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder.WithOpt(solana.TransactionAddressTables(addressLookupTable))
-
-	instructions, err := utils.ConvertJupiterInstructions(swapInstructions.Instructions)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, inst := range instructions {
-		txBuilder.AddInstruction(inst)
-	}
-
-	txBuilder.SetFeePayer(h.privateKey.PublicKey())
-	blockHash, err := h.GetRecentBlockHash(ctx)
-
-	if err != nil {
-		panic(fmt.Errorf("server error: could not retrieve block hash: %w", err))
-	}
-
-	hash, err := solana.HashFromBase58(blockHash.BlockHash)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder.SetRecentBlockHash(hash)
-	tx, err := txBuilder.Build()
-	if err != nil {
-		return nil, err
+	if h.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
 	}
-
-	err = transaction.PartialSign(tx, h.privateKey.PublicKey(), make(map[solana.PublicKey]solana.PrivateKey))
+	opts.request = h.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
+	swapInstructions, err := h.PostJupiterSwapInstructions(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	var txToBeSigned []*pb.TransactionMessage
-
-	txBase64, err := tx.ToBase64()
+	transactions, err := builtTx(ctx, transaction.NewTxBuilder(h.privateKey.PublicKey(), h).AddJupiterSwap(swapInstructions))
 	if err != nil {
 		return nil, err
 	}
-
-	txToBeSigned = append(txToBeSigned, &pb.TransactionMessage{
-		Content:   txBase64,
-		IsCleanup: false,
-	})
-
-	return h.SignAndSubmitBatch(ctx, txToBeSigned, useBundle, opts)
+	return h.SignAndSubmitBatch(ctx, transactions, useBundle, opts)
 }
 
 // SubmitRaydiumSwapInstructions builds a Raydium Swap transaction then signs it, and submits to the network.
 func (h *HTTPClient) SubmitRaydiumSwapInstructions(ctx context.Context, request *pb.PostRaydiumSwapInstructionsRequest, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if h.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
+	}
+	opts.request = h.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
 	swapInstructions, err := h.PostRaydiumSwapInstructions(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	instructions, err := utils.ConvertRaydiumInstructions(swapInstructions.Instructions)
+	transactions, err := builtTx(ctx, transaction.NewTxBuilder(h.privateKey.PublicKey(), h).AddRaydiumSwap(swapInstructions))
 	if err != nil {
 		return nil, err
 	}
+	return h.SignAndSubmitBatch(ctx, transactions, useBundle, opts)
+}
 
-	txBuilder := solana.NewTransactionBuilder()
-	for _, inst := range instructions {
-		txBuilder.AddInstruction(inst)
+// SubmitComposed builds the transaction of a composer, merging several swaps and instructions, then signs it and
+// submits it to the network.
+func (h *HTTPClient) SubmitComposed(ctx context.Context, composer *transaction.Composer, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if h.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
 	}
-
-	txBuilder.SetFeePayer(h.privateKey.PublicKey())
 	blockHash, err := h.GetRecentBlockHash(ctx)
-
-	if err != nil {
-		panic(fmt.Errorf("server error: could not retrieve block hash: %w", err))
-	}
-
-	hash, err := solana.HashFromBase58(blockHash.BlockHash)
 	if err != nil {
 		return nil, err
 	}
-
-	txBuilder.SetRecentBlockHash(hash)
-	tx, err := txBuilder.Build()
+	transactions, err := composedTx(composer, blockHash.BlockHash)
 	if err != nil {
 		return nil, err
 	}
+	return h.SignAndSubmitBatch(ctx, transactions, useBundle, opts)
+}
 
-	err = transaction.PartialSign(tx, h.privateKey.PublicKey(), make(map[solana.PublicKey]solana.PrivateKey))
-	if err != nil {
-		return nil, err
+// SubmitTxBuilder builds the transaction of a builder, with the Trader API memo, compute budget and tip, then signs it
+// and submits it to the network.
+func (h *HTTPClient) SubmitTxBuilder(ctx context.Context, builder *transaction.TxBuilder, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if h.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
 	}
-
-	var txToBeSigned []*pb.TransactionMessage
-
-	txBase64, err := tx.ToBase64()
+	transactions, err := builtTx(ctx, builder)
 	if err != nil {
 		return nil, err
 	}
-
-	txToBeSigned = append(txToBeSigned, &pb.TransactionMessage{
-		Content:   txBase64,
-		IsCleanup: false,
-	})
-
-	return h.SignAndSubmitBatch(ctx, txToBeSigned, useBundle, opts)
+	return h.SignAndSubmitBatch(ctx, transactions, useBundle, opts)
 }
 
 // SubmitJupiterRouteSwap builds a Jupiter RouteSwap transaction then signs it, and submits to the network.
 func (h *HTTPClient) SubmitJupiterRouteSwap(ctx context.Context, request *pb.PostJupiterRouteSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = routePolicy(ctx, h.requests(), request.OwnerAddress, request.Tip, request.Steps)
 	resp, err := h.PostJupiterRouteSwap(ctx, request)
 	if err != nil {
 		return nil, err
@@ -869,7 +925,7 @@ func (h *HTTPClient) SubmitOrder(ctx context.Context, owner, payer, market strin
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	sig, err := h.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	sig, err := h.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, h.requests().owner(owner))
 	if err != nil {
 		return "", err
 	}
@@ -921,7 +977,7 @@ func (h *HTTPClient) SubmitCancelOrder(
 		return "", err
 	}
 
-	return h.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return h.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, h.requests().owner(owner))
 }
 
 // PostCancelByClientOrderID builds a Serum cancel order by client ID.
@@ -966,7 +1022,7 @@ func (h *HTTPClient) SubmitCancelByClientOrderID(
 		return "", err
 	}
 
-	return h.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return h.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, h.requests().owner(owner))
 }
 
 func (h *HTTPClient) PostCancelAll(ctx context.Context, market, owner string, openOrdersAddresses []string, project pb.Project) (*pb.PostCancelAllResponse, error) {
@@ -988,6 +1044,7 @@ func (h *HTTPClient) PostCancelAll(ctx context.Context, market, owner string, op
 }
 
 func (h *HTTPClient) SubmitCancelAll(ctx context.Context, market, owner string, openOrders []string, project pb.Project, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = h.requests().owner(owner)
 	orders, err := h.PostCancelAll(ctx, market, owner, openOrders, project)
 	if err != nil {
 		return nil, err
@@ -1022,7 +1079,7 @@ func (h *HTTPClient) SubmitSettle(ctx context.Context, owner, market, baseTokenW
 		return "", err
 	}
 
-	return h.SignAndSubmit(ctx, order.Transaction, skipPreflight, false, false)
+	return h.signAndSubmitRequest(ctx, order.Transaction, skipPreflight, h.requests().owner(owner))
 }
 
 func (h *HTTPClient) PostReplaceByClientOrderID(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts PostOrderOpts) (*pb.PostOrderResponse, error) {
@@ -1057,7 +1114,7 @@ func (h *HTTPClient) SubmitReplaceByClientOrderID(ctx context.Context, owner, pa
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	return h.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return h.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, h.requests().owner(owner))
 }
 
 func (h *HTTPClient) PostReplaceOrder(ctx context.Context, orderID, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts PostOrderOpts) (*pb.PostOrderResponse, error) {
@@ -1093,7 +1150,7 @@ func (h *HTTPClient) SubmitReplaceOrder(ctx context.Context, orderID, owner, pay
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	return h.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return h.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, h.requests().owner(owner))
 }
 
 // GetRecentBlockHash returns recent block hash.
@@ -1263,7 +1320,7 @@ func (h *HTTPClient) SubmitOrderV2(ctx context.Context, owner, payer, market str
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	sig, err := h.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	sig, err := h.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, h.requests().owner(owner))
 	return sig, err
 }
 
@@ -1279,7 +1336,7 @@ func (h *HTTPClient) SubmitOrderV2WithPriorityFee(ctx context.Context, owner, pa
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	sig, err := h.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	sig, err := h.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, h.requests().owner(owner))
 	return sig, err
 }
 
@@ -1323,6 +1380,7 @@ func (h *HTTPClient) SubmitCancelOrderV2(
 	openOrders string,
 	opts SubmitOpts,
 ) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = h.requests().owner(owner)
 	order, err := h.PostCancelOrderV2(ctx, orderID, clientOrderID, side, owner, market, openOrders)
 	if err != nil {
 		return nil, err
@@ -1357,7 +1415,7 @@ func (h *HTTPClient) SubmitSettleV2(ctx context.Context, owner, market, baseToke
 		return "", err
 	}
 
-	return h.SignAndSubmit(ctx, order.Transaction, skipPreflight, false, false)
+	return h.signAndSubmitRequest(ctx, order.Transaction, skipPreflight, h.requests().owner(owner))
 }
 
 func (h *HTTPClient) PostReplaceOrderV2(ctx context.Context, orderID, owner, payer, market string, side string, orderType string, amount, price float64, opts PostOrderOpts) (*pb.PostOrderResponse, error) {
@@ -1392,7 +1450,7 @@ func (h *HTTPClient) SubmitReplaceOrderV2(ctx context.Context, orderID, owner, p
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	return h.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return h.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, h.requests().owner(owner))
 }
 
 type stringable interface {
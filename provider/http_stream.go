@@ -0,0 +1,485 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// HTTPStreamMode selects how HTTPClient streams are delivered
+type HTTPStreamMode int
+
+const (
+	// HTTPStreamAuto uses server-sent events when RPCOpts.HTTPSSEEndpoint is set and supports a stream, and polling
+	// otherwise
+	HTTPStreamAuto HTTPStreamMode = iota
+	// HTTPStreamSSE only uses server-sent events, from RPCOpts.HTTPSSEEndpoint
+	HTTPStreamSSE
+	// HTTPStreamPoll only uses polling of the equivalent request/response endpoints
+	HTTPStreamPoll
+)
+
+var (
+	ErrNoSSEEndpoint         = errors.New("server-sent events need RPCOpts.HTTPSSEEndpoint")
+	ErrHTTPStreamUnsupported = errors.New("stream has no polling equivalent and needs server-sent events: use a websocket or GRPC client instead")
+)
+
+// httpStream opens the named stream over server-sent events, falling back to polling according to the client's stream
+// mode. Streams without a polling equivalent pass a nil spec and fail if server-sent events are unavailable.
+func httpStream[T proto.Message](
+	ctx context.Context,
+	h *HTTPClient,
+	streamName string,
+	streamParams proto.Message,
+	resultInitFn func() T,
+	spec *connections.PollSpec[T],
+) (connections.Streamer[T], error) {
+	if h.streamMode == HTTPStreamSSE && h.sseEndpoint == "" {
+		return nil, ErrNoSSEEndpoint
+	}
+	_, unsupported := h.sseUnsupported.Get(streamName)
+	if h.sseEndpoint != "" && (h.streamMode == HTTPStreamSSE || (h.streamMode == HTTPStreamAuto && !unsupported)) {
+		url := fmt.Sprintf("%s/%s", h.sseEndpoint, streamName)
+		stream, err := connections.HTTPStreamSSE[T](ctx, url, h.httpClient, streamParams, h.authHeader, resultInitFn)
+		if err == nil || !errors.Is(err, connections.ErrSSEUnsupported) || h.streamMode == HTTPStreamSSE {
+			return stream, err
+		}
+		h.sseUnsupported.Set(streamName, true)
+	}
+
+	if spec == nil {
+		return nil, fmt.Errorf("%v: %w", streamName, ErrHTTPStreamUnsupported)
+	}
+	return connections.PollStream[T](ctx, *spec, h.pollOpts), nil
+}
+
+// GetPricesStream subscribes to a stream for getting recent prices of tokens of interest. When polling, only price
+// changes are emitted.
+func (h *HTTPClient) GetPricesStream(ctx context.Context, projects []pb.Project, tokens []string) (connections.Streamer[*pb.GetPricesStreamResponse], error) {
+	request := &pb.GetPricesStreamRequest{
+		Projects: projects,
+		Tokens:   tokens,
+	}
+	spec := &connections.PollSpec[*pb.GetPricesStreamResponse]{
+		Poll: func(ctx context.Context) ([]*pb.GetPricesStreamResponse, error) {
+			prices, err := h.GetPrice(ctx, tokens)
+			if err != nil {
+				return nil, err
+			}
+
+			now := timestamppb.Now()
+			var updates []*pb.GetPricesStreamResponse
+			for _, price := range prices.TokenPrices {
+				if !projectRequested(projects, price.Project) {
+					continue
+				}
+				updates = append(updates, &pb.GetPricesStreamResponse{Price: price, Timestamp: now})
+			}
+			return updates, nil
+		},
+		Key: func(v *pb.GetPricesStreamResponse) string {
+			return v.Price.TokenAddress + "/" + v.Price.Project.String()
+		},
+		Equal: func(a, b *pb.GetPricesStreamResponse) bool {
+			return proto.Equal(a.Price, b.Price)
+		},
+	}
+	return httpStream(ctx, h, "GetPricesStream", request, func() *pb.GetPricesStreamResponse {
+		return &pb.GetPricesStreamResponse{}
+	}, spec)
+}
+
+// GetOrderbooksStream subscribes to a stream for changes to the requested market updates (e.g. asks and bids. Set limit to 0 for all bids/ asks).
+func (h *HTTPClient) GetOrderbooksStream(ctx context.Context, markets []string, limit uint32, project pb.Project) (connections.Streamer[*pb.GetOrderbooksStreamResponse], error) {
+	request := &pb.GetOrderbooksRequest{
+		Markets: markets,
+		Limit:   limit,
+		Project: project,
+	}
+	spec := &connections.PollSpec[*pb.GetOrderbooksStreamResponse]{
+		Poll: func(ctx context.Context) ([]*pb.GetOrderbooksStreamResponse, error) {
+			now := timestamppb.Now()
+			var updates []*pb.GetOrderbooksStreamResponse
+			for _, market := range markets {
+				orderbook, err := h.GetOrderbook(ctx, market, limit, project)
+				if err != nil {
+					return nil, err
+				}
+				updates = append(updates, &pb.GetOrderbooksStreamResponse{Orderbook: orderbook, Timestamp: now})
+			}
+			return updates, nil
+		},
+		Key: func(v *pb.GetOrderbooksStreamResponse) string {
+			return v.Orderbook.MarketAddress
+		},
+		Equal: func(a, b *pb.GetOrderbooksStreamResponse) bool {
+			return proto.Equal(a.Orderbook, b.Orderbook)
+		},
+	}
+	return httpStream(ctx, h, "GetOrderbooksStream", request, func() *pb.GetOrderbooksStreamResponse {
+		return &pb.GetOrderbooksStreamResponse{}
+	}, spec)
+}
+
+// GetMarketDepthsStream subscribes to a stream for changes to the requested market data updates (e.g. asks and bids. Set limit to 0 for all bids/ asks).
+func (h *HTTPClient) GetMarketDepthsStream(ctx context.Context, markets []string, limit uint32, project pb.Project) (connections.Streamer[*pb.GetMarketDepthsStreamResponse], error) {
+	request := &pb.GetMarketDepthsRequest{
+		Markets: markets,
+		Limit:   limit,
+		Project: project,
+	}
+	spec := &connections.PollSpec[*pb.GetMarketDepthsStreamResponse]{
+		Poll: func(ctx context.Context) ([]*pb.GetMarketDepthsStreamResponse, error) {
+			now := timestamppb.Now()
+			var updates []*pb.GetMarketDepthsStreamResponse
+			for _, market := range markets {
+				depth, err := h.GetMarketDepth(ctx, market, limit, project)
+				if err != nil {
+					return nil, err
+				}
+				updates = append(updates, &pb.GetMarketDepthsStreamResponse{Data: depth, Timestamp: now})
+			}
+			return updates, nil
+		},
+		Key: func(v *pb.GetMarketDepthsStreamResponse) string {
+			return v.Data.MarketAddress
+		},
+		Equal: func(a, b *pb.GetMarketDepthsStreamResponse) bool {
+			return proto.Equal(a.Data, b.Data)
+		},
+	}
+	return httpStream(ctx, h, "GetMarketDepthsStream", request, func() *pb.GetMarketDepthsStreamResponse {
+		return &pb.GetMarketDepthsStreamResponse{}
+	}, spec)
+}
+
+// GetTradesStream subscribes to a stream for trades as they execute. Set limit to 0 for all trades. When polling,
+// each newly seen trade is emitted in its own update and trades that existed before subscribing are skipped.
+func (h *HTTPClient) GetTradesStream(ctx context.Context, market string, limit uint32, project pb.Project) (connections.Streamer[*pb.GetTradesStreamResponse], error) {
+	request := &pb.GetTradesRequest{
+		Market:  market,
+		Limit:   limit,
+		Project: project,
+	}
+	spec := &connections.PollSpec[*pb.GetTradesStreamResponse]{
+		Poll: func(ctx context.Context) ([]*pb.GetTradesStreamResponse, error) {
+			trades, err := h.GetTrades(ctx, market, limit, project)
+			if err != nil {
+				return nil, err
+			}
+
+			now := timestamppb.Now()
+			updates := make([]*pb.GetTradesStreamResponse, 0, len(trades.Trades))
+			for _, trade := range trades.Trades {
+				updates = append(updates, &pb.GetTradesStreamResponse{
+					Trades:    &pb.GetTradesResponse{Trades: []*pb.Trade{trade}},
+					Timestamp: now,
+				})
+			}
+			return updates, nil
+		},
+		Key: func(v *pb.GetTradesStreamResponse) string {
+			t := v.Trades.Trades[0]
+			return strings.Join([]string{t.OrderID, t.Address, t.Side.String(),
+				strconv.FormatFloat(t.Size, 'g', -1, 64), strconv.FormatFloat(t.FillPrice, 'g', -1, 64)}, "/")
+		},
+		SkipSnapshot: true,
+	}
+	return httpStream(ctx, h, "GetTradesStream", request, func() *pb.GetTradesStreamResponse {
+		return &pb.GetTradesStreamResponse{}
+	}, spec)
+}
+
+// GetTickersStream subscribes to a stream for getting recent tickers of specified markets.
+func (h *HTTPClient) GetTickersStream(ctx context.Context, request *pb.GetTickersStreamRequest) (connections.Streamer[*pb.GetTickersStreamResponse], error) {
+	markets := request.Markets
+	if len(markets) == 0 {
+		markets = []string{""}
+	}
+	spec := &connections.PollSpec[*pb.GetTickersStreamResponse]{
+		Poll: func(ctx context.Context) ([]*pb.GetTickersStreamResponse, error) {
+			now := timestamppb.Now()
+			var updates []*pb.GetTickersStreamResponse
+			for _, market := range markets {
+				tickers, err := h.GetTickers(ctx, market, request.Project)
+				if err != nil {
+					return nil, err
+				}
+				for _, ticker := range tickers.Tickers {
+					updates = append(updates, &pb.GetTickersStreamResponse{
+						Ticker:    &pb.GetTickersResponse{Tickers: []*pb.Ticker{ticker}},
+						Timestamp: now,
+					})
+				}
+			}
+			return updates, nil
+		},
+		Key: func(v *pb.GetTickersStreamResponse) string {
+			return v.Ticker.Tickers[0].MarketAddress
+		},
+		Equal: func(a, b *pb.GetTickersStreamResponse) bool {
+			return proto.Equal(a.Ticker, b.Ticker)
+		},
+	}
+	return httpStream(ctx, h, "GetTickersStream", request, func() *pb.GetTickersStreamResponse {
+		return &pb.GetTickersStreamResponse{}
+	}, spec)
+}
+
+// GetRecentBlockHashStream subscribes to a stream for getting recent block hash.
+func (h *HTTPClient) GetRecentBlockHashStream(ctx context.Context) (connections.Streamer[*pb.GetRecentBlockHashResponse], error) {
+	spec := &connections.PollSpec[*pb.GetRecentBlockHashResponse]{
+		Poll: func(ctx context.Context) ([]*pb.GetRecentBlockHashResponse, error) {
+			hash, err := h.GetRecentBlockHash(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return []*pb.GetRecentBlockHashResponse{hash}, nil
+		},
+		Key: func(*pb.GetRecentBlockHashResponse) string {
+			return ""
+		},
+		Equal: func(a, b *pb.GetRecentBlockHashResponse) bool {
+			return a.BlockHash == b.BlockHash
+		},
+	}
+	return httpStream(ctx, h, "GetRecentBlockHashStream", &pb.GetRecentBlockHashRequest{}, func() *pb.GetRecentBlockHashResponse {
+		return &pb.GetRecentBlockHashResponse{}
+	}, spec)
+}
+
+// GetPriorityFeeStream subscribes to a stream for getting a recent priority fee estimate based on a percentile.
+func (h *HTTPClient) GetPriorityFeeStream(ctx context.Context, project pb.Project, percentile *float64) (connections.Streamer[*pb.GetPriorityFeeResponse], error) {
+	request := &pb.GetPriorityFeeRequest{
+		Project:    project,
+		Percentile: percentile,
+	}
+	spec := &connections.PollSpec[*pb.GetPriorityFeeResponse]{
+		Poll: func(ctx context.Context) ([]*pb.GetPriorityFeeResponse, error) {
+			fee, err := h.GetPriorityFee(ctx, project, percentile)
+			if err != nil {
+				return nil, err
+			}
+			return []*pb.GetPriorityFeeResponse{fee}, nil
+		},
+		Key: func(*pb.GetPriorityFeeResponse) string {
+			return ""
+		},
+		Equal: func(a, b *pb.GetPriorityFeeResponse) bool {
+			return proto.Equal(a, b)
+		},
+	}
+	return httpStream(ctx, h, "GetPriorityFeeStream", request, func() *pb.GetPriorityFeeResponse {
+		return &pb.GetPriorityFeeResponse{}
+	}, spec)
+}
+
+// GetOrderStatusStream subscribes to a stream that shows updates to the owner's orders. Requires server-sent events.
+func (h *HTTPClient) GetOrderStatusStream(ctx context.Context, market, ownerAddress string, project pb.Project) (connections.Streamer[*pb.GetOrderStatusStreamResponse], error) {
+	return httpStream(ctx, h, "GetOrderStatusStream", &pb.GetOrderStatusStreamRequest{
+		Market:       market,
+		OwnerAddress: ownerAddress,
+		Project:      project,
+	}, func() *pb.GetOrderStatusStreamResponse {
+		return &pb.GetOrderStatusStreamResponse{}
+	}, nil)
+}
+
+// GetSwapsStream subscribes to a stream for getting recent swaps on projects & markets of interest. Requires server-sent events.
+func (h *HTTPClient) GetSwapsStream(
+	ctx context.Context,
+	projects []pb.Project,
+	markets []string,
+	includeFailed bool,
+) (connections.Streamer[*pb.GetSwapsStreamResponse], error) {
+	return httpStream(ctx, h, "GetSwapsStream", &pb.GetSwapsStreamRequest{
+		Projects:      projects,
+		Pools:         markets,
+		IncludeFailed: includeFailed,
+	}, func() *pb.GetSwapsStreamResponse {
+		return &pb.GetSwapsStreamResponse{}
+	}, nil)
+}
+
+// GetQuotesStream subscribes to a stream for getting recent quotes of tokens of interest. When polling, the best route
+// of each project is quoted with GetQuotes and only changed amounts are emitted.
+func (h *HTTPClient) GetQuotesStream(ctx context.Context, projects []pb.Project, tokenPairs []*pb.TokenPair) (connections.Streamer[*pb.GetQuotesStreamResponse], error) {
+	spec := &connections.PollSpec[*pb.GetQuotesStreamResponse]{
+		Poll: func(ctx context.Context) ([]*pb.GetQuotesStreamResponse, error) {
+			now := timestamppb.Now()
+			var updates []*pb.GetQuotesStreamResponse
+			for _, pair := range tokenPairs {
+				quotes, err := h.GetQuotes(ctx, pair.InToken, pair.OutToken, pair.InAmount, 0, 1, projects)
+				if err != nil {
+					return nil, err
+				}
+				for _, quote := range quotes.Quotes {
+					if len(quote.Routes) == 0 {
+						continue
+					}
+					updates = append(updates, &pb.GetQuotesStreamResponse{
+						Quote: &pb.GetQuotesStreamUpdate{
+							InToken:         quotes.InToken,
+							InTokenAddress:  quotes.InTokenAddress,
+							OutToken:        quotes.OutToken,
+							OutTokenAddress: quotes.OutTokenAddress,
+							InAmount:        quote.Routes[0].InAmount,
+							OutAmount:       quote.Routes[0].OutAmount,
+							Project:         quote.Project,
+						},
+						Timestamp: now,
+					})
+				}
+			}
+			return updates, nil
+		},
+		Key: func(v *pb.GetQuotesStreamResponse) string {
+			q := v.Quote
+			return strings.Join([]string{q.InTokenAddress, q.OutTokenAddress, strconv.FormatFloat(q.InAmount, 'g', -1, 64), q.Project.String()}, "/")
+		},
+		Equal: func(a, b *pb.GetQuotesStreamResponse) bool {
+			return proto.Equal(a.Quote, b.Quote)
+		},
+	}
+	return httpStream(ctx, h, "GetQuotesStream", &pb.GetQuotesStreamRequest{
+		Projects:   projects,
+		TokenPairs: tokenPairs,
+	}, func() *pb.GetQuotesStreamResponse {
+		return &pb.GetQuotesStreamResponse{}
+	}, spec)
+}
+
+// GetPoolReservesStream subscribes to a stream for getting recent pool reserves. Only Raydium pools can be polled, with
+// GetRaydiumPoolReserve: streams of other projects require server-sent events.
+func (h *HTTPClient) GetPoolReservesStream(ctx context.Context, request *pb.GetPoolReservesStreamRequest) (connections.Streamer[*pb.GetPoolReservesStreamResponse], error) {
+	var spec *connections.PollSpec[*pb.GetPoolReservesStreamResponse]
+	if onlyProject(request.Projects, pb.Project_P_RAYDIUM) {
+		spec = &connections.PollSpec[*pb.GetPoolReservesStreamResponse]{
+			Poll: func(ctx context.Context) ([]*pb.GetPoolReservesStreamResponse, error) {
+				reserves, err := h.GetRaydiumPoolReserve(ctx, &pb.GetRaydiumPoolReserveRequest{PairsOrAddresses: request.Pools})
+				if err != nil {
+					return nil, err
+				}
+
+				now := timestamppb.Now()
+				updates := make([]*pb.GetPoolReservesStreamResponse, 0, len(reserves.Pools))
+				for _, pool := range reserves.Pools {
+					updates = append(updates, &pb.GetPoolReservesStreamResponse{
+						Reserves: &pb.PoolReserves{
+							Token1Reserves: strconv.FormatInt(pool.Token1Reserves, 10),
+							Token1Address:  pool.Token1MintAddress,
+							Token2Reserves: strconv.FormatInt(pool.Token2Reserves, 10),
+							Token2Address:  pool.Token2MintAddress,
+							PoolAddress:    pool.PoolAddress,
+							Project:        pb.Project_P_RAYDIUM,
+						},
+						Timestamp: now,
+					})
+				}
+				return updates, nil
+			},
+			Key: func(v *pb.GetPoolReservesStreamResponse) string {
+				return v.Reserves.PoolAddress
+			},
+			Equal: func(a, b *pb.GetPoolReservesStreamResponse) bool {
+				return proto.Equal(a.Reserves, b.Reserves)
+			},
+		}
+	}
+	return httpStream(ctx, h, "GetPoolReservesStream", request, func() *pb.GetPoolReservesStreamResponse {
+		return &pb.GetPoolReservesStreamResponse{}
+	}, spec)
+}
+
+// GetBlockStream subscribes to a stream for getting recent blocks. Requires server-sent events.
+func (h *HTTPClient) GetBlockStream(ctx context.Context) (connections.Streamer[*pb.GetBlockStreamResponse], error) {
+	return httpStream(ctx, h, "GetBlockStream", &pb.GetBlockStreamRequest{}, func() *pb.GetBlockStreamResponse {
+		return &pb.GetBlockStreamResponse{}
+	}, nil)
+}
+
+// GetBundleTipStream subscribes to a stream of recent bundle tip percentiles. Requires server-sent events.
+func (h *HTTPClient) GetBundleTipStream(ctx context.Context) (connections.Streamer[*pb.GetBundleTipResponse], error) {
+	return httpStream(ctx, h, "GetBundleTipStream", &pb.GetBundleTipRequest{}, func() *pb.GetBundleTipResponse {
+		return &pb.GetBundleTipResponse{}
+	}, nil)
+}
+
+// cpmmPoolType is the ProjectPool.PoolType of Raydium CPMM pools
+const cpmmPoolType = "cpmm"
+
+// GetNewRaydiumPoolsStream subscribes to a stream for new Raydium Pools when they are created. When polling, the pools
+// GetRaydiumPools returns that weren't listed when subscribing are emitted, leaving out CPMM pools unless includeCPMM
+// is set.
+func (h *HTTPClient) GetNewRaydiumPoolsStream(ctx context.Context, includeCPMM bool) (connections.Streamer[*pb.GetNewRaydiumPoolsResponse], error) {
+	spec := &connections.PollSpec[*pb.GetNewRaydiumPoolsResponse]{
+		Poll: func(ctx context.Context) ([]*pb.GetNewRaydiumPoolsResponse, error) {
+			pools, err := h.GetRaydiumPools(ctx, &pb.GetRaydiumPoolsRequest{})
+			if err != nil {
+				return nil, err
+			}
+
+			now := timestamppb.Now()
+			updates := make([]*pb.GetNewRaydiumPoolsResponse, 0, len(pools.Pools))
+			for _, pool := range pools.Pools {
+				if !includeCPMM && strings.EqualFold(pool.PoolType, cpmmPoolType) {
+					continue
+				}
+				updates = append(updates, &pb.GetNewRaydiumPoolsResponse{Pool: pool, Timestamp: now})
+			}
+			return updates, nil
+		},
+		Key: func(v *pb.GetNewRaydiumPoolsResponse) string {
+			return v.Pool.PoolAddress
+		},
+		SkipSnapshot: true,
+	}
+	return httpStream(ctx, h, "GetNewRaydiumPoolsStream", &pb.GetNewRaydiumPoolsRequest{
+		IncludeCPMM: &includeCPMM,
+	}, func() *pb.GetNewRaydiumPoolsResponse {
+		return &pb.GetNewRaydiumPoolsResponse{}
+	}, spec)
+}
+
+// GetPumpFunNewTokensStream subscribes to a stream for pumpdotfun's new pool events. Requires server-sent events.
+func (h *HTTPClient) GetPumpFunNewTokensStream(ctx context.Context, req *pb.GetPumpFunNewTokensStreamRequest) (connections.Streamer[*pb.GetPumpFunNewTokensStreamResponse], error) {
+	return httpStream(ctx, h, "GetPumpFunNewTokensStream", req, func() *pb.GetPumpFunNewTokensStreamResponse {
+		return &pb.GetPumpFunNewTokensStreamResponse{}
+	}, nil)
+}
+
+// GetPumpFunSwapsStream subscribes to a stream for swap events related to a set of pumpdotfun tokens. Requires server-sent events.
+func (h *HTTPClient) GetPumpFunSwapsStream(ctx context.Context, req *pb.GetPumpFunSwapsStreamRequest) (connections.Streamer[*pb.GetPumpFunSwapsStreamResponse], error) {
+	return httpStream(ctx, h, "GetPumpFunSwapsStream", req, func() *pb.GetPumpFunSwapsStreamResponse {
+		return &pb.GetPumpFunSwapsStreamResponse{}
+	}, nil)
+}
+
+// onlyProject returns true if no project other than project is requested
+func onlyProject(projects []pb.Project, project pb.Project) bool {
+	for _, p := range projects {
+		if p != project {
+			return false
+		}
+	}
+	return true
+}
+
+func projectRequested(projects []pb.Project, project pb.Project) bool {
+	if len(projects) == 0 {
+		return true
+	}
+	for _, p := range projects {
+		if p == pb.Project_P_ALL || p == project {
+			return true
+		}
+	}
+	return false
+}
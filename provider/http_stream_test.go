@@ -0,0 +1,154 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// streamServer serves prices that change on every other poll, Raydium pools that are created after the first poll, and server-sent events of GetPricesStream if sse is set
+type streamServer struct {
+	sse      bool
+	polls    atomic.Int32
+	sseCalls atomic.Int32
+}
+
+func (s *streamServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/api/v1/market/price":
+		poll := s.polls.Add(1)
+		body, _ := protojson.Marshal(&pb.GetPriceResponse{TokenPrices: []*pb.TokenPrice{
+			{TokenAddress: "SOL", Project: pb.Project_P_RAYDIUM, Buy: float64(100 + (poll-1)/2)},
+			{TokenAddress: "SOL", Project: pb.Project_P_JUPITER, Buy: 100},
+		}})
+		_, _ = w.Write(body)
+	case "/api/v2/raydium/pools":
+		// a standard and a CPMM pool are created after the first poll
+		pools := []*pb.ProjectPool{{PoolAddress: "A", PoolType: "amm"}}
+		if s.polls.Add(1) > 1 {
+			pools = append(pools, &pb.ProjectPool{PoolAddress: "B", PoolType: "cpmm"}, &pb.ProjectPool{PoolAddress: "C", PoolType: "amm"})
+		}
+		body, _ := protojson.Marshal(&pb.GetRaydiumPoolsResponse{Pools: pools})
+		_, _ = w.Write(body)
+	case "/sse/GetPricesStream":
+		s.sseCalls.Add(1)
+		if !s.sse {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := protojson.Marshal(&pb.GetPricesStreamResponse{Slot: 7})
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", body)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func streamClient(t *testing.T, s *streamServer, mode HTTPStreamMode, sse bool) *HTTPClient {
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	opts := RPCOpts{
+		Endpoint:       server.URL,
+		HTTPStreamMode: mode,
+		HTTPPollOpts:   connections.PollOpts{MinInterval: time.Millisecond, MaxInterval: time.Millisecond},
+	}
+	if sse {
+		opts.HTTPSSEEndpoint = server.URL + "/sse/"
+	}
+	return NewHTTPClientWithOpts(nil, opts)
+}
+
+func TestHTTPStreamPoll(t *testing.T) {
+	ctx := context.Background()
+	s := &streamServer{}
+	h := streamClient(t, s, HTTPStreamAuto, false)
+
+	// only the requested projects, and only changes after the first prices
+	stream, err := h.GetPricesStream(ctx, []pb.Project{pb.Project_P_RAYDIUM}, []string{"SOL"})
+	require.Nil(t, err)
+	for _, expected := range []float64{100, 101, 102} {
+		update, err := stream()
+		require.Nil(t, err)
+		assert.Equal(t, pb.Project_P_RAYDIUM, update.Price.Project)
+		assert.Equal(t, expected, update.Price.Buy)
+	}
+	assert.Equal(t, int32(5), s.polls.Load())
+	assert.Zero(t, s.sseCalls.Load())
+
+	// streams without a polling equivalent need server-sent events
+	_, err = h.GetBlockStream(ctx)
+	assert.ErrorIs(t, err, ErrHTTPStreamUnsupported)
+	_, err = h.GetPoolReservesStream(ctx, &pb.GetPoolReservesStreamRequest{Projects: []pb.Project{pb.Project_P_RAYDIUM, pb.Project_P_OPENBOOK}})
+	assert.ErrorIs(t, err, ErrHTTPStreamUnsupported)
+
+	h = streamClient(t, s, HTTPStreamSSE, false)
+	_, err = h.GetPricesStream(ctx, nil, []string{"SOL"})
+	assert.ErrorIs(t, err, ErrNoSSEEndpoint)
+}
+
+func TestHTTPStreamSSE(t *testing.T) {
+	ctx := context.Background()
+	s := &streamServer{sse: true}
+	h := streamClient(t, s, HTTPStreamAuto, true)
+
+	stream, err := h.GetPricesStream(ctx, nil, []string{"SOL"})
+	require.Nil(t, err)
+	update, err := stream()
+	require.Nil(t, err)
+	assert.Equal(t, int64(7), update.Slot)
+	assert.Zero(t, s.polls.Load())
+}
+
+func TestHTTPStreamFallback(t *testing.T) {
+	ctx := context.Background()
+	s := &streamServer{}
+	h := streamClient(t, s, HTTPStreamAuto, true)
+
+	// streams the server-sent events endpoint doesn't support are polled, without asking again
+	for i := 0; i < 2; i++ {
+		stream, err := h.GetPricesStream(ctx, nil, []string{"SOL"})
+		require.Nil(t, err)
+		update, err := stream()
+		require.Nil(t, err)
+		assert.NotNil(t, update.Price)
+	}
+	assert.Equal(t, int32(1), s.sseCalls.Load())
+
+	// unless only server-sent events are allowed
+	h = streamClient(t, s, HTTPStreamSSE, true)
+	_, err := h.GetPricesStream(ctx, nil, []string{"SOL"})
+	assert.ErrorIs(t, err, connections.ErrSSEUnsupported)
+}
+
+func TestHTTPStreamPollNewRaydiumPools(t *testing.T) {
+	ctx := context.Background()
+
+	for _, includeCPMM := range []bool{false, true} {
+		s := &streamServer{}
+		h := streamClient(t, s, HTTPStreamPoll, false)
+
+		stream, err := h.GetNewRaydiumPoolsStream(ctx, includeCPMM)
+		require.Nil(t, err)
+		var pools []string
+		for len(pools) < 1 || includeCPMM && len(pools) < 2 {
+			update, err := stream()
+			require.Nil(t, err)
+			pools = append(pools, update.Pool.PoolAddress)
+		}
+		if includeCPMM {
+			assert.Equal(t, []string{"B", "C"}, pools)
+		} else {
+			assert.Equal(t, []string{"C"}, pools)
+		}
+	}
+}
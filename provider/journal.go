@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/journal"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+type batchSubmitFunc func(ctx context.Context, request *pb.PostSubmitBatchRequest) (*pb.PostSubmitBatchResponse, error)
+
+// journalSubmit records a signed transaction in the journal before submit, and its signature after
+func journalSubmit(ctx context.Context, j *journal.Journal, tx *pb.TransactionMessage, skipPreFlight, frontRunningProtection, useStakedRPCs bool, submit func(ctx context.Context) (string, error)) (string, error) {
+	if j == nil {
+		return submit(ctx)
+	}
+	id, err := j.RecordSubmit(tx, skipPreFlight, frontRunningProtection, useStakedRPCs)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := submit(ctx)
+	var response *pb.PostSubmitBatchResponse
+	if err == nil {
+		response = &pb.PostSubmitBatchResponse{Transactions: []*pb.PostSubmitBatchResponseEntry{{Signature: signature, Submitted: true}}}
+	}
+	if journalErr := j.RecordResponse([]uint64{id}, response, err); journalErr != nil {
+		log.Errorf("could not record response of journal entry %v: %v", id, journalErr)
+	}
+	return signature, err
+}
+
+// journalBatch records the transactions of a batch request in the journal before submit, and the response after
+func journalBatch(j *journal.Journal, submit batchSubmitFunc) batchSubmitFunc {
+	if j == nil {
+		return submit
+	}
+	return func(ctx context.Context, request *pb.PostSubmitBatchRequest) (*pb.PostSubmitBatchResponse, error) {
+		ids, err := j.RecordBatch(request)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := submit(ctx, request)
+		if journalErr := j.RecordResponse(ids, response, err); journalErr != nil {
+			log.Errorf("could not record response of journal entries %v: %v", ids, journalErr)
+		}
+		return response, err
+	}
+}
@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/journal"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalBatch(t *testing.T) {
+	j, err := journal.Open(filepath.Join(t.TempDir(), "journal.db"))
+	require.Nil(t, err)
+	defer func() { _ = j.Close() }()
+
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	owner := privateKey.PublicKey()
+	server := &fakeBatchServer{t: t, rejected: map[uint64]string{2: "blockhash not found"}}
+	client := server.client(privateKey)
+	client.submit = journalBatch(j, client.submit)
+
+	// each attempt is journaled, the retry with its new signature
+	transactions := []*pb.TransactionMessage{unsignedTx(t, owner, 1), unsignedTx(t, owner, 2)}
+	result, err := submitBatch(context.Background(), client, transactions, BatchOpts{MaxRetries: 1})
+	require.Nil(t, err)
+	require.True(t, result.Succeeded())
+
+	entries, err := j.Query(journal.Filter{})
+	require.Nil(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, []journal.State{journal.StateSubmitted, journal.StateRejected, journal.StateSubmitted},
+		[]journal.State{entries[0].State, entries[1].State, entries[2].State})
+	assert.Equal(t, result.Entries[1].Signature, entries[2].Signature)
+
+	unconfirmed, err := j.Unconfirmed()
+	require.Nil(t, err)
+	assert.Len(t, unconfirmed, 2)
+}
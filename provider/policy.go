@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"math"
+	"strings"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	log "github.com/sirupsen/logrus"
+)
+
+// The Submit* methods check the transactions the API builds against a policy derived from their own parameters, in
+// addition to RPCOpts.Policy and SubmitOpts.Policy: the owner pays and signs, token instructions only name the tokens of
+// the request and wrapped SOL, and the owner spends at most the input amount. SOL spending also covers the tip of the
+// request and the rent of the accounts a swap creates.
+
+// rentAllowance is the SOL a swap may spend besides its input and tip, for the rent of the accounts it creates
+const rentAllowance = 10_000_000
+
+const pumpFunDecimals = 6
+
+var (
+	usdcMint = solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+	usdtMint = solana.MustPublicKeyFromBase58("Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCeBvSuN9rS")
+
+	// symbolMints resolve the symbols requests commonly use instead of mints
+	symbolMints = map[string]solana.PublicKey{
+		"SOL":  solana.SolMint,
+		"WSOL": solana.SolMint,
+		"USDC": usdcMint,
+		"USDT": usdtMint,
+	}
+	knownDecimals = map[solana.PublicKey]uint8{
+		solana.SolMint: 9,
+		usdcMint:       6,
+		usdtMint:       6,
+	}
+)
+
+// requestPolicy builds the policies of requests for a client, looking up the decimals of input mints with fetcher
+type requestPolicy struct {
+	fetcher transaction.AccountFetcher
+}
+
+// owner is the policy of a request that only names its owner, e.g. orders and cancels
+func (r requestPolicy) owner(owner string) *transaction.Policy {
+	address, err := solana.PublicKeyFromBase58(owner)
+	if err != nil {
+		return nil
+	}
+	return &transaction.Policy{Owner: address}
+}
+
+// swap is the policy of a swap of inAmount of the first token, through the others, with a tip in lamports
+func (r requestPolicy) swap(ctx context.Context, owner string, inAmount float64, tip *uint64, tokens ...string) *transaction.Policy {
+	policy := r.owner(owner)
+	if policy == nil {
+		return nil
+	}
+
+	mints := []solana.PublicKey{solana.SolMint}
+	for _, t := range tokens {
+		mint, ok := tokenMint(t)
+		if !ok {
+			mints = nil
+			break
+		}
+		mints = append(mints, mint)
+	}
+	policy.Mints = mints
+
+	sol := uint64(rentAllowance)
+	if tip != nil {
+		sol += *tip
+	}
+	policy.MaxInput = map[solana.PublicKey]uint64{solana.SolMint: sol}
+	if len(tokens) == 0 {
+		return policy
+	}
+	in, ok := tokenMint(tokens[0])
+	if !ok {
+		return policy
+	}
+	decimals, ok := r.decimals(ctx, in)
+	if !ok {
+		return policy
+	}
+	policy.MaxInput[in] += uint64(math.Ceil(inAmount * math.Pow10(int(decimals))))
+	return policy
+}
+
+// pumpFunSwap is the policy of a Pump.fun swap: buys spend at most the SOL threshold, sells the token amount
+func (r requestPolicy) pumpFunSwap(ctx context.Context, request *pb.PostPumpFunSwapRequest) *transaction.Policy {
+	if request.IsBuy {
+		return r.swap(ctx, request.UserAddress, request.SolThreshold, request.Tip, "SOL", request.TokenAddress)
+	}
+	policy := r.swap(ctx, request.UserAddress, 0, request.Tip, request.TokenAddress, "SOL")
+	if mint, ok := tokenMint(request.TokenAddress); ok && policy != nil {
+		policy.MaxInput[mint] = uint64(math.Ceil(request.TokenAmount * math.Pow10(pumpFunDecimals)))
+	}
+	return policy
+}
+
+// routeStep is a step of a route swap request
+type routeStep interface {
+	GetInToken() string
+	GetOutToken() string
+	GetInAmount() float64
+}
+
+// routePolicy is the policy of a route swap, spending the input amount of its first step
+func routePolicy[S routeStep](ctx context.Context, r requestPolicy, owner string, tip *uint64, steps []S) *transaction.Policy {
+	if len(steps) == 0 {
+		return r.swap(ctx, owner, 0, tip)
+	}
+	tokens := []string{steps[0].GetInToken()}
+	for _, step := range steps {
+		tokens = append(tokens, step.GetOutToken())
+	}
+	return r.swap(ctx, owner, steps[0].GetInAmount(), tip, tokens...)
+}
+
+// decimals returns the decimals of a mint, known or loaded with the fetcher
+func (r requestPolicy) decimals(ctx context.Context, mint solana.PublicKey) (uint8, bool) {
+	if decimals, ok := knownDecimals[mint]; ok {
+		return decimals, true
+	}
+	if r.fetcher == nil {
+		return 0, false
+	}
+	account, err := r.fetcher.GetAccountInfo(ctx, mint)
+	if err != nil || account == nil || account.Value == nil {
+		log.Debugf("could not load mint %v, its input amount isn't checked: %v", mint, err)
+		return 0, false
+	}
+	var m token.Mint
+	if err = m.UnmarshalWithDecoder(bin.NewBinDecoder(account.GetBinary())); err != nil {
+		log.Debugf("could not decode mint %v, its input amount isn't checked: %v", mint, err)
+		return 0, false
+	}
+	return m.Decimals, true
+}
+
+// tokenMint resolves a token of a request, a mint address or a common symbol
+func tokenMint(t string) (solana.PublicKey, bool) {
+	if mint, ok := symbolMints[strings.ToUpper(t)]; ok {
+		return mint, true
+	}
+	mint, err := solana.PublicKeyFromBase58(t)
+	return mint, err == nil
+}
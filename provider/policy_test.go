@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// fakeMintDecimals serves mint accounts with the given decimals
+type fakeMintDecimals map[solana.PublicKey]uint8
+
+func (f fakeMintDecimals) GetAccountInfo(_ context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	decimals, ok := f[account]
+	if !ok {
+		return nil, rpc.ErrNotFound
+	}
+	var buf bytes.Buffer
+	if err := (token.Mint{Decimals: decimals, IsInitialized: true}).MarshalWithEncoder(bin.NewBinEncoder(&buf)); err != nil {
+		return nil, err
+	}
+	return &rpc.GetAccountInfoResult{Value: &rpc.Account{Owner: solana.TokenProgramID, Data: rpc.DataBytesOrJSONFromBytes(buf.Bytes())}}, nil
+}
+
+func TestRequestPolicy(t *testing.T) {
+	ctx := context.Background()
+	owner := solana.NewWallet().PublicKey()
+	bonk := solana.NewWallet().PublicKey()
+	tip := uint64(1_000)
+	requests := requestPolicy{fetcher: fakeMintDecimals{bonk: 5}}
+
+	assert.Nil(t, requests.owner("not a key"))
+	assert.Equal(t, &transaction.Policy{Owner: owner}, requests.owner(owner.String()))
+
+	policy := requests.swap(ctx, owner.String(), 1.5, &tip, "usdc", bonk.String())
+	require.NotNil(t, policy)
+	assert.Equal(t, owner, policy.Owner)
+	assert.Equal(t, []solana.PublicKey{solana.SolMint, usdcMint, bonk}, policy.Mints)
+	assert.Equal(t, map[solana.PublicKey]uint64{solana.SolMint: rentAllowance + tip, usdcMint: 1_500_000}, policy.MaxInput)
+
+	// the decimals of other mints are loaded
+	policy = requests.swap(ctx, owner.String(), 0.1, nil, bonk.String(), "SOL")
+	assert.Equal(t, uint64(10_000), policy.MaxInput[bonk])
+	assert.Equal(t, uint64(rentAllowance), policy.MaxInput[solana.SolMint])
+
+	// mints aren't checked if a token isn't resolved, and amounts of unknown mints aren't checked
+	policy = requests.swap(ctx, owner.String(), 1, nil, "RAY", "SOL")
+	assert.Nil(t, policy.Mints)
+	assert.Len(t, policy.MaxInput, 1)
+	policy = requestPolicy{}.swap(ctx, owner.String(), 1, nil, bonk.String(), "SOL")
+	assert.Len(t, policy.MaxInput, 1)
+
+	// route swaps spend the input of their first step
+	policy = routePolicy(ctx, requests, owner.String(), nil, []*pb.RouteStep{
+		{InToken: "SOL", OutToken: "USDC", InAmount: 0.5},
+		{InToken: "USDC", OutToken: bonk.String(), InAmount: 80},
+	})
+	assert.Equal(t, []solana.PublicKey{solana.SolMint, solana.SolMint, usdcMint, bonk}, policy.Mints)
+	assert.Equal(t, uint64(rentAllowance+500_000_000), policy.MaxInput[solana.SolMint])
+	assert.NotContains(t, policy.MaxInput, usdcMint)
+
+	// Pump.fun sells spend the token amount, at 6 decimals
+	policy = requests.pumpFunSwap(ctx, &pb.PostPumpFunSwapRequest{UserAddress: owner.String(), TokenAddress: bonk.String(), TokenAmount: 2})
+	assert.Equal(t, uint64(2_000_000), policy.MaxInput[bonk])
+}
+
+func TestSubmitChecksRequestPolicy(t *testing.T) {
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	owner := privateKey.PublicKey()
+
+	var lamports uint64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/trade/swap", r.URL.Path)
+		body, err := protojson.Marshal(&pb.TradeSwapResponse{Transactions: []*pb.TransactionMessage{unsignedTx(t, owner, lamports)}})
+		require.Nil(t, err)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	h := NewHTTPClientWithOpts(nil, RPCOpts{Endpoint: server.URL, PrivateKey: &privateKey})
+	submit := func() error {
+		_, err := h.SubmitTradeSwap(ctx, owner.String(), "SOL", "USDC", 0.01, 0.1, pb.Project_P_RAYDIUM, SubmitOpts{DryRun: true})
+		return err
+	}
+
+	// a swap of 0.01 SOL may spend it and the rent of new accounts
+	lamports = 10_000_000
+	requireDryRun(t, submit())
+
+	lamports = 10_000_000 + rentAllowance + 1
+	err = submit()
+	require.ErrorIs(t, err, transaction.ErrUnsafeTransaction)
+	var verification *transaction.VerificationError
+	require.ErrorAs(t, err, &verification)
+	assert.Equal(t, transaction.ViolationAmount, verification.Violation)
+}
@@ -0,0 +1,286 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultRaceCapacity = 10000
+
+// RaceSource is one of several redundant subscriptions to the same feed, e.g. the same stream on another region or
+// transport
+type RaceSource[T any] struct {
+	Name string
+	Open func(ctx context.Context) (connections.Streamer[T], error)
+}
+
+type RaceOpts struct {
+	// Capacity is the number of recent updates remembered to recognize later copies (default 10000)
+	Capacity int
+	// ReconnectInterval reopens a source this long after it ends. Sources are not reopened if it is 0.
+	ReconnectInterval time.Duration
+}
+
+// RaceSourceStats describes how a source performed against the others
+type RaceSourceStats struct {
+	Name string
+	// Received counts all updates received from the source, including late copies
+	Received uint64
+	// Wins counts updates for which this source delivered the first copy
+	Wins    uint64
+	WinRate float64
+	// AverageLag and MaxLag measure how far this source's late copies arrived behind the first copy
+	AverageLag time.Duration
+	MaxLag     time.Duration
+	// Err is the last error that ended the source's stream
+	Err error
+}
+
+type RaceStats struct {
+	// Unique counts updates emitted by the race
+	Unique  uint64
+	Sources []RaceSourceStats
+}
+
+type raceSourceState struct {
+	received uint64
+	wins     uint64
+	lagTotal time.Duration
+	lagCount uint64
+	maxLag   time.Duration
+	err      error
+}
+
+// StreamRace subscribes to the same feed through several sources and emits each unique update once, as soon as the
+// first copy arrives. Updates are identified by key. Like connections.FirstOf, but with statistics of the sources.
+type StreamRace[T any, K comparable] struct {
+	key     func(T) K
+	names   []string
+	stream  connections.Streamer[connections.Tagged[T]]
+	seen    *connections.KeyCache[K]
+	m       sync.Mutex
+	unique  uint64
+	sources []raceSourceState
+}
+
+// NewStreamRace opens all sources and starts racing them. Sources that fail to open are reported through Stats, and
+// an error is only returned if none could be opened.
+func NewStreamRace[T any, K comparable](ctx context.Context, key func(T) K, opts RaceOpts, sources ...RaceSource[T]) (*StreamRace[T, K], error) {
+	if len(sources) == 0 {
+		return nil, errors.New("at least one race source is required")
+	}
+	if opts.Capacity <= 0 {
+		opts.Capacity = defaultRaceCapacity
+	}
+
+	r := &StreamRace[T, K]{
+		key:     key,
+		names:   make([]string, len(sources)),
+		seen:    connections.NewKeyCache[K](opts.Capacity),
+		sources: make([]raceSourceState, len(sources)),
+	}
+
+	var (
+		streams []connections.Streamer[T]
+		// MergeTagged tags updates with the index among opened streams, so keep track of the index among sources
+		indexes []int
+		errs    []error
+	)
+	for i, source := range sources {
+		r.names[i] = source.Name
+		stream, err := r.openSource(ctx, i, source, opts.ReconnectInterval)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("source %v: %w", source.Name, err))
+			continue
+		}
+		streams = append(streams, stream)
+		indexes = append(indexes, i)
+	}
+	if len(streams) == 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	tagged := connections.MergeTagged(ctx, streams...)
+	r.stream = func() (connections.Tagged[T], error) {
+		t, err := tagged()
+		if err != nil {
+			return t, err
+		}
+		t.Source = indexes[t.Source]
+		return t, nil
+	}
+	return r, nil
+}
+
+func (r *StreamRace[T, K]) openSource(ctx context.Context, i int, source RaceSource[T], reconnectInterval time.Duration) (connections.Streamer[T], error) {
+	stream, err := source.Open(ctx)
+	if err != nil {
+		r.setErr(i, err)
+		return nil, err
+	}
+	if reconnectInterval <= 0 {
+		return func() (T, error) {
+			v, err := stream()
+			if err != nil {
+				r.setErr(i, err)
+			}
+			return v, err
+		}, nil
+	}
+
+	return func() (T, error) {
+		for {
+			v, err := stream()
+			if err == nil {
+				return v, nil
+			}
+			r.setErr(i, err)
+			if ctx.Err() != nil {
+				return v, err
+			}
+
+			log.Warnf("race source %v ended, reconnecting in %v: %v", source.Name, reconnectInterval, err)
+			for {
+				select {
+				case <-time.After(reconnectInterval):
+				case <-ctx.Done():
+					return v, err
+				}
+				stream, err = source.Open(ctx)
+				if err == nil {
+					break
+				}
+				r.setErr(i, err)
+			}
+		}
+	}, nil
+}
+
+func (r *StreamRace[T, K]) setErr(i int, err error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.sources[i].err = err
+}
+
+// Stream returns the deduplicated updates. It must only be read by a single consumer.
+func (r *StreamRace[T, K]) Stream() connections.Streamer[T] {
+	return func() (T, error) {
+		for {
+			t, err := r.stream()
+			if err != nil {
+				var zero T
+				return zero, err
+			}
+			if r.record(t) {
+				return t.Value, nil
+			}
+		}
+	}
+}
+
+// record updates the source statistics and returns true if this is the first copy of the update
+func (r *StreamRace[T, K]) record(t connections.Tagged[T]) bool {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	state := &r.sources[t.Source]
+	state.received++
+
+	firstSeen, first := r.seen.AddAt(r.key(t.Value), t.ReceivedAt)
+	if !first {
+		lag := t.ReceivedAt.Sub(firstSeen)
+		state.lagTotal += lag
+		state.lagCount++
+		if lag > state.maxLag {
+			state.maxLag = lag
+		}
+		return false
+	}
+	state.wins++
+	r.unique++
+	return true
+}
+
+// Stats returns a snapshot of per-source win rates and latencies
+func (r *StreamRace[T, K]) Stats() RaceStats {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	stats := RaceStats{
+		Unique:  r.unique,
+		Sources: make([]RaceSourceStats, len(r.sources)),
+	}
+	for i, state := range r.sources {
+		s := RaceSourceStats{
+			Name:     r.names[i],
+			Received: state.received,
+			Wins:     state.wins,
+			MaxLag:   state.maxLag,
+			Err:      state.err,
+		}
+		if r.unique > 0 {
+			s.WinRate = float64(state.wins) / float64(r.unique)
+		}
+		if state.lagCount > 0 {
+			s.AverageLag = state.lagTotal / time.Duration(state.lagCount)
+		}
+		stats.Sources[i] = s
+	}
+	return stats
+}
+
+// PumpFunNewTokensStreamer is implemented by all clients
+type PumpFunNewTokensStreamer interface {
+	GetPumpFunNewTokensStream(ctx context.Context, req *pb.GetPumpFunNewTokensStreamRequest) (connections.Streamer[*pb.GetPumpFunNewTokensStreamResponse], error)
+}
+
+// BlockStreamer is implemented by all clients
+type BlockStreamer interface {
+	GetBlockStream(ctx context.Context) (connections.Streamer[*pb.GetBlockStreamResponse], error)
+}
+
+// PumpFunNewTokensRaceSource creates a race source for the pumpfun new tokens stream of a client
+func PumpFunNewTokensRaceSource(name string, client PumpFunNewTokensStreamer, req *pb.GetPumpFunNewTokensStreamRequest) RaceSource[*pb.GetPumpFunNewTokensStreamResponse] {
+	return RaceSource[*pb.GetPumpFunNewTokensStreamResponse]{
+		Name: name,
+		Open: func(ctx context.Context) (connections.Streamer[*pb.GetPumpFunNewTokensStreamResponse], error) {
+			return client.GetPumpFunNewTokensStream(ctx, req)
+		},
+	}
+}
+
+// PumpFunNewTokenKey identifies a new token event by its transaction
+func PumpFunNewTokenKey(v *pb.GetPumpFunNewTokensStreamResponse) string {
+	return v.TxnHash
+}
+
+// BlockRaceSource creates a race source for the block stream of a client
+func BlockRaceSource(name string, client BlockStreamer) RaceSource[*pb.GetBlockStreamResponse] {
+	return RaceSource[*pb.GetBlockStreamResponse]{
+		Name: name,
+		Open: func(ctx context.Context) (connections.Streamer[*pb.GetBlockStreamResponse], error) {
+			return client.GetBlockStream(ctx)
+		},
+	}
+}
+
+// BlockKey identifies a block by its slot
+func BlockKey(v *pb.GetBlockStreamResponse) uint64 {
+	return v.GetBlock().GetSlot()
+}
+
+var (
+	_ PumpFunNewTokensStreamer = (*GRPCClient)(nil)
+	_ PumpFunNewTokensStreamer = (*WSClient)(nil)
+	_ PumpFunNewTokensStreamer = (*HTTPClient)(nil)
+	_ BlockStreamer            = (*GRPCClient)(nil)
+	_ BlockStreamer            = (*WSClient)(nil)
+	_ BlockStreamer            = (*HTTPClient)(nil)
+)
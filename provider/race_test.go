@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errSourceEnded = errors.New("source ended")
+
+// chanSource streams the values sent to ch, and ends once it's closed
+func chanSource(name string, ch chan int) RaceSource[int] {
+	return RaceSource[int]{
+		Name: name,
+		Open: func(context.Context) (connections.Streamer[int], error) {
+			return func() (int, error) {
+				v, ok := <-ch
+				if !ok {
+					return 0, errSourceEnded
+				}
+				return v, nil
+			}, nil
+		},
+	}
+}
+
+func identity(v int) int { return v }
+
+func TestStreamRace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, b := make(chan int), make(chan int)
+	failing := RaceSource[int]{Name: "c", Open: func(context.Context) (connections.Streamer[int], error) {
+		return nil, errors.New("unreachable")
+	}}
+	race, err := NewStreamRace(ctx, identity, RaceOpts{}, chanSource("a", a), chanSource("b", b), failing)
+	require.Nil(t, err)
+	stream := race.Stream()
+
+	// the first copy is emitted, and late copies only count towards the statistics
+	a <- 1
+	v, err := stream()
+	require.Nil(t, err)
+	assert.Equal(t, 1, v)
+	time.Sleep(5 * time.Millisecond)
+	b <- 1
+	b <- 2
+	v, err = stream()
+	require.Nil(t, err)
+	assert.Equal(t, 2, v)
+
+	stats := race.Stats()
+	assert.Equal(t, uint64(2), stats.Unique)
+	require.Len(t, stats.Sources, 3)
+	assert.Equal(t, RaceSourceStats{Name: "a", Received: 1, Wins: 1, WinRate: 0.5}, stats.Sources[0])
+	assert.Equal(t, "b", stats.Sources[1].Name)
+	assert.Equal(t, uint64(2), stats.Sources[1].Received)
+	assert.Equal(t, uint64(1), stats.Sources[1].Wins)
+	assert.GreaterOrEqual(t, stats.Sources[1].MaxLag, 5*time.Millisecond)
+	assert.Equal(t, stats.Sources[1].MaxLag, stats.Sources[1].AverageLag)
+	assert.EqualError(t, stats.Sources[2].Err, "unreachable")
+
+	// the race ends with its sources
+	close(a)
+	close(b)
+	_, err = stream()
+	assert.ErrorIs(t, err, errSourceEnded)
+	assert.ErrorIs(t, race.Stats().Sources[0].Err, errSourceEnded)
+}
+
+func TestStreamRaceCapacity(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := make(chan int, 3)
+	race, err := NewStreamRace(ctx, identity, RaceOpts{Capacity: 1}, chanSource("a", a))
+	require.Nil(t, err)
+	stream := race.Stream()
+
+	// only the last update is remembered
+	a <- 1
+	a <- 2
+	a <- 1
+	for _, expected := range []int{1, 2, 1} {
+		v, err := stream()
+		require.Nil(t, err)
+		assert.Equal(t, expected, v)
+	}
+}
+
+func TestStreamRaceReconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var opens atomic.Int32
+	source := RaceSource[int]{Name: "a", Open: func(context.Context) (connections.Streamer[int], error) {
+		open := int(opens.Add(1))
+		sent := false
+		return func() (int, error) {
+			if sent {
+				return 0, errSourceEnded
+			}
+			sent = true
+			return open, nil
+		}, nil
+	}}
+	race, err := NewStreamRace(ctx, identity, RaceOpts{ReconnectInterval: time.Millisecond}, source)
+	require.Nil(t, err)
+	stream := race.Stream()
+
+	// ended sources are reopened
+	for _, expected := range []int{1, 2} {
+		v, err := stream()
+		require.Nil(t, err)
+		assert.Equal(t, expected, v)
+	}
+	assert.ErrorIs(t, race.Stats().Sources[0].Err, errSourceEnded)
+}
+
+func TestStreamRaceNoSources(t *testing.T) {
+	_, err := NewStreamRace[int, int](context.Background(), identity, RaceOpts{})
+	assert.NotNil(t, err)
+
+	failing := RaceSource[int]{Name: "a", Open: func(context.Context) (connections.Streamer[int], error) {
+		return nil, errors.New("unreachable")
+	}}
+	_, err = NewStreamRace(context.Background(), identity, RaceOpts{}, failing)
+	assert.ErrorContains(t, err, "source a: unreachable")
+}
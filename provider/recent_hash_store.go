@@ -42,7 +42,7 @@ func (s *recentBlockHashStore) run(ctx context.Context) {
 		log.Error("can't open recent block hash stream")
 		return
 	}
-	ch := stream.Channel(1)
+	ch := stream.ChannelWithContext(ctx, 1)
 	for {
 		select {
 		case hash := <-ch:
@@ -0,0 +1,354 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+)
+
+// The helpers below manage the token accounts of the client's private key. Their transactions carry the Trader API
+// memo and are submitted with the client's sign and submit path, as a batch of one transaction per chunk of accounts.
+
+const (
+	// maxCreatesPerTx and maxClosesPerTx keep the transactions of the helpers within the transaction size
+	maxCreatesPerTx = 8
+	maxClosesPerTx  = 20
+
+	// sweepOutToken is what SweepDust swaps into
+	sweepOutToken = "SOL"
+)
+
+var ErrNoDustFilter = errors.New("DustOpts.IsDust is required")
+
+// DustOpts configures SweepDust
+type DustOpts struct {
+	SubmitOpts
+	// IsDust selects the token accounts swept into SOL. Wrapped SOL and empty accounts are never swept.
+	IsDust   func(account *pb.TokenAccount) bool
+	Slippage float64
+	Project  pb.Project
+}
+
+// SweepResult is the swap of a dust balance
+type SweepResult struct {
+	Account  *pb.TokenAccount
+	Response *pb.PostSubmitBatchResponse
+}
+
+// TradeSwapFunc is SubmitTradeSwap, for SweepDustAccounts
+type TradeSwapFunc func(ctx context.Context, owner, inToken, outToken string, inAmount, slippage float64, project pb.Project, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error)
+
+// tokenAccountClient is what the token account helpers need from a client
+type tokenAccountClient struct {
+	privateKey       *solana.PrivateKey
+	blockHash        transaction.BlockHashSource
+	getTokenAccounts func(ctx context.Context, request *pb.GetTokenAccountsRequest) (*pb.GetTokenAccountsResponse, error)
+	submit           func(ctx context.Context, transactions []*pb.TransactionMessage, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error)
+	tradeSwap        TradeSwapFunc
+	accountFetcher   transaction.AccountFetcher
+}
+
+func (c tokenAccountClient) owner() (solana.PublicKey, error) {
+	return publicKey(c.privateKey)
+}
+
+func (c tokenAccountClient) accounts(ctx context.Context, owner solana.PublicKey) ([]*pb.TokenAccount, error) {
+	response, err := c.getTokenAccounts(ctx, &pb.GetTokenAccountsRequest{OwnerAddress: owner.String()})
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve token accounts of %v: %w", owner, err)
+	}
+	return response.Accounts, nil
+}
+
+// tokenProgram returns the program of mint, loaded with RPCOpts.AccountFetcher. Without it, the program is inferred
+// from account, the owner's token account of the mint when known, and is the token program otherwise.
+func (c tokenAccountClient) tokenProgram(ctx context.Context, owner, mint solana.PublicKey, account string) (solana.PublicKey, error) {
+	if c.accountFetcher != nil {
+		return transaction.TokenProgram(ctx, c.accountFetcher, mint)
+	}
+	if account != "" {
+		token2022, err := transaction.AssociatedTokenAddress(owner, mint, transaction.Token2022ProgramID)
+		if err != nil {
+			return solana.PublicKey{}, err
+		}
+		if token2022.String() == account {
+			return transaction.Token2022ProgramID, nil
+		}
+	}
+	return solana.TokenProgramID, nil
+}
+
+// submitChunks builds a transaction for each chunk of instructions and submits them together
+func (c tokenAccountClient) submitChunks(ctx context.Context, owner solana.PublicKey, instructions []solana.Instruction, perTx int, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	var transactions []*pb.TransactionMessage
+	for start := 0; start < len(instructions); start += perTx {
+		end := start + perTx
+		if end > len(instructions) {
+			end = len(instructions)
+		}
+		txs, err := builtTx(ctx, transaction.NewTxBuilder(owner, c.blockHash).AddInstructions(instructions[start:end]...))
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, txs...)
+	}
+	if len(transactions) == 0 {
+		return &pb.PostSubmitBatchResponse{}, nil
+	}
+	return c.submit(ctx, transactions, false, opts)
+}
+
+func (c tokenAccountClient) createTokenAccounts(ctx context.Context, mints []solana.PublicKey, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	owner, err := c.owner()
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := c.accounts(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool)
+	mintAccounts := make(map[string]string)
+	for _, account := range accounts {
+		existing[account.TokenAccount] = true
+		mintAccounts[account.TokenMint] = account.TokenAccount
+	}
+
+	var instructions []solana.Instruction
+	seen := make(map[solana.PublicKey]bool)
+	for _, mint := range mints {
+		if seen[mint] {
+			continue
+		}
+		seen[mint] = true
+		program, err := c.tokenProgram(ctx, owner, mint, mintAccounts[mint.String()])
+		if err != nil {
+			return nil, err
+		}
+		instruction, account, err := transaction.CreateAssociatedTokenAccountInstruction(owner, owner, mint, program)
+		if err != nil {
+			return nil, err
+		}
+		if existing[account.String()] {
+			continue
+		}
+		instructions = append(instructions, instruction)
+	}
+	return c.submitChunks(ctx, owner, instructions, maxCreatesPerTx, opts)
+}
+
+func (c tokenAccountClient) wrapSOL(ctx context.Context, lamports uint64, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	owner, err := c.owner()
+	if err != nil {
+		return nil, err
+	}
+	instructions, err := transaction.WrapSOLInstructions(owner, lamports)
+	if err != nil {
+		return nil, err
+	}
+	return c.submitChunks(ctx, owner, instructions, len(instructions), opts)
+}
+
+func (c tokenAccountClient) unwrapSOL(ctx context.Context, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	owner, err := c.owner()
+	if err != nil {
+		return nil, err
+	}
+	instruction, err := transaction.UnwrapSOLInstruction(owner)
+	if err != nil {
+		return nil, err
+	}
+	return c.submitChunks(ctx, owner, []solana.Instruction{instruction}, 1, opts)
+}
+
+func (c tokenAccountClient) closeEmptyTokenAccounts(ctx context.Context, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	owner, err := c.owner()
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := c.accounts(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var instructions []solana.Instruction
+	programs := make(map[solana.PublicKey]solana.PublicKey)
+	for _, account := range accounts {
+		if account.Amount != 0 || account.TokenAccount == "" {
+			continue
+		}
+		address, err := solana.PublicKeyFromBase58(account.TokenAccount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token account %v: %w", account.TokenAccount, err)
+		}
+		mint, err := solana.PublicKeyFromBase58(account.TokenMint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mint %v of token account %v: %w", account.TokenMint, account.TokenAccount, err)
+		}
+		program, ok := programs[mint]
+		if !ok {
+			if program, err = c.tokenProgram(ctx, owner, mint, account.TokenAccount); err != nil {
+				return nil, err
+			}
+			programs[mint] = program
+		}
+		instruction, err := transaction.CloseTokenAccountInstruction(address, owner, program)
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, instruction)
+	}
+	return c.submitChunks(ctx, owner, instructions, maxClosesPerTx, opts)
+}
+
+func (c tokenAccountClient) sweepDust(ctx context.Context, opts DustOpts) ([]SweepResult, error) {
+	if opts.IsDust == nil {
+		return nil, ErrNoDustFilter
+	}
+	owner, err := c.owner()
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := c.accounts(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return SweepDustAccounts(ctx, owner.String(), accounts, opts, c.tradeSwap)
+}
+
+// SweepDustAccounts swaps the dust balances of accounts into SOL with tradeSwap, one by one. The swaps submitted before
+// an error are returned with it. It's SweepDust for clients that discover balances differently, e.g. paper trading.
+func SweepDustAccounts(ctx context.Context, owner string, accounts []*pb.TokenAccount, opts DustOpts, tradeSwap TradeSwapFunc) ([]SweepResult, error) {
+	if opts.IsDust == nil {
+		return nil, ErrNoDustFilter
+	}
+	var results []SweepResult
+	for _, account := range accounts {
+		if account.Amount <= 0 || account.TokenMint == solana.SolMint.String() || !opts.IsDust(account) {
+			continue
+		}
+		response, err := tradeSwap(ctx, owner, account.TokenMint, sweepOutToken, account.Amount, opts.Slippage, opts.Project, opts.SubmitOpts)
+		if err != nil {
+			return results, fmt.Errorf("could not sweep %v %v: %w", account.Amount, account.TokenMint, err)
+		}
+		results = append(results, SweepResult{Account: account, Response: response})
+	}
+	return results, nil
+}
+
+func (g *GRPCClient) tokenAccounts() tokenAccountClient {
+	return tokenAccountClient{
+		privateKey:       g.privateKey,
+		blockHash:        g,
+		accountFetcher:   g.accountFetcher,
+		getTokenAccounts: g.GetTokenAccounts,
+		submit:           g.SignAndSubmitBatch,
+		tradeSwap:        g.SubmitTradeSwap,
+	}
+}
+
+// CreateTokenAccounts creates the associated token accounts of the client's key for mints, skipping the existing ones
+func (g *GRPCClient) CreateTokenAccounts(ctx context.Context, mints []solana.PublicKey, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return g.tokenAccounts().createTokenAccounts(ctx, mints, opts)
+}
+
+// WrapSOL moves lamports of the client's key into its wrapped SOL account, created if needed
+func (g *GRPCClient) WrapSOL(ctx context.Context, lamports uint64, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return g.tokenAccounts().wrapSOL(ctx, lamports, opts)
+}
+
+// UnwrapSOL closes the wrapped SOL account of the client's key, returning its balance as SOL
+func (g *GRPCClient) UnwrapSOL(ctx context.Context, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return g.tokenAccounts().unwrapSOL(ctx, opts)
+}
+
+// CloseEmptyTokenAccounts closes the empty token accounts of the client's key listed by GetTokenAccounts, reclaiming
+// their rent
+func (g *GRPCClient) CloseEmptyTokenAccounts(ctx context.Context, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return g.tokenAccounts().closeEmptyTokenAccounts(ctx, opts)
+}
+
+// SweepDust swaps the balances of the client's key selected by DustOpts.IsDust into SOL with SubmitTradeSwap
+func (g *GRPCClient) SweepDust(ctx context.Context, opts DustOpts) ([]SweepResult, error) {
+	return g.tokenAccounts().sweepDust(ctx, opts)
+}
+
+func (w *WSClient) tokenAccounts() tokenAccountClient {
+	return tokenAccountClient{
+		privateKey:       w.privateKey,
+		blockHash:        w,
+		accountFetcher:   w.accountFetcher,
+		getTokenAccounts: w.GetTokenAccounts,
+		submit:           w.SignAndSubmitBatch,
+		tradeSwap: func(ctx context.Context, owner, inToken, outToken string, inAmount, slippage float64, project pb.Project, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+			return w.SubmitTradeSwap(ctx, owner, inToken, outToken, inAmount, slippage, ProjectToString(project), opts)
+		},
+	}
+}
+
+// CreateTokenAccounts creates the associated token accounts of the client's key for mints, skipping the existing ones
+func (w *WSClient) CreateTokenAccounts(ctx context.Context, mints []solana.PublicKey, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return w.tokenAccounts().createTokenAccounts(ctx, mints, opts)
+}
+
+// WrapSOL moves lamports of the client's key into its wrapped SOL account, created if needed
+func (w *WSClient) WrapSOL(ctx context.Context, lamports uint64, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return w.tokenAccounts().wrapSOL(ctx, lamports, opts)
+}
+
+// UnwrapSOL closes the wrapped SOL account of the client's key, returning its balance as SOL
+func (w *WSClient) UnwrapSOL(ctx context.Context, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return w.tokenAccounts().unwrapSOL(ctx, opts)
+}
+
+// CloseEmptyTokenAccounts closes the empty token accounts of the client's key listed by GetTokenAccounts, reclaiming
+// their rent
+func (w *WSClient) CloseEmptyTokenAccounts(ctx context.Context, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return w.tokenAccounts().closeEmptyTokenAccounts(ctx, opts)
+}
+
+// SweepDust swaps the balances of the client's key selected by DustOpts.IsDust into SOL with SubmitTradeSwap
+func (w *WSClient) SweepDust(ctx context.Context, opts DustOpts) ([]SweepResult, error) {
+	return w.tokenAccounts().sweepDust(ctx, opts)
+}
+
+func (h *HTTPClient) tokenAccounts() tokenAccountClient {
+	return tokenAccountClient{
+		privateKey:       h.privateKey,
+		blockHash:        h,
+		accountFetcher:   h.accountFetcher,
+		getTokenAccounts: h.GetTokenAccounts,
+		submit:           h.SignAndSubmitBatch,
+		tradeSwap:        h.SubmitTradeSwap,
+	}
+}
+
+// CreateTokenAccounts creates the associated token accounts of the client's key for mints, skipping the existing ones
+func (h *HTTPClient) CreateTokenAccounts(ctx context.Context, mints []solana.PublicKey, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return h.tokenAccounts().createTokenAccounts(ctx, mints, opts)
+}
+
+// WrapSOL moves lamports of the client's key into its wrapped SOL account, created if needed
+func (h *HTTPClient) WrapSOL(ctx context.Context, lamports uint64, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return h.tokenAccounts().wrapSOL(ctx, lamports, opts)
+}
+
+// UnwrapSOL closes the wrapped SOL account of the client's key, returning its balance as SOL
+func (h *HTTPClient) UnwrapSOL(ctx context.Context, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return h.tokenAccounts().unwrapSOL(ctx, opts)
+}
+
+// CloseEmptyTokenAccounts closes the empty token accounts of the client's key listed by GetTokenAccounts, reclaiming
+// their rent
+func (h *HTTPClient) CloseEmptyTokenAccounts(ctx context.Context, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return h.tokenAccounts().closeEmptyTokenAccounts(ctx, opts)
+}
+
+// SweepDust swaps the balances of the client's key selected by DustOpts.IsDust into SOL with SubmitTradeSwap
+func (h *HTTPClient) SweepDust(ctx context.Context, opts DustOpts) ([]SweepResult, error) {
+	return h.tokenAccounts().sweepDust(ctx, opts)
+}
@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedBlockHash solana.Hash
+
+func (h fixedBlockHash) RecentBlockHash(context.Context) (*pb.GetRecentBlockHashResponse, error) {
+	return &pb.GetRecentBlockHashResponse{BlockHash: solana.Hash(h).String()}, nil
+}
+
+// fakeTokenAccounts lists accounts and records the submitted transactions and swaps
+type fakeTokenAccounts struct {
+	accounts []*pb.TokenAccount
+	batches  [][]*solana.Transaction
+	swaps    []string
+}
+
+func (f *fakeTokenAccounts) client(t *testing.T, privateKey solana.PrivateKey) tokenAccountClient {
+	return tokenAccountClient{
+		privateKey: &privateKey,
+		blockHash:  fixedBlockHash(testBlockHash),
+		getTokenAccounts: func(_ context.Context, request *pb.GetTokenAccountsRequest) (*pb.GetTokenAccountsResponse, error) {
+			assert.Equal(t, privateKey.PublicKey().String(), request.OwnerAddress)
+			return &pb.GetTokenAccountsResponse{Accounts: f.accounts}, nil
+		},
+		submit: func(_ context.Context, transactions []*pb.TransactionMessage, _ bool, _ SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+			var batch []*solana.Transaction
+			for _, tx := range transactions {
+				ins, err := transaction.Inspect(tx.Content, transaction.InspectOpts{})
+				require.Nil(t, err)
+				assert.Contains(t, ins.Transaction().Message.AccountKeys, transaction.TraderAPIMemoProgram)
+				batch = append(batch, ins.Transaction())
+			}
+			f.batches = append(f.batches, batch)
+			return &pb.PostSubmitBatchResponse{}, nil
+		},
+		tradeSwap: func(_ context.Context, owner, inToken, outToken string, inAmount, _ float64, _ pb.Project, _ SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+			assert.Equal(t, privateKey.PublicKey().String(), owner)
+			assert.Equal(t, "SOL", outToken)
+			f.swaps = append(f.swaps, inToken)
+			return &pb.PostSubmitBatchResponse{}, nil
+		},
+	}
+}
+
+// fakeMints maps mints to their owner program
+type fakeMints map[solana.PublicKey]solana.PublicKey
+
+func (f fakeMints) GetAccountInfo(_ context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	owner, ok := f[account]
+	if !ok {
+		return nil, rpc.ErrNotFound
+	}
+	return &rpc.GetAccountInfoResult{Value: &rpc.Account{Owner: owner}}, nil
+}
+
+func TestTokenAccounts(t *testing.T) {
+	ctx := context.Background()
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	owner := privateKey.PublicKey()
+
+	usdc := solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+	existing, _, err := solana.FindAssociatedTokenAddress(owner, usdc)
+	require.Nil(t, err)
+	fake := &fakeTokenAccounts{accounts: []*pb.TokenAccount{
+		{Symbol: "USDC", TokenMint: usdc.String(), TokenAccount: existing.String(), Amount: 0.01},
+		{Symbol: "SOL", TokenMint: solana.SolMint.String(), TokenAccount: solana.NewWallet().PublicKey().String(), Amount: 0.5},
+	}}
+	for i := 0; i < maxClosesPerTx+4; i++ {
+		mint := solana.NewWallet().PublicKey().String()
+		fake.accounts = append(fake.accounts, &pb.TokenAccount{TokenMint: mint, TokenAccount: solana.NewWallet().PublicKey().String()})
+	}
+	token2022Mint := solana.NewWallet().PublicKey()
+	token2022Account, err := transaction.AssociatedTokenAddress(owner, token2022Mint, transaction.Token2022ProgramID)
+	require.Nil(t, err)
+	fake.accounts = append(fake.accounts, &pb.TokenAccount{TokenMint: token2022Mint.String(), TokenAccount: token2022Account.String()})
+	client := fake.client(t, privateKey)
+
+	// existing accounts and duplicates are skipped
+	other := solana.NewWallet().PublicKey()
+	_, err = client.createTokenAccounts(ctx, []solana.PublicKey{usdc, other, other}, SubmitOpts{})
+	require.Nil(t, err)
+	require.Len(t, fake.batches, 1)
+	require.Len(t, fake.batches[0], 1)
+	assert.Equal(t, owner, fake.batches[0][0].Message.AccountKeys[0])
+
+	_, err = client.createTokenAccounts(ctx, []solana.PublicKey{usdc}, SubmitOpts{})
+	require.Nil(t, err)
+	assert.Len(t, fake.batches, 1)
+
+	// the empty accounts are closed in chunks, each with the program of its mint
+	_, err = client.closeEmptyTokenAccounts(ctx, SubmitOpts{})
+	require.Nil(t, err)
+	require.Len(t, fake.batches, 2)
+	require.Len(t, fake.batches[1], 2)
+	last := fake.batches[1][1]
+	assert.Contains(t, last.Message.AccountKeys, transaction.Token2022ProgramID)
+	assert.Contains(t, last.Message.AccountKeys, token2022Account)
+
+	// with an account fetcher, new accounts of Token-2022 mints are created with it
+	newMint := solana.NewWallet().PublicKey()
+	client.accountFetcher = fakeMints{newMint: transaction.Token2022ProgramID}
+	_, err = client.createTokenAccounts(ctx, []solana.PublicKey{newMint}, SubmitOpts{})
+	require.Nil(t, err)
+	expected, err := transaction.AssociatedTokenAddress(owner, newMint, transaction.Token2022ProgramID)
+	require.Nil(t, err)
+	assert.Contains(t, fake.batches[2][0].Message.AccountKeys, expected)
+	assert.Contains(t, fake.batches[2][0].Message.AccountKeys, transaction.Token2022ProgramID)
+	client.accountFetcher = nil
+
+	_, err = client.wrapSOL(ctx, 1_000_000, SubmitOpts{})
+	require.Nil(t, err)
+	_, err = client.unwrapSOL(ctx, SubmitOpts{})
+	require.Nil(t, err)
+	assert.Len(t, fake.batches, 5)
+
+	// SOL and empty accounts are never swept
+	_, err = client.sweepDust(ctx, DustOpts{})
+	require.ErrorIs(t, err, ErrNoDustFilter)
+	results, err := client.sweepDust(ctx, DustOpts{IsDust: func(*pb.TokenAccount) bool { return true }})
+	require.Nil(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, []string{usdc.String()}, fake.swaps)
+}
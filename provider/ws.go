@@ -3,11 +3,10 @@ package provider
 import (
 	"context"
 	"errors"
-	"fmt"
 
 	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/journal"
 	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
-	"github.com/bloXroute-Labs/solana-trader-client-go/utils"
 	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
 	"github.com/bloXroute-Labs/solana-trader-proto/common"
 	"github.com/gagliardetto/solana-go"
@@ -19,6 +18,10 @@ type WSClient struct {
 	addr                 string
 	conn                 *connections.WS
 	privateKey           *solana.PrivateKey
+	policy               *transaction.Policy
+	dryRun               bool
+	journal              *journal.Journal
+	accountFetcher       transaction.AccountFetcher
 	recentBlockHashStore *recentBlockHashStore
 }
 
@@ -61,9 +64,13 @@ func NewWSClientWithOpts(opts RPCOpts) (*WSClient, error) {
 	}
 
 	client := &WSClient{
-		addr:       opts.Endpoint,
-		conn:       conn,
-		privateKey: opts.PrivateKey,
+		addr:           opts.Endpoint,
+		conn:           conn,
+		privateKey:     opts.PrivateKey,
+		policy:         opts.Policy,
+		dryRun:         opts.DryRun,
+		journal:        opts.Journal,
+		accountFetcher: opts.AccountFetcher,
 	}
 	client.recentBlockHashStore = newRecentBlockHashStore(
 		func(ctx context.Context) (*pb.GetRecentBlockHashResponse, error) {
@@ -244,6 +251,7 @@ func (w *WSClient) PostRaydiumRouteSwap(ctx context.Context, request *pb.PostRay
 
 // SubmitRaydiumCLMMSwap builds a Raydium Swap transaction then signs it, and submits to the network.
 func (w *WSClient) SubmitRaydiumCLMMSwap(ctx context.Context, request *pb.PostRaydiumSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = w.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
 	resp, err := w.PostRaydiumCLMMSwap(ctx, request)
 	if err != nil {
 		return nil, err
@@ -253,6 +261,7 @@ func (w *WSClient) SubmitRaydiumCLMMSwap(ctx context.Context, request *pb.PostRa
 
 // SubmitRaydiumCLMMRouteSwap builds a Raydium RouteSwap transaction then signs it, and submits to the network.
 func (w *WSClient) SubmitRaydiumCLMMRouteSwap(ctx context.Context, request *pb.PostRaydiumRouteSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = routePolicy(ctx, w.requests(), request.OwnerAddress, request.Tip, request.Steps)
 	resp, err := w.PostRaydiumCLMMRouteSwap(ctx, request)
 	if err != nil {
 		return nil, err
@@ -583,28 +592,39 @@ func (w *WSClient) PostSubmitBatch(ctx context.Context, request *pb.PostSubmitBa
 	return &response, nil
 }
 
-// PostSubmitV2 posts the transaction string to the Solana network.
+// PostSubmitV2 signs the transaction string and posts it to the Solana network.
 func (w *WSClient) PostSubmitV2(ctx context.Context, txBase64 string, skipPreFlight bool,
 	useBundle bool, useStakedRPCs bool) (*pb.PostSubmitResponse, error) {
 	if w.privateKey == nil {
 		return &pb.PostSubmitResponse{}, ErrPrivateKeyNotFound
 	}
 
+	if err := verifyTx(txBase64, *w.privateKey, w.policy); err != nil {
+		return &pb.PostSubmitResponse{}, err
+	}
+
 	txBase64, err := transaction.SignTxWithPrivateKey(txBase64, *w.privateKey)
 	if err != nil {
 		return &pb.PostSubmitResponse{}, err
 	}
-
-	request := &pb.PostSubmitRequest{
-		Transaction: &pb.TransactionMessage{
-			Content: txBase64,
-		},
-		SkipPreFlight:          skipPreFlight,
-		FrontRunningProtection: &useBundle,
-		UseStakedRPCs:          &useStakedRPCs,
+	signed := &pb.TransactionMessage{Content: txBase64}
+	if w.dryRun {
+		return &pb.PostSubmitResponse{}, dryRunSubmit(signed, skipPreFlight)
 	}
+
 	var response pb.PostSubmitResponse
-	err = w.conn.Request(ctx, "PostSubmitV2", request, &response)
+	_, err = journalSubmit(ctx, w.journal, signed, skipPreFlight, useBundle, useStakedRPCs, func(ctx context.Context) (string, error) {
+		request := &pb.PostSubmitRequest{
+			Transaction:            signed,
+			SkipPreFlight:          skipPreFlight,
+			FrontRunningProtection: &useBundle,
+			UseStakedRPCs:          &useStakedRPCs,
+		}
+		if err := w.conn.Request(ctx, "PostSubmitV2", request, &response); err != nil {
+			return "", err
+		}
+		return response.Signature, nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -621,6 +641,11 @@ func (w *WSClient) PostSubmitBatchV2(ctx context.Context, request *pb.PostSubmit
 	return &response, nil
 }
 
+// PublicKey returns the address of the client's private key
+func (w *WSClient) PublicKey() (solana.PublicKey, error) {
+	return publicKey(w.privateKey)
+}
+
 // SignAndSubmit signs the given transaction and submits it.
 func (w *WSClient) SignAndSubmit(ctx context.Context, tx *pb.TransactionMessage,
 	skipPreFlight bool, frontRunningProtection bool, useStakedRPCs bool) (string, error) {
@@ -628,17 +653,41 @@ func (w *WSClient) SignAndSubmit(ctx context.Context, tx *pb.TransactionMessage,
 		return "", ErrPrivateKeyNotFound
 	}
 
+	if err := verifyTx(tx.Content, *w.privateKey, w.policy); err != nil {
+		return "", err
+	}
 	txBase64, err := transaction.SignTxWithPrivateKey(tx.Content, *w.privateKey)
 	if err != nil {
 		return "", err
 	}
+	if w.dryRun {
+		return "", dryRunSubmit(&pb.TransactionMessage{Content: txBase64, IsCleanup: tx.IsCleanup}, skipPreFlight)
+	}
 
-	response, err := w.PostSubmit(ctx, txBase64, skipPreFlight, frontRunningProtection, useStakedRPCs)
-	if err != nil {
+	signed := &pb.TransactionMessage{Content: txBase64, IsCleanup: tx.IsCleanup}
+	return journalSubmit(ctx, w.journal, signed, skipPreFlight, frontRunningProtection, useStakedRPCs, func(ctx context.Context) (string, error) {
+		response, err := w.PostSubmit(ctx, txBase64, skipPreFlight, frontRunningProtection, useStakedRPCs)
+		if err != nil {
+			return "", err
+		}
+		return response.Signature, nil
+	})
+}
+
+// signAndSubmitRequest checks the transaction of a Submit* method against the policy derived from its parameters, then
+// signs and submits it
+func (w *WSClient) signAndSubmitRequest(ctx context.Context, tx *pb.TransactionMessage, skipPreFlight bool, policy *transaction.Policy) (string, error) {
+	if w.privateKey == nil {
+		return "", ErrPrivateKeyNotFound
+	}
+	if err := verifyTx(tx.Content, *w.privateKey, policy); err != nil {
 		return "", err
 	}
+	return w.SignAndSubmit(ctx, tx, skipPreFlight, false, false)
+}
 
-	return response.Signature, nil
+func (w *WSClient) requests() requestPolicy {
+	return requestPolicy{fetcher: w.accountFetcher}
 }
 
 // SignAndSubmitBatch signs the given transactions and submits them.
@@ -647,8 +696,23 @@ func (w *WSClient) SignAndSubmitBatch(ctx context.Context, transactions []*pb.Tr
 		return nil, ErrPrivateKeyNotFound
 	}
 
+	if opts.DryRun || w.dryRun {
+		batchRequest, err := buildBatchRequest(transactions, *w.privateKey, useBundle, opts, w.policy)
+		if err != nil {
+			return nil, err
+		}
+		return nil, newDryRunError(batchRequest)
+	}
+
 	if len(transactions) == 1 {
-		signature, err := w.SignAndSubmit(ctx, transactions[0], *opts.SkipPreFlight, false, false)
+		if err := verifyTx(transactions[0].Content, *w.privateKey, opts.Policy, opts.request); err != nil {
+			return nil, err
+		}
+		skipPreFlight := true
+		if opts.SkipPreFlight != nil {
+			skipPreFlight = *opts.SkipPreFlight
+		}
+		signature, err := w.SignAndSubmit(ctx, transactions[0], skipPreFlight, false, false)
 		if err != nil {
 			return nil, err
 		}
@@ -663,15 +727,31 @@ func (w *WSClient) SignAndSubmitBatch(ctx context.Context, transactions []*pb.Tr
 		}, nil
 	}
 
-	batchRequest, err := buildBatchRequest(transactions, *w.privateKey, useBundle, opts)
+	batchRequest, err := buildBatchRequest(transactions, *w.privateKey, useBundle, opts, w.policy)
 	if err != nil {
 		return nil, err
 	}
-	return w.PostSubmitBatch(ctx, batchRequest)
+	return journalBatch(w.journal, w.PostSubmitBatch)(ctx, batchRequest)
+}
+
+// SubmitBatch signs and submits transactions, returning the outcome of each. Failed transactions are retried with a
+// fresh block hash up to BatchOpts.MaxRetries times.
+func (w *WSClient) SubmitBatch(ctx context.Context, transactions []*pb.TransactionMessage, opts BatchOpts) (*BatchResult, error) {
+	if w.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
+	}
+	return submitBatch(ctx, batchClient{
+		submit:     journalBatch(w.journal, w.PostSubmitBatch),
+		blockHash:  w.RecentBlockHash,
+		privateKey: *w.privateKey,
+		policy:     w.policy,
+		dryRun:     w.dryRun,
+	}, transactions, opts)
 }
 
 // SubmitTradeSwap builds a TradeSwap transaction then signs it, and submits to the network.
 func (w *WSClient) SubmitTradeSwap(ctx context.Context, owner, inToken, outToken string, inAmount, slippage float64, project string, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = w.requests().swap(ctx, owner, inAmount, nil, inToken, outToken)
 	resp, err := w.PostTradeSwap(ctx, owner, inToken, outToken, inAmount, slippage, project)
 	if err != nil {
 		return nil, err
@@ -682,6 +762,7 @@ func (w *WSClient) SubmitTradeSwap(ctx context.Context, owner, inToken, outToken
 // SubmitTradeSwapWithPriorityFee builds a TradeSwap transaction then signs it, and submits to the network.
 func (w *WSClient) SubmitTradeSwapWithPriorityFee(ctx context.Context, owner, inToken, outToken string,
 	inAmount, slippage float64, project string, computeLimit uint32, computePrice uint64, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = w.requests().swap(ctx, owner, inAmount, nil, inToken, outToken)
 	resp, err := w.PostTradeSwapWithPriorityFee(ctx, owner, inToken, outToken, inAmount, slippage, computeLimit,
 		computePrice, project)
 	if err != nil {
@@ -692,6 +773,7 @@ func (w *WSClient) SubmitTradeSwapWithPriorityFee(ctx context.Context, owner, in
 
 // SubmitRouteTradeSwap builds a RouteTradeSwap transaction then signs it, and submits to the network.
 func (w *WSClient) SubmitRouteTradeSwap(ctx context.Context, request *pb.RouteTradeSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = routePolicy(ctx, w.requests(), request.OwnerAddress, request.Tip, request.Steps)
 	resp, err := w.PostRouteTradeSwap(ctx, request)
 	if err != nil {
 		return nil, err
@@ -701,6 +783,7 @@ func (w *WSClient) SubmitRouteTradeSwap(ctx context.Context, request *pb.RouteTr
 
 // SubmitRaydiumSwap builds a Raydium Swap transaction then signs it, and submits to the network.
 func (w *WSClient) SubmitRaydiumSwap(ctx context.Context, request *pb.PostRaydiumSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = w.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
 	resp, err := w.PostRaydiumSwap(ctx, request)
 	if err != nil {
 		return nil, err
@@ -715,7 +798,8 @@ func (w *WSClient) SubmitRaydiumSwapCPMM(ctx context.Context, request *pb.PostRa
 		return "", err
 	}
 
-	sig, err := w.SignAndSubmit(ctx, resp.Transaction, true, false, false)
+	policy := w.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
+	sig, err := w.signAndSubmitRequest(ctx, resp.Transaction, true, policy)
 	if err != nil {
 		return "", err
 	}
@@ -730,13 +814,14 @@ func (w *WSClient) SubmitPostPumpFunSwap(ctx context.Context, request *pb.PostPu
 	if err != nil {
 		return "", err
 	}
-	return w.SignAndSubmit(ctx, &pb.TransactionMessage{
+	return w.signAndSubmitRequest(ctx, &pb.TransactionMessage{
 		Content: resp.Transaction.Content,
-	}, false, false, false)
+	}, false, w.requests().pumpFunSwap(ctx, request))
 }
 
 // SubmitRaydiumRouteSwap builds a Raydium RouteSwap transaction then signs it, and submits to the network.
 func (w *WSClient) SubmitRaydiumRouteSwap(ctx context.Context, request *pb.PostRaydiumRouteSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = routePolicy(ctx, w.requests(), request.OwnerAddress, request.Tip, request.Steps)
 	resp, err := w.PostRaydiumRouteSwap(ctx, request)
 	if err != nil {
 		return nil, err
@@ -746,6 +831,7 @@ func (w *WSClient) SubmitRaydiumRouteSwap(ctx context.Context, request *pb.PostR
 
 // SubmitJupiterSwap builds a Jupiter Swap transaction then signs it, and submits to the network.
 func (w *WSClient) SubmitJupiterSwap(ctx context.Context, request *pb.PostJupiterSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = w.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
 	resp, err := w.PostJupiterSwap(ctx, request)
 	if err != nil {
 		return nil, err
@@ -755,124 +841,73 @@ func (w *WSClient) SubmitJupiterSwap(ctx context.Context, request *pb.PostJupite
 
 // SubmitJupiterSwapInstructions builds a Jupiter Swap transaction then signs it, and submits to the network.
 func (w *WSClient) SubmitJupiterSwapInstructions(ctx context.Context, request *pb.PostJupiterSwapInstructionsRequest, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
-	swapInstructions, err := w.PostJupiterSwapInstructions(ctx, request)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder := solana.NewTransactionBuilder()
-
-	addressLookupTable, err := utils.ConvertProtoAddressLookupTable(swapInstructions.AddressLookupTableAddresses)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder.WithOpt(solana.TransactionAddressTables(addressLookupTable))
-
-	instructions, err := utils.ConvertJupiterInstructions(swapInstructions.Instructions)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, inst := range instructions {
-		txBuilder.AddInstruction(inst)
-	}
-
-	txBuilder.SetFeePayer(w.privateKey.PublicKey())
-	blockHash, err := w.RecentBlockHash(ctx)
-
-	if err != nil {
-		panic(fmt.Errorf("server error: could not retrieve block hash: %w", err))
-	}
-
-	hash, err := solana.HashFromBase58(blockHash.BlockHash)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder.SetRecentBlockHash(hash)
-	tx, err := txBuilder.Build()
-	if err != nil {
-		return nil, err
+	if w.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
 	}
-
-	err = transaction.PartialSign(tx, w.privateKey.PublicKey(), make(map[solana.PublicKey]solana.PrivateKey))
+	opts.request = w.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
+	swapInstructions, err := w.PostJupiterSwapInstructions(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	var txToBeSigned []*pb.TransactionMessage
-
-	txBase64, err := tx.ToBase64()
+	transactions, err := builtTx(ctx, transaction.NewTxBuilder(w.privateKey.PublicKey(), w).AddJupiterSwap(swapInstructions))
 	if err != nil {
 		return nil, err
 	}
-
-	txToBeSigned = append(txToBeSigned, &pb.TransactionMessage{
-		Content:   txBase64,
-		IsCleanup: false,
-	})
-
-	return w.SignAndSubmitBatch(ctx, txToBeSigned, useBundle, opts)
+	return w.SignAndSubmitBatch(ctx, transactions, useBundle, opts)
 }
 
 // SubmitRaydiumSwapInstructions builds a Raydium Swap transaction then signs it, and submits to the network.
 func (w *WSClient) SubmitRaydiumSwapInstructions(ctx context.Context, request *pb.PostRaydiumSwapInstructionsRequest, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if w.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
+	}
+	opts.request = w.requests().swap(ctx, request.OwnerAddress, request.InAmount, request.Tip, request.InToken, request.OutToken)
 	swapInstructions, err := w.PostRaydiumSwapInstructions(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	instructions, err := utils.ConvertRaydiumInstructions(swapInstructions.Instructions)
+	transactions, err := builtTx(ctx, transaction.NewTxBuilder(w.privateKey.PublicKey(), w).AddRaydiumSwap(swapInstructions))
 	if err != nil {
 		return nil, err
 	}
-	txBuilder := solana.NewTransactionBuilder()
+	return w.SignAndSubmitBatch(ctx, transactions, useBundle, opts)
+}
 
-	for _, inst := range instructions {
-		txBuilder.AddInstruction(inst)
+// SubmitComposed builds the transaction of a composer, merging several swaps and instructions, then signs it and
+// submits it to the network.
+func (w *WSClient) SubmitComposed(ctx context.Context, composer *transaction.Composer, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if w.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
 	}
-
-	txBuilder.SetFeePayer(w.privateKey.PublicKey())
 	blockHash, err := w.RecentBlockHash(ctx)
-
-	if err != nil {
-		panic(fmt.Errorf("server error: could not retrieve block hash: %w", err))
-	}
-
-	hash, err := solana.HashFromBase58(blockHash.BlockHash)
 	if err != nil {
 		return nil, err
 	}
-
-	txBuilder.SetRecentBlockHash(hash)
-	tx, err := txBuilder.Build()
+	transactions, err := composedTx(composer, blockHash.BlockHash)
 	if err != nil {
 		return nil, err
 	}
+	return w.SignAndSubmitBatch(ctx, transactions, useBundle, opts)
+}
 
-	err = transaction.PartialSign(tx, w.privateKey.PublicKey(), make(map[solana.PublicKey]solana.PrivateKey))
-	if err != nil {
-		return nil, err
+// SubmitTxBuilder builds the transaction of a builder, with the Trader API memo, compute budget and tip, then signs it
+// and submits it to the network.
+func (w *WSClient) SubmitTxBuilder(ctx context.Context, builder *transaction.TxBuilder, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if w.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
 	}
-
-	var txToBeSigned []*pb.TransactionMessage
-
-	txBase64, err := tx.ToBase64()
+	transactions, err := builtTx(ctx, builder)
 	if err != nil {
 		return nil, err
 	}
-
-	txToBeSigned = append(txToBeSigned, &pb.TransactionMessage{
-		Content:   txBase64,
-		IsCleanup: false,
-	})
-
-	return w.SignAndSubmitBatch(ctx, txToBeSigned, useBundle, opts)
+	return w.SignAndSubmitBatch(ctx, transactions, useBundle, opts)
 }
 
 // SubmitJupiterRouteSwap builds a Jupiter RouteSwap transaction then signs it, and submits to the network.
 func (w *WSClient) SubmitJupiterRouteSwap(ctx context.Context, request *pb.PostJupiterRouteSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = routePolicy(ctx, w.requests(), request.OwnerAddress, request.Tip, request.Steps)
 	resp, err := w.PostJupiterRouteSwap(ctx, request)
 	if err != nil {
 		return nil, err
@@ -893,7 +928,7 @@ func (w *WSClient) SubmitOrder(ctx context.Context, owner, payer, market string,
 		skipPreFlight = *opts.SkipPreFlight
 	}
 
-	return w.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return w.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, w.requests().owner(owner))
 }
 
 // PostCancelOrder builds a Serum cancel order.
@@ -913,7 +948,7 @@ func (w *WSClient) SubmitCancelOrder(ctx context.Context, request *pb.PostCancel
 		return "", err
 	}
 
-	return w.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return w.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, w.requests().owner(request.OwnerAddress))
 }
 
 // PostCancelByClientOrderID builds a Serum cancel order by client ID.
@@ -955,7 +990,7 @@ func (w *WSClient) SubmitCancelByClientOrderID(
 		return "", err
 	}
 
-	return w.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return w.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, w.requests().owner(owner))
 }
 
 func (w *WSClient) PostCancelAll(
@@ -980,6 +1015,7 @@ func (w *WSClient) PostCancelAll(
 }
 
 func (w *WSClient) SubmitCancelAll(ctx context.Context, market, owner string, openOrdersAddresses []string, project pb.Project, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts.request = w.requests().owner(owner)
 	orders, err := w.PostCancelAll(ctx, market, owner, openOrdersAddresses, project)
 	if err != nil {
 		return nil, err
@@ -1011,7 +1047,7 @@ func (w *WSClient) SubmitSettle(ctx context.Context, owner, market, baseTokenWal
 	if err != nil {
 		return "", err
 	}
-	return w.SignAndSubmit(ctx, order.Transaction, skipPreflight, false, false)
+	return w.signAndSubmitRequest(ctx, order.Transaction, skipPreflight, w.requests().owner(owner))
 }
 
 func (w *WSClient) PostReplaceByClientOrderID(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts PostOrderOpts) (*pb.PostOrderResponse, error) {
@@ -1044,7 +1080,7 @@ func (w *WSClient) SubmitReplaceByClientOrderID(ctx context.Context, owner, paye
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	return w.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return w.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, w.requests().owner(owner))
 }
 
 func (w *WSClient) PostReplaceOrder(ctx context.Context, orderID, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts PostOrderOpts) (*pb.PostOrderResponse, error) {
@@ -1078,7 +1114,7 @@ func (w *WSClient) SubmitReplaceOrder(ctx context.Context, orderID, owner, payer
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	return w.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return w.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, w.requests().owner(owner))
 }
 
 func (w *WSClient) Close() error {
@@ -1352,7 +1388,7 @@ func (w *WSClient) SubmitOrderV2(ctx context.Context, owner, payer, market strin
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	return w.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return w.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, w.requests().owner(owner))
 }
 
 // PostCancelOrderV2 builds a Serum cancel order.
@@ -1375,6 +1411,7 @@ func (w *WSClient) SubmitCancelOrderV2(ctx context.Context, request *pb.PostCanc
 	return w.SignAndSubmitBatch(ctx, order.Transactions, false, SubmitOpts{
 		SubmitStrategy: pb.SubmitStrategy_P_SUBMIT_ALL,
 		SkipPreFlight:  &skipPreFlight,
+		request:        w.requests().owner(request.OwnerAddress),
 	})
 }
 
@@ -1401,7 +1438,7 @@ func (w *WSClient) SubmitSettleV2(ctx context.Context, owner, market, baseTokenW
 	if err != nil {
 		return "", err
 	}
-	return w.SignAndSubmit(ctx, order.Transaction, skipPreflight, false, false)
+	return w.signAndSubmitRequest(ctx, order.Transaction, skipPreflight, w.requests().owner(owner))
 }
 
 func (w *WSClient) PostReplaceOrderV2(ctx context.Context, orderID, owner, payer, market string, side string, orderType string, amount, price float64, opts PostOrderOpts) (*pb.PostOrderResponse, error) {
@@ -1434,7 +1471,7 @@ func (w *WSClient) SubmitReplaceOrderV2(ctx context.Context, orderID, owner, pay
 	if opts.SkipPreFlight != nil {
 		skipPreFlight = *opts.SkipPreFlight
 	}
-	return w.SignAndSubmit(ctx, order.Transaction, skipPreFlight, false, false)
+	return w.signAndSubmitRequest(ctx, order.Transaction, skipPreFlight, w.requests().owner(owner))
 }
 
 // GetRecentBlockHash returns recent block hash.
@@ -0,0 +1,142 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+)
+
+// Commitment is how far a submitted transaction has landed
+type Commitment int
+
+const (
+	// CommitmentNone is the commitment of a transaction that hasn't landed yet
+	CommitmentNone Commitment = iota
+	CommitmentProcessed
+	CommitmentConfirmed
+	CommitmentFinalized
+)
+
+func (c Commitment) String() string {
+	switch c {
+	case CommitmentProcessed:
+		return "processed"
+	case CommitmentConfirmed:
+		return "confirmed"
+	case CommitmentFinalized:
+		return "finalized"
+	default:
+		return "none"
+	}
+}
+
+// parseCommitment reads the status of GetTransaction
+func parseCommitment(status string) Commitment {
+	status = strings.ToLower(status)
+	switch {
+	case strings.Contains(status, "final"):
+		return CommitmentFinalized
+	case strings.Contains(status, "confirm"):
+		return CommitmentConfirmed
+	case strings.Contains(status, "process"):
+		return CommitmentProcessed
+	default:
+		return CommitmentNone
+	}
+}
+
+// State is the stage of a job
+type State string
+
+const (
+	StateQueued     State = "queued"
+	StateSubmitting State = "submitting"
+	// StateSubmitted jobs are waiting for their transactions to land. Event.Commitment tells how far they have.
+	StateSubmitted State = "submitted"
+	// StateDone jobs have reached their commitment
+	StateDone      State = "done"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// Final reports whether the job won't change anymore
+func (s State) Final() bool {
+	return s == StateDone || s == StateFailed || s == StateCancelled
+}
+
+// SubmitFunc submits the transactions of a job and returns their signatures
+type SubmitFunc func(ctx context.Context) ([]string, error)
+
+// Batch adapts a Submit* method returning a batch response. The job fails if a transaction wasn't accepted.
+func Batch(submit func(ctx context.Context) (*pb.PostSubmitBatchResponse, error)) SubmitFunc {
+	return func(ctx context.Context) ([]string, error) {
+		response, err := submit(ctx)
+		if err != nil {
+			return nil, err
+		}
+		result := provider.NewBatchResult(response)
+		if failed := result.Failed(); len(failed) > 0 {
+			return result.Signatures(), fmt.Errorf("%w: transaction %v %v: %v", ErrSubmitFailed, failed[0].Index, failed[0].Outcome, failed[0].Reason)
+		}
+		return result.Signatures(), nil
+	}
+}
+
+// Single adapts a Submit* method returning a signature, e.g. SubmitOrder
+func Single(submit func(ctx context.Context) (string, error)) SubmitFunc {
+	return func(ctx context.Context) ([]string, error) {
+		signature, err := submit(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []string{signature}, nil
+	}
+}
+
+// Dependency is a job that must reach a commitment before another job is submitted
+type Dependency struct {
+	Job string
+	// Commitment defaults to Opts.Commitment
+	Commitment Commitment
+}
+
+// After is a dependency on a job reaching a commitment
+func After(job string, commitment Commitment) Dependency {
+	return Dependency{Job: job, Commitment: commitment}
+}
+
+// Job is a submission of the queue
+type Job struct {
+	// ID identifies the job in dependencies, events and Cancel
+	ID string
+	// Wallet is the owner of the transactions, for Opts.MaxPerWallet
+	Wallet string
+	// Priority orders the jobs ready to be submitted, highest first, then in the order they were added
+	Priority int
+	// DependsOn are earlier jobs that must reach their commitment first. The job fails if one of them fails.
+	DependsOn []Dependency
+	// Submit calls a Submit* method
+	Submit SubmitFunc
+}
+
+// Status is the progress of a job
+type Status struct {
+	ID         string
+	State      State
+	Signatures []string
+	// Commitment is the lowest commitment of the job's transactions
+	Commitment Commitment
+	// Err is why the job failed or was cancelled. Jobs submitted in dry-run mode are done, with the
+	// *provider.DryRunError of their Submit function.
+	Err error
+}
+
+// Event is a change of a job's status
+type Event struct {
+	Status
+	Time time.Time
+}
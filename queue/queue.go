@@ -0,0 +1,443 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultPollInterval   = time.Second
+	defaultConfirmTimeout = 90 * time.Second
+	defaultMaxPerWallet   = 1
+)
+
+var (
+	ErrMissingJob           = errors.New("job needs an ID and a submit function")
+	ErrDuplicateJob         = errors.New("job ID is already queued")
+	ErrUnknownJob           = errors.New("unknown job")
+	ErrJobNotFinal          = errors.New("job is not done, failed or cancelled")
+	ErrCommitmentNotTracked = errors.New("dependency is done at a lower commitment")
+	ErrDependencyFailed     = errors.New("dependency failed")
+	ErrCancelled            = errors.New("job cancelled")
+	ErrSubmitFailed         = errors.New("submission failed")
+	ErrTransactionFailed    = errors.New("transaction failed")
+	ErrNotConfirmed         = errors.New("timed out waiting for commitment")
+)
+
+// StatusClient is implemented by all clients
+type StatusClient interface {
+	GetTransaction(ctx context.Context, request *pb.GetTransactionRequest) (*pb.GetTransactionResponse, error)
+}
+
+type Opts struct {
+	// Commitment is how far jobs are tracked, and the default commitment of dependencies (default CommitmentConfirmed)
+	Commitment Commitment
+	// MaxPerWallet limits the jobs of a wallet from submission until their transactions are processed (default 1).
+	// Jobs without a wallet are only limited by MaxConcurrent.
+	MaxPerWallet int
+	// MaxConcurrent limits all jobs from submission until their transactions are processed (default unlimited)
+	MaxConcurrent int
+	// PollInterval is how often GetTransaction is called for submitted transactions (default 1s)
+	PollInterval time.Duration
+	// ConfirmTimeout bounds how long submitted transactions are tracked (default 90s)
+	ConfirmTimeout time.Duration
+
+	// OnEvent is called after each change of a job, one event at a time and in order, from a goroutine of the queue. It
+	// may call the methods of the queue.
+	OnEvent func(Event)
+}
+
+type job struct {
+	Job
+	seq    int
+	ctx    context.Context
+	cancel context.CancelFunc
+	status Status
+	// target is the commitment the job is tracked to
+	target Commitment
+	// holding is set while the job counts toward the concurrency limits
+	holding bool
+	done    chan struct{}
+}
+
+// Queue submits jobs once their dependencies have landed, by priority and within concurrency limits, and tracks their
+// transactions until they reach a commitment
+type Queue struct {
+	client StatusClient
+	opts   Opts
+
+	m       sync.Mutex
+	jobs    map[string]*job
+	seq     int
+	wallets map[string]int
+	running int
+	events  []Event
+	// dispatching is set while a goroutine delivers events
+	dispatching bool
+}
+
+// NewQueue creates a queue tracking transactions with client
+func NewQueue(client StatusClient, opts Opts) *Queue {
+	if opts.Commitment == CommitmentNone {
+		opts.Commitment = CommitmentConfirmed
+	}
+	if opts.MaxPerWallet <= 0 {
+		opts.MaxPerWallet = defaultMaxPerWallet
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+	if opts.ConfirmTimeout <= 0 {
+		opts.ConfirmTimeout = defaultConfirmTimeout
+	}
+	return &Queue{
+		client:  client,
+		opts:    opts,
+		jobs:    make(map[string]*job),
+		wallets: make(map[string]int),
+	}
+}
+
+// Add queues a job. Its dependencies must have been added before. The job is cancelled with ctx.
+func (q *Queue) Add(ctx context.Context, newJob Job) error {
+	if newJob.ID == "" || newJob.Submit == nil {
+		return ErrMissingJob
+	}
+
+	q.m.Lock()
+	if _, ok := q.jobs[newJob.ID]; ok {
+		q.m.Unlock()
+		return fmt.Errorf("%w: %v", ErrDuplicateJob, newJob.ID)
+	}
+	for i, dependency := range newJob.DependsOn {
+		dep, ok := q.jobs[dependency.Job]
+		if !ok {
+			q.m.Unlock()
+			return fmt.Errorf("%w: dependency %v", ErrUnknownJob, dependency.Job)
+		}
+		if dependency.Commitment == CommitmentNone {
+			newJob.DependsOn[i].Commitment = q.opts.Commitment
+		}
+		if dep.status.State == StateDone && dep.status.Commitment < newJob.DependsOn[i].Commitment {
+			q.m.Unlock()
+			return fmt.Errorf("%w: %v is %v", ErrCommitmentNotTracked, dependency.Job, dep.status.Commitment)
+		}
+	}
+	for _, dependency := range newJob.DependsOn {
+		if dep := q.jobs[dependency.Job]; dependency.Commitment > dep.target {
+			dep.target = dependency.Commitment
+		}
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	j := &job{
+		Job:    newJob,
+		seq:    q.seq,
+		ctx:    jobCtx,
+		cancel: cancel,
+		status: Status{ID: newJob.ID, State: StateQueued},
+		target: q.opts.Commitment,
+		done:   make(chan struct{}),
+	}
+	q.seq++
+	q.jobs[j.ID] = j
+	q.emit(j)
+	q.schedule()
+	q.m.Unlock()
+
+	go q.watch(j)
+	return nil
+}
+
+// Cancel cancels a job and the jobs depending on it. Transactions already submitted are no longer tracked.
+func (q *Queue) Cancel(id string) error {
+	q.m.Lock()
+	j, ok := q.jobs[id]
+	if !ok {
+		q.m.Unlock()
+		return fmt.Errorf("%w: %v", ErrUnknownJob, id)
+	}
+	q.finish(j, StateCancelled, ErrCancelled)
+	q.m.Unlock()
+	return nil
+}
+
+// Forget removes a job that is done, failed or cancelled, so the queue doesn't grow without bound. Jobs depending on
+// it can no longer be added.
+func (q *Queue) Forget(id string) error {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	j, ok := q.jobs[id]
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrUnknownJob, id)
+	}
+	if !j.status.State.Final() {
+		return fmt.Errorf("%w: %v is %v", ErrJobNotFinal, id, j.status.State)
+	}
+	delete(q.jobs, id)
+	return nil
+}
+
+// Status returns the progress of a job
+func (q *Queue) Status(id string) (Status, error) {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	j, ok := q.jobs[id]
+	if !ok {
+		return Status{}, fmt.Errorf("%w: %v", ErrUnknownJob, id)
+	}
+	return j.snapshot(), nil
+}
+
+// Wait blocks until a job is done, failed or cancelled, and returns its status and error
+func (q *Queue) Wait(ctx context.Context, id string) (Status, error) {
+	q.m.Lock()
+	j, ok := q.jobs[id]
+	q.m.Unlock()
+	if !ok {
+		return Status{}, fmt.Errorf("%w: %v", ErrUnknownJob, id)
+	}
+
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		return Status{}, ctx.Err()
+	}
+	q.m.Lock()
+	status := j.snapshot()
+	q.m.Unlock()
+	return status, status.Err
+}
+
+// watch cancels a job when its context is done
+func (q *Queue) watch(j *job) {
+	<-j.ctx.Done()
+
+	q.m.Lock()
+	q.finish(j, StateCancelled, fmt.Errorf("%w: %v", ErrCancelled, j.ctx.Err()))
+	q.m.Unlock()
+}
+
+// schedule fails the queued jobs whose dependencies failed and starts the ready ones. The lock must be held.
+func (q *Queue) schedule() {
+	for {
+		var next *job
+		failed := false
+		for _, j := range q.jobs {
+			if j.status.State != StateQueued {
+				continue
+			}
+			ready := true
+			for _, dependency := range j.DependsOn {
+				// forgotten dependencies were done, or the job would have failed with them
+				dep, ok := q.jobs[dependency.Job]
+				if !ok {
+					continue
+				}
+				if dep.status.State == StateFailed || dep.status.State == StateCancelled {
+					q.finish(j, StateFailed, fmt.Errorf("%w: %v: %v", ErrDependencyFailed, dep.ID, dep.status.Err))
+					failed = true
+					break
+				}
+				if dep.status.Commitment < dependency.Commitment {
+					ready = false
+				}
+			}
+			if j.status.State != StateQueued || !ready || !q.available(j) {
+				continue
+			}
+			if next == nil || j.Priority > next.Priority || (j.Priority == next.Priority && j.seq < next.seq) {
+				next = j
+			}
+		}
+		if next == nil {
+			if failed {
+				continue
+			}
+			return
+		}
+		q.start(next)
+	}
+}
+
+func (q *Queue) available(j *job) bool {
+	if q.opts.MaxConcurrent > 0 && q.running >= q.opts.MaxConcurrent {
+		return false
+	}
+	return j.Wallet == "" || q.wallets[j.Wallet] < q.opts.MaxPerWallet
+}
+
+func (q *Queue) start(j *job) {
+	j.holding = true
+	q.running++
+	if j.Wallet != "" {
+		q.wallets[j.Wallet]++
+	}
+	j.status.State = StateSubmitting
+	q.emit(j)
+	go q.run(j)
+}
+
+func (q *Queue) release(j *job) {
+	if !j.holding {
+		return
+	}
+	j.holding = false
+	q.running--
+	if j.Wallet != "" {
+		q.wallets[j.Wallet]--
+	}
+}
+
+// finish ends a job that isn't final yet. The lock must be held.
+func (q *Queue) finish(j *job, state State, err error) {
+	if j.status.State.Final() {
+		return
+	}
+	j.status.State, j.status.Err = state, err
+	q.release(j)
+	close(j.done)
+	j.cancel()
+	q.emit(j)
+	q.schedule()
+}
+
+// run submits a job, then tracks its transactions
+func (q *Queue) run(j *job) {
+	signatures, err := j.Submit(j.ctx)
+
+	q.m.Lock()
+	switch {
+	case j.status.State.Final():
+	case errors.Is(err, provider.ErrDryRun):
+		// nothing was submitted, so dependents can be dry run as well
+		j.status.Commitment = CommitmentFinalized
+		q.finish(j, StateDone, err)
+	case err != nil:
+		j.status.Signatures = signatures
+		q.finish(j, StateFailed, err)
+	case len(signatures) == 0:
+		j.status.Commitment = CommitmentFinalized
+		q.finish(j, StateDone, nil)
+	default:
+		j.status.Signatures = signatures
+		j.status.State = StateSubmitted
+		q.emit(j)
+	}
+	tracked := j.status.State == StateSubmitted
+	q.m.Unlock()
+
+	if tracked {
+		q.track(j)
+	}
+}
+
+// track polls the transactions of a job until they reach its target commitment
+func (q *Queue) track(j *job) {
+	ticker := time.NewTicker(q.opts.PollInterval)
+	defer ticker.Stop()
+	timeout := time.NewTimer(q.opts.ConfirmTimeout)
+	defer timeout.Stop()
+
+	for {
+		commitment, err := q.poll(j)
+
+		q.m.Lock()
+		switch {
+		case j.status.State.Final():
+		case err != nil:
+			q.finish(j, StateFailed, err)
+		case commitment > j.status.Commitment:
+			j.status.Commitment = commitment
+			q.release(j)
+			if commitment >= j.target {
+				q.finish(j, StateDone, nil)
+			} else {
+				q.emit(j)
+				q.schedule()
+			}
+		}
+		final := j.status.State.Final()
+		q.m.Unlock()
+		if final {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-timeout.C:
+			q.m.Lock()
+			q.finish(j, StateFailed, fmt.Errorf("%w: %v after %v", ErrNotConfirmed, j.target, q.opts.ConfirmTimeout))
+			q.m.Unlock()
+			return
+		case <-j.ctx.Done():
+			return
+		}
+	}
+}
+
+// poll returns the lowest commitment of the job's transactions, or the error of a failed transaction
+func (q *Queue) poll(j *job) (Commitment, error) {
+	lowest := CommitmentFinalized
+	for _, signature := range j.status.Signatures {
+		response, err := q.client.GetTransaction(j.ctx, &pb.GetTransactionRequest{Signature: signature})
+		if err != nil {
+			log.Debugf("transaction %v of job %v not found yet: %v", signature, j.ID, err)
+			return CommitmentNone, nil
+		}
+		if response.Metadata != nil && response.Metadata.Errored {
+			return CommitmentNone, fmt.Errorf("%w: %v: %v", ErrTransactionFailed, signature, response.Metadata.Err)
+		}
+		if commitment := parseCommitment(response.Status); commitment < lowest {
+			lowest = commitment
+		}
+	}
+	return lowest, nil
+}
+
+// emit queues an event of the job for delivery. The lock must be held.
+func (q *Queue) emit(j *job) {
+	if q.opts.OnEvent == nil {
+		return
+	}
+	q.events = append(q.events, Event{Status: j.snapshot(), Time: time.Now()})
+	if !q.dispatching {
+		q.dispatching = true
+		go q.dispatch()
+	}
+}
+
+// dispatch delivers the queued events until there are none left. Only one dispatch runs at a time, and it doesn't
+// hold the lock while calling OnEvent.
+func (q *Queue) dispatch() {
+	for {
+		q.m.Lock()
+		events := q.events
+		q.events = nil
+		if len(events) == 0 {
+			q.dispatching = false
+			q.m.Unlock()
+			return
+		}
+		q.m.Unlock()
+
+		for _, event := range events {
+			q.opts.OnEvent(event)
+		}
+	}
+}
+
+func (j *job) snapshot() Status {
+	status := j.status
+	status.Signatures = append([]string(nil), j.status.Signatures...)
+	return status
+}
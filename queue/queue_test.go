@@ -0,0 +1,239 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChain lands every submitted transaction at the given status, and records the submission order
+type fakeChain struct {
+	m         sync.Mutex
+	statuses  map[string]*pb.GetTransactionResponse
+	submitted []string
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{statuses: make(map[string]*pb.GetTransactionResponse)}
+}
+
+func (c *fakeChain) GetTransaction(_ context.Context, request *pb.GetTransactionRequest) (*pb.GetTransactionResponse, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	response, ok := c.statuses[request.Signature]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return response, nil
+}
+
+func (c *fakeChain) submit(signature string, response *pb.GetTransactionResponse) SubmitFunc {
+	return Single(func(context.Context) (string, error) {
+		c.m.Lock()
+		defer c.m.Unlock()
+
+		c.submitted = append(c.submitted, signature)
+		c.statuses[signature] = response
+		return signature, nil
+	})
+}
+
+func (c *fakeChain) order() []string {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return append([]string(nil), c.submitted...)
+}
+
+// blocked submits once release is closed
+func blocked(release chan struct{}, submit SubmitFunc) SubmitFunc {
+	return func(ctx context.Context) ([]string, error) {
+		<-release
+		return submit(ctx)
+	}
+}
+
+var finalized = &pb.GetTransactionResponse{Status: "finalized"}
+
+func testOpts() Opts {
+	return Opts{PollInterval: time.Millisecond, ConfirmTimeout: time.Second}
+}
+
+func TestQueueDependencies(t *testing.T) {
+	ctx := context.Background()
+	chain := newFakeChain()
+
+	var m sync.Mutex
+	var events []Event
+	opts := testOpts()
+	opts.OnEvent = func(event Event) {
+		m.Lock()
+		defer m.Unlock()
+		events = append(events, event)
+	}
+	q := NewQueue(chain, opts)
+
+	// a isn't landing until it's released, so b waits for it
+	release := make(chan struct{})
+	require.Nil(t, q.Add(ctx, Job{ID: "a", Submit: blocked(release, chain.submit("sig-a", finalized))}))
+	require.Nil(t, q.Add(ctx, Job{ID: "b", DependsOn: []Dependency{After("a", CommitmentFinalized)}, Submit: chain.submit("sig-b", finalized)}))
+	require.ErrorIs(t, q.Add(ctx, Job{ID: "b", Submit: chain.submit("sig-b", finalized)}), ErrDuplicateJob)
+	require.ErrorIs(t, q.Add(ctx, Job{ID: "c", DependsOn: []Dependency{{Job: "unknown"}}, Submit: chain.submit("sig-c", finalized)}), ErrUnknownJob)
+
+	status, err := q.Status("b")
+	require.Nil(t, err)
+	assert.Equal(t, StateQueued, status.State)
+
+	close(release)
+	status, err = q.Wait(ctx, "b")
+	require.Nil(t, err)
+	assert.Equal(t, StateDone, status.State)
+	assert.Equal(t, CommitmentFinalized, status.Commitment)
+	assert.Equal(t, []string{"sig-b"}, status.Signatures)
+	assert.Equal(t, []string{"sig-a", "sig-b"}, chain.order())
+
+	// events are delivered after the change, in order
+	states := func() []State {
+		m.Lock()
+		defer m.Unlock()
+		var states []State
+		for _, event := range events {
+			if event.ID == "b" {
+				states = append(states, event.State)
+			}
+		}
+		return states
+	}
+	assert.Eventually(t, func() bool { return len(states()) == 4 }, time.Second, time.Millisecond)
+	assert.Equal(t, []State{StateQueued, StateSubmitting, StateSubmitted, StateDone}, states())
+
+	// forgotten jobs are removed once they're final, and their dependents still run
+	require.Nil(t, q.Add(ctx, Job{ID: "c", DependsOn: []Dependency{After("b", CommitmentFinalized)}, Submit: blocked(release, chain.submit("sig-c", finalized))}))
+	require.Nil(t, q.Forget("b"))
+	_, err = q.Status("b")
+	require.ErrorIs(t, err, ErrUnknownJob)
+	_, err = q.Wait(ctx, "c")
+	require.Nil(t, err)
+}
+
+func TestQueueEventHandler(t *testing.T) {
+	ctx := context.Background()
+	chain := newFakeChain()
+
+	// the handler may add and cancel jobs
+	opts := testOpts()
+	var q *Queue
+	opts.OnEvent = func(event Event) {
+		if event.ID == "a" && event.State == StateQueued {
+			assert.Nil(t, q.Add(ctx, Job{ID: "b", Submit: chain.submit("sig-b", finalized)}))
+		}
+		if event.ID == "b" && event.State == StateDone {
+			assert.ErrorIs(t, q.Forget("c"), ErrJobNotFinal)
+			assert.Nil(t, q.Cancel("c"))
+		}
+	}
+	q = NewQueue(chain, opts)
+
+	block := make(chan struct{})
+	require.Nil(t, q.Add(ctx, Job{ID: "c", Submit: blocked(block, chain.submit("sig-c", finalized))}))
+	require.Nil(t, q.Add(ctx, Job{ID: "a", Submit: chain.submit("sig-a", finalized)}))
+
+	_, err := q.Wait(ctx, "c")
+	require.ErrorIs(t, err, ErrCancelled)
+	status, err := q.Wait(ctx, "b")
+	require.Nil(t, err)
+	assert.Equal(t, StateDone, status.State)
+	close(block)
+}
+
+func TestQueuePriority(t *testing.T) {
+	ctx := context.Background()
+	chain := newFakeChain()
+	q := NewQueue(chain, testOpts())
+
+	// the wallet is busy with the first job, so the others are submitted by priority once it lands
+	release := make(chan struct{})
+	require.Nil(t, q.Add(ctx, Job{ID: "first", Wallet: "w", Submit: blocked(release, chain.submit("first", finalized))}))
+	require.Nil(t, q.Add(ctx, Job{ID: "low", Wallet: "w", Submit: chain.submit("low", finalized)}))
+	require.Nil(t, q.Add(ctx, Job{ID: "high", Wallet: "w", Priority: 1, Submit: chain.submit("high", finalized)}))
+	require.Nil(t, q.Add(ctx, Job{ID: "other", Wallet: "v", Submit: chain.submit("other", finalized)}))
+
+	_, err := q.Wait(ctx, "other")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"other"}, chain.order())
+
+	close(release)
+	_, err = q.Wait(ctx, "low")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"other", "first", "high", "low"}, chain.order())
+}
+
+func TestQueueFailures(t *testing.T) {
+	ctx := context.Background()
+	chain := newFakeChain()
+	q := NewQueue(chain, testOpts())
+
+	// a failed transaction fails its dependents
+	errored := &pb.GetTransactionResponse{Status: "confirmed", Metadata: &pb.TransactionMeta{Errored: true, Err: "custom program error"}}
+	require.Nil(t, q.Add(ctx, Job{ID: "a", Submit: chain.submit("sig-a", errored)}))
+	require.Nil(t, q.Add(ctx, Job{ID: "b", DependsOn: []Dependency{{Job: "a"}}, Submit: chain.submit("sig-b", finalized)}))
+	_, err := q.Wait(ctx, "a")
+	require.ErrorIs(t, err, ErrTransactionFailed)
+	status, err := q.Wait(ctx, "b")
+	require.ErrorIs(t, err, ErrDependencyFailed)
+	assert.Equal(t, StateFailed, status.State)
+
+	// a cancelled job fails its dependents
+	block := make(chan struct{})
+	require.Nil(t, q.Add(ctx, Job{ID: "c", Submit: Single(func(ctx context.Context) (string, error) {
+		select {
+		case <-block:
+		case <-ctx.Done():
+		}
+		return "", ctx.Err()
+	})}))
+	require.Nil(t, q.Add(ctx, Job{ID: "d", DependsOn: []Dependency{{Job: "c"}}, Submit: chain.submit("sig-d", finalized)}))
+	require.Nil(t, q.Cancel("c"))
+	status, err = q.Wait(ctx, "c")
+	require.ErrorIs(t, err, ErrCancelled)
+	assert.Equal(t, StateCancelled, status.State)
+	_, err = q.Wait(ctx, "d")
+	require.ErrorIs(t, err, ErrDependencyFailed)
+
+	// transactions that never land time out
+	opts := testOpts()
+	opts.ConfirmTimeout = 10 * time.Millisecond
+	q = NewQueue(chain, opts)
+	require.Nil(t, q.Add(ctx, Job{ID: "e", Submit: Single(func(context.Context) (string, error) { return "missing", nil })}))
+	_, err = q.Wait(ctx, "e")
+	require.ErrorIs(t, err, ErrNotConfirmed)
+
+	assert.NotContains(t, chain.order(), "sig-b")
+	assert.NotContains(t, chain.order(), "sig-d")
+}
+
+func TestQueueDryRun(t *testing.T) {
+	ctx := context.Background()
+	q := NewQueue(newFakeChain(), testOpts())
+
+	// dry runs aren't failures, so dependents are dry run too
+	dryRun := Single(func(context.Context) (string, error) {
+		return "", &provider.DryRunError{}
+	})
+	require.Nil(t, q.Add(ctx, Job{ID: "a", Submit: dryRun}))
+	require.Nil(t, q.Add(ctx, Job{ID: "b", DependsOn: []Dependency{{Job: "a"}}, Submit: dryRun}))
+	for _, id := range []string{"a", "b"} {
+		status, err := q.Wait(ctx, id)
+		var dryRunErr *provider.DryRunError
+		require.True(t, errors.As(err, &dryRunErr))
+		assert.Equal(t, StateDone, status.State)
+		assert.Empty(t, status.Signatures)
+	}
+}
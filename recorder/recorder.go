@@ -0,0 +1,232 @@
+package recorder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	defaultPrefix      = "stream"
+	defaultMaxFileSize = 100 << 20
+
+	protoExtension = ".pb"
+	jsonExtension  = ".jsonl"
+	fileTimeFormat = "20060102T150405.000000000"
+)
+
+// envelope fields of the protobuf format
+const (
+	receivedAtField protowire.Number = 1
+	slotField       protowire.Number = 2
+	messageField    protowire.Number = 3
+)
+
+var ErrClosed = errors.New("recorder is closed")
+
+// Format of the recording files
+type Format int
+
+const (
+	// FormatProto writes each update as a varint length followed by an envelope message with the receive time (unix
+	// nanoseconds, field 1), the slot (field 2) and the update (field 3)
+	FormatProto Format = iota
+	// FormatJSON writes each update as a line of JSON with the receive time, the slot and the protojson update
+	FormatJSON
+)
+
+func (f Format) extension() string {
+	if f == FormatJSON {
+		return jsonExtension
+	}
+	return protoExtension
+}
+
+type Opts struct {
+	// Dir the files are written to, created if needed
+	Dir string
+	// Prefix of the file names, followed by the time the file was opened (default "stream")
+	Prefix string
+	Format Format
+	// MaxFileSize in bytes after which a new file is started (default 100MB)
+	MaxFileSize int64
+	// MaxFileAge after which a new file is started. It's disabled if 0.
+	MaxFileAge time.Duration
+	// Slot returns the slot of an update. By default, the update's top level slot field is used if it has one.
+	Slot func(proto.Message) uint64
+}
+
+// Record is a recorded update
+type Record[T proto.Message] struct {
+	Message    T
+	ReceivedAt time.Time
+	Slot       uint64
+}
+
+type jsonRecord struct {
+	ReceivedAt time.Time       `json:"receivedAt"`
+	Slot       uint64          `json:"slot,omitempty"`
+	Message    json.RawMessage `json:"message"`
+}
+
+// Recorder writes stream updates to rotating files
+type Recorder[T proto.Message] struct {
+	opts Opts
+
+	m        sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+}
+
+func NewRecorder[T proto.Message](opts Opts) (*Recorder[T], error) {
+	if opts.Prefix == "" {
+		opts.Prefix = defaultPrefix
+	}
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = defaultMaxFileSize
+	}
+	if opts.Slot == nil {
+		opts.Slot = Slot
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create recording directory %v: %w", opts.Dir, err)
+	}
+	return &Recorder[T]{opts: opts}, nil
+}
+
+// Wrap records each update of a stream as it's read. Recording errors are logged and don't interrupt the stream.
+func (r *Recorder[T]) Wrap(s connections.Streamer[T]) connections.Streamer[T] {
+	return func() (T, error) {
+		msg, err := s()
+		if err != nil {
+			return msg, err
+		}
+		if recordErr := r.Record(msg, time.Now()); recordErr != nil {
+			log.Errorf("could not record update: %v", recordErr)
+		}
+		return msg, nil
+	}
+}
+
+// Record writes an update received at receivedAt
+func (r *Recorder[T]) Record(msg T, receivedAt time.Time) error {
+	data, err := r.encode(msg, receivedAt)
+	if err != nil {
+		return err
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if r.closed {
+		return ErrClosed
+	}
+	if err = r.rotate(int64(len(data))); err != nil {
+		return err
+	}
+	n, err := r.file.Write(data)
+	r.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("could not write to %v: %w", r.file.Name(), err)
+	}
+	return nil
+}
+
+// Close closes the current file. Further updates aren't recorded.
+func (r *Recorder[T]) Close() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.closed = true
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *Recorder[T]) encode(msg T, receivedAt time.Time) ([]byte, error) {
+	slot := r.opts.Slot(msg)
+	if r.opts.Format == FormatJSON {
+		payload, err := protojson.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal update: %w", err)
+		}
+		line, err := json.Marshal(jsonRecord{ReceivedAt: receivedAt.UTC(), Slot: slot, Message: payload})
+		if err != nil {
+			return nil, fmt.Errorf("could not marshal record: %w", err)
+		}
+		return append(line, '\n'), nil
+	}
+
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal update: %w", err)
+	}
+	var envelope []byte
+	envelope = protowire.AppendTag(envelope, receivedAtField, protowire.VarintType)
+	envelope = protowire.AppendVarint(envelope, uint64(receivedAt.UnixNano()))
+	envelope = protowire.AppendTag(envelope, slotField, protowire.VarintType)
+	envelope = protowire.AppendVarint(envelope, slot)
+	envelope = protowire.AppendTag(envelope, messageField, protowire.BytesType)
+	envelope = protowire.AppendBytes(envelope, payload)
+
+	data := protowire.AppendVarint(nil, uint64(len(envelope)))
+	return append(data, envelope...), nil
+}
+
+// rotate opens a new file if there's none yet or the current one is full or too old. The lock must be held.
+func (r *Recorder[T]) rotate(size int64) error {
+	if r.file != nil {
+		full := r.size > 0 && r.size+size > r.opts.MaxFileSize
+		old := r.opts.MaxFileAge > 0 && time.Since(r.openedAt) >= r.opts.MaxFileAge
+		if !full && !old {
+			return nil
+		}
+		if err := r.file.Close(); err != nil {
+			return fmt.Errorf("could not close %v: %w", r.file.Name(), err)
+		}
+		r.file = nil
+	}
+
+	now := time.Now().UTC()
+	name := filepath.Join(r.opts.Dir, fmt.Sprintf("%v-%v%v", r.opts.Prefix, now.Format(fileTimeFormat), r.opts.Format.extension()))
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("could not create recording file: %w", err)
+	}
+	r.file, r.size, r.openedAt = file, 0, now
+	return nil
+}
+
+// Slot returns the value of a message's top level slot field, or 0 if it has none
+func Slot(msg proto.Message) uint64 {
+	m := msg.ProtoReflect()
+	field := m.Descriptor().Fields().ByName("slot")
+	if field == nil {
+		return 0
+	}
+	switch field.Kind() {
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind, protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if v := m.Get(field).Int(); v > 0 {
+			return uint64(v)
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind, protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return m.Get(field).Uint()
+	}
+	return 0
+}
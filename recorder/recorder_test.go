@@ -0,0 +1,157 @@
+package recorder
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRecordReplay(t *testing.T) {
+	for _, format := range []Format{FormatProto, FormatJSON} {
+		dir := t.TempDir()
+		r, err := NewRecorder[*pb.GetTradesStreamResponse](Opts{Dir: dir, Format: format, MaxFileSize: 1})
+		require.Nil(t, err)
+
+		start := time.Now()
+		var updates []*pb.GetTradesStreamResponse
+		for i := 0; i < 3; i++ {
+			update := &pb.GetTradesStreamResponse{Slot: int64(100 + i), Trades: &pb.GetTradesResponse{Trades: []*pb.Trade{{Size: float64(i), FillPrice: 100}}}}
+			updates = append(updates, update)
+			require.Nil(t, r.Record(update, start.Add(time.Duration(i)*100*time.Millisecond)))
+			// file names have a nanosecond resolution
+			time.Sleep(time.Millisecond)
+		}
+		require.Nil(t, r.Close())
+		assert.ErrorIs(t, r.Record(updates[0], start), ErrClosed)
+
+		// each update is in its own file
+		files, err := Files(dir, "")
+		require.Nil(t, err)
+		require.Len(t, files, 3)
+
+		// 200ms of recording at 10x
+		replayStart := time.Now()
+		records := ReplayRecords(context.Background(), files, func() *pb.GetTradesStreamResponse { return &pb.GetTradesStreamResponse{} }, ReplayOpts{Speed: 10})
+		for i, update := range updates {
+			record, err := records()
+			require.Nil(t, err)
+			assert.True(t, proto.Equal(update, record.Message))
+			assert.Equal(t, uint64(100+i), record.Slot)
+			assert.True(t, start.Add(time.Duration(i)*100*time.Millisecond).Equal(record.ReceivedAt))
+		}
+		_, err = records()
+		assert.Equal(t, io.EOF, err)
+		assert.GreaterOrEqual(t, time.Since(replayStart), 20*time.Millisecond)
+		assert.Less(t, time.Since(replayStart), 200*time.Millisecond)
+	}
+}
+
+func TestReplayMaxSpeed(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRecorder[*pb.GetTradesStreamResponse](Opts{Dir: dir})
+	require.Nil(t, err)
+
+	start := time.Now()
+	require.Nil(t, r.Record(&pb.GetTradesStreamResponse{Slot: 1}, start))
+	require.Nil(t, r.Record(&pb.GetTradesStreamResponse{Slot: 2}, start.Add(time.Hour)))
+	require.Nil(t, r.Close())
+
+	files, err := Files(dir, "")
+	require.Nil(t, err)
+	stream := Replay(context.Background(), files, func() *pb.GetTradesStreamResponse { return &pb.GetTradesStreamResponse{} }, ReplayOpts{Speed: MaxSpeed})
+	for _, slot := range []int64{1, 2} {
+		update, err := stream()
+		require.Nil(t, err)
+		assert.Equal(t, slot, update.Slot)
+	}
+}
+
+func TestReplayInvalid(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRecorder[*pb.GetTradesStreamResponse](Opts{Dir: dir})
+	require.Nil(t, err)
+	start := time.Now()
+	require.Nil(t, r.Record(&pb.GetTradesStreamResponse{Slot: 1}, start))
+	require.Nil(t, r.Close())
+
+	// a length prefix above the maximum isn't allocated, and the replay continues with the next file
+	corrupt := filepath.Join(dir, "corrupt.pb")
+	require.Nil(t, os.WriteFile(corrupt, binary.AppendUvarint(nil, maxRecordSize+1), 0o644))
+	files, err := Files(dir, "")
+	require.Nil(t, err)
+	stream := Replay(context.Background(), append([]string{corrupt}, files...), func() *pb.GetTradesStreamResponse { return &pb.GetTradesStreamResponse{} }, ReplayOpts{Speed: MaxSpeed})
+	_, err = stream()
+	assert.ErrorIs(t, err, ErrInvalidRecord)
+	update, err := stream()
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), update.Slot)
+	_, err = stream()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReplayTo(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRecorder[*pb.GetTradesStreamResponse](Opts{Dir: dir})
+	require.Nil(t, err)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		require.Nil(t, r.Record(&pb.GetTradesStreamResponse{Slot: int64(i)}, start.Add(time.Duration(i)*time.Minute)))
+	}
+	require.Nil(t, r.Close())
+
+	// files after To aren't read
+	files, err := Files(dir, "")
+	require.Nil(t, err)
+	corrupt := filepath.Join(dir, "corrupt.pb")
+	require.Nil(t, os.WriteFile(corrupt, []byte{0xff}, 0o644))
+	stream := Replay(context.Background(), append(files, corrupt), func() *pb.GetTradesStreamResponse { return &pb.GetTradesStreamResponse{} }, ReplayOpts{
+		Speed: MaxSpeed,
+		From:  start.Add(time.Second),
+		To:    start.Add(90 * time.Second),
+	})
+	update, err := stream()
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), update.Slot)
+	for i := 0; i < 2; i++ {
+		_, err = stream()
+		assert.Equal(t, io.EOF, err)
+	}
+}
+
+func TestReplayMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRecorder[*pb.GetTradesStreamResponse](Opts{Dir: dir, MaxFileSize: 1})
+	require.Nil(t, err)
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		require.Nil(t, r.Record(&pb.GetTradesStreamResponse{Slot: int64(i)}, start.Add(time.Duration(i)*time.Millisecond)))
+		time.Sleep(time.Millisecond)
+	}
+	require.Nil(t, r.Close())
+	files, err := Files(dir, "")
+	require.Nil(t, err)
+	require.Len(t, files, 2)
+
+	// a file that can't be opened is reported once, between the updates of the others
+	files = []string{files[0], filepath.Join(dir, "missing.pb"), files[1]}
+	stream := Replay(context.Background(), files, func() *pb.GetTradesStreamResponse { return &pb.GetTradesStreamResponse{} }, ReplayOpts{Speed: MaxSpeed})
+	update, err := stream()
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), update.Slot)
+	_, err = stream()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	update, err = stream()
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), update.Slot)
+	_, err = stream()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,254 @@
+package recorder
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// MaxSpeed replays recordings as fast as possible
+var MaxSpeed = math.Inf(1)
+
+// maxRecordSize bounds the length of a protobuf record, so that a corrupt length prefix isn't allocated
+const maxRecordSize = 64 << 20
+
+var ErrInvalidRecord = errors.New("invalid record")
+
+type ReplayOpts struct {
+	// Speed relative to the recording: 1 replays at real speed, 10 ten times faster and MaxSpeed without waiting
+	// (default 1)
+	Speed float64
+	// From skips the updates received before it, and To ends the replay at the first update received after it, if set
+	From time.Time
+	To   time.Time
+}
+
+// Files returns the recording files of a prefix in a directory, in recording order
+func Files(dir, prefix string) ([]string, error) {
+	if prefix == "" {
+		prefix = defaultPrefix
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read recording directory %v: %w", dir, err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix+"-") {
+			continue
+		}
+		if ext := filepath.Ext(name); ext == protoExtension || ext == jsonExtension {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Replay streams the updates recorded in files, paced by their receive times. newMsg returns an empty update to
+// unmarshal into. The stream returns io.EOF after the last update.
+func Replay[T proto.Message](ctx context.Context, files []string, newMsg func() T, opts ReplayOpts) connections.Streamer[T] {
+	records := ReplayRecords(ctx, files, newMsg, opts)
+	return func() (T, error) {
+		record, err := records()
+		return record.Message, err
+	}
+}
+
+// ReplayRecords is like Replay, but streams the receive times and slots with the updates. A file that can't be read is
+// reported once, and the replay continues with the next file.
+func ReplayRecords[T proto.Message](ctx context.Context, files []string, newMsg func() T, opts ReplayOpts) connections.Streamer[Record[T]] {
+	if opts.Speed <= 0 {
+		opts.Speed = 1
+	}
+
+	var (
+		file    *os.File
+		reader  *bufio.Reader
+		index   int
+		first   time.Time
+		started time.Time
+	)
+	next := func() (Record[T], error) {
+		for {
+			if reader == nil {
+				if index >= len(files) {
+					return Record[T]{}, io.EOF
+				}
+				f, err := os.Open(files[index])
+				if err != nil {
+					index++
+					return Record[T]{}, fmt.Errorf("could not open recording file: %w", err)
+				}
+				file, reader = f, bufio.NewReader(f)
+			}
+
+			record, err := readRecord(reader, jsonFile(files[index]), newMsg)
+			if err == io.EOF {
+				_ = file.Close()
+				file, reader = nil, nil
+				index++
+				continue
+			}
+			if err != nil {
+				_ = file.Close()
+				file, reader = nil, nil
+				index++
+				return Record[T]{}, fmt.Errorf("could not read %v: %w", files[index-1], err)
+			}
+			return record, nil
+		}
+	}
+	// stop closes the current file and skips the remaining ones
+	stop := func() {
+		if file != nil {
+			_ = file.Close()
+		}
+		file, reader, index = nil, nil, len(files)
+	}
+
+	return func() (Record[T], error) {
+		for {
+			record, err := next()
+			if err != nil {
+				return record, err
+			}
+			if !opts.From.IsZero() && record.ReceivedAt.Before(opts.From) {
+				continue
+			}
+			// recordings are in receive order, so the remaining updates are after To as well
+			if !opts.To.IsZero() && record.ReceivedAt.After(opts.To) {
+				stop()
+				return Record[T]{}, io.EOF
+			}
+
+			if first.IsZero() {
+				first, started = record.ReceivedAt, time.Now()
+			} else if !math.IsInf(opts.Speed, 1) {
+				due := started.Add(time.Duration(float64(record.ReceivedAt.Sub(first)) / opts.Speed))
+				if wait := time.Until(due); wait > 0 {
+					timer := time.NewTimer(wait)
+					select {
+					case <-timer.C:
+					case <-ctx.Done():
+						timer.Stop()
+						return Record[T]{}, ctx.Err()
+					}
+				}
+			}
+			if err = ctx.Err(); err != nil {
+				return Record[T]{}, err
+			}
+			return record, nil
+		}
+	}
+}
+
+func jsonFile(name string) bool {
+	return filepath.Ext(name) == jsonExtension
+}
+
+func readRecord[T proto.Message](reader *bufio.Reader, isJSON bool, newMsg func() T) (Record[T], error) {
+	record := Record[T]{Message: newMsg()}
+	if isJSON {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) > 0 {
+			return record, fmt.Errorf("%w: truncated line", ErrInvalidRecord)
+		}
+		if err != nil {
+			return record, err
+		}
+
+		var r jsonRecord
+		if err = json.Unmarshal(line, &r); err != nil {
+			return record, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+		}
+		if err = protojson.Unmarshal(r.Message, record.Message); err != nil {
+			return record, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+		}
+		record.ReceivedAt, record.Slot = r.ReceivedAt, r.Slot
+		return record, nil
+	}
+
+	length, err := readUvarint(reader)
+	if err != nil {
+		return record, err
+	}
+	if length > maxRecordSize {
+		return record, fmt.Errorf("%w: length %v above %v", ErrInvalidRecord, length, maxRecordSize)
+	}
+	envelope := make([]byte, length)
+	if _, err = io.ReadFull(reader, envelope); err != nil {
+		return record, fmt.Errorf("%w: truncated record", ErrInvalidRecord)
+	}
+
+	for len(envelope) > 0 {
+		number, wireType, n := protowire.ConsumeTag(envelope)
+		if n < 0 {
+			return record, fmt.Errorf("%w: %v", ErrInvalidRecord, protowire.ParseError(n))
+		}
+		envelope = envelope[n:]
+
+		switch {
+		case number == receivedAtField && wireType == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(envelope)
+			if n < 0 {
+				return record, fmt.Errorf("%w: %v", ErrInvalidRecord, protowire.ParseError(n))
+			}
+			record.ReceivedAt = time.Unix(0, int64(v))
+			envelope = envelope[n:]
+		case number == slotField && wireType == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(envelope)
+			if n < 0 {
+				return record, fmt.Errorf("%w: %v", ErrInvalidRecord, protowire.ParseError(n))
+			}
+			record.Slot = v
+			envelope = envelope[n:]
+		case number == messageField && wireType == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(envelope)
+			if n < 0 {
+				return record, fmt.Errorf("%w: %v", ErrInvalidRecord, protowire.ParseError(n))
+			}
+			if err = proto.Unmarshal(v, record.Message); err != nil {
+				return record, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+			}
+			envelope = envelope[n:]
+		default:
+			n := protowire.ConsumeFieldValue(number, wireType, envelope)
+			if n < 0 {
+				return record, fmt.Errorf("%w: %v", ErrInvalidRecord, protowire.ParseError(n))
+			}
+			envelope = envelope[n:]
+		}
+	}
+	return record, nil
+}
+
+// readUvarint reads a length prefix, returning io.EOF only at a record boundary
+func readUvarint(reader *bufio.Reader) (uint64, error) {
+	v, err := binary.ReadUvarint(reader)
+	if err == io.EOF {
+		return 0, io.EOF
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+	}
+	return v, nil
+}
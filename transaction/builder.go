@@ -0,0 +1,221 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/utils"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+)
+
+var (
+	ErrNoBlockHash        = errors.New("no recent block hash or block hash source")
+	ErrLegacyLookupTables = errors.New("lookup tables need a v0 message")
+	ErrUnexpectedSigner   = errors.New("key is not a signer of the transaction")
+)
+
+var bloxrouteTipAccount = solana.MustPublicKeyFromBase58(utils.BloxrouteTipAddress)
+
+// BlockHashSource provides recent block hashes, e.g. the RecentBlockHash store of the provider clients
+type BlockHashSource interface {
+	RecentBlockHash(ctx context.Context) (*pb.GetRecentBlockHashResponse, error)
+}
+
+// TxBuilder builds transactions following the Trader API conventions: the Trader API memo is added, the compute
+// budget is set and an optional tip is paid to bloXroute. Errors of the chained calls are returned by Build.
+//
+//	tx, err := transaction.NewTxBuilder(owner, client).
+//		AddInstructions(instructions...).
+//		WithComputeUnitPrice(10_000).
+//		WithTip(1_000_000).
+//		Sign(ctx, privateKey)
+type TxBuilder struct {
+	composer   *Composer
+	source     BlockHashSource
+	blockHash  *solana.Hash
+	version    *solana.MessageVersion
+	tip        uint64
+	tipAccount solana.PublicKey
+	signers    []solana.PrivateKey
+	err        error
+}
+
+// NewTxBuilder creates a builder of transactions paid by the fee payer, with block hashes from source. The source may
+// be nil if the block hash is set with WithBlockHash.
+func NewTxBuilder(feePayer solana.PublicKey, source BlockHashSource) *TxBuilder {
+	composer := NewComposer(feePayer)
+	composer.estimateLimit = true
+	return &TxBuilder{
+		composer:   composer,
+		source:     source,
+		tipAccount: bloxrouteTipAccount,
+	}
+}
+
+// AddInstructions adds instructions, run after the ones added before
+func (b *TxBuilder) AddInstructions(instructions ...solana.Instruction) *TxBuilder {
+	b.composer.AddInstructions(instructions...)
+	return b
+}
+
+// AddJupiterSwap adds the instructions and lookup tables of PostJupiterSwapInstructions
+func (b *TxBuilder) AddJupiterSwap(response *pb.PostJupiterSwapInstructionsResponse) *TxBuilder {
+	b.setErr(b.composer.AddJupiterSwap(response))
+	return b
+}
+
+// AddRaydiumSwap adds the instructions of PostRaydiumSwapInstructions
+func (b *TxBuilder) AddRaydiumSwap(response *pb.PostRaydiumSwapInstructionsResponse) *TxBuilder {
+	b.setErr(b.composer.AddRaydiumSwap(response))
+	return b
+}
+
+// WithLookupTables adds lookup tables, making the message v0
+func (b *TxBuilder) WithLookupTables(tables map[solana.PublicKey]solana.PublicKeySlice) *TxBuilder {
+	b.setErr(b.composer.AddLookupTables(tables))
+	return b
+}
+
+// WithComputeUnitLimit replaces the unit limit, otherwise estimated from the instructions
+func (b *TxBuilder) WithComputeUnitLimit(limit uint32) *TxBuilder {
+	b.composer.computeLimit = limit
+	return b
+}
+
+// WithComputeUnitPrice replaces the unit price in micro-lamports, otherwise the highest price of the instructions
+func (b *TxBuilder) WithComputeUnitPrice(price uint64) *TxBuilder {
+	b.composer.computePrice = &price
+	return b
+}
+
+// WithTip pays lamports to the bloXroute tip account, e.g. for bundles and front running protection
+func (b *TxBuilder) WithTip(lamports uint64) *TxBuilder {
+	return b.WithTipAccount(bloxrouteTipAccount, lamports)
+}
+
+// WithTipAccount pays lamports to a tip account
+func (b *TxBuilder) WithTipAccount(account solana.PublicKey, lamports uint64) *TxBuilder {
+	b.tipAccount, b.tip = account, lamports
+	return b
+}
+
+// WithMemo replaces the message of the Trader API memo
+func (b *TxBuilder) WithMemo(msg string) *TxBuilder {
+	b.composer.memo = msg
+	return b
+}
+
+// WithVersion forces the message version. By default, messages with lookup tables are v0 and others are legacy.
+func (b *TxBuilder) WithVersion(version solana.MessageVersion) *TxBuilder {
+	b.version = &version
+	return b
+}
+
+// WithBlockHash sets the block hash instead of taking it from the source
+func (b *TxBuilder) WithBlockHash(hash solana.Hash) *TxBuilder {
+	b.blockHash = &hash
+	return b
+}
+
+// WithSigners signs the transaction with additional keys, e.g. of accounts created by the instructions
+func (b *TxBuilder) WithSigners(signers ...solana.PrivateKey) *TxBuilder {
+	b.signers = append(b.signers, signers...)
+	return b
+}
+
+// WithMaxAccountLocks changes the account lock limit, DefaultMaxAccountLocks by default
+func (b *TxBuilder) WithMaxAccountLocks(locks int) *TxBuilder {
+	b.composer.SetMaxAccountLocks(locks)
+	return b
+}
+
+// Build builds the transaction, signed by the additional signers only
+func (b *TxBuilder) Build(ctx context.Context) (*solana.Transaction, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	hash, err := b.recentBlockHash(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	composer := *b.composer
+	if b.tip > 0 {
+		tip := system.NewTransferInstruction(b.tip, composer.feePayer, b.tipAccount).Build()
+		composer.legs = append(composer.legs[:len(composer.legs):len(composer.legs)], []solana.Instruction{tip})
+	}
+	if b.version != nil && *b.version == solana.MessageVersionLegacy && len(composer.tables) > 0 {
+		return nil, ErrLegacyLookupTables
+	}
+
+	tx, err := composer.Build(hash)
+	if err != nil {
+		return nil, err
+	}
+	if b.version != nil && *b.version == solana.MessageVersionV0 && !tx.Message.IsVersioned() {
+		tx.Message.SetVersion(solana.MessageVersionV0)
+		size, err := txSize(tx)
+		if err != nil {
+			return nil, err
+		}
+		if size > MaxTxSize {
+			return nil, fmt.Errorf("%w: %v bytes, more than %v", ErrTransactionTooLarge, size, MaxTxSize)
+		}
+	}
+
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+	for _, signer := range b.signers {
+		index := signerIndex(tx, signer.PublicKey())
+		if index == -1 {
+			return nil, fmt.Errorf("%w: %v", ErrUnexpectedSigner, signer.PublicKey())
+		}
+		if err = placeSignature(tx, signer, index); err != nil {
+			return nil, err
+		}
+	}
+	return tx, nil
+}
+
+// BuildBase64 builds the transaction, ready for the fee payer's signature and the SignAndSubmit methods
+func (b *TxBuilder) BuildBase64(ctx context.Context) (string, error) {
+	tx, err := b.Build(ctx)
+	if err != nil {
+		return "", err
+	}
+	return tx.ToBase64()
+}
+
+// Sign builds the transaction and signs it with the fee payer's key
+func (b *TxBuilder) Sign(ctx context.Context, privateKey solana.PrivateKey) (*solana.Transaction, error) {
+	tx, err := b.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = signTx(tx, privateKey); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func (b *TxBuilder) recentBlockHash(ctx context.Context) (solana.Hash, error) {
+	if b.blockHash != nil {
+		return *b.blockHash, nil
+	}
+	if b.source == nil {
+		return solana.Hash{}, ErrNoBlockHash
+	}
+	response, err := b.source.RecentBlockHash(ctx)
+	if err != nil {
+		return solana.Hash{}, fmt.Errorf("could not retrieve block hash: %w", err)
+	}
+	return solana.HashFromBase58(response.BlockHash)
+}
+
+func (b *TxBuilder) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
@@ -0,0 +1,107 @@
+package transaction
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBlockHashSource struct {
+	hash solana.Hash
+}
+
+func (s fakeBlockHashSource) RecentBlockHash(context.Context) (*pb.GetRecentBlockHashResponse, error) {
+	return &pb.GetRecentBlockHashResponse{BlockHash: s.hash.String()}, nil
+}
+
+func TestTxBuilder(t *testing.T) {
+	ctx := context.Background()
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	owner := privateKey.PublicKey()
+	recipient := newKey(t)
+
+	tx, err := NewTxBuilder(owner, fakeBlockHashSource{hash: testBlockHash}).
+		AddInstructions(system.NewTransferInstruction(1_000, owner, recipient).Build()).
+		WithComputeUnitPrice(5_000).
+		WithTip(2_000).
+		Sign(ctx, privateKey)
+	require.Nil(t, err)
+	require.Nil(t, tx.VerifySignatures())
+	assert.Equal(t, testBlockHash, tx.Message.RecentBlockhash)
+	assert.False(t, tx.Message.IsVersioned())
+
+	ins, err := InspectTransaction(tx, InspectOpts{TipAccounts: []solana.PublicKey{bloxrouteTipAccount}})
+	require.Nil(t, err)
+	assert.Equal(t, owner, ins.FeePayer)
+	assert.Equal(t, uint32(2*defaultInstructionUnits), ins.ComputeUnitLimit)
+	assert.Equal(t, uint64(5_000), ins.ComputeUnitPrice)
+	require.Len(t, ins.Tips, 1)
+	assert.Equal(t, uint64(2_000), ins.Tips[0].Amount)
+	memo, err := tx.Message.Program(tx.Message.Instructions[len(tx.Message.Instructions)-1].ProgramIDIndex)
+	require.Nil(t, err)
+	assert.Equal(t, TraderAPIMemoProgram, memo)
+
+	// the tip isn't kept between builds
+	builder := NewTxBuilder(owner, nil).
+		WithBlockHash(testBlockHash).
+		AddInstructions(system.NewTransferInstruction(1_000, owner, recipient).Build()).
+		WithVersion(solana.MessageVersionV0)
+	tx, err = builder.Build(ctx)
+	require.Nil(t, err)
+	assert.True(t, tx.Message.IsVersioned())
+	assert.Len(t, tx.Message.Instructions, 3)
+	assert.Equal(t, []solana.Signature{{}}, tx.Signatures)
+
+	builder.WithLookupTables(map[solana.PublicKey]solana.PublicKeySlice{newKey(t): {recipient}}).
+		WithVersion(solana.MessageVersionLegacy)
+	_, err = builder.Build(ctx)
+	require.ErrorIs(t, err, ErrLegacyLookupTables)
+
+	_, err = NewTxBuilder(owner, nil).Build(ctx)
+	require.ErrorIs(t, err, ErrNoBlockHash)
+
+	_, err = NewTxBuilder(owner, nil).
+		WithBlockHash(testBlockHash).
+		AddRaydiumSwap(&pb.PostRaydiumSwapInstructionsResponse{Instructions: []*pb.InstructionRaydium{{ProgramID: "invalid"}}}).
+		Build(ctx)
+	require.NotNil(t, err)
+}
+
+func TestTxBuilderSigners(t *testing.T) {
+	ctx := context.Background()
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	account, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	owner := privateKey.PublicKey()
+
+	builder := NewTxBuilder(owner, nil).
+		WithBlockHash(testBlockHash).
+		AddInstructions(system.NewCreateAccountInstruction(1_000_000, 0, solana.SystemProgramID, owner, account.PublicKey()).Build())
+
+	tx, err := builder.Sign(ctx, privateKey)
+	require.Nil(t, err)
+	require.NotNil(t, tx.VerifySignatures())
+
+	builder.WithSigners(account)
+	txBase64, err := builder.BuildBase64(ctx)
+	require.Nil(t, err)
+	signed, err := SignTxWithPrivateKey(txBase64, privateKey)
+	require.Nil(t, err)
+	require.Nil(t, decodeTx(t, signed).VerifySignatures())
+
+	_, err = builder.WithSigners(privateKey, account).WithSigners(newSigner(t)).Build(ctx)
+	require.ErrorIs(t, err, ErrUnexpectedSigner)
+}
+
+func newSigner(t *testing.T) solana.PrivateKey {
+	key, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	return key
+}
@@ -0,0 +1,258 @@
+package transaction
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/utils"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+)
+
+const (
+	// DefaultMaxAccountLocks is the number of accounts a transaction may lock
+	DefaultMaxAccountLocks = 64
+	// MaxComputeUnitLimit is the most compute units a transaction may request
+	MaxComputeUnitLimit = 1_400_000
+
+	// defaultInstructionUnits are the compute units of an instruction without an explicit limit
+	defaultInstructionUnits = 200_000
+)
+
+var (
+	ErrTransactionTooLarge = errors.New("transaction too large")
+	ErrTooManyAccountLocks = errors.New("transaction locks too many accounts")
+	ErrLookupTableConflict = errors.New("conflicting lookup table contents")
+)
+
+// Composer merges the instructions of several swaps and user instructions into one atomic transaction. Compute budget
+// instructions are deduplicated: the unit limits of the legs are added up and the highest unit price is kept. Memo
+// instructions of the legs are dropped and the Trader API memo is added once.
+type Composer struct {
+	feePayer        solana.PublicKey
+	legs            [][]solana.Instruction
+	tables          map[solana.PublicKey]solana.PublicKeySlice
+	computeLimit    uint32
+	computePrice    *uint64
+	maxAccountLocks int
+
+	// memo is the message of the memo instruction, BxMemoMarkerMsg if empty
+	memo string
+	// estimateLimit sets a unit limit even when no leg has one
+	estimateLimit bool
+}
+
+// NewComposer creates a composer of transactions paid by the fee payer
+func NewComposer(feePayer solana.PublicKey) *Composer {
+	return &Composer{
+		feePayer:        feePayer,
+		tables:          make(map[solana.PublicKey]solana.PublicKeySlice),
+		maxAccountLocks: DefaultMaxAccountLocks,
+	}
+}
+
+// AddJupiterSwap adds the instructions and lookup tables of PostJupiterSwapInstructions
+func (c *Composer) AddJupiterSwap(response *pb.PostJupiterSwapInstructionsResponse) error {
+	tables, err := utils.ConvertProtoAddressLookupTable(response.AddressLookupTableAddresses)
+	if err != nil {
+		return err
+	}
+	instructions, err := utils.ConvertJupiterInstructions(response.Instructions)
+	if err != nil {
+		return err
+	}
+	if err = c.AddLookupTables(tables); err != nil {
+		return err
+	}
+	c.AddInstructions(instructions...)
+	return nil
+}
+
+// AddRaydiumSwap adds the instructions of PostRaydiumSwapInstructions
+func (c *Composer) AddRaydiumSwap(response *pb.PostRaydiumSwapInstructionsResponse) error {
+	instructions, err := utils.ConvertRaydiumInstructions(response.Instructions)
+	if err != nil {
+		return err
+	}
+	c.AddInstructions(instructions...)
+	return nil
+}
+
+// AddInstructions adds a leg of instructions, run after the legs added before
+func (c *Composer) AddInstructions(instructions ...solana.Instruction) {
+	if len(instructions) > 0 {
+		c.legs = append(c.legs, instructions)
+	}
+}
+
+// AddLookupTables merges lookup tables. A table added twice keeps its longer contents, which must extend the shorter.
+func (c *Composer) AddLookupTables(tables map[solana.PublicKey]solana.PublicKeySlice) error {
+	for address, accounts := range tables {
+		existing, ok := c.tables[address]
+		shorter, longer := existing, accounts
+		if len(shorter) > len(longer) {
+			shorter, longer = longer, shorter
+		}
+		for i := range shorter {
+			if ok && !shorter[i].Equals(longer[i]) {
+				return fmt.Errorf("%w: table %v", ErrLookupTableConflict, address)
+			}
+		}
+		c.tables[address] = longer
+	}
+	return nil
+}
+
+// SetComputeBudget replaces the compute budget of the legs. A zero limit keeps the legs' limits.
+func (c *Composer) SetComputeBudget(limit uint32, price uint64) {
+	c.computeLimit = limit
+	c.computePrice = &price
+}
+
+// SetMaxAccountLocks changes the account lock limit, DefaultMaxAccountLocks by default
+func (c *Composer) SetMaxAccountLocks(locks int) {
+	c.maxAccountLocks = locks
+}
+
+// Build composes an unsigned transaction, v0 if lookup tables were added
+func (c *Composer) Build(recentBlockHash solana.Hash) (*solana.Transaction, error) {
+	instructions, err := c.instructions()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []solana.TransactionOption{solana.TransactionPayer(c.feePayer)}
+	if len(c.tables) > 0 {
+		opts = append(opts, solana.TransactionAddressTables(c.tables))
+	}
+	tx, err := solana.NewTransaction(instructions, recentBlockHash, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	locks := len(tx.Message.AccountKeys) + tx.Message.NumLookups()
+	if locks > c.maxAccountLocks {
+		return nil, fmt.Errorf("%w: %v accounts, more than %v", ErrTooManyAccountLocks, locks, c.maxAccountLocks)
+	}
+	size, err := txSize(tx)
+	if err != nil {
+		return nil, err
+	}
+	if size > MaxTxSize {
+		return nil, fmt.Errorf("%w: %v bytes, more than %v", ErrTransactionTooLarge, size, MaxTxSize)
+	}
+	return tx, nil
+}
+
+// BuildBase64 composes a transaction with zero signatures, ready for SignTx and the SignAndSubmit methods
+func (c *Composer) BuildBase64(recentBlockHash solana.Hash) (string, error) {
+	tx, err := c.Build(recentBlockHash)
+	if err != nil {
+		return "", err
+	}
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+	return tx.ToBase64()
+}
+
+// instructions lists the compute budget, the instructions of the legs and the memo
+func (c *Composer) instructions() ([]solana.Instruction, error) {
+	var (
+		body      []solana.Instruction
+		limit     uint32
+		limitSet  bool
+		price     uint64
+		heapFrame uint32
+		seen      = make(map[string]bool)
+	)
+	for _, leg := range c.legs {
+		var legLimit, legInstructions uint32
+		for _, instruction := range leg {
+			data, err := instruction.Data()
+			if err != nil {
+				return nil, err
+			}
+			program := instruction.ProgramID()
+			switch {
+			case program.Equals(solana.ComputeBudget):
+				switch {
+				case len(data) >= 5 && data[0] == computebudget.Instruction_SetComputeUnitLimit:
+					legLimit = binary.LittleEndian.Uint32(data[1:5])
+					limitSet = true
+				case len(data) >= 9 && data[0] == computebudget.Instruction_SetComputeUnitPrice:
+					if p := binary.LittleEndian.Uint64(data[1:9]); p > price {
+						price = p
+					}
+				case len(data) >= 5 && data[0] == computebudget.Instruction_RequestHeapFrame:
+					if h := binary.LittleEndian.Uint32(data[1:5]); h > heapFrame {
+						heapFrame = h
+					}
+				}
+				continue
+			case program.Equals(TraderAPIMemoProgram):
+				continue
+			case program.Equals(solana.SPLAssociatedTokenAccountProgramID) && len(data) == 1 && data[0] == 1:
+				// idempotent account creations of several legs are the same
+				key := instructionKey(instruction, data)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			body = append(body, instruction)
+			legInstructions++
+		}
+		if legLimit == 0 {
+			legLimit = legInstructions * defaultInstructionUnits
+		}
+		limit += legLimit
+	}
+
+	if c.computeLimit > 0 {
+		limit, limitSet = c.computeLimit, true
+	}
+	if c.estimateLimit {
+		limitSet = true
+	}
+	if c.computePrice != nil {
+		price = *c.computePrice
+	}
+	if limit > MaxComputeUnitLimit {
+		limit = MaxComputeUnitLimit
+	}
+
+	var instructions []solana.Instruction
+	if limitSet {
+		instructions = append(instructions, computebudget.NewSetComputeUnitLimitInstruction(limit).Build())
+	}
+	if price > 0 {
+		instructions = append(instructions, computebudget.NewSetComputeUnitPriceInstruction(price).Build())
+	}
+	if heapFrame > 0 {
+		instructions = append(instructions, computebudget.NewRequestHeapFrameInstruction(heapFrame).Build())
+	}
+	instructions = append(instructions, body...)
+	return append(instructions, CreateTraderAPIMemoInstruction(c.memo)), nil
+}
+
+func instructionKey(instruction solana.Instruction, data []byte) string {
+	key := instruction.ProgramID().String() + string(data)
+	for _, account := range instruction.Accounts() {
+		key += account.PublicKey.String()
+	}
+	return key
+}
+
+// txSize is the serialized size of a signed transaction
+func txSize(tx *solana.Transaction) (int, error) {
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	signatures := int(tx.Message.Header.NumRequiredSignatures)
+	var length []byte
+	bin.EncodeCompactU16Length(&length, signatures)
+	return len(length) + signatures*solana.SignatureLength + len(message), nil
+}
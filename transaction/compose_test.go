@@ -0,0 +1,126 @@
+package transaction
+
+import (
+	"testing"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func accountProto(key solana.PublicKey, writable, signer bool) *pb.AccountMeta {
+	return &pb.AccountMeta{ProgramID: key.String(), IsWritable: writable, IsSigner: signer}
+}
+
+func TestComposer(t *testing.T) {
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	owner := privateKey.PublicKey()
+	recipient := newKey(t)
+	jupiter := newKey(t)
+	raydium := newKey(t)
+	table := newKey(t)
+	tableAccounts := []solana.PublicKey{newKey(t), newKey(t), newKey(t)}
+
+	limit := computebudget.NewSetComputeUnitLimitInstruction(300_000).Build()
+	limitData, err := limit.Data()
+	require.Nil(t, err)
+	price := computebudget.NewSetComputeUnitPriceInstruction(1_000).Build()
+	priceData, err := price.Data()
+	require.Nil(t, err)
+
+	jupiterSwap := &pb.PostJupiterSwapInstructionsResponse{
+		Instructions: []*pb.InstructionJupiter{
+			{ProgramID: solana.ComputeBudget.String(), Data: limitData},
+			{ProgramID: solana.ComputeBudget.String(), Data: priceData},
+			{ProgramID: jupiter.String(), Accounts: []*pb.AccountMeta{
+				accountProto(owner, true, true),
+				accountProto(tableAccounts[0], true, false),
+				accountProto(tableAccounts[1], false, false),
+			}, Data: []byte{1}},
+		},
+		AddressLookupTableAddresses: map[string]*pb.PublicKeys{
+			table.String(): {Pks: []string{tableAccounts[0].String(), tableAccounts[1].String(), tableAccounts[2].String()}},
+		},
+	}
+	raydiumSwap := &pb.PostRaydiumSwapInstructionsResponse{
+		Instructions: []*pb.InstructionRaydium{
+			{ProgramID: solana.ComputeBudget.String(), Data: priceData},
+			{ProgramID: raydium.String(), Accounts: []*pb.AccountMeta{
+				accountProto(owner, true, true),
+				accountProto(tableAccounts[2], true, false),
+			}, Data: []byte{2}},
+			{ProgramID: TraderAPIMemoProgram.String(), Data: []byte(BxMemoMarkerMsg)},
+		},
+	}
+
+	composer := NewComposer(owner)
+	require.Nil(t, composer.AddJupiterSwap(jupiterSwap))
+	require.Nil(t, composer.AddRaydiumSwap(raydiumSwap))
+	composer.AddInstructions(system.NewTransferInstruction(1_000, owner, recipient).Build())
+
+	txBase64, err := composer.BuildBase64(testBlockHash)
+	require.Nil(t, err)
+
+	ins, err := Inspect(txBase64, InspectOpts{LookupTables: map[solana.PublicKey]solana.PublicKeySlice{table: tableAccounts}})
+	require.Nil(t, err)
+	assert.True(t, ins.Versioned)
+	assert.True(t, ins.Resolved)
+	assert.Equal(t, []solana.PublicKey{table}, ins.LookupTables)
+	assert.Equal(t, owner, ins.FeePayer)
+
+	// one limit and one price; the raydium and transfer legs have no limit and count as default instructions
+	assert.Equal(t, 6, ins.Instructions)
+	assert.Equal(t, uint32(300_000+2*defaultInstructionUnits), ins.ComputeUnitLimit)
+	assert.Equal(t, uint64(1_000), ins.ComputeUnitPrice)
+	assert.Equal(t, []solana.PublicKey{solana.ComputeBudget, jupiter, raydium, solana.SystemProgramID, TraderAPIMemoProgram}, ins.Programs)
+
+	tx := ins.Transaction()
+	memo, err := tx.Message.Program(tx.Message.Instructions[len(tx.Message.Instructions)-1].ProgramIDIndex)
+	require.Nil(t, err)
+	assert.Equal(t, TraderAPIMemoProgram, memo)
+
+	composer.SetComputeBudget(500_000, 2_000)
+	ins, err = Inspect(mustBase64(t, composer), InspectOpts{})
+	require.Nil(t, err)
+	assert.Equal(t, uint32(500_000), ins.ComputeUnitLimit)
+	assert.Equal(t, uint64(2_000), ins.ComputeUnitPrice)
+
+	signed, err := SignTxWithPrivateKey(mustBase64(t, composer), privateKey)
+	require.Nil(t, err)
+	ins, err = Inspect(signed, InspectOpts{})
+	require.Nil(t, err)
+	require.Nil(t, ins.Transaction().VerifySignatures())
+}
+
+func mustBase64(t *testing.T, composer *Composer) string {
+	txBase64, err := composer.BuildBase64(testBlockHash)
+	require.Nil(t, err)
+	return txBase64
+}
+
+func TestComposerLimits(t *testing.T) {
+	owner := newKey(t)
+
+	composer := NewComposer(owner)
+	for i := 0; i < 4; i++ {
+		var accounts solana.AccountMetaSlice
+		for j := 0; j < 20; j++ {
+			accounts = append(accounts, solana.Meta(newKey(t)).WRITE())
+		}
+		composer.AddInstructions(&solana.GenericInstruction{ProgID: newKey(t), AccountValues: accounts})
+	}
+	_, err := composer.Build(testBlockHash)
+	require.ErrorIs(t, err, ErrTooManyAccountLocks)
+
+	composer.SetMaxAccountLocks(128)
+	_, err = composer.Build(testBlockHash)
+	require.ErrorIs(t, err, ErrTransactionTooLarge)
+
+	composer = NewComposer(owner)
+	require.Nil(t, composer.AddLookupTables(map[solana.PublicKey]solana.PublicKeySlice{owner: {newKey(t)}}))
+	require.ErrorIs(t, composer.AddLookupTables(map[solana.PublicKey]solana.PublicKeySlice{owner: {newKey(t)}}), ErrLookupTableConflict)
+}
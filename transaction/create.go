@@ -1,33 +1,22 @@
 package transaction
 
 import (
+	"context"
+
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/programs/system"
 )
 
 const ReceipientAddress = "5wiGAqf4BX23XU6jc3MDDZAFoNV5pz61thsUuSgpsAxS"
 
+// CreateSampleTx creates a signed transfer of lamports to ReceipientAddress. Like any TxBuilder transaction, it also has
+// a compute unit limit instruction and the Trader API memo.
 func CreateSampleTx(privateKey solana.PrivateKey, recentBlockHash solana.Hash, lamports uint64) (*solana.Transaction, error) {
 
 	recipient := solana.MustPublicKeyFromBase58(ReceipientAddress)
 
-	tx, err := solana.NewTransaction([]solana.Instruction{
-		system.NewTransferInstruction(lamports, privateKey.PublicKey(), recipient).Build(),
-	}, recentBlockHash)
-	if err != nil {
-		return nil, err
-	}
-
-	signatures, err := tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(privateKey.PublicKey()) {
-			return &privateKey
-		}
-		return nil
-	})
-	if err != nil {
-		return nil, err
-	}
-	tx.Signatures = signatures
-
-	return tx, nil
+	return NewTxBuilder(privateKey.PublicKey(), nil).
+		WithBlockHash(recentBlockHash).
+		AddInstructions(system.NewTransferInstruction(lamports, privateKey.PublicKey(), recipient).Build()).
+		Sign(context.Background(), privateKey)
 }
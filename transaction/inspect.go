@@ -0,0 +1,350 @@
+package transaction
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	solanarpc "github.com/gagliardetto/solana-go/rpc"
+)
+
+// Token2022ProgramID is the SPL Token-2022 program, decoded like the token program
+var Token2022ProgramID = solana.MustPublicKeyFromBase58("TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb")
+
+// TransferKind is the way an instruction moves funds
+type TransferKind string
+
+const (
+	// TransferSOL moves lamports with the system program, including the lamports of created accounts
+	TransferSOL TransferKind = "sol"
+	// TransferToken moves tokens between token accounts
+	TransferToken TransferKind = "token"
+	// TransferApprove lets a delegate move up to Amount tokens
+	TransferApprove TransferKind = "approve"
+	// TransferClose closes a token account, sending its lamports to To
+	TransferClose TransferKind = "close"
+)
+
+// Transfer is a movement of funds by a top level instruction
+type Transfer struct {
+	Kind    TransferKind
+	Program solana.PublicKey
+	// Mint is solana.SolMint for SOL, and zero when neither the instruction nor the transaction names it
+	Mint      solana.PublicKey
+	From      solana.PublicKey
+	To        solana.PublicKey
+	Authority solana.PublicKey
+	Amount    uint64
+	// Decimals is set by checked token instructions
+	Decimals *uint8
+}
+
+// Inspection describes a decoded transaction. Accounts loaded from lookup tables that weren't provided are zero
+// public keys; programs and signers can't be loaded from lookup tables, so they are always known.
+type Inspection struct {
+	Versioned    bool
+	FeePayer     solana.PublicKey
+	Signers      []solana.PublicKey
+	Programs     []solana.PublicKey
+	Accounts     []solana.PublicKey
+	LookupTables []solana.PublicKey
+	// Resolved is false when accounts of lookup tables couldn't be resolved
+	Resolved     bool
+	Instructions int
+
+	Transfers []Transfer
+	Tips      []Transfer
+	// Mints are the mints named by token instructions and associated token account creation
+	Mints []solana.PublicKey
+	// AuthorityChanges are the token accounts or mints whose authority is changed
+	AuthorityChanges []solana.PublicKey
+
+	ComputeUnitLimit uint32
+	// ComputeUnitPrice is in micro-lamports per compute unit
+	ComputeUnitPrice uint64
+
+	tx *solana.Transaction
+}
+
+// InspectOpts are the context needed to fully decode a transaction
+type InspectOpts struct {
+	// LookupTables resolves the accounts of v0 messages
+	LookupTables map[solana.PublicKey]solana.PublicKeySlice
+	// TipAccounts are the recipients of SOL transfers reported as tips
+	TipAccounts []solana.PublicKey
+}
+
+// PriorityFee is the priority fee in lamports, when a compute unit limit is set
+func (i *Inspection) PriorityFee() uint64 {
+	return uint64(i.ComputeUnitLimit) * i.ComputeUnitPrice / 1_000_000
+}
+
+// Transaction returns the decoded transaction
+func (i *Inspection) Transaction() *solana.Transaction {
+	return i.tx
+}
+
+// Inspect decodes a base64 transaction, legacy or v0, as returned by the Post* methods
+func Inspect(txBase64 string, opts InspectOpts) (*Inspection, error) {
+	tx, err := decodeBase64Tx(txBase64)
+	if err != nil {
+		return nil, err
+	}
+	return InspectTransaction(tx, opts)
+}
+
+func decodeBase64Tx(txBase64 string) (*solana.Transaction, error) {
+	txBytes, err := solanarpc.DataBytesOrJSONFromBase64(txBase64)
+	if err != nil {
+		return nil, err
+	}
+	return (&solanarpc.TransactionWithMeta{Transaction: txBytes}).GetTransaction()
+}
+
+// InspectTransaction describes a decoded transaction. The transaction is not modified.
+func InspectTransaction(tx *solana.Transaction, opts InspectOpts) (*Inspection, error) {
+	msg := tx.Message
+	if len(msg.AccountKeys) == 0 {
+		return nil, fmt.Errorf("transaction has no accounts")
+	}
+
+	accounts, resolved, err := allAccounts(msg, opts.LookupTables)
+	if err != nil {
+		return nil, err
+	}
+
+	ins := &Inspection{
+		Versioned:    msg.IsVersioned(),
+		FeePayer:     msg.AccountKeys[0],
+		Accounts:     accounts,
+		LookupTables: msg.GetAddressTableLookups().GetTableIDs(),
+		Resolved:     resolved,
+		Instructions: len(msg.Instructions),
+		tx:           tx,
+	}
+	for i := 0; i < int(msg.Header.NumRequiredSignatures) && i < len(msg.AccountKeys); i++ {
+		ins.Signers = append(ins.Signers, msg.AccountKeys[i])
+	}
+
+	d := decoder{ins: ins, accounts: accounts, tips: opts.TipAccounts, tokenMints: make(map[solana.PublicKey]solana.PublicKey)}
+	for _, instruction := range msg.Instructions {
+		if int(instruction.ProgramIDIndex) >= len(msg.AccountKeys) {
+			return nil, fmt.Errorf("program index %v out of range", instruction.ProgramIDIndex)
+		}
+		program := msg.AccountKeys[instruction.ProgramIDIndex]
+		ins.Programs = appendUnique(ins.Programs, program)
+		d.decode(program, instruction)
+	}
+
+	// attribute token transfers to the mints of accounts initialized in the same transaction
+	for i, transfer := range ins.Transfers {
+		if transfer.Mint.IsZero() && transfer.Kind != TransferSOL {
+			ins.Transfers[i].Mint = d.tokenMints[transfer.From]
+		}
+	}
+	return ins, nil
+}
+
+// allAccounts lists the static accounts followed by the writable and readonly accounts of the lookup tables, with
+// zero keys for lookups that can't be resolved
+func allAccounts(msg solana.Message, tables map[solana.PublicKey]solana.PublicKeySlice) ([]solana.PublicKey, bool, error) {
+	accounts := append([]solana.PublicKey{}, msg.AccountKeys...)
+	lookups := msg.GetAddressTableLookups()
+	if len(lookups) == 0 {
+		return accounts, true, nil
+	}
+
+	resolved := true
+	var writable, readonly []solana.PublicKey
+	resolve := func(table solana.PublicKeySlice, ok bool, idx uint8) (solana.PublicKey, error) {
+		if !ok {
+			resolved = false
+			return solana.PublicKey{}, nil
+		}
+		if int(idx) >= len(table) {
+			return solana.PublicKey{}, fmt.Errorf("lookup table index %v out of range", idx)
+		}
+		return table[idx], nil
+	}
+	for _, lookup := range lookups {
+		table, ok := tables[lookup.AccountKey]
+		for _, idx := range lookup.WritableIndexes {
+			key, err := resolve(table, ok, idx)
+			if err != nil {
+				return nil, false, fmt.Errorf("lookup table %v: %w", lookup.AccountKey, err)
+			}
+			writable = append(writable, key)
+		}
+		for _, idx := range lookup.ReadonlyIndexes {
+			key, err := resolve(table, ok, idx)
+			if err != nil {
+				return nil, false, fmt.Errorf("lookup table %v: %w", lookup.AccountKey, err)
+			}
+			readonly = append(readonly, key)
+		}
+	}
+	return append(append(accounts, writable...), readonly...), resolved, nil
+}
+
+type decoder struct {
+	ins      *Inspection
+	accounts []solana.PublicKey
+	tips     []solana.PublicKey
+	// tokenMints are the mints of token accounts initialized or created in the transaction
+	tokenMints map[solana.PublicKey]solana.PublicKey
+}
+
+func (d *decoder) account(instruction solana.CompiledInstruction, i int) solana.PublicKey {
+	if i >= len(instruction.Accounts) || int(instruction.Accounts[i]) >= len(d.accounts) {
+		return solana.PublicKey{}
+	}
+	return d.accounts[instruction.Accounts[i]]
+}
+
+func (d *decoder) decode(program solana.PublicKey, instruction solana.CompiledInstruction) {
+	data := []byte(instruction.Data)
+	switch {
+	case program.Equals(solana.SystemProgramID):
+		d.system(instruction, data)
+	case program.Equals(solana.TokenProgramID) || program.Equals(Token2022ProgramID):
+		d.token(program, instruction, data)
+	case program.Equals(solana.SPLAssociatedTokenAccountProgramID):
+		// create and create idempotent: payer, associated account, wallet, mint
+		if len(data) == 0 || data[0] <= 1 {
+			d.tokenAccount(d.account(instruction, 1), d.account(instruction, 3))
+		}
+	case program.Equals(solana.ComputeBudget):
+		switch {
+		case len(data) >= 5 && data[0] == 2:
+			d.ins.ComputeUnitLimit = binary.LittleEndian.Uint32(data[1:5])
+		case len(data) >= 9 && data[0] == 3:
+			d.ins.ComputeUnitPrice = binary.LittleEndian.Uint64(data[1:9])
+		}
+	}
+}
+
+func (d *decoder) system(instruction solana.CompiledInstruction, data []byte) {
+	if len(data) < 4 {
+		return
+	}
+	var (
+		lamports uint64
+		from     = d.account(instruction, 0)
+		to       = d.account(instruction, 1)
+	)
+	switch binary.LittleEndian.Uint32(data) {
+	case 0, 2: // create account, transfer
+		if len(data) < 12 {
+			return
+		}
+		lamports = binary.LittleEndian.Uint64(data[4:12])
+	case 3: // create account with seed: base, seed, lamports
+		if len(data) < 44 {
+			return
+		}
+		offset := 44 + binary.LittleEndian.Uint64(data[36:44])
+		if uint64(len(data)) < offset+8 {
+			return
+		}
+		lamports = binary.LittleEndian.Uint64(data[offset : offset+8])
+	case 11: // transfer with seed: from, base, to
+		if len(data) < 12 {
+			return
+		}
+		lamports = binary.LittleEndian.Uint64(data[4:12])
+		to = d.account(instruction, 2)
+	default:
+		return
+	}
+
+	transfer := Transfer{
+		Kind:      TransferSOL,
+		Program:   solana.SystemProgramID,
+		Mint:      solana.SolMint,
+		From:      from,
+		To:        to,
+		Authority: from,
+		Amount:    lamports,
+	}
+	if binary.LittleEndian.Uint32(data) == 11 {
+		transfer.Authority = d.account(instruction, 1)
+	}
+	if containsKey(d.tips, to) {
+		d.ins.Tips = append(d.ins.Tips, transfer)
+		return
+	}
+	d.ins.Transfers = append(d.ins.Transfers, transfer)
+}
+
+func (d *decoder) token(program solana.PublicKey, instruction solana.CompiledInstruction, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	amount := func() uint64 {
+		if len(data) < 9 {
+			return 0
+		}
+		return binary.LittleEndian.Uint64(data[1:9])
+	}
+	decimals := func() *uint8 {
+		if len(data) < 10 {
+			return nil
+		}
+		decimals := data[9]
+		return &decimals
+	}
+
+	switch data[0] {
+	case 1, 16, 18: // initialize account: account, mint
+		d.tokenAccount(d.account(instruction, 0), d.account(instruction, 1))
+	case 3: // transfer: source, destination, authority
+		d.ins.Transfers = append(d.ins.Transfers, Transfer{Kind: TransferToken, Program: program,
+			From: d.account(instruction, 0), To: d.account(instruction, 1), Authority: d.account(instruction, 2), Amount: amount()})
+	case 12: // transfer checked: source, mint, destination, authority
+		mint := d.account(instruction, 1)
+		d.ins.Mints = appendUnique(d.ins.Mints, mint)
+		d.ins.Transfers = append(d.ins.Transfers, Transfer{Kind: TransferToken, Program: program, Mint: mint,
+			From: d.account(instruction, 0), To: d.account(instruction, 2), Authority: d.account(instruction, 3),
+			Amount: amount(), Decimals: decimals()})
+	case 4: // approve: source, delegate, owner
+		d.ins.Transfers = append(d.ins.Transfers, Transfer{Kind: TransferApprove, Program: program,
+			From: d.account(instruction, 0), To: d.account(instruction, 1), Authority: d.account(instruction, 2), Amount: amount()})
+	case 13: // approve checked: source, mint, delegate, owner
+		mint := d.account(instruction, 1)
+		d.ins.Mints = appendUnique(d.ins.Mints, mint)
+		d.ins.Transfers = append(d.ins.Transfers, Transfer{Kind: TransferApprove, Program: program, Mint: mint,
+			From: d.account(instruction, 0), To: d.account(instruction, 2), Authority: d.account(instruction, 3),
+			Amount: amount(), Decimals: decimals()})
+	case 9: // close account: account, destination, owner
+		d.ins.Transfers = append(d.ins.Transfers, Transfer{Kind: TransferClose, Program: program,
+			From: d.account(instruction, 0), To: d.account(instruction, 1), Authority: d.account(instruction, 2)})
+	case 6: // set authority: account, current authority
+		d.ins.AuthorityChanges = appendUnique(d.ins.AuthorityChanges, d.account(instruction, 0))
+	}
+}
+
+func (d *decoder) tokenAccount(account, mint solana.PublicKey) {
+	if mint.IsZero() {
+		return
+	}
+	d.ins.Mints = appendUnique(d.ins.Mints, mint)
+	if !account.IsZero() {
+		d.tokenMints[account] = mint
+	}
+}
+
+func appendUnique(keys []solana.PublicKey, key solana.PublicKey) []solana.PublicKey {
+	if containsKey(keys, key) {
+		return keys
+	}
+	return append(keys, key)
+}
+
+func containsKey(keys []solana.PublicKey, key solana.PublicKey) bool {
+	for _, k := range keys {
+		if k.Equals(key) {
+			return true
+		}
+	}
+	return false
+}
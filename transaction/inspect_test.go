@@ -0,0 +1,189 @@
+package transaction
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testBlockHash = solana.MustHashFromBase58("A1xapHMk7Y9tj2NuVKw1ddKASsCce2M5EyD1xXo3RWr1")
+	testUSDC      = solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+	testDEX       = solana.MustPublicKeyFromBase58("675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8")
+)
+
+func newKey(t *testing.T) solana.PublicKey {
+	key, err := solana.NewRandomPrivateKey()
+	require.Nil(t, err)
+	return key.PublicKey()
+}
+
+func encode(t *testing.T, payer solana.PublicKey, instructions []solana.Instruction, opts ...solana.TransactionOption) string {
+	tx, err := solana.NewTransaction(instructions, testBlockHash, append(opts, solana.TransactionPayer(payer))...)
+	require.Nil(t, err)
+	return tx.MustToBase64()
+}
+
+func requireViolation(t *testing.T, err error, v Violation) {
+	require.ErrorIs(t, err, ErrUnsafeTransaction)
+	var verr *VerificationError
+	require.True(t, errors.As(err, &verr))
+	assert.Equal(t, v, verr.Violation)
+}
+
+func TestInspect(t *testing.T) {
+	owner := newKey(t)
+	tip := newKey(t)
+	pool := newKey(t)
+	source, _, err := solana.FindAssociatedTokenAddress(owner, testUSDC)
+	require.Nil(t, err)
+
+	txBase64 := encode(t, owner, []solana.Instruction{
+		computebudget.NewSetComputeUnitLimitInstruction(200_000).Build(),
+		computebudget.NewSetComputeUnitPriceInstruction(5_000).Build(),
+		associatedtokenaccount.NewCreateInstruction(owner, owner, testUSDC).Build(),
+		token.NewTransferInstruction(100, source, pool, owner, nil).Build(),
+		system.NewTransferInstruction(1_000, owner, tip).Build(),
+		&solana.GenericInstruction{ProgID: testDEX, AccountValues: solana.AccountMetaSlice{solana.Meta(pool).WRITE()}},
+	})
+
+	ins, err := Inspect(txBase64, InspectOpts{TipAccounts: []solana.PublicKey{tip}})
+	require.Nil(t, err)
+	assert.False(t, ins.Versioned)
+	assert.True(t, ins.Resolved)
+	assert.Equal(t, owner, ins.FeePayer)
+	assert.Equal(t, []solana.PublicKey{owner}, ins.Signers)
+	assert.Equal(t, 6, ins.Instructions)
+	assert.Contains(t, ins.Programs, testDEX)
+	assert.Equal(t, uint32(200_000), ins.ComputeUnitLimit)
+	assert.Equal(t, uint64(5_000), ins.ComputeUnitPrice)
+	assert.Equal(t, uint64(1_000), ins.PriorityFee())
+	assert.Equal(t, []solana.PublicKey{testUSDC}, ins.Mints)
+
+	require.Len(t, ins.Tips, 1)
+	assert.Equal(t, uint64(1_000), ins.Tips[0].Amount)
+	require.Len(t, ins.Transfers, 1)
+	assert.Equal(t, TransferToken, ins.Transfers[0].Kind)
+	assert.Equal(t, testUSDC, ins.Transfers[0].Mint)
+	assert.Equal(t, uint64(100), ins.Transfers[0].Amount)
+
+	policy := Policy{
+		InspectOpts:         InspectOpts{TipAccounts: []solana.PublicKey{tip}},
+		Owner:               owner,
+		Mints:               []solana.PublicKey{testUSDC},
+		MaxInput:            map[solana.PublicKey]uint64{testUSDC: 100},
+		AllowedPrograms:     []solana.PublicKey{testDEX},
+		MaxComputeUnitPrice: 10_000,
+		MaxTip:              1_000,
+	}
+	_, err = VerifyTx(txBase64, policy)
+	require.Nil(t, err)
+
+	strict := policy
+	strict.MaxInput = map[solana.PublicKey]uint64{testUSDC: 99}
+	_, err = VerifyTx(txBase64, strict)
+	requireViolation(t, err, ViolationAmount)
+
+	strict = policy
+	strict.Owner = newKey(t)
+	_, err = VerifyTx(txBase64, strict)
+	requireViolation(t, err, ViolationOwner)
+
+	strict = policy
+	strict.AllowedPrograms = []solana.PublicKey{newKey(t)}
+	_, err = VerifyTx(txBase64, strict)
+	requireViolation(t, err, ViolationProgram)
+
+	strict = policy
+	strict.Mints = []solana.PublicKey{solana.SolMint}
+	_, err = VerifyTx(txBase64, strict)
+	requireViolation(t, err, ViolationMint)
+
+	strict = policy
+	strict.MaxComputeUnitPrice = 1_000
+	_, err = VerifyTx(txBase64, strict)
+	requireViolation(t, err, ViolationComputePrice)
+
+	strict = policy
+	strict.MaxTip = 999
+	_, err = VerifyTx(txBase64, strict)
+	requireViolation(t, err, ViolationTip)
+
+	// without tip accounts, the tip is a SOL transfer of the owner
+	strict = policy
+	strict.TipAccounts = nil
+	strict.MaxInput = map[solana.PublicKey]uint64{solana.SolMint: 500}
+	_, err = VerifyTx(txBase64, strict)
+	requireViolation(t, err, ViolationAmount)
+}
+
+func TestVerifyAuthority(t *testing.T) {
+	owner := newKey(t)
+	account := newKey(t)
+	other := newKey(t)
+
+	txBase64 := encode(t, owner, []solana.Instruction{
+		token.NewApproveInstruction(10, account, other, owner, nil).Build(),
+	})
+	_, err := VerifyTx(txBase64, Policy{Owner: owner})
+	requireViolation(t, err, ViolationAuthority)
+
+	txBase64 = encode(t, owner, []solana.Instruction{
+		token.NewCloseAccountInstruction(account, other, owner, nil).Build(),
+	})
+	_, err = VerifyTx(txBase64, Policy{Owner: owner})
+	requireViolation(t, err, ViolationOwner)
+
+	txBase64 = encode(t, owner, []solana.Instruction{
+		token.NewCloseAccountInstruction(account, owner, owner, nil).Build(),
+	})
+	_, err = VerifyTx(txBase64, Policy{Owner: owner})
+	require.Nil(t, err)
+}
+
+func TestInspectV0(t *testing.T) {
+	owner := newKey(t)
+	recipient := newKey(t)
+	pool := newKey(t)
+	tableA := newKey(t)
+	tableB := newKey(t)
+	tables := map[solana.PublicKey]solana.PublicKeySlice{
+		tableA: {newKey(t), recipient},
+		tableB: {pool, newKey(t)},
+	}
+
+	txBase64 := encode(t, owner, []solana.Instruction{
+		system.NewTransferInstruction(5_000, owner, recipient).Build(),
+		&solana.GenericInstruction{ProgID: testDEX, AccountValues: solana.AccountMetaSlice{solana.Meta(pool)}},
+	}, solana.TransactionAddressTables(tables))
+
+	ins, err := Inspect(txBase64, InspectOpts{})
+	require.Nil(t, err)
+	assert.True(t, ins.Versioned)
+	assert.False(t, ins.Resolved)
+	assert.ElementsMatch(t, []solana.PublicKey{tableA, tableB}, ins.LookupTables)
+	require.Len(t, ins.Transfers, 1)
+	assert.True(t, ins.Transfers[0].To.IsZero())
+	assert.Equal(t, uint64(5_000), ins.Transfers[0].Amount)
+
+	_, err = VerifyTx(txBase64, Policy{Owner: owner, RequireResolved: true})
+	requireViolation(t, err, ViolationLookup)
+
+	policy := Policy{InspectOpts: InspectOpts{LookupTables: tables}, Owner: owner, RequireResolved: true}
+	ins, err = VerifyTx(txBase64, policy)
+	require.Nil(t, err)
+	assert.True(t, ins.Resolved)
+	assert.Contains(t, ins.Accounts, pool)
+	assert.Equal(t, recipient, ins.Transfers[0].To)
+
+	policy.MaxInput = map[solana.PublicKey]uint64{solana.SolMint: 4_999}
+	_, err = VerifyTx(txBase64, policy)
+	requireViolation(t, err, ViolationAmount)
+}
@@ -0,0 +1,223 @@
+package transaction
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	// lookupTableOverhead is the size of a table in a v0 message: its address and the lengths of its index lists
+	lookupTableOverhead = solana.PublicKeyLength + 2
+	// lookupSaving is the size saved by looking up an account instead of listing it: its key minus the index
+	lookupSaving = solana.PublicKeyLength - 1
+)
+
+// newTablePlaceholder stands for the table of LookupPlan.NewTable while measuring the transaction
+var newTablePlaceholder = solana.PublicKeyFromBytes(make([]byte, solana.PublicKeyLength))
+
+// LookupPlan is how a transaction uses lookup tables to stay under MaxTxSize
+type LookupPlan struct {
+	// Tables are the tables to look up, with their contents after the extensions
+	Tables map[solana.PublicKey]solana.PublicKeySlice
+	// Extend are the addresses to append to owned tables before sending the transaction
+	Extend map[solana.PublicKey]solana.PublicKeySlice
+	// NewTable are the addresses of a table to create, when the owned tables are full
+	NewTable solana.PublicKeySlice
+	// Size is the serialized size of the signed transaction
+	Size int
+}
+
+// ExtendInstructions are the instructions extending the owned tables of the plan
+func (p *LookupPlan) ExtendInstructions(authority, payer solana.PublicKey) []solana.Instruction {
+	var instructions []solana.Instruction
+	for _, table := range sortedKeys(p.Extend) {
+		instructions = append(instructions, ExtendLookupTableInstructions(table, authority, payer, p.Extend[table])...)
+	}
+	return instructions
+}
+
+// OptimizeLookupTables picks the candidate tables that make the transaction smallest. If that isn't small enough,
+// it moves just enough of the remaining accounts into the owned tables, which must be among the candidates, then into
+// a new table. Signers and programs can't be looked up and stay in the message.
+func OptimizeLookupTables(feePayer solana.PublicKey, instructions []solana.Instruction, candidates map[solana.PublicKey]solana.PublicKeySlice, owned []solana.PublicKey) (*LookupPlan, error) {
+	eligible := lookupEligible(feePayer, instructions)
+
+	plan := &LookupPlan{
+		Tables: make(map[solana.PublicKey]solana.PublicKeySlice),
+		Extend: make(map[solana.PublicKey]solana.PublicKeySlice),
+	}
+	covered := make(map[solana.PublicKey]bool)
+	for {
+		var (
+			best     solana.PublicKey
+			bestGain int
+		)
+		for _, table := range sortedKeys(candidates) {
+			if _, ok := plan.Tables[table]; ok {
+				continue
+			}
+			var accounts int
+			for _, address := range uniqueKeys(candidates[table]) {
+				if eligible[address] && !covered[address] {
+					accounts++
+				}
+			}
+			if gain := accounts*lookupSaving - lookupTableOverhead; gain > bestGain {
+				best, bestGain = table, gain
+			}
+		}
+		if bestGain == 0 {
+			break
+		}
+		plan.Tables[best] = candidates[best]
+		for _, address := range candidates[best] {
+			covered[address] = true
+		}
+	}
+
+	size, err := plan.measure(feePayer, instructions)
+	if err != nil {
+		return nil, err
+	}
+
+	var uncovered []solana.PublicKey
+	for _, address := range orderedAccounts(instructions) {
+		if eligible[address] && !covered[address] {
+			uncovered = append(uncovered, address)
+		}
+	}
+	owners := append([]solana.PublicKey{}, owned...)
+	for size > MaxTxSize && len(uncovered) > 0 {
+		address := uncovered[0]
+		uncovered = uncovered[1:]
+
+		added := false
+		for len(owners) > 0 && !added {
+			table := owners[0]
+			contents := candidates[table]
+			if _, ok := plan.Tables[table]; ok {
+				contents = plan.Tables[table]
+			}
+			if len(contents) >= MaxLookupTableAddresses {
+				owners = owners[1:]
+				continue
+			}
+			plan.Tables[table] = append(append(solana.PublicKeySlice{}, contents...), address)
+			plan.Extend[table] = append(plan.Extend[table], address)
+			added = true
+		}
+		if !added {
+			if len(plan.NewTable) >= MaxLookupTableAddresses {
+				break
+			}
+			plan.NewTable = append(plan.NewTable, address)
+		}
+
+		if size, err = plan.measure(feePayer, instructions); err != nil {
+			return nil, err
+		}
+	}
+	if size > MaxTxSize {
+		return nil, fmt.Errorf("%w: %v bytes with lookup tables, more than %v", ErrTransactionTooLarge, size, MaxTxSize)
+	}
+
+	plan.Size = size
+	for table, addresses := range plan.Extend {
+		if len(addresses) == 0 {
+			delete(plan.Extend, table)
+		}
+	}
+	return plan, nil
+}
+
+// OptimizeLookupTables plans the lookup tables of the composed transaction, with the tables already added among
+// the candidates. Apply the plan with AddLookupTables once its tables are extended.
+func (c *Composer) OptimizeLookupTables(candidates map[solana.PublicKey]solana.PublicKeySlice, owned []solana.PublicKey) (*LookupPlan, error) {
+	instructions, err := c.instructions()
+	if err != nil {
+		return nil, err
+	}
+	merged := make(map[solana.PublicKey]solana.PublicKeySlice, len(candidates)+len(c.tables))
+	for table, addresses := range c.tables {
+		merged[table] = addresses
+	}
+	for table, addresses := range candidates {
+		if len(addresses) > len(merged[table]) {
+			merged[table] = addresses
+		}
+	}
+	return OptimizeLookupTables(c.feePayer, instructions, merged, owned)
+}
+
+// measure builds the transaction of the plan and returns its size
+func (p *LookupPlan) measure(feePayer solana.PublicKey, instructions []solana.Instruction) (int, error) {
+	tables := make(map[solana.PublicKey]solana.PublicKeySlice, len(p.Tables)+1)
+	for table, addresses := range p.Tables {
+		tables[table] = addresses
+	}
+	if len(p.NewTable) > 0 {
+		tables[newTablePlaceholder] = p.NewTable
+	}
+
+	opts := []solana.TransactionOption{solana.TransactionPayer(feePayer)}
+	if len(tables) > 0 {
+		opts = append(opts, solana.TransactionAddressTables(tables))
+	}
+	tx, err := solana.NewTransaction(instructions, solana.Hash{}, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return txSize(tx)
+}
+
+// lookupEligible are the accounts of the instructions that can be looked up
+func lookupEligible(feePayer solana.PublicKey, instructions []solana.Instruction) map[solana.PublicKey]bool {
+	eligible := make(map[solana.PublicKey]bool)
+	for _, instruction := range instructions {
+		for _, account := range instruction.Accounts() {
+			eligible[account.PublicKey] = true
+		}
+	}
+	delete(eligible, feePayer)
+	for _, instruction := range instructions {
+		delete(eligible, instruction.ProgramID())
+		for _, account := range instruction.Accounts() {
+			if account.IsSigner {
+				delete(eligible, account.PublicKey)
+			}
+		}
+	}
+	return eligible
+}
+
+// orderedAccounts lists the accounts of the instructions once, in order of appearance
+func orderedAccounts(instructions []solana.Instruction) []solana.PublicKey {
+	var accounts []solana.PublicKey
+	for _, instruction := range instructions {
+		for _, account := range instruction.Accounts() {
+			accounts = appendUnique(accounts, account.PublicKey)
+		}
+	}
+	return accounts
+}
+
+func uniqueKeys(keys solana.PublicKeySlice) []solana.PublicKey {
+	var unique []solana.PublicKey
+	for _, key := range keys {
+		unique = appendUnique(unique, key)
+	}
+	return unique
+}
+
+func sortedKeys(tables map[solana.PublicKey]solana.PublicKeySlice) []solana.PublicKey {
+	keys := make([]solana.PublicKey, 0, len(tables))
+	for key := range tables {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
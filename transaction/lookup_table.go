@@ -0,0 +1,280 @@
+package transaction
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	addresslookuptable "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// MaxLookupTableAddresses is the capacity of a lookup table
+	MaxLookupTableAddresses = addresslookuptable.LOOKUP_TABLE_MAX_ADDRESSES
+	// MaxExtendAddresses is the most addresses one extend instruction can add within the transaction size limit
+	MaxExtendAddresses = 20
+)
+
+const (
+	lookupTableCreate uint32 = iota
+	lookupTableFreeze
+	lookupTableExtend
+	lookupTableDeactivate
+	lookupTableClose
+)
+
+// AddressLookupTableProgramID is the program owning address lookup tables
+var AddressLookupTableProgramID = solana.MustPublicKeyFromBase58("AddressLookupTab1e1111111111111111111111111")
+
+var (
+	ErrLookupTableNotFound = errors.New("lookup table not found")
+	ErrLookupTableIndex    = errors.New("lookup table index out of range")
+)
+
+// LookupTableAddress derives the address of the table created by authority with a recent slot
+func LookupTableAddress(authority solana.PublicKey, recentSlot uint64) (solana.PublicKey, uint8, error) {
+	slot := make([]byte, 8)
+	binary.LittleEndian.PutUint64(slot, recentSlot)
+	return solana.FindProgramAddress([][]byte{authority[:], slot}, AddressLookupTableProgramID)
+}
+
+// CreateLookupTableInstruction creates a table owned by authority. The recent slot must be one of the last 512 slots.
+func CreateLookupTableInstruction(authority, payer solana.PublicKey, recentSlot uint64) (solana.Instruction, solana.PublicKey, error) {
+	table, bump, err := LookupTableAddress(authority, recentSlot)
+	if err != nil {
+		return nil, solana.PublicKey{}, err
+	}
+
+	data := lookupTableData(lookupTableCreate, 9)
+	data = binary.LittleEndian.AppendUint64(data, recentSlot)
+	data = append(data, bump)
+	return &solana.GenericInstruction{
+		ProgID: AddressLookupTableProgramID,
+		AccountValues: solana.AccountMetaSlice{
+			solana.Meta(table).WRITE(),
+			solana.Meta(authority).SIGNER(),
+			solana.Meta(payer).WRITE().SIGNER(),
+			solana.Meta(solana.SystemProgramID),
+		},
+		DataBytes: data,
+	}, table, nil
+}
+
+// ExtendLookupTableInstructions appends addresses to a table, MaxExtendAddresses per instruction. Each instruction
+// should be sent in its own transaction, and the new addresses can be looked up from the next slot.
+func ExtendLookupTableInstructions(table, authority, payer solana.PublicKey, addresses solana.PublicKeySlice) []solana.Instruction {
+	var instructions []solana.Instruction
+	for start := 0; start < len(addresses); start += MaxExtendAddresses {
+		end := start + MaxExtendAddresses
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+		chunk := addresses[start:end]
+
+		data := lookupTableData(lookupTableExtend, 8+len(chunk)*solana.PublicKeyLength)
+		data = binary.LittleEndian.AppendUint64(data, uint64(len(chunk)))
+		for _, address := range chunk {
+			data = append(data, address[:]...)
+		}
+		instructions = append(instructions, &solana.GenericInstruction{
+			ProgID: AddressLookupTableProgramID,
+			AccountValues: solana.AccountMetaSlice{
+				solana.Meta(table).WRITE(),
+				solana.Meta(authority).SIGNER(),
+				solana.Meta(payer).WRITE().SIGNER(),
+				solana.Meta(solana.SystemProgramID),
+			},
+			DataBytes: data,
+		})
+	}
+	return instructions
+}
+
+// DeactivateLookupTableInstruction starts the cool down after which the table can be closed
+func DeactivateLookupTableInstruction(table, authority solana.PublicKey) solana.Instruction {
+	return &solana.GenericInstruction{
+		ProgID: AddressLookupTableProgramID,
+		AccountValues: solana.AccountMetaSlice{
+			solana.Meta(table).WRITE(),
+			solana.Meta(authority).SIGNER(),
+		},
+		DataBytes: lookupTableData(lookupTableDeactivate, 0),
+	}
+}
+
+// CloseLookupTableInstruction closes a deactivated table, returning its rent to recipient
+func CloseLookupTableInstruction(table, authority, recipient solana.PublicKey) solana.Instruction {
+	return &solana.GenericInstruction{
+		ProgID: AddressLookupTableProgramID,
+		AccountValues: solana.AccountMetaSlice{
+			solana.Meta(table).WRITE(),
+			solana.Meta(authority).SIGNER(),
+			solana.Meta(recipient).WRITE(),
+		},
+		DataBytes: lookupTableData(lookupTableClose, 0),
+	}
+}
+
+func lookupTableData(instruction uint32, size int) []byte {
+	data := make([]byte, 4, 4+size)
+	binary.LittleEndian.PutUint32(data, instruction)
+	return data
+}
+
+// AccountFetcher loads accounts, e.g. *rpc.Client
+type AccountFetcher interface {
+	GetAccountInfo(ctx context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error)
+}
+
+// LookupTableCacheOpts configures a LookupTableCache
+type LookupTableCacheOpts struct {
+	// TTL is how long fetched contents are used before they are fetched again. Zero keeps them until invalidated:
+	// tables are append only, so indexes past the known contents are fetched again anyway.
+	TTL time.Duration
+}
+
+type cachedLookupTable struct {
+	addresses solana.PublicKeySlice
+	fetched   time.Time
+}
+
+// LookupTableCache resolves the contents of lookup tables, e.g. for InspectOpts.LookupTables
+type LookupTableCache struct {
+	fetcher AccountFetcher
+	opts    LookupTableCacheOpts
+
+	mu     sync.Mutex
+	tables map[solana.PublicKey]cachedLookupTable
+}
+
+// NewLookupTableCache creates a cache fetching tables with fetcher. A nil fetcher only resolves tables added with Set.
+func NewLookupTableCache(fetcher AccountFetcher, opts LookupTableCacheOpts) *LookupTableCache {
+	return &LookupTableCache{
+		fetcher: fetcher,
+		opts:    opts,
+		tables:  make(map[solana.PublicKey]cachedLookupTable),
+	}
+}
+
+// Set seeds the cache, e.g. with the tables returned by PostJupiterSwapInstructions
+func (c *LookupTableCache) Set(tables map[solana.PublicKey]solana.PublicKeySlice) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for table, addresses := range tables {
+		if cached, ok := c.tables[table]; ok && len(cached.addresses) > len(addresses) {
+			continue
+		}
+		c.tables[table] = cachedLookupTable{addresses: addresses, fetched: now}
+	}
+}
+
+// Invalidate drops a table, e.g. after it was extended or closed
+func (c *LookupTableCache) Invalidate(table solana.PublicKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.tables, table)
+}
+
+// Get returns the contents of a table, fetching it if it isn't cached or has expired
+func (c *LookupTableCache) Get(ctx context.Context, table solana.PublicKey) (solana.PublicKeySlice, error) {
+	return c.get(ctx, table, 0)
+}
+
+// get returns the contents of a table holding at least minLength addresses
+func (c *LookupTableCache) get(ctx context.Context, table solana.PublicKey, minLength int) (solana.PublicKeySlice, error) {
+	c.mu.Lock()
+	cached, ok := c.tables[table]
+	c.mu.Unlock()
+
+	expired := c.opts.TTL > 0 && time.Since(cached.fetched) > c.opts.TTL
+	if ok && !expired && len(cached.addresses) >= minLength {
+		return cached.addresses, nil
+	}
+	if c.fetcher == nil {
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("%w: %v", ErrLookupTableNotFound, table)
+		case len(cached.addresses) < minLength:
+			return nil, fmt.Errorf("%w: table %v has %v addresses, index %v", ErrLookupTableIndex, table, len(cached.addresses), minLength-1)
+		}
+		return cached.addresses, nil
+	}
+
+	addresses, err := c.fetch(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) < minLength {
+		return nil, fmt.Errorf("%w: table %v has %v addresses, index %v", ErrLookupTableIndex, table, len(addresses), minLength-1)
+	}
+	return addresses, nil
+}
+
+func (c *LookupTableCache) fetch(ctx context.Context, table solana.PublicKey) (solana.PublicKeySlice, error) {
+	account, err := c.fetcher.GetAccountInfo(ctx, table)
+	if errors.Is(err, rpc.ErrNotFound) || (err == nil && (account == nil || account.Value == nil)) {
+		return nil, fmt.Errorf("%w: %v", ErrLookupTableNotFound, table)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch lookup table %v: %w", table, err)
+	}
+	if !account.Value.Owner.Equals(AddressLookupTableProgramID) {
+		return nil, fmt.Errorf("%w: %v is owned by %v", ErrLookupTableNotFound, table, account.Value.Owner)
+	}
+	state, err := addresslookuptable.DecodeAddressLookupTableState(account.GetBinary())
+	if err != nil {
+		return nil, fmt.Errorf("could not decode lookup table %v: %w", table, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tables[table] = cachedLookupTable{addresses: state.Addresses, fetched: time.Now()}
+	return state.Addresses, nil
+}
+
+// Resolve returns the contents of the tables a transaction looks up, fetching tables that are missing or too short for
+// its indexes
+func (c *LookupTableCache) Resolve(ctx context.Context, tx *solana.Transaction) (map[solana.PublicKey]solana.PublicKeySlice, error) {
+	tables := make(map[solana.PublicKey]solana.PublicKeySlice)
+	for _, lookup := range tx.Message.AddressTableLookups {
+		var highest int
+		for _, index := range append(append([]uint8{}, lookup.WritableIndexes...), lookup.ReadonlyIndexes...) {
+			if int(index)+1 > highest {
+				highest = int(index) + 1
+			}
+		}
+		addresses, err := c.get(ctx, lookup.AccountKey, highest)
+		if err != nil {
+			return nil, err
+		}
+		tables[lookup.AccountKey] = addresses
+	}
+	return tables, nil
+}
+
+// Inspect inspects a base64 transaction after resolving its lookup tables
+func (c *LookupTableCache) Inspect(ctx context.Context, txBase64 string, opts InspectOpts) (*Inspection, error) {
+	tx, err := decodeBase64Tx(txBase64)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := c.Resolve(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	for table, addresses := range opts.LookupTables {
+		if _, ok := tables[table]; !ok {
+			tables[table] = addresses
+		}
+	}
+	opts.LookupTables = tables
+	return InspectTransaction(tx, opts)
+}
@@ -0,0 +1,178 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	addresslookuptable "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeFetcher struct {
+	tables map[solana.PublicKey]solana.PublicKeySlice
+	calls  int
+}
+
+func (f *fakeFetcher) GetAccountInfo(_ context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	f.calls++
+	addresses, ok := f.tables[account]
+	if !ok {
+		return nil, rpc.ErrNotFound
+	}
+
+	var buf bytes.Buffer
+	state := addresslookuptable.AddressLookupTableState{TypeIndex: 1, DeactivationSlot: math.MaxUint64, Addresses: addresses}
+	if err := state.MarshalWithEncoder(bin.NewBinEncoder(&buf)); err != nil {
+		return nil, err
+	}
+	return &rpc.GetAccountInfoResult{Value: &rpc.Account{
+		Owner: AddressLookupTableProgramID,
+		Data:  rpc.DataBytesOrJSONFromBytes(buf.Bytes()),
+	}}, nil
+}
+
+func TestLookupTableInstructions(t *testing.T) {
+	authority := newKey(t)
+	payer := newKey(t)
+
+	create, table, err := CreateLookupTableInstruction(authority, payer, 1234)
+	require.Nil(t, err)
+	expected, bump, err := LookupTableAddress(authority, 1234)
+	require.Nil(t, err)
+	assert.Equal(t, expected, table)
+	assert.Equal(t, AddressLookupTableProgramID, create.ProgramID())
+	assert.Equal(t, table, create.Accounts()[0].PublicKey)
+
+	data, err := create.Data()
+	require.Nil(t, err)
+	require.Len(t, data, 13)
+	assert.Equal(t, uint32(0), binary.LittleEndian.Uint32(data))
+	assert.Equal(t, uint64(1234), binary.LittleEndian.Uint64(data[4:]))
+	assert.Equal(t, bump, data[12])
+
+	addresses := randomKeys(t, 45)
+	extends := ExtendLookupTableInstructions(table, authority, payer, addresses)
+	require.Len(t, extends, 3)
+	data, err = extends[2].Data()
+	require.Nil(t, err)
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(data))
+	assert.Equal(t, uint64(5), binary.LittleEndian.Uint64(data[4:]))
+	assert.Equal(t, addresses[40][:], data[12:44])
+
+	// an extend with the most addresses fits in a transaction
+	tx, err := solana.NewTransaction([]solana.Instruction{extends[0], CreateTraderAPIMemoInstruction("")}, testBlockHash, solana.TransactionPayer(payer))
+	require.Nil(t, err)
+	size, err := txSize(tx)
+	require.Nil(t, err)
+	assert.LessOrEqual(t, size, MaxTxSize)
+
+	data, err = DeactivateLookupTableInstruction(table, authority).Data()
+	require.Nil(t, err)
+	assert.Equal(t, []byte{3, 0, 0, 0}, data)
+
+	closeTable := CloseLookupTableInstruction(table, authority, payer)
+	data, err = closeTable.Data()
+	require.Nil(t, err)
+	assert.Equal(t, []byte{4, 0, 0, 0}, data)
+	assert.True(t, closeTable.Accounts()[2].IsWritable)
+}
+
+func TestLookupTableCache(t *testing.T) {
+	owner := newKey(t)
+	recipient := newKey(t)
+	table := newKey(t)
+	contents := append(randomKeys(t, 3), recipient)
+	fetcher := &fakeFetcher{tables: map[solana.PublicKey]solana.PublicKeySlice{table: contents}}
+
+	txBase64 := encode(t, owner, []solana.Instruction{
+		system.NewTransferInstruction(5_000, owner, recipient).Build(),
+	}, solana.TransactionAddressTables(map[solana.PublicKey]solana.PublicKeySlice{table: contents}))
+
+	ctx := context.Background()
+	cache := NewLookupTableCache(fetcher, LookupTableCacheOpts{})
+
+	// the seeded contents are too short for the transaction's index and are fetched again
+	cache.Set(map[solana.PublicKey]solana.PublicKeySlice{table: contents[:2]})
+	ins, err := cache.Inspect(ctx, txBase64, InspectOpts{})
+	require.Nil(t, err)
+	assert.True(t, ins.Resolved)
+	assert.Equal(t, recipient, ins.Transfers[0].To)
+	assert.Equal(t, 1, fetcher.calls)
+
+	addresses, err := cache.Get(ctx, table)
+	require.Nil(t, err)
+	assert.Equal(t, contents, addresses)
+	assert.Equal(t, 1, fetcher.calls)
+
+	cache.Invalidate(table)
+	_, err = cache.Get(ctx, table)
+	require.Nil(t, err)
+	assert.Equal(t, 2, fetcher.calls)
+
+	_, err = cache.Get(ctx, newKey(t))
+	require.ErrorIs(t, err, ErrLookupTableNotFound)
+
+	offline := NewLookupTableCache(nil, LookupTableCacheOpts{})
+	offline.Set(map[solana.PublicKey]solana.PublicKeySlice{table: contents[:2]})
+	_, err = offline.Inspect(ctx, txBase64, InspectOpts{})
+	require.ErrorIs(t, err, ErrLookupTableIndex)
+}
+
+func TestOptimizeLookupTables(t *testing.T) {
+	owner := newKey(t)
+	program := newKey(t)
+	accounts := randomKeys(t, 40)
+
+	metas := solana.AccountMetaSlice{solana.Meta(owner).WRITE().SIGNER()}
+	for _, account := range accounts {
+		metas = append(metas, solana.Meta(account).WRITE())
+	}
+	instructions := []solana.Instruction{&solana.GenericInstruction{ProgID: program, AccountValues: metas}}
+
+	// a table holding a few accounts isn't worth its overhead
+	useful := newKey(t)
+	useless := newKey(t)
+	candidates := map[solana.PublicKey]solana.PublicKeySlice{
+		useful:  append(randomKeys(t, 2), accounts[:30]...),
+		useless: {accounts[35]},
+	}
+	plan, err := OptimizeLookupTables(owner, instructions, candidates, nil)
+	require.Nil(t, err)
+	assert.Equal(t, map[solana.PublicKey]solana.PublicKeySlice{useful: candidates[useful]}, plan.Tables)
+	assert.Empty(t, plan.Extend)
+	assert.Empty(t, plan.NewTable)
+	assert.LessOrEqual(t, plan.Size, MaxTxSize)
+
+	// without tables, just enough accounts are moved into the owned table, then a new one
+	owned := newKey(t)
+	full := newKey(t)
+	candidates = map[solana.PublicKey]solana.PublicKeySlice{
+		owned: randomKeys(t, MaxLookupTableAddresses-2),
+		full:  randomKeys(t, MaxLookupTableAddresses),
+	}
+	plan, err = OptimizeLookupTables(owner, instructions, candidates, []solana.PublicKey{full, owned})
+	require.Nil(t, err)
+	assert.Equal(t, solana.PublicKeySlice(accounts[:2]), plan.Extend[owned])
+	assert.Len(t, plan.Tables[owned], MaxLookupTableAddresses)
+	assert.NotContains(t, plan.Tables, full)
+	assert.NotEmpty(t, plan.NewTable)
+	assert.Equal(t, solana.PublicKeySlice(accounts[2:2+len(plan.NewTable)]), plan.NewTable)
+	assert.LessOrEqual(t, plan.Size, MaxTxSize)
+	assert.Len(t, plan.ExtendInstructions(owner, owner), 1)
+
+	// signers can't be looked up
+	signers := solana.AccountMetaSlice{}
+	for _, account := range accounts {
+		signers = append(signers, solana.Meta(account).SIGNER())
+	}
+	_, err = OptimizeLookupTables(owner, []solana.Instruction{&solana.GenericInstruction{ProgID: program, AccountValues: signers}}, nil, nil)
+	require.ErrorIs(t, err, ErrTransactionTooLarge)
+}
@@ -30,6 +30,15 @@ func CreateTraderAPIMemoInstruction(msg string) solana.Instruction {
 	return instruction
 }
 
+// maxAccounts is the number of accounts instructions can reference with their one byte indices
+const maxAccounts = 256
+
+// MaxTxSize is the largest serialized transaction that fits in a packet
+const MaxTxSize = 1232
+
+// addMemo adds the memo instruction to a decoded message, legacy or v0. The memo program is a read-only unsigned
+// account, the last section of the static keys. Accounts of v0 lookup tables are indexed after the static keys, so
+// references to them move up by one.
 func addMemo(tx *solana.Transaction) error {
 	memoInstruction := CreateTraderAPIMemoInstruction("")
 	memoData, err := memoInstruction.Data()
@@ -38,15 +47,23 @@ func addMemo(tx *solana.Transaction) error {
 	}
 
 	cutoff := uint16(len(tx.Message.AccountKeys))
-	for _, instruction := range tx.Message.Instructions {
-		for i, accountIdx := range instruction.Accounts {
+	if int(cutoff)+tx.Message.NumLookups()+1 > maxAccounts {
+		return fmt.Errorf("transaction has too many accounts to add a memo")
+	}
+	for i := range tx.Message.Instructions {
+		instruction := &tx.Message.Instructions[i]
+		if instruction.ProgramIDIndex >= cutoff {
+			instruction.ProgramIDIndex++
+		}
+		for j, accountIdx := range instruction.Accounts {
 			if accountIdx >= cutoff {
-				instruction.Accounts[i] = accountIdx + 1
+				instruction.Accounts[j] = accountIdx + 1
 			}
 		}
 	}
 
 	tx.Message.AccountKeys = append(tx.Message.AccountKeys, memoInstruction.ProgramID())
+	tx.Message.Header.NumReadonlyUnsignedAccounts++
 	tx.Message.Instructions = append(tx.Message.Instructions, solana.CompiledInstruction{
 		ProgramIDIndex: cutoff,
 		Accounts:       nil,
@@ -69,10 +86,6 @@ func AddMemoAndSign(txBase64 string, privateKey solana.PrivateKey) (string, erro
 		return "", err
 	}
 
-	if len(solanaTx.Message.AccountKeys) >= 32 {
-		return "", fmt.Errorf("transaction has too many account keys")
-	}
-
 	for _, key := range solanaTx.Message.AccountKeys {
 		if key == TraderAPIMemoProgram {
 			return "", fmt.Errorf("transaction already has bloXroute memo instruction")
@@ -93,6 +106,9 @@ func AddMemoAndSign(txBase64 string, privateKey solana.PrivateKey) (string, erro
 	if err != nil {
 		return "", err
 	}
+	if len(txnBytes) > MaxTxSize {
+		return "", fmt.Errorf("transaction with memo is %v bytes, more than %v", len(txnBytes), MaxTxSize)
+	}
 
 	return base64.StdEncoding.EncodeToString(txnBytes), nil
 
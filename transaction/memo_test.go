@@ -1,6 +1,7 @@
 package transaction
 
 import (
+	"fmt"
 	"github.com/gagliardetto/solana-go"
 	solanarpc "github.com/gagliardetto/solana-go/rpc"
 	log "github.com/sirupsen/logrus"
@@ -57,3 +58,132 @@ func TestAddMemoToSerializedTxn(t *testing.T) {
 	require.Equal(t, TraderAPIMemoProgram, program)
 
 }
+
+func randomKeys(t *testing.T, n int) solana.PublicKeySlice {
+	keys := make(solana.PublicKeySlice, 0, n)
+	for i := 0; i < n; i++ {
+		keys = append(keys, solana.NewWallet().PublicKey())
+	}
+	return keys
+}
+
+// resolvedInstructions lists the program and accounts of each instruction, with their writability
+func resolvedInstructions(t *testing.T, tx *solana.Transaction, tables map[solana.PublicKey]solana.PublicKeySlice) [][]string {
+	msg := tx.Message
+	if msg.IsVersioned() {
+		require.NoError(t, msg.SetAddressTables(tables))
+	}
+	keys, err := msg.GetAllKeys()
+	require.NoError(t, err)
+
+	var instructions [][]string
+	for _, instruction := range msg.Instructions {
+		accounts := []string{keys[instruction.ProgramIDIndex].String()}
+		for _, idx := range instruction.Accounts {
+			writable, err := msg.IsWritable(keys[idx])
+			require.NoError(t, err)
+			accounts = append(accounts, fmt.Sprintf("%v:%v", keys[idx], writable))
+		}
+		instructions = append(instructions, accounts)
+	}
+	return instructions
+}
+
+func decodeTx(t *testing.T, txBase64 string) *solana.Transaction {
+	txBytes, err := solanarpc.DataBytesOrJSONFromBase64(txBase64)
+	require.NoError(t, err)
+	tx, err := (&solanarpc.TransactionWithMeta{Transaction: txBytes}).GetTransaction()
+	require.NoError(t, err)
+	return tx
+}
+
+func TestAddMemoAndSignV0(t *testing.T) {
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.NoError(t, err)
+	owner := privateKey.PublicKey()
+
+	tables := map[solana.PublicKey]solana.PublicKeySlice{}
+	var lookedUp solana.PublicKeySlice
+	for i := 0; i < 3; i++ {
+		table := randomKeys(t, 20)
+		tables[solana.NewWallet().PublicKey()] = table
+		lookedUp = append(lookedUp, table[3:8]...)
+	}
+	static := randomKeys(t, 4)
+	programs := randomKeys(t, 2)
+
+	var instructions []solana.Instruction
+	for i, program := range programs {
+		var accounts solana.AccountMetaSlice
+		accounts = append(accounts, solana.Meta(owner).WRITE().SIGNER(), solana.Meta(static[i]).WRITE(), solana.Meta(static[i+2]))
+		for j, key := range lookedUp {
+			if j%2 == i {
+				accounts = append(accounts, &solana.AccountMeta{PublicKey: key, IsWritable: j%3 == 0})
+			}
+		}
+		instructions = append(instructions, &solana.GenericInstruction{ProgID: program, AccountValues: accounts, DataBytes: []byte{byte(i)}})
+	}
+
+	tx, err := solana.NewTransaction(instructions, solana.MustHashFromBase58("A1xapHMk7Y9tj2NuVKw1ddKASsCce2M5EyD1xXo3RWr1"),
+		solana.TransactionPayer(owner), solana.TransactionAddressTables(tables))
+	require.NoError(t, err)
+	require.True(t, tx.Message.IsVersioned())
+	require.Len(t, tx.Message.AddressTableLookups, 3)
+
+	original := decodeTx(t, tx.MustToBase64())
+	before := resolvedInstructions(t, original, tables)
+
+	signed, err := AddMemoAndSign(tx.MustToBase64(), privateKey)
+	require.NoError(t, err)
+
+	result := decodeTx(t, signed)
+	require.True(t, result.Message.IsVersioned())
+	require.Equal(t, original.Message.AddressTableLookups, result.Message.AddressTableLookups)
+	require.Equal(t, append(original.Message.AccountKeys, TraderAPIMemoProgram), result.Message.AccountKeys)
+	require.Equal(t, original.Message.Header.NumRequiredSignatures, result.Message.Header.NumRequiredSignatures)
+	require.Equal(t, original.Message.Header.NumReadonlySignedAccounts, result.Message.Header.NumReadonlySignedAccounts)
+	require.Equal(t, original.Message.Header.NumReadonlyUnsignedAccounts+1, result.Message.Header.NumReadonlyUnsignedAccounts)
+
+	after := resolvedInstructions(t, result, tables)
+	require.Len(t, after, len(before)+1)
+	require.Equal(t, before, after[:len(before)])
+	require.Equal(t, []string{TraderAPIMemoProgram.String()}, after[len(before)])
+
+	require.NoError(t, result.VerifySignatures())
+
+	// signing again keeps the signature in place
+	resigned, err := SignTxWithPrivateKey(signed, privateKey)
+	require.NoError(t, err)
+	require.Equal(t, signed, resigned)
+}
+
+func TestAddMemoAndSignLegacy(t *testing.T) {
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.NoError(t, err)
+	owner := privateKey.PublicKey()
+
+	// 32 static accounts, the previous limit
+	accounts := solana.AccountMetaSlice{solana.Meta(owner).WRITE().SIGNER()}
+	for i, key := range randomKeys(t, 30) {
+		accounts = append(accounts, &solana.AccountMeta{PublicKey: key, IsWritable: i%2 == 0})
+	}
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		&solana.GenericInstruction{ProgID: solana.NewWallet().PublicKey(), AccountValues: accounts},
+	}, solana.MustHashFromBase58("A1xapHMk7Y9tj2NuVKw1ddKASsCce2M5EyD1xXo3RWr1"), solana.TransactionPayer(owner))
+	require.NoError(t, err)
+
+	before := resolvedInstructions(t, tx, nil)
+	signed, err := AddMemoAndSign(tx.MustToBase64(), privateKey)
+	require.NoError(t, err)
+
+	result := decodeTx(t, signed)
+	require.False(t, result.Message.IsVersioned())
+	require.Equal(t, tx.Message.Header.NumReadonlyUnsignedAccounts+1, result.Message.Header.NumReadonlyUnsignedAccounts)
+	writable, err := result.Message.IsWritable(TraderAPIMemoProgram)
+	require.NoError(t, err)
+	require.False(t, writable)
+
+	after := resolvedInstructions(t, result, nil)
+	require.Equal(t, before, after[:len(before)])
+	require.NoError(t, result.VerifySignatures())
+}
@@ -49,9 +49,18 @@ func SignTxWithPrivateKey(unsignedTxBase64 string, privateKey solana.PrivateKey)
 	return solanaTx.ToBase64()
 }
 
+// signTx signs the message, legacy or v0, and places the signature at the private key's position among the signers.
+// For a key that isn't a signer, it replaces the only missing or zero signature.
 func signTx(solanaTx *solana.Transaction, privateKey solana.PrivateKey) error {
 	signaturesRequired := int(solanaTx.Message.Header.NumRequiredSignatures)
 	signaturesPresent := len(solanaTx.Signatures)
+	if signaturesPresent > signaturesRequired {
+		return fmt.Errorf("transaction requires %v signatures and has %v signatures", signaturesRequired, signaturesPresent)
+	}
+	if signerIndex := signerIndex(solanaTx, privateKey.PublicKey()); signerIndex != -1 {
+		return placeSignature(solanaTx, privateKey, signerIndex)
+	}
+
 	if signaturesPresent != signaturesRequired {
 		if signaturesRequired-signaturesPresent == 1 {
 			return appendSignature(solanaTx, privateKey)
@@ -62,6 +71,35 @@ func signTx(solanaTx *solana.Transaction, privateKey solana.PrivateKey) error {
 	return replaceZeroSignature(solanaTx, privateKey)
 }
 
+func signerIndex(solanaTx *solana.Transaction, signer solana.PublicKey) int {
+	signaturesRequired := int(solanaTx.Message.Header.NumRequiredSignatures)
+	for i := 0; i < signaturesRequired && i < len(solanaTx.Message.AccountKeys); i++ {
+		if solanaTx.Message.AccountKeys[i].Equals(signer) {
+			return i
+		}
+	}
+	return -1
+}
+
+// placeSignature sets the signature at an index, with zero signatures for the signers that haven't signed yet
+func placeSignature(solanaTx *solana.Transaction, privateKey solana.PrivateKey, index int) error {
+	messageContent, err := solanaTx.Message.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("unable to encode message for signing: %w", err)
+	}
+
+	signedMessageContent, err := privateKey.Sign(messageContent)
+	if err != nil {
+		return fmt.Errorf("unable to sign message: %v", err)
+	}
+
+	for len(solanaTx.Signatures) <= index {
+		solanaTx.Signatures = append(solanaTx.Signatures, solana.Signature{})
+	}
+	solanaTx.Signatures[index] = signedMessageContent
+	return nil
+}
+
 func appendSignature(solanaTx *solana.Transaction, privateKey solana.PrivateKey) error {
 	messageContent, err := solanaTx.Message.MarshalBinary()
 	if err != nil {
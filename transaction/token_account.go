@@ -0,0 +1,96 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// createIdempotentInstruction is the index of CreateIdempotent in the associated token account program
+const createIdempotentInstruction = 1
+
+var ErrNotTokenMint = errors.New("account is not owned by the token or Token-2022 program")
+
+// TokenProgram returns the program owning mint, the token program or Token-2022. The token account instructions take
+// it, since an account of a Token-2022 mint has a different address and is only closed by Token-2022.
+func TokenProgram(ctx context.Context, fetcher AccountFetcher, mint solana.PublicKey) (solana.PublicKey, error) {
+	account, err := fetcher.GetAccountInfo(ctx, mint)
+	if errors.Is(err, rpc.ErrNotFound) || (err == nil && (account == nil || account.Value == nil)) {
+		return solana.PublicKey{}, fmt.Errorf("%w: mint %v not found", ErrNotTokenMint, mint)
+	}
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("could not fetch mint %v: %w", mint, err)
+	}
+	owner := account.Value.Owner
+	if !owner.Equals(solana.TokenProgramID) && !owner.Equals(Token2022ProgramID) {
+		return solana.PublicKey{}, fmt.Errorf("%w: mint %v is owned by %v", ErrNotTokenMint, mint, owner)
+	}
+	return owner, nil
+}
+
+// AssociatedTokenAddress derives the associated token account of owner for a mint of tokenProgram
+func AssociatedTokenAddress(owner, mint, tokenProgram solana.PublicKey) (solana.PublicKey, error) {
+	account, _, err := solana.FindProgramAddress([][]byte{owner[:], tokenProgram[:], mint[:]}, solana.SPLAssociatedTokenAccountProgramID)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("could not derive token account of %v for %v: %w", owner, mint, err)
+	}
+	return account, nil
+}
+
+// CreateAssociatedTokenAccountInstruction creates the associated token account of owner for a mint of tokenProgram,
+// paid by payer. The instruction succeeds if the account already exists. It returns the address of the account.
+func CreateAssociatedTokenAccountInstruction(payer, owner, mint, tokenProgram solana.PublicKey) (solana.Instruction, solana.PublicKey, error) {
+	account, err := AssociatedTokenAddress(owner, mint, tokenProgram)
+	if err != nil {
+		return nil, solana.PublicKey{}, err
+	}
+	return solana.NewInstruction(solana.SPLAssociatedTokenAccountProgramID, solana.AccountMetaSlice{
+		solana.Meta(payer).WRITE().SIGNER(),
+		solana.Meta(account).WRITE(),
+		solana.Meta(owner),
+		solana.Meta(mint),
+		solana.Meta(solana.SystemProgramID),
+		solana.Meta(tokenProgram),
+	}, []byte{createIdempotentInstruction}), account, nil
+}
+
+// WrapSOLInstructions creates the wrapped SOL account of owner if needed, then moves lamports into it
+func WrapSOLInstructions(owner solana.PublicKey, lamports uint64) ([]solana.Instruction, error) {
+	create, account, err := CreateAssociatedTokenAccountInstruction(owner, owner, solana.SolMint, solana.TokenProgramID)
+	if err != nil {
+		return nil, err
+	}
+	return []solana.Instruction{
+		create,
+		system.NewTransferInstruction(lamports, owner, account).Build(),
+		token.NewSyncNativeInstruction(account).Build(),
+	}, nil
+}
+
+// UnwrapSOLInstruction closes the wrapped SOL account of owner, returning its balance and rent to owner as SOL
+func UnwrapSOLInstruction(owner solana.PublicKey) (solana.Instruction, error) {
+	account, err := AssociatedTokenAddress(owner, solana.SolMint, solana.TokenProgramID)
+	if err != nil {
+		return nil, err
+	}
+	return CloseTokenAccountInstruction(account, owner, solana.TokenProgramID)
+}
+
+// CloseTokenAccountInstruction closes an empty token account of owner, returning its rent to owner. Token-2022 shares
+// the instruction of the token program.
+func CloseTokenAccountInstruction(account, owner, tokenProgram solana.PublicKey) (solana.Instruction, error) {
+	instruction := token.NewCloseAccountInstruction(account, owner, owner, nil).Build()
+	if tokenProgram.Equals(solana.TokenProgramID) {
+		return instruction, nil
+	}
+	data, err := instruction.Data()
+	if err != nil {
+		return nil, fmt.Errorf("could not encode close of %v: %w", account, err)
+	}
+	return solana.NewInstruction(tokenProgram, instruction.Accounts(), data), nil
+}
@@ -0,0 +1,82 @@
+package transaction
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMints maps accounts to their owner program
+type fakeMints map[solana.PublicKey]solana.PublicKey
+
+func (f fakeMints) GetAccountInfo(_ context.Context, account solana.PublicKey) (*rpc.GetAccountInfoResult, error) {
+	owner, ok := f[account]
+	if !ok {
+		return nil, rpc.ErrNotFound
+	}
+	return &rpc.GetAccountInfoResult{Value: &rpc.Account{Owner: owner}}, nil
+}
+
+func TestTokenAccountInstructions(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	mint := solana.NewWallet().PublicKey()
+	expected, _, err := solana.FindAssociatedTokenAddress(owner, mint)
+	require.Nil(t, err)
+
+	create, account, err := CreateAssociatedTokenAccountInstruction(owner, owner, mint, solana.TokenProgramID)
+	require.Nil(t, err)
+	assert.Equal(t, expected, account)
+	assert.Equal(t, solana.SPLAssociatedTokenAccountProgramID, create.ProgramID())
+	data, err := create.Data()
+	require.Nil(t, err)
+	assert.Equal(t, []byte{createIdempotentInstruction}, data)
+	accounts := create.Accounts()
+	require.Len(t, accounts, 6)
+	assert.True(t, accounts[0].IsSigner)
+	assert.Equal(t, account, accounts[1].PublicKey)
+	assert.Equal(t, mint, accounts[3].PublicKey)
+
+	// Token-2022 accounts have their own address and program
+	create, account, err = CreateAssociatedTokenAccountInstruction(owner, owner, mint, Token2022ProgramID)
+	require.Nil(t, err)
+	assert.NotEqual(t, expected, account)
+	assert.Equal(t, Token2022ProgramID, create.Accounts()[5].PublicKey)
+	closeToken2022, err := CloseTokenAccountInstruction(account, owner, Token2022ProgramID)
+	require.Nil(t, err)
+	assert.Equal(t, Token2022ProgramID, closeToken2022.ProgramID())
+	data, err = closeToken2022.Data()
+	require.Nil(t, err)
+	assert.Equal(t, []byte{token.Instruction_CloseAccount}, data)
+	assert.Equal(t, account, closeToken2022.Accounts()[0].PublicKey)
+
+	fetcher := fakeMints{mint: Token2022ProgramID, solana.SolMint: solana.TokenProgramID, owner: solana.SystemProgramID}
+	program, err := TokenProgram(context.Background(), fetcher, mint)
+	require.Nil(t, err)
+	assert.Equal(t, Token2022ProgramID, program)
+	_, err = TokenProgram(context.Background(), fetcher, owner)
+	assert.ErrorIs(t, err, ErrNotTokenMint)
+	_, err = TokenProgram(context.Background(), fetcher, solana.NewWallet().PublicKey())
+	assert.ErrorIs(t, err, ErrNotTokenMint)
+
+	wrap, err := WrapSOLInstructions(owner, 1_000_000)
+	require.Nil(t, err)
+	require.Len(t, wrap, 3)
+	assert.Equal(t, solana.SystemProgramID, wrap[1].ProgramID())
+	wsol, _, err := solana.FindAssociatedTokenAddress(owner, solana.SolMint)
+	require.Nil(t, err)
+	assert.Equal(t, wsol, wrap[1].Accounts()[1].PublicKey)
+	assert.Equal(t, solana.TokenProgramID, wrap[2].ProgramID())
+
+	unwrap, err := UnwrapSOLInstruction(owner)
+	require.Nil(t, err)
+	closeInstruction, ok := unwrap.(*token.Instruction)
+	require.True(t, ok)
+	assert.Equal(t, uint8(token.Instruction_CloseAccount), closeInstruction.TypeID.Uint8())
+	assert.Equal(t, wsol, unwrap.Accounts()[0].PublicKey)
+	assert.Equal(t, owner, unwrap.Accounts()[1].PublicKey)
+}
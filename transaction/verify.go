@@ -0,0 +1,185 @@
+package transaction
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// ErrUnsafeTransaction is wrapped by every VerificationError
+var ErrUnsafeTransaction = errors.New("transaction refused before signing")
+
+// Violation is the check a transaction failed
+type Violation string
+
+const (
+	ViolationOwner        Violation = "owner"
+	ViolationProgram      Violation = "program"
+	ViolationMint         Violation = "mint"
+	ViolationAmount       Violation = "amount"
+	ViolationAuthority    Violation = "authority"
+	ViolationComputePrice Violation = "compute price"
+	ViolationTip          Violation = "tip"
+	ViolationLookup       Violation = "lookup table"
+)
+
+// VerificationError is returned for a transaction that doesn't match its policy
+type VerificationError struct {
+	Violation Violation
+	Detail    string
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("%v: %v: %v", ErrUnsafeTransaction, e.Violation, e.Detail)
+}
+
+func (e *VerificationError) Unwrap() error {
+	return ErrUnsafeTransaction
+}
+
+func violation(v Violation, format string, args ...interface{}) error {
+	return &VerificationError{Violation: v, Detail: fmt.Sprintf(format, args...)}
+}
+
+// infrastructurePrograms are allowed in addition to Policy.AllowedPrograms
+var infrastructurePrograms = []solana.PublicKey{
+	solana.SystemProgramID,
+	solana.TokenProgramID,
+	Token2022ProgramID,
+	solana.SPLAssociatedTokenAccountProgramID,
+	solana.ComputeBudget,
+	TraderAPIMemoProgram,
+}
+
+// Policy is what a transaction built for a request may do. Zero values are not checked. Only top level instructions
+// are decoded: transfers made by the programs they invoke are the programs' responsibility, so AllowedPrograms is the
+// main defense against unexpected transactions.
+type Policy struct {
+	InspectOpts
+
+	// Owner must be the fee payer and a signer. Funds moved with the owner's authority count toward MaxInput, token
+	// accounts it closes must return their lamports to it, and it may not approve delegates or change authorities.
+	Owner solana.PublicKey
+	// Mints are the only mints token instructions may name
+	Mints []solana.PublicKey
+	// MaxInput limits the raw amount of each mint moved with the owner's authority. SOL is keyed by solana.SolMint, in
+	// lamports, and includes the rent of created accounts.
+	MaxInput map[solana.PublicKey]uint64
+	// AllowedPrograms are the programs that may be invoked, besides the system, token, associated token, compute
+	// budget and memo programs
+	AllowedPrograms []solana.PublicKey
+	// MaxComputeUnitPrice is in micro-lamports per compute unit
+	MaxComputeUnitPrice uint64
+	// MaxTip limits the lamports sent to InspectOpts.TipAccounts
+	MaxTip uint64
+	// RequireResolved refuses v0 transactions whose lookup tables aren't in InspectOpts.LookupTables
+	RequireResolved bool
+}
+
+// VerifyTx inspects a base64 transaction and checks it against a policy
+func VerifyTx(txBase64 string, policy Policy) (*Inspection, error) {
+	ins, err := Inspect(txBase64, policy.InspectOpts)
+	if err != nil {
+		return nil, fmt.Errorf("could not inspect transaction: %w", err)
+	}
+	return ins, ins.Verify(policy)
+}
+
+// Verify checks the transaction against a policy, returning a *VerificationError for the first mismatch
+func (i *Inspection) Verify(policy Policy) error {
+	if policy.RequireResolved && !i.Resolved {
+		return violation(ViolationLookup, "accounts of lookup tables %v are unknown", i.LookupTables)
+	}
+
+	owner := policy.Owner
+	if !owner.IsZero() {
+		if !i.FeePayer.Equals(owner) {
+			return violation(ViolationOwner, "fee payer is %v, not %v", i.FeePayer, owner)
+		}
+		if !containsKey(i.Signers, owner) {
+			return violation(ViolationOwner, "%v is not a signer", owner)
+		}
+		if len(i.AuthorityChanges) > 0 {
+			return violation(ViolationAuthority, "authority of %v is changed", i.AuthorityChanges[0])
+		}
+	}
+
+	if len(policy.AllowedPrograms) > 0 {
+		for _, program := range i.Programs {
+			if !containsKey(policy.AllowedPrograms, program) && !containsKey(infrastructurePrograms, program) {
+				return violation(ViolationProgram, "program %v is not allowed", program)
+			}
+		}
+	}
+
+	if len(policy.Mints) > 0 {
+		for _, mint := range i.Mints {
+			if !containsKey(policy.Mints, mint) {
+				return violation(ViolationMint, "mint %v is not allowed", mint)
+			}
+		}
+	}
+
+	spent := make(map[solana.PublicKey]uint64)
+	for _, transfer := range i.Transfers {
+		if owner.IsZero() || !transfer.Authority.Equals(owner) {
+			continue
+		}
+		switch transfer.Kind {
+		case TransferClose:
+			if !transfer.To.Equals(owner) {
+				return violation(ViolationOwner, "account %v is closed to %v", transfer.From, transfer.To)
+			}
+			continue
+		case TransferApprove:
+			return violation(ViolationAuthority, "delegate %v is approved for %v", transfer.To, transfer.From)
+		case TransferToken:
+			mint := transfer.Mint
+			if mint.IsZero() {
+				mint = ownerMint(owner, transfer.From, policy.Mints)
+			}
+			if mint.IsZero() && (len(policy.Mints) > 0 || len(policy.MaxInput) > 0) {
+				return violation(ViolationMint, "mint of transfer from %v is unknown", transfer.From)
+			}
+			if len(policy.Mints) > 0 && !containsKey(policy.Mints, mint) {
+				return violation(ViolationMint, "transfer of mint %v is not allowed", mint)
+			}
+			transfer.Mint = mint
+		}
+		spent[transfer.Mint] += transfer.Amount
+	}
+	for mint, max := range policy.MaxInput {
+		if spent[mint] > max {
+			return violation(ViolationAmount, "transfers %v of %v, more than %v", spent[mint], mint, max)
+		}
+	}
+
+	if policy.MaxComputeUnitPrice > 0 && i.ComputeUnitPrice > policy.MaxComputeUnitPrice {
+		return violation(ViolationComputePrice, "compute unit price %v, more than %v", i.ComputeUnitPrice, policy.MaxComputeUnitPrice)
+	}
+
+	if policy.MaxTip > 0 {
+		var tips uint64
+		for _, tip := range i.Tips {
+			tips += tip.Amount
+		}
+		if tips > policy.MaxTip {
+			return violation(ViolationTip, "tips %v lamports, more than %v", tips, policy.MaxTip)
+		}
+	}
+	return nil
+}
+
+// ownerMint returns the mint of one of the owner's associated token accounts
+func ownerMint(owner, account solana.PublicKey, mints []solana.PublicKey) solana.PublicKey {
+	for _, mint := range append([]solana.PublicKey{solana.SolMint}, mints...) {
+		for _, program := range []solana.PublicKey{solana.TokenProgramID, Token2022ProgramID} {
+			address, _, err := solana.FindProgramAddress([][]byte{owner[:], program[:], mint[:]}, solana.SPLAssociatedTokenAccountProgramID)
+			if err == nil && address.Equals(account) {
+				return mint
+			}
+		}
+	}
+	return solana.PublicKey{}
+}
@@ -13,6 +13,9 @@ const (
 
 // CreateBloxrouteTipTransactionToUseBundles creates a transaction you can use to when using PostSubmitBundle endpoints.
 // This transaction should be the LAST transaction in your submission bundle
+//
+// Deprecated: use transaction.NewTxBuilder with WithTip, which adds the tip to your own transaction along with the
+// Trader API memo and compute budget.
 func CreateBloxrouteTipTransactionToUseBundles(privateKey solana.PrivateKey, tipAmount uint64, recentBlockHash solana.Hash) (*solana.Transaction, error) {
 	recipient := solana.MustPublicKeyFromBase58(BloxrouteTipAddress)
 